@@ -0,0 +1,50 @@
+package addressbook
+
+import (
+	"errors"
+	"time"
+
+	"go.sia.tech/core/types"
+)
+
+var (
+	// ErrNotFound is returned when an address book entry is not found.
+	ErrNotFound = errors.New("address book entry not found")
+	// ErrExists is returned when adding an address that is already in the
+	// address book.
+	ErrExists = errors.New("address already in address book")
+)
+
+type (
+	// An ID is a unique identifier for an address book entry.
+	ID int64
+
+	// An Entry is a labeled external destination address.
+	Entry struct {
+		ID        ID            `json:"id"`
+		Address   types.Address `json:"address"`
+		Label     string        `json:"label"`
+		Verified  bool          `json:"verified"`
+		CreatedAt time.Time     `json:"createdAt"`
+		UpdatedAt time.Time     `json:"updatedAt"`
+	}
+
+	// A Store persists address book entries.
+	Store interface {
+		// AddressBookEntries returns a paginated list of address book
+		// entries sorted by creation time, ASC.
+		AddressBookEntries(limit, offset int) ([]Entry, error)
+		// AddressBookEntry returns the entry with the given ID. If the
+		// entry is not found, [ErrNotFound] is returned.
+		AddressBookEntry(ID) (Entry, error)
+		// AddAddressBookEntry adds a new entry to the address book. If the
+		// address is already in the address book, [ErrExists] is returned.
+		AddAddressBookEntry(addr types.Address, label string, verified bool) (Entry, error)
+		// UpdateAddressBookEntry updates the label and verification status
+		// of an entry. If the entry is not found, [ErrNotFound] is returned.
+		UpdateAddressBookEntry(id ID, label string, verified bool) (Entry, error)
+		// DeleteAddressBookEntry removes an entry from the address book. If
+		// the entry is not found, [ErrNotFound] is returned.
+		DeleteAddressBookEntry(ID) error
+	}
+)
@@ -1,18 +1,48 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
+	"go.sia.tech/vaultd/addressbook"
+	"go.sia.tech/vaultd/apitoken"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/importkey"
+	"go.sia.tech/vaultd/internal/bip39"
 	"go.sia.tech/vaultd/internal/siad"
+	"go.sia.tech/vaultd/notify"
+	"go.sia.tech/vaultd/offline"
 	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/snapshot"
 	"go.sia.tech/vaultd/vault"
+	"go.sia.tech/vaultd/webhooks"
 	"go.uber.org/zap"
 	"lukechampine.com/frand"
 )
@@ -25,7 +55,7 @@ func (c *chain) TipState(ctx context.Context) (consensus.State, error) {
 	return c.cs, nil
 }
 
-func startServer(tb testing.TB, chain Chain, secret string) (client *Client) {
+func startServer(tb testing.TB, chain Chain, secret string, opts ...ServerOption) (client *Client) {
 	tb.Helper()
 	log := zap.NewNop()
 
@@ -50,7 +80,7 @@ func startServer(tb testing.TB, chain Chain, secret string) (client *Client) {
 	}
 
 	s := &http.Server{
-		Handler: Handler(chain, vault, log.Named("api")),
+		Handler: Handler(chain, vault, log.Named("api"), append([]ServerOption{WithAddressBook(store)}, opts...)...),
 	}
 	tb.Cleanup(func() { s.Close() })
 	go func() {
@@ -153,6 +183,301 @@ func TestAddSiadSeed(t *testing.T) {
 	}
 }
 
+func TestValidatePhrase(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	bip39Phrase := wallet.NewSeedPhrase()
+	longPhrase := "hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length"
+	siadPhrase := "mocked southern dehydrate unusual navy pegs aided ruined festival yearbook total building wife greater befit drunk judge thwart erosion hefty saucepan hijack request welders bomb remedy each sayings actress"
+
+	firstKey := func(phrase string) types.PublicKey {
+		t.Helper()
+		var seed [32]byte
+		if _, err := seedFromPhrase(&seed, phrase); err != nil {
+			t.Fatal(err)
+		}
+		return wallet.KeyFromSeed(&seed, 0).PublicKey()
+	}
+
+	for _, test := range []struct {
+		phrase string
+		typ    PhraseType
+		lang   string
+	}{
+		{bip39Phrase, PhraseTypeBIP39, "english"},
+		{longPhrase, PhraseTypeBIP39, "english"},
+		{siadPhrase, PhraseTypeSiad, "english"},
+	} {
+		resp, err := client.ValidatePhrase(context.Background(), test.phrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pk := firstKey(test.phrase)
+		switch {
+		case !resp.Valid:
+			t.Fatalf("%s: expected valid phrase, got %q", test.typ, resp.Error)
+		case resp.Type != test.typ:
+			t.Fatalf("expected type %q, got %q", test.typ, resp.Type)
+		case resp.Language != test.lang:
+			t.Fatalf("%s: expected language %q, got %q", test.typ, test.lang, resp.Language)
+		case resp.Fingerprint != seedFingerprint(pk):
+			t.Fatalf("%s: expected fingerprint %q, got %q", test.typ, seedFingerprint(pk), resp.Fingerprint)
+		case resp.Address != types.StandardUnlockHash(pk):
+			t.Fatalf("%s: expected address %s, got %s", test.typ, types.StandardUnlockHash(pk), resp.Address)
+		}
+	}
+
+	// unknown words are reported by position
+	words := strings.Fields(bip39Phrase)
+	words[3] = "notaword"
+	resp, err := client.ValidatePhrase(context.Background(), strings.Join(words, " "))
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Valid || resp.Type != PhraseTypeBIP39 || !slices.Equal(resp.UnknownWords, []int{3}) {
+		t.Fatalf("unexpected response %+v", resp)
+	} else if resp.Fingerprint != "" {
+		t.Fatal("expected no fingerprint for an invalid phrase")
+	}
+
+	// the checksum of zero entropy is "about", not "abandon"
+	resp, err = client.ValidatePhrase(context.Background(), strings.TrimSpace(strings.Repeat("abandon ", 12)))
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Valid || len(resp.UnknownWords) != 0 || resp.Error == "" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	resp, err = client.ValidatePhrase(context.Background(), "foo bar baz")
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Valid || resp.Type != "" || resp.WordCount != 3 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	// the phrase is never stored
+	seeds, err := client.Seeds(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 0 {
+		t.Fatalf("expected no seeds, got %d", len(seeds))
+	}
+}
+
+func TestPhraseLanguages(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	if _, err := client.NewPhrase(context.Background(), "klingon"); err == nil {
+		t.Fatal("expected an error for an unknown language")
+	}
+
+	resp, err := client.NewPhrase(context.Background(), "spanish")
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Language != "spanish" {
+		t.Fatalf("expected spanish, got %q", resp.Language)
+	}
+
+	// generating a phrase does not add a seed
+	seeds, err := client.Seeds(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 0 {
+		t.Fatalf("expected no seeds, got %d", len(seeds))
+	}
+
+	seed, err := client.AddSeed(context.Background(), resp.Phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if seed.Language != "spanish" {
+		t.Fatalf("expected spanish, got %q", seed.Language)
+	}
+
+	// the language is recorded in the seed's metadata
+	meta, err := client.Seed(context.Background(), seed.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if meta.Language != "spanish" {
+		t.Fatalf("expected spanish, got %q", meta.Language)
+	}
+
+	// the same entropy in English is the same seed and keeps its language
+	entropy, _, err := bip39.DecodePhrase(resp.Phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	english, err := bip39.EncodePhrase(entropy, bip39.English)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup, err := client.AddSeed(context.Background(), english)
+	if err != nil {
+		t.Fatal(err)
+	} else if dup.ID != seed.ID {
+		t.Fatalf("expected seed %d, got %d", seed.ID, dup.ID)
+	} else if dup.Language != "spanish" {
+		t.Fatalf("expected spanish, got %q", dup.Language)
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	// the phrase is only returned once, so the caller must confirm
+	err := client.c.POST(context.Background(), "/phrases/generate", SeedGenerateRequest{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "confirm") {
+		t.Fatalf("expected confirmation error, got %v", err)
+	} else if _, err := client.GenerateSeed(context.Background(), "klingon"); err == nil {
+		t.Fatal("expected an error for an unknown language")
+	}
+
+	resp, err := client.GenerateSeed(context.Background(), "french")
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Seed.Language != "french" {
+		t.Fatalf("expected french, got %q", resp.Seed.Language)
+	} else if _, lang, err := bip39.DecodePhrase(resp.Phrase); err != nil {
+		t.Fatal(err)
+	} else if lang != bip39.French {
+		t.Fatalf("expected french phrase, got %q", lang)
+	}
+
+	// the seed is stored and derived from the returned phrase
+	seeds, err := client.Seeds(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 1 || seeds[0].ID != resp.Seed.ID {
+		t.Fatalf("expected seed %d, got %v", resp.Seed.ID, seeds)
+	}
+	var seed [32]byte
+	if _, err := bip39.SeedFromPhrase(&seed, resp.Phrase); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), resp.Seed.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if keys[0].PublicKey != wallet.KeyFromSeed(&seed, 0).PublicKey() {
+		t.Fatal("expected the key to be derived from the returned phrase")
+	} else if dup, err := client.AddSeed(context.Background(), resp.Phrase); err != nil {
+		t.Fatal(err)
+	} else if dup.ID != resp.Seed.ID {
+		t.Fatalf("expected seed %d, got %d", resp.Seed.ID, dup.ID)
+	}
+}
+
+func TestGenerateKeysCount(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(id vault.SeedID, count uint64) int {
+		t.Helper()
+		buf, err := json.Marshal(SeedDeriveRequest{Count: count})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(fmt.Sprintf("%s/seeds/%d/keys", client.c.baseURL, id), "application/json", bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post(meta.ID, 0); code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for no keys, got %d", code)
+	} else if code := post(meta.ID, maxDeriveCount+1); code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for too many keys, got %d", code)
+	} else if code := post(meta.ID+1, 1); code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a missing seed, got %d", code)
+	}
+
+	// rejected requests must not reserve any indices
+	if resp, err := client.SeedDerivation(context.Background(), meta.ID); err != nil {
+		t.Fatal(err)
+	} else if resp.NextIndex != 0 {
+		t.Fatalf("expected next index 0, got %d", resp.NextIndex)
+	} else if code := post(meta.ID, maxDeriveCount); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+}
+
+func TestSeedKeysPolicyType(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v2Keys, err := client.GenerateKeys(context.Background(), meta.ID, 3, KeysWithPolicyType(PolicyTypePublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := client.SeedKeys(context.Background(), meta.ID, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Keys) != len(v2Keys) {
+		t.Fatalf("expected %d keys, got %d", len(v2Keys), len(page.Keys))
+	} else if page.Total != len(v2Keys) {
+		t.Fatalf("expected %d total keys, got %d", len(v2Keys), page.Total)
+	}
+	v1Keys := page.Keys
+
+	for i := range v2Keys {
+		pk := v2Keys[i].PublicKey
+		if v1Keys[i].PublicKey != pk {
+			t.Fatalf("expected key %d to be %v, got %v", i, pk, v1Keys[i].PublicKey)
+		} else if v1Keys[i].Address != types.StandardUnlockHash(pk) {
+			t.Fatalf("expected v1 address %v, got %v", types.StandardUnlockHash(pk), v1Keys[i].Address)
+		} else if v2Keys[i].Address != types.PolicyPublicKey(pk).Address() {
+			t.Fatalf("expected v2 address %v, got %v", types.PolicyPublicKey(pk).Address(), v2Keys[i].Address)
+		} else if v1Keys[i].Address == v2Keys[i].Address {
+			t.Fatal("expected v1 and v2 addresses to differ")
+		} else if v1Keys[i].V1Address != v1Keys[i].Address || v1Keys[i].V2Address != v2Keys[i].Address {
+			t.Fatal("expected both addresses to be reported")
+		}
+
+		for _, addr := range []types.Address{v1Keys[i].Address, v2Keys[i].Address} {
+			resp, err := client.AddressKey(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			} else if resp.SeedID != meta.ID || resp.Index != uint64(i) {
+				t.Fatalf("expected seed %d index %d, got seed %d index %d", meta.ID, i, resp.SeedID, resp.Index)
+			} else if resp.Key.PublicKey != pk || resp.Key.Address != addr {
+				t.Fatalf("expected key %v with address %v, got %v with %v", pk, addr, resp.Key.PublicKey, resp.Key.Address)
+			}
+		}
+	}
+
+	if _, err := client.AddressKey(context.Background(), frand.Entropy256()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	page, err = client.SeedKeys(context.Background(), meta.ID, 0, 100, KeysWithPolicyType(PolicyTypePublicKey))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(page.Keys, v2Keys) {
+		t.Fatal("expected listed keys to match derived keys")
+	}
+
+	// the total is every key of the seed, not just the page
+	page, err = client.SeedKeys(context.Background(), meta.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Keys) != 1 || page.Keys[0].PublicKey != v2Keys[1].PublicKey {
+		t.Fatalf("expected key 1, got %v", page.Keys)
+	} else if page.Total != len(v2Keys) {
+		t.Fatalf("expected %d total keys, got %d", len(v2Keys), page.Total)
+	}
+
+	if _, err := client.SeedKeys(context.Background(), meta.ID, 0, 100, KeysWithPolicyType("foo")); err == nil || !strings.Contains(err.Error(), "unknown policy type") {
+		t.Fatalf("expected unknown policy type error, got %v", err)
+	}
+}
+
 func TestSignV1(t *testing.T) {
 	client := startServer(t, &chain{}, "foo bar baz")
 
@@ -229,6 +554,198 @@ func TestSignV1(t *testing.T) {
 	}
 }
 
+func TestClientHelpers(t *testing.T) {
+	client := startServer(t, &chain{}, "")
+
+	unlocked := make(chan error, 1)
+	go func() {
+		unlocked <- client.WaitForUnlock(context.Background())
+	}()
+	select {
+	case err := <-unlocked:
+		t.Fatalf("expected to wait for unlock, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-unlocked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for unlock")
+	}
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := client.EnsureKeys(context.Background(), meta.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(keys))
+	}
+	for i, key := range keys {
+		if key.PublicKey != wallet.KeyFromSeed(&seed, uint64(i)).PublicKey() {
+			t.Fatalf("key %d: unexpected public key", i)
+		}
+	}
+	before, err := client.Seed(context.Background(), meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if keys, err := client.EnsureKeys(context.Background(), meta.ID, 2); err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	} else if after, err := client.Seed(context.Background(), meta.ID); err != nil {
+		t.Fatal(err)
+	} else if after.LastIndex != before.LastIndex {
+		t.Fatalf("expected no new keys to be derived, last index %d != %d", after.LastIndex, before.LastIndex)
+	}
+
+	// the iterators page through more keys than the server returns at once
+	if _, err := client.EnsureKeys(context.Background(), meta.ID, maxPageSize+10); err != nil {
+		t.Fatal(err)
+	}
+	var n uint64
+	for key, err := range client.AllSeedKeys(context.Background(), meta.ID) {
+		if err != nil {
+			t.Fatal(err)
+		} else if key.PublicKey != wallet.KeyFromSeed(&seed, n).PublicKey() {
+			t.Fatalf("key %d: unexpected public key", n)
+		}
+		n++
+	}
+	if n != maxPageSize+10 {
+		t.Fatalf("expected %d keys, got %d", maxPageSize+10, n)
+	}
+	for _, err := range client.AllSeedKeys(context.Background(), meta.ID+1) {
+		if err == nil {
+			t.Fatal("expected an error for an unknown seed")
+		}
+	}
+
+	other, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []vault.SeedID
+	for seed, err := range client.AllSeeds(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, seed.ID)
+	}
+	if !slices.Equal(ids, []vault.SeedID{meta.ID, other.ID}) {
+		t.Fatalf("expected seeds %v, got %v", []vault.SeedID{meta.ID, other.ID}, ids)
+	}
+
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID:         frand.Entropy256(),
+			UnlockConditions: types.StandardUnlockConditions(keys[1].PublicKey),
+		}},
+	}
+	txn.Signatures = []types.TransactionSignature{{
+		ParentID:      types.Hash256(txn.SiacoinInputs[0].ParentID),
+		CoveredFields: types.CoveredFields{WholeTransaction: true},
+	}}
+	cs := consensus.State{Network: &consensus.Network{}}
+	cs.Network.HardforkV2.AllowHeight = 10
+	signed, fullySigned, err := client.SignAndVerify(context.Background(), txn, cs)
+	if err != nil {
+		t.Fatal(err)
+	} else if !fullySigned || signed.Signatures[0].Signature == nil {
+		t.Fatal("expected transaction to be signed")
+	}
+}
+
+func TestSignV1Skipped(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.GenerateKeys(context.Background(), meta.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a multisig input with an entropy key, a vault key, and a foreign key
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{
+			{
+				ParentID: frand.Entropy256(),
+				UnlockConditions: types.UnlockConditions{
+					PublicKeys: []types.UnlockKey{
+						{Algorithm: types.SpecifierEntropy, Key: frand.Bytes(32)},
+						wallet.KeyFromSeed(&seed, 0).PublicKey().UnlockKey(),
+						types.GeneratePrivateKey().PublicKey().UnlockKey(),
+					},
+					SignaturesRequired: 3,
+				},
+			},
+		},
+	}
+	parentID := types.Hash256(txn.SiacoinInputs[0].ParentID)
+	for i := range 3 {
+		txn.Signatures = append(txn.Signatures, types.TransactionSignature{
+			ParentID:       parentID,
+			PublicKeyIndex: uint64(i),
+			CoveredFields:  types.CoveredFields{WholeTransaction: true},
+		})
+	}
+
+	cs := consensus.State{
+		Network: &consensus.Network{},
+		Index:   types.ChainIndex{Height: 5, ID: frand.Entropy256()},
+	}
+	cs.Network.HardforkV2.AllowHeight = 10
+	cs.Network.HardforkV2.RequireHeight = 20
+
+	var resp SignResponse
+	if err := client.c.POST(context.Background(), "/sign", SignRequest{State: &cs, Network: cs.Network, Transaction: txn}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.FullySigned {
+		t.Fatal("expected transaction to be partially signed")
+	} else if resp.Transaction.Signatures[1].Signature == nil {
+		t.Fatal("expected vault key to be signed")
+	}
+
+	expected := []SkipReason{SkipReasonUnsupportedAlgorithm, SkipReasonNotFound}
+	if len(resp.Skipped) != len(expected) {
+		t.Fatalf("expected %d skipped signatures, got %d", len(expected), len(resp.Skipped))
+	}
+	for i, skip := range resp.Skipped {
+		if skip.Reason != expected[i] {
+			t.Fatalf("expected skip %d to be %q, got %q", i, expected[i], skip.Reason)
+		}
+	}
+	if resp.Skipped[0].Index != 0 || resp.Skipped[1].Index != 2 {
+		t.Fatalf("unexpected skipped indices %d, %d", resp.Skipped[0].Index, resp.Skipped[1].Index)
+	}
+
+	// if no signatures are added, the reason is returned
+	txn.Signatures = txn.Signatures[:1]
+	if _, _, err := client.Sign(context.Background(), txn, SignWithState(cs)); err == nil || !strings.Contains(err.Error(), "unsupported public key algorithm") {
+		t.Fatalf("expected unsupported algorithm error, got %v", err)
+	}
+}
+
 func TestSignV2(t *testing.T) {
 	client := startServer(t, &chain{}, "foo bar baz")
 
@@ -534,6 +1051,47 @@ func TestSignV2LoadState(t *testing.T) {
 	}
 }
 
+func TestStateBuild(t *testing.T) {
+	client := startServer(t, &chain{}, "")
+
+	resp, err := client.Build(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.GoVersion != runtime.Version() {
+		t.Fatalf("expected Go %s, got %s", runtime.Version(), resp.GoVersion)
+	} else if resp.OS != runtime.GOOS || resp.Arch != runtime.GOARCH {
+		t.Fatalf("expected %s/%s, got %s/%s", runtime.GOOS, runtime.GOARCH, resp.OS, resp.Arch)
+	}
+	for _, mod := range []string{"go.sia.tech/core", "go.sia.tech/coreutils"} {
+		if v := resp.Dependencies[mod]; !strings.HasPrefix(v, "v") {
+			t.Fatalf("expected a version of %s, got %q", mod, v)
+		}
+	}
+	for mod := range resp.Dependencies {
+		if !strings.HasPrefix(mod, "go.sia.tech/") {
+			t.Fatalf("unexpected dependency %s", mod)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	client := startServer(t, &chain{}, "")
+	features, err := client.Features(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if features != (FeaturesResponse{Signing: true, AddressBook: true}) {
+		t.Fatalf("unexpected features %+v", features)
+	}
+
+	client = startServer(t, &feeChain{}, "", WithWatchOnly(true), WithUnlockApproval(func(context.Context) (string, error) { return "", nil }), WithMaintenanceWindows([]TimeWindow{{}}, time.Hour))
+	features, err = client.Features(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if features != (FeaturesResponse{Fees: true, AddressBook: true, UnlockApproval: true, MaintenanceWindows: true}) {
+		t.Fatalf("unexpected features %+v", features)
+	}
+}
+
 func TestLockUnlock(t *testing.T) {
 	client := startServer(t, &chain{}, "")
 
@@ -578,3 +1136,2493 @@ func TestLockUnlock(t *testing.T) {
 		t.Fatalf("expected \"incorrect secret\", got %q", err)
 	}
 }
+
+func TestWaitState(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	state, err := client.WaitState(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if !state.Unlocked {
+		t.Fatal("expected vault to be unlocked")
+	} else if state.Changed {
+		t.Fatal("expected initial state to be unchanged")
+	}
+
+	// nothing changes before the timeout
+	resp, err := client.WaitState(context.Background(), state.ID, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Changed {
+		t.Fatal("expected state to be unchanged")
+	} else if resp.ID != state.ID {
+		t.Fatalf("expected ID %q, got %q", state.ID, resp.ID)
+	}
+
+	if _, err := client.WaitState(context.Background(), state.ID, time.Hour); err == nil {
+		t.Fatal("expected timeout to be rejected")
+	}
+
+	type result struct {
+		resp StateWaitResponse
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		resp, err := client.WaitState(context.Background(), state.ID, 10*time.Second)
+		ch <- result{resp, err}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			t.Fatal(res.err)
+		} else if !res.resp.Changed {
+			t.Fatal("expected state to be changed")
+		} else if res.resp.Unlocked {
+			t.Fatal("expected vault to be locked")
+		} else if res.resp.ID == state.ID {
+			t.Fatal("expected a new state ID")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock did not wake the waiter")
+	}
+}
+
+func TestWaitStateWriteTimeout(t *testing.T) {
+	store, err := sqlite.OpenDatabase(filepath.Join(t.TempDir(), "vaultd.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := vault.New(store)
+	defer v.Close()
+
+	// the wait outlasts the server's write timeout
+	srv := httptest.NewUnstartedServer(Handler(&chain{}, v, zap.NewNop()))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+	client := NewClient(srv.URL, "")
+
+	state, err := client.WaitState(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.WaitState(context.Background(), state.ID, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Changed || resp.ID != state.ID {
+		t.Fatal("expected state to be unchanged")
+	}
+}
+
+func TestUnlockApproval(t *testing.T) {
+	var fetched atomic.Int32
+	client := startServer(t, &chain{}, "", WithUnlockApproval(func(context.Context) (string, error) {
+		fetched.Add(1)
+		return "foo bar baz", nil
+	}))
+
+	req, err := client.RequestUnlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(req.Code) != 9 || req.Approved {
+		t.Fatalf("unexpected request %+v", req)
+	} else if fetched.Load() != 0 {
+		t.Fatal("expected the secret not to be fetched before approval")
+	}
+
+	if _, err := client.ApproveUnlock(context.Background(), "BBBB-BBBB"); err == nil || !strings.Contains(err.Error(), "unknown or expired") {
+		t.Fatalf("expected unknown request error, got %v", err)
+	} else if state, err := client.State(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if state.Unlocked {
+		t.Fatal("expected vault to be locked")
+	}
+
+	resp, err := client.ApproveUnlock(context.Background(), req.Code)
+	if err != nil {
+		t.Fatal(err)
+	} else if !resp.Approved {
+		t.Fatal("expected request to be approved")
+	} else if n := fetched.Load(); n != 1 {
+		t.Fatalf("expected the secret to be fetched once, got %d", n)
+	}
+
+	if state, err := client.State(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !state.Unlocked {
+		t.Fatal("expected vault to be unlocked")
+	} else if status, err := client.UnlockRequest(context.Background(), req.Code); err != nil {
+		t.Fatal(err)
+	} else if !status.Approved {
+		t.Fatal("expected request to be approved")
+	} else if _, err := client.ApproveUnlock(context.Background(), req.Code); err == nil || !strings.Contains(err.Error(), "already approved") {
+		t.Fatalf("expected already approved error, got %v", err)
+	} else if _, err := client.RequestUnlock(context.Background()); err == nil || !strings.Contains(err.Error(), vault.ErrUnlocked.Error()) {
+		t.Fatalf("expected %q, got %v", vault.ErrUnlocked, err)
+	}
+}
+
+func TestUnlockApprovalFailed(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("foo bar baz")
+	client := startServer(t, &chain{}, "", WithUnlockApproval(func(context.Context) (string, error) {
+		return secret.Load().(string), nil
+	}))
+
+	// initialize the vault so the secret is verified
+	if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase()); err != nil {
+		t.Fatal(err)
+	} else if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := client.RequestUnlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an incorrect secret is reported as such and the request can be
+	// approved again; repeated failures trigger the unlock backoff
+	secret.Store("wrong")
+	for {
+		_, err := client.ApproveUnlock(context.Background(), req.Code)
+		if err == nil {
+			t.Fatal("expected approval with an incorrect secret to fail")
+		} else if strings.Contains(err.Error(), vault.ErrTooManyAttempts.Error()) {
+			break
+		} else if !strings.Contains(err.Error(), vault.ErrIncorrectSecret.Error()) {
+			t.Fatalf("expected %q, got %v", vault.ErrIncorrectSecret, err)
+		}
+	}
+	if status, err := client.UnlockRequest(context.Background(), req.Code); err != nil {
+		t.Fatal(err)
+	} else if status.Approved {
+		t.Fatal("expected request to be pending")
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable:", err)
+	}
+	// Friday 22:00 to Saturday 06:00 New York time
+	w := TimeWindow{
+		Days:     []time.Weekday{time.Friday},
+		Start:    22 * time.Hour,
+		End:      6 * time.Hour,
+		Location: loc,
+	}
+	for _, test := range []struct {
+		t      time.Time
+		active bool
+	}{
+		{time.Date(2026, 10, 16, 21, 59, 0, 0, loc), false}, // Friday
+		{time.Date(2026, 10, 16, 22, 0, 0, 0, loc), true},
+		{time.Date(2026, 10, 17, 5, 59, 0, 0, loc), true}, // Saturday
+		{time.Date(2026, 10, 17, 6, 0, 0, 0, loc), false},
+		{time.Date(2026, 10, 17, 23, 0, 0, 0, loc), false},
+		{time.Date(2026, 10, 15, 23, 0, 0, 0, loc), false}, // Thursday
+	} {
+		if active, end := w.contains(test.t); active != test.active {
+			t.Fatalf("%v: expected active %v, got %v", test.t, test.active, active)
+		} else if active && !end.Equal(time.Date(2026, 10, 17, 6, 0, 0, 0, loc)) {
+			t.Fatalf("%v: unexpected end %v", test.t, end)
+		}
+	}
+
+	// a window that starts and ends at midnight lasts all day
+	if active, _ := (TimeWindow{}).contains(time.Now()); !active {
+		t.Fatal("expected an all day window to be active")
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz", WithMaintenanceWindows([]TimeWindow{{}}, time.Hour))
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err := client.Maintenance(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !state.Active || state.EndsAt.IsZero() || !state.OverrideExpiresAt.IsZero() {
+		t.Fatalf("unexpected state %+v", state)
+	} else if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err == nil || !strings.Contains(err.Error(), "maintenance window") {
+		t.Fatalf("expected maintenance window error, got %v", err)
+	} else if _, err := client.RequestMaintenanceOverride(context.Background(), ""); err == nil {
+		t.Fatal("expected an error without a reason")
+	}
+
+	override, err := client.RequestMaintenanceOverride(context.Background(), "urgent payout")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(override.Code) != 9 || override.Approved || override.Reason != "urgent payout" {
+		t.Fatalf("unexpected override %+v", override)
+	} else if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err == nil {
+		t.Fatal("expected signing to be refused before approval")
+	}
+
+	if _, err := client.ApproveMaintenanceOverride(context.Background(), "BBBB-BBBB"); err == nil || !strings.Contains(err.Error(), "unknown or expired") {
+		t.Fatalf("expected unknown request error, got %v", err)
+	} else if resp, err := client.ApproveMaintenanceOverride(context.Background(), override.Code); err != nil {
+		t.Fatal(err)
+	} else if !resp.Approved {
+		t.Fatal("expected override to be approved")
+	} else if _, err := client.ApproveMaintenanceOverride(context.Background(), override.Code); err == nil || !strings.Contains(err.Error(), "already approved") {
+		t.Fatalf("expected already approved error, got %v", err)
+	}
+
+	if state, err := client.Maintenance(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !state.Active || state.OverrideExpiresAt.IsZero() {
+		t.Fatalf("unexpected state %+v", state)
+	} else if status, err := client.MaintenanceOverride(context.Background(), override.Code); err != nil {
+		t.Fatal(err)
+	} else if !status.Approved {
+		t.Fatal("expected override to be approved")
+	} else if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaintenanceOverrideApproval(t *testing.T) {
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := vault.New(store)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	const password = "sia is cool"
+	srv := NewServer(&chain{}, v, zap.NewNop(), WithAPITokens(store), WithMaintenanceWindows([]TimeWindow{{}}, time.Hour))
+	server := httptest.NewServer(AuthenticateTokens(password, store, srv))
+	defer server.Close()
+	admin := NewClient(server.URL, password)
+
+	meta, err := admin.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := admin.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := admin.CreateAPIToken(context.Background(), "operator", apitoken.ScopeAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator := NewClient(server.URL, token.Secret)
+
+	// requests that do not go through the HTTP API are refused until an
+	// override is approved
+	req := offline.SignRequest{
+		TransactionID: types.TransactionID(frand.Entropy256()),
+		SigHashes:     []offline.SigHash{{PublicKey: keys[0].PublicKey, SigHash: frand.Entropy256()}},
+	}
+	if _, err := srv.SignRequest(req); err == nil || !strings.Contains(err.Error(), "maintenance window") {
+		t.Fatalf("expected maintenance window error, got %v", err)
+	}
+
+	// the credential that requested an override cannot approve it
+	override, err := operator.RequestMaintenanceOverride(context.Background(), "urgent payout")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(override.RequestedBy) != 1 || override.RequestedBy[0] != "token:operator" {
+		t.Fatalf("unexpected requester %v", override.RequestedBy)
+	} else if _, err := operator.ApproveMaintenanceOverride(context.Background(), override.Code); err == nil || !strings.Contains(err.Error(), "different credential") {
+		t.Fatalf("expected self-approval to be refused, got %v", err)
+	}
+
+	override, err = admin.RequestMaintenanceOverride(context.Background(), "urgent payout")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := admin.ApproveMaintenanceOverride(context.Background(), override.Code); err == nil || !strings.Contains(err.Error(), "different credential") {
+		t.Fatalf("expected self-approval to be refused, got %v", err)
+	} else if _, err := operator.ApproveMaintenanceOverride(context.Background(), override.Code); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp, err := srv.SignRequest(req); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Signatures) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(resp.Signatures))
+	}
+}
+
+func TestSignPolicies(t *testing.T) {
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := vault.New(store)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	// seed 1 can only be signed by the "signer" token, seed 2 only from
+	// 10.0.0.0/8, and seed 3 only on a day that is not today
+	policies := []SignPolicy{
+		{Seeds: []vault.SeedID{1}, Credentials: []string{"token:signer"}, Times: []TimeWindow{{}}},
+		{Seeds: []vault.SeedID{2}, Networks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+		{Seeds: []vault.SeedID{3}, Times: []TimeWindow{{Days: []time.Weekday{(time.Now().UTC().Weekday() + 3) % 7}}}},
+	}
+	const password = "sia is cool"
+	server := httptest.NewServer(AuthenticateTokens(password, store, Handler(&chain{}, v, zap.NewNop(), WithAPITokens(store), WithSignPolicies(policies))))
+	defer server.Close()
+	admin := NewClient(server.URL, password)
+
+	keys := make([]types.PublicKey, 3)
+	for i := range keys {
+		meta, err := admin.AddSeed(context.Background(), wallet.NewSeedPhrase())
+		if err != nil {
+			t.Fatal(err)
+		} else if meta.ID != vault.SeedID(i+1) {
+			t.Fatalf("expected seed %d, got %d", i+1, meta.ID)
+		}
+		seedKeys, err := admin.GenerateKeys(context.Background(), meta.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = seedKeys[0].PublicKey
+	}
+
+	token, err := admin.CreateAPIToken(context.Background(), "signer", apitoken.ScopeSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewClient(server.URL, token.Secret)
+
+	if _, err := admin.BlindSign(context.Background(), keys[0], frand.Entropy256()); err == nil || !strings.Contains(err.Error(), "cannot sign with this credential") {
+		t.Fatalf("expected credential error, got %v", err)
+	} else if _, err := signer.BlindSign(context.Background(), keys[0], frand.Entropy256()); err != nil {
+		t.Fatal(err)
+	} else if _, err := signer.BlindSign(context.Background(), keys[1], frand.Entropy256()); err == nil || !strings.Contains(err.Error(), "cannot sign from 127.0.0.1") {
+		t.Fatalf("expected network error, got %v", err)
+	} else if _, err := signer.BlindSign(context.Background(), keys[2], frand.Entropy256()); err == nil || !strings.Contains(err.Error(), "cannot sign at this time") {
+		t.Fatalf("expected time error, got %v", err)
+	}
+
+	// a denied key in a v2 policy is skipped instead of failing the policy
+	cs := consensus.State{
+		Network: &consensus.Network{},
+		Index:   types.ChainIndex{Height: 5, ID: frand.Entropy256()},
+	}
+	txn := types.V2Transaction{
+		SiacoinInputs: []types.V2SiacoinInput{{
+			Parent: types.SiacoinElement{ID: frand.Entropy256()},
+			SatisfiedPolicy: types.SatisfiedPolicy{
+				Policy: types.PolicyThreshold(2, []types.SpendPolicy{
+					types.PolicyPublicKey(keys[2]),
+					types.PolicyPublicKey(keys[0]),
+				}),
+			},
+		}},
+	}
+	sigHash := cs.InputSigHash(txn)
+	txn, _, err = signer.SignV2(context.Background(), txn, SignV2WithState(cs))
+	if err != nil {
+		t.Fatal(err)
+	} else if sigs := txn.SiacoinInputs[0].SatisfiedPolicy.Signatures; len(sigs) != 1 || !keys[0].VerifyHash(sigHash, sigs[0]) {
+		t.Fatalf("expected a signature from the allowed key, got %d signatures", len(sigs))
+	}
+}
+
+func TestKeyExpiration(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	seed, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), seed.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a key past its expiration cannot sign
+	past := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	if err := client.SetKeyExpiration(context.Background(), keys[0].PublicKey, past); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err == nil || !strings.Contains(err.Error(), vault.ErrExpired.Error()) {
+		t.Fatalf("expected %q, got %v", vault.ErrExpired, err)
+	} else if _, err := client.BlindSign(context.Background(), keys[1].PublicKey, frand.Entropy256()); err != nil {
+		t.Fatal(err)
+	}
+
+	// neither can the keys of an expired seed
+	future := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	if err := client.SetKeyExpiration(context.Background(), keys[0].PublicKey, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if err := client.SetSeedExpiration(context.Background(), seed.ID, future); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err != nil {
+		t.Fatal(err)
+	} else if meta, err := client.Seed(context.Background(), seed.ID); err != nil {
+		t.Fatal(err)
+	} else if !meta.ExpiresAt.Equal(future) {
+		t.Fatalf("expected expiration %v, got %v", future, meta.ExpiresAt)
+	} else if err := client.SetSeedExpiration(context.Background(), seed.ID, past); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err == nil || !strings.Contains(err.Error(), vault.ErrExpired.Error()) {
+		t.Fatalf("expected %q, got %v", vault.ErrExpired, err)
+	}
+
+	if err := client.SetKeyExpiration(context.Background(), keys[1].PublicKey, future); err != nil {
+		t.Fatal(err)
+	}
+	exps, err := client.Expirations(context.Background(), time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(exps) != 2 {
+		t.Fatalf("expected 2 expirations, got %d", len(exps))
+	} else if exps[0].SeedID != seed.ID || exps[0].PublicKey != (types.PublicKey{}) || !exps[0].Expired {
+		t.Fatalf("unexpected seed expiration %+v", exps[0])
+	} else if exps[1].PublicKey != keys[1].PublicKey || exps[1].Expired {
+		t.Fatalf("unexpected key expiration %+v", exps[1])
+	}
+
+	if err := client.SetSeedExpiration(context.Background(), 100, future); err == nil || !strings.Contains(err.Error(), vault.ErrNotFound.Error()) {
+		t.Fatalf("expected %q, got %v", vault.ErrNotFound, err)
+	} else if err := client.SetKeyExpiration(context.Background(), types.GeneratePrivateKey().PublicKey(), future); err == nil || !strings.Contains(err.Error(), vault.ErrNotFound.Error()) {
+		t.Fatalf("expected %q, got %v", vault.ErrNotFound, err)
+	}
+}
+
+func TestUnlockShares(t *testing.T) {
+	client := startServer(t, &chain{}, "")
+
+	if _, err := client.SplitUnlockSecret(context.Background(), 1, 3); err == nil {
+		t.Fatal("expected an error for a threshold of 1")
+	}
+
+	split, err := client.SplitUnlockSecret(context.Background(), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(split.Shares) != 3 || split.Threshold != 2 {
+		t.Fatalf("unexpected split %+v", split)
+	} else if _, err := client.SplitUnlockSecret(context.Background(), 2, 3); err == nil || !strings.Contains(err.Error(), "already initialized") {
+		t.Fatalf("expected already initialized error, got %v", err)
+	}
+
+	// the split unlocks the vault so a seed can be added
+	if _, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase()); err != nil {
+		t.Fatal(err)
+	} else if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.UnlockShare(context.Background(), split.Shares[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Received != 1 || resp.Threshold != 2 || resp.Unlocked {
+		t.Fatalf("unexpected response %+v", resp)
+	} else if _, err := client.UnlockShare(context.Background(), split.Shares[0]); err == nil || !strings.Contains(err.Error(), "already submitted") {
+		t.Fatalf("expected already submitted error, got %v", err)
+	} else if state, err := client.State(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if state.Unlocked {
+		t.Fatal("expected vault to be locked")
+	}
+
+	resp, err = client.UnlockShare(context.Background(), split.Shares[2])
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Received != 2 || !resp.Unlocked {
+		t.Fatalf("unexpected response %+v", resp)
+	} else if state, err := client.State(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !state.Unlocked {
+		t.Fatal("expected vault to be unlocked")
+	} else if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupted share recovers the wrong secret
+	buf, err := hex.DecodeString(split.Shares[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[len(buf)-1] ^= 1
+	if _, err := client.UnlockShare(context.Background(), split.Shares[0]); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.UnlockShare(context.Background(), hex.EncodeToString(buf)); err == nil || !strings.Contains(err.Error(), vault.ErrIncorrectSecret.Error()) {
+		t.Fatalf("expected %q, got %v", vault.ErrIncorrectSecret, err)
+	}
+
+	// the pending shares were discarded, so the correct shares unlock the
+	// vault
+	if _, err := client.UnlockShare(context.Background(), split.Shares[1]); err != nil {
+		t.Fatal(err)
+	} else if resp, err := client.UnlockShare(context.Background(), split.Shares[2]); err != nil {
+		t.Fatal(err)
+	} else if !resp.Unlocked {
+		t.Fatal("expected vault to be unlocked")
+	}
+}
+
+func TestAddressBook(t *testing.T) {
+	client := startServer(t, &chain{}, "")
+
+	addr := types.StandardUnlockHash(types.GeneratePrivateKey().PublicKey())
+	entry, err := client.AddAddressBookEntry(context.Background(), addr, "exchange", false)
+	if err != nil {
+		t.Fatal(err)
+	} else if entry.Address != addr {
+		t.Fatalf("expected address %v, got %v", addr, entry.Address)
+	} else if entry.Label != "exchange" {
+		t.Fatalf("expected label %q, got %q", "exchange", entry.Label)
+	} else if entry.Verified {
+		t.Fatal("expected entry to be unverified")
+	}
+
+	if _, err := client.AddAddressBookEntry(context.Background(), addr, "duplicate", false); err == nil || !strings.Contains(err.Error(), addressbook.ErrExists.Error()) {
+		t.Fatalf("expected %q, got %v", addressbook.ErrExists, err)
+	}
+
+	if err := client.UpdateAddressBookEntry(context.Background(), entry.ID, "cold storage", true); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err = client.AddressBookEntry(context.Background(), entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if entry.Label != "cold storage" {
+		t.Fatalf("expected label %q, got %q", "cold storage", entry.Label)
+	} else if !entry.Verified {
+		t.Fatal("expected entry to be verified")
+	}
+
+	entries, err := client.AddressBook(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	} else if entries[0] != entry {
+		t.Fatalf("expected entry %v, got %v", entry, entries[0])
+	}
+
+	if err := client.DeleteAddressBookEntry(context.Background(), entry.ID); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.AddressBookEntry(context.Background(), entry.ID); err == nil || !strings.Contains(err.Error(), addressbook.ErrNotFound.Error()) {
+		t.Fatalf("expected %q, got %v", addressbook.ErrNotFound, err)
+	}
+}
+
+func TestOfflineSigning(t *testing.T) {
+	cs := consensus.State{
+		Network: &consensus.Network{},
+		Index: types.ChainIndex{
+			Height: 5,
+			ID:     frand.Entropy256(),
+		},
+	}
+	online := startServer(t, &chain{cs}, "", WithWatchOnly(true))
+	signer := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	pk := wallet.KeyFromSeed(&seed, 0).PublicKey()
+
+	if _, err := online.AddSeed(context.Background(), phrase); err == nil {
+		t.Fatal("expected watch-only instance to reject seeds")
+	}
+
+	meta, err := signer.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := signer.GenerateKeys(context.Background(), meta.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("v1", func(t *testing.T) {
+		txn := types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{
+				{
+					ParentID:         frand.Entropy256(),
+					UnlockConditions: types.StandardUnlockConditions(pk),
+				},
+			},
+		}
+		txn.Signatures = []types.TransactionSignature{
+			{
+				ParentID:      types.Hash256(txn.SiacoinInputs[0].ParentID),
+				CoveredFields: types.CoveredFields{WholeTransaction: true},
+			},
+		}
+
+		req, err := online.OfflineRequest(context.Background(), txn)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(req.SigHashes) != 1 {
+			t.Fatalf("expected 1 sighash, got %d", len(req.SigHashes))
+		}
+
+		resp, err := signer.OfflineSign(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(resp.Signatures) != 1 {
+			t.Fatalf("expected 1 signature, got %d", len(resp.Signatures))
+		}
+
+		signed, fullySigned, err := online.OfflineMerge(context.Background(), txn, resp)
+		if err != nil {
+			t.Fatal(err)
+		} else if !fullySigned {
+			t.Fatal("expected transaction to be fully signed")
+		}
+		sigHash := cs.WholeSigHash(txn, txn.Signatures[0].ParentID, 0, 0, nil)
+		if !pk.VerifyHash(sigHash, types.Signature(signed.Signatures[0].Signature)) {
+			t.Fatal("signature verification failed")
+		}
+
+		// merging into a different transaction must fail
+		txn.MinerFees = append(txn.MinerFees, types.Siacoins(1))
+		if _, _, err := online.OfflineMerge(context.Background(), txn, resp); err == nil {
+			t.Fatal("expected merge into a different transaction to fail")
+		}
+	})
+
+	t.Run("v2", func(t *testing.T) {
+		txn := types.V2Transaction{
+			SiacoinInputs: []types.V2SiacoinInput{
+				{
+					Parent: types.SiacoinElement{
+						ID: frand.Entropy256(),
+					},
+					SatisfiedPolicy: types.SatisfiedPolicy{
+						Policy: types.PolicyThreshold(1, []types.SpendPolicy{
+							types.PolicyPublicKey(types.GeneratePrivateKey().PublicKey()),
+							types.PolicyPublicKey(pk),
+						}),
+					},
+				},
+			},
+		}
+
+		req, err := online.OfflineRequestV2(context.Background(), txn)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(req.SigHashes) != 2 {
+			t.Fatalf("expected 2 sighashes, got %d", len(req.SigHashes))
+		}
+
+		resp, err := signer.OfflineSign(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(resp.Signatures) != 1 {
+			t.Fatalf("expected 1 signature, got %d", len(resp.Signatures))
+		}
+
+		signed, fullySigned, err := online.OfflineMergeV2(context.Background(), txn, resp)
+		if err != nil {
+			t.Fatal(err)
+		} else if !fullySigned {
+			t.Fatal("expected transaction to be fully signed")
+		}
+		sigs := signed.SiacoinInputs[0].SatisfiedPolicy.Signatures
+		if len(sigs) != 1 {
+			t.Fatalf("expected 1 signature, got %d", len(sigs))
+		} else if !pk.VerifyHash(cs.InputSigHash(txn), sigs[0]) {
+			t.Fatal("signature verification failed")
+		}
+	})
+}
+
+type chanNotifier chan notify.Event
+
+func (cn chanNotifier) Notify(e notify.Event) error {
+	cn <- e
+	return nil
+}
+
+func TestNotifications(t *testing.T) {
+	events := make(chanNotifier, 10)
+	client := startServer(t, &chain{}, "", WithNotifier(events))
+
+	expectEvent := func(eventType string) {
+		t.Helper()
+		select {
+		case e := <-events:
+			if e.Type != eventType {
+				t.Fatalf("expected event %q, got %q", eventType, e.Type)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected event %q", eventType)
+		}
+	}
+
+	// first call to unlock initializes the vault
+	if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(notify.EventUnlocked)
+	// the secret is only verified once a seed has been added
+	if _, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase()); err != nil {
+		t.Fatal(err)
+	} else if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(notify.EventLocked)
+
+	for i := 0; i < failedUnlockAlertThreshold; i++ {
+		if err := client.Unlock(context.Background(), "wrong password"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	expectEvent(notify.EventUnlockFailed)
+
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %q", e.Type)
+	default:
+	}
+}
+
+func TestMinClientVersion(t *testing.T) {
+	client := startServer(t, &chain{}, "", WithMinClientVersion(APIVersion))
+
+	state, err := client.State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if state.MinClientVersion != APIVersion {
+		t.Fatalf("expected min client version %d, got %d", APIVersion, state.MinClientVersion)
+	} else if len(state.APIVersions) == 0 || state.APIVersions[len(state.APIVersions)-1] != APIVersion {
+		t.Fatalf("expected API version %d to be supported, got %v", APIVersion, state.APIVersions)
+	}
+
+	// requests without a version are rejected
+	resp, err := http.Get(client.c.baseURL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Fatalf("expected status %d, got %d", http.StatusUpgradeRequired, resp.StatusCode)
+	} else if resp.Header.Get(APIVersionHeader) != strconv.Itoa(APIVersion) {
+		t.Fatalf("expected server version header %d, got %q", APIVersion, resp.Header.Get(APIVersionHeader))
+	}
+
+	client = startServer(t, &chain{}, "", WithMinClientVersion(APIVersion+1))
+	if _, err := client.State(context.Background()); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedVersion, err)
+	}
+}
+
+type requestIDChain struct {
+	chain
+	ids chan string
+}
+
+func (c *requestIDChain) TipState(ctx context.Context) (consensus.State, error) {
+	c.ids <- RequestID(ctx)
+	return c.cs, nil
+}
+
+func TestRequestID(t *testing.T) {
+	ch := &requestIDChain{ids: make(chan string, 1)}
+	client := startServer(t, ch, "foo bar baz")
+
+	doRequest := func(id string) string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, client.c.baseURL+"/state", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get(RequestIDHeader)
+	}
+
+	if id := doRequest("renterd-1234"); id != "renterd-1234" {
+		t.Fatalf("expected request ID to be echoed, got %q", id)
+	} else if id := doRequest(""); len(id) != 32 {
+		t.Fatalf("expected generated request ID, got %q", id)
+	} else if id := doRequest("bad id"); id == "bad id" || len(id) != 32 {
+		t.Fatalf("expected invalid request ID to be replaced, got %q", id)
+	}
+
+	// the client propagates the request ID from the context to the handler
+	ctx := ContextWithRequestID(context.Background(), "renterd-5678")
+	client.Sign(ctx, types.Transaction{})
+	select {
+	case id := <-ch.ids:
+		if id != "renterd-5678" {
+			t.Fatalf("expected request ID %q, got %q", "renterd-5678", id)
+		}
+	default:
+		t.Fatal("expected chain to be called")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	// the server hangs before responding to /state and after sending part
+	// of the response to /seeds
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/seeds" {
+			w.Write([]byte(`{"seeds":`))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := NewClient(server.URL, "", WithTimeout(100*time.Millisecond))
+	start := time.Now()
+	if _, err := client.State(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	} else if _, err := client.Seeds(context.Background(), 0, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	} else if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected requests to time out, took %v", elapsed)
+	}
+
+	// an earlier deadline on the context takes precedence
+	client = NewClient(server.URL, "", WithTimeout(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.Seeds(ctx, 0, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// long polls are allowed their wait in addition to the timeout
+	client = startServer(t, &chain{}, "")
+	client = NewClient(client.c.baseURL, client.c.password, WithTimeout(100*time.Millisecond))
+	state, err := client.WaitState(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.WaitState(context.Background(), state.ID, 500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type authorizerFunc func(context.Context, AuthRequest) error
+
+func (fn authorizerFunc) Authorize(ctx context.Context, req AuthRequest) error {
+	return fn(ctx, req)
+}
+
+func TestAuthorizer(t *testing.T) {
+	var maxCount uint64 = 5
+	az := authorizerFunc(func(_ context.Context, req AuthRequest) error {
+		switch req.Route {
+		case "POST /seeds/:id/keys":
+			if req.Params["id"] != "1" {
+				return errors.New("only seed 1 may derive keys")
+			}
+			var dr SeedDeriveRequest
+			if err := json.Unmarshal(req.Body, &dr); err != nil {
+				return err
+			} else if dr.Count > maxCount {
+				return errors.New("too many keys")
+			}
+		case "DELETE /addressbook/:id":
+			return errors.New("address book entries cannot be deleted")
+		}
+		return nil
+	})
+	client := startServer(t, &chain{}, "foo bar baz", WithAuthorizer(az))
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GenerateKeys(context.Background(), meta.ID, maxCount); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.GenerateKeys(context.Background(), meta.ID, maxCount+1); err == nil || err.Error() != "too many keys" {
+		t.Fatalf("expected \"too many keys\", got %v", err)
+	} else if _, err := client.GenerateKeys(context.Background(), 2, 1); err == nil || err.Error() != "only seed 1 may derive keys" {
+		t.Fatalf("expected \"only seed 1 may derive keys\", got %v", err)
+	} else if err := client.DeleteAddressBookEntry(context.Background(), 1); err == nil || err.Error() != "address book entries cannot be deleted" {
+		t.Fatalf("expected \"address book entries cannot be deleted\", got %v", err)
+	}
+}
+
+func TestSignMetadata(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := map[string]string{"caller": "renterd", "ticket": "OPS-1234"}
+	var resp BlindSignResponse
+	err = client.c.POST(context.Background(), "/blind/sign", BlindSignRequest{
+		PublicKey: keys[0].PublicKey,
+		SigHash:   frand.Entropy256(),
+		Metadata:  md,
+	}, &resp)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(resp.Metadata, md) {
+		t.Fatalf("expected metadata %v, got %v", md, resp.Metadata)
+	}
+
+	large := make(map[string]string)
+	for i := 0; i <= maxMetadataEntries; i++ {
+		large[strconv.Itoa(i)] = "value"
+	}
+	if _, _, err := client.Sign(context.Background(), types.Transaction{}, SignWithMetadata(large)); err == nil || !strings.Contains(err.Error(), "metadata has") {
+		t.Fatalf("expected metadata error, got %v", err)
+	}
+}
+
+func TestExportDescriptor(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	desc, err := client.ExportDescriptor(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(desc.Seeds) != 0 {
+		t.Fatalf("expected no seeds, got %d", len(desc.Seeds))
+	}
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase()); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the third seed has a gap: only the last of its reserved indices is
+	// registered
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	gapped, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.ReserveIndices(context.Background(), gapped.ID, 3); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.RegisterKeys(context.Background(), gapped.ID, []RegisterKey{{Index: 2, PublicKey: wallet.KeyFromSeed(&seed, 2).PublicKey()}}); err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := seedFingerprint(wallet.KeyFromSeed(&seed, 0).PublicKey())
+
+	// the descriptor does not require the vault to be unlocked
+	if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err = client.ExportDescriptor(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(desc.Seeds) != 3 {
+		t.Fatalf("expected 3 seeds, got %d", len(desc.Seeds))
+	}
+
+	sd := desc.Seeds[0]
+	if sd.ID != meta.ID {
+		t.Fatalf("expected seed %d, got %d", meta.ID, sd.ID)
+	} else if sd.DerivationScheme != DerivationSchemeSia {
+		t.Fatalf("expected derivation scheme %q, got %q", DerivationSchemeSia, sd.DerivationScheme)
+	} else if sd.DerivedCount != 5 {
+		t.Fatalf("expected 5 derived keys, got %d", sd.DerivedCount)
+	} else if !reflect.DeepEqual(sd.Keys, keys) {
+		t.Fatalf("expected keys %v, got %v", keys, sd.Keys)
+	} else if sd.Fingerprint != seedFingerprint(keys[0].PublicKey) {
+		t.Fatalf("expected fingerprint %q, got %q", seedFingerprint(keys[0].PublicKey), sd.Fingerprint)
+	} else if desc.Seeds[1].Fingerprint != "" || len(desc.Seeds[1].Keys) != 0 {
+		t.Fatal("expected empty descriptor for seed without keys")
+	}
+
+	// the count covers the reserved indices; the fingerprint is that of
+	// index 0, which can only be derived once the vault is unlocked
+	sd = desc.Seeds[2]
+	if sd.DerivedCount != 3 || len(sd.Keys) != 1 {
+		t.Fatalf("expected 1 of 3 keys, got %d of %d", len(sd.Keys), sd.DerivedCount)
+	} else if sd.Fingerprint != "" {
+		t.Fatalf("expected no fingerprint, got %q", sd.Fingerprint)
+	} else if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	} else if desc, err = client.ExportDescriptor(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if desc.Seeds[2].Fingerprint != fingerprint {
+		t.Fatalf("expected fingerprint %q, got %q", fingerprint, desc.Seeds[2].Fingerprint)
+	}
+}
+
+func TestRedactMode(t *testing.T) {
+	pk := types.GeneratePrivateKey().PublicKey()
+	addr := types.StandardUnlockHash(pk)
+
+	if s := RedactNone.redact(pk.String()); s != pk.String() {
+		t.Fatalf("expected %q, got %q", pk.String(), s)
+	}
+
+	truncated := RedactTruncate.redact(pk.String())
+	if expected := pk.String()[:len("ed25519:")+redactPrefixLen] + "..."; truncated != expected {
+		t.Fatalf("expected %q, got %q", expected, truncated)
+	}
+
+	hashed := RedactHash.redact(pk.String())
+	if !strings.HasPrefix(hashed, "ed25519:h") || strings.Contains(pk.String(), hashed[len("ed25519:h"):]) {
+		t.Fatalf("expected hashed key, got %q", hashed)
+	} else if RedactHash.redact(pk.String()) != hashed {
+		t.Fatal("expected hashes to be stable")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/addresses/"+addr.String()+"/key", nil)
+	if path := RedactHash.redactPath(req); strings.Contains(path, addr.String()) {
+		t.Fatalf("expected address to be redacted, got %q", path)
+	} else if expected := "/addresses/" + RedactHash.redact(addr.String()) + "/key"; path != expected {
+		t.Fatalf("expected %q, got %q", expected, path)
+	}
+
+	var mode RedactMode
+	if err := mode.UnmarshalText([]byte("foo")); err == nil {
+		t.Fatal("expected unknown mode to be rejected")
+	}
+}
+
+func TestDeleteRestoreSeed(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindSign := func() error {
+		return client.c.POST(context.Background(), "/blind/sign", BlindSignRequest{
+			PublicKey: keys[0].PublicKey,
+			SigHash:   frand.Entropy256(),
+		}, new(BlindSignResponse))
+	}
+	if err := blindSign(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.DeleteSeed(context.Background(), meta.ID); err != nil {
+		t.Fatal(err)
+	} else if err := client.DeleteSeed(context.Background(), meta.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	// deleted seeds are hidden and cannot sign
+	var seeds SeedsResponse
+	if err := client.c.GET(context.Background(), "/seeds", &seeds); err != nil {
+		t.Fatal(err)
+	} else if len(seeds.Seeds) != 0 {
+		t.Fatalf("expected 0 seeds, got %d", len(seeds.Seeds))
+	} else if _, err := client.SeedKeys(context.Background(), meta.ID, 0, 100); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	} else if err := blindSign(); err == nil {
+		t.Fatal("expected signing with a deleted seed to fail")
+	}
+
+	restored, err := client.RestoreSeed(context.Background(), meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if restored.ID != meta.ID || restored.LastIndex != 0 {
+		t.Fatalf("unexpected restored seed %+v", restored)
+	} else if err := blindSign(); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.RestoreSeed(context.Background(), meta.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestReserveIndices(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.GenerateKeys(context.Background(), meta.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// reserving does not require the vault to be unlocked
+	if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	reserved, err := client.ReserveIndices(context.Background(), meta.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	} else if reserved.Start != 1 || reserved.End != 11 {
+		t.Fatalf("expected indices [1, 11), got [%d, %d)", reserved.Start, reserved.End)
+	} else if _, err := client.ReserveIndices(context.Background(), meta.ID, 0); err == nil || !strings.Contains(err.Error(), "greater than zero") {
+		t.Fatalf("expected count error, got %v", err)
+	} else if _, err := client.ReserveIndices(context.Background(), meta.ID+1, 10); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	// derivation continues after the reserved indices
+	if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := wallet.KeyFromSeed(&seed, 11).PublicKey(); keys[0].PublicKey != expected {
+		t.Fatalf("expected key 11 %v, got %v", expected, keys[0].PublicKey)
+	}
+
+	// the unregistered reservation is not reported as missing indices
+	if issues, err := client.CheckConsistency(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestRegisterKeys(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved, err := client.ReserveIndices(context.Background(), meta.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// derive the reserved keys externally
+	var keys []RegisterKey
+	for i := reserved.Start; i < reserved.End; i++ {
+		keys = append(keys, RegisterKey{Index: i, PublicKey: wallet.KeyFromSeed(&seed, i).PublicKey()})
+	}
+
+	// keys outside of a reservation and keys that do not match their
+	// index are rejected
+	if _, err := client.RegisterKeys(context.Background(), meta.ID, []RegisterKey{{Index: reserved.End, PublicKey: wallet.KeyFromSeed(&seed, reserved.End).PublicKey()}}); err == nil || !strings.Contains(err.Error(), vault.ErrNotReserved.Error()) {
+		t.Fatalf("expected ErrNotReserved, got %v", err)
+	} else if _, err := client.RegisterKeys(context.Background(), meta.ID, []RegisterKey{{Index: keys[0].Index, PublicKey: keys[1].PublicKey}}); err == nil || !strings.Contains(err.Error(), vault.ErrKeyMismatch.Error()) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+
+	// the vault must be unlocked to verify the keys
+	if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.RegisterKeys(context.Background(), meta.ID, keys); err == nil || !strings.Contains(err.Error(), vault.ErrLocked.Error()) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	} else if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	registered, err := client.RegisterKeys(context.Background(), meta.ID, keys, KeysWithPolicyType(PolicyTypePublicKey))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(registered) != len(keys) {
+		t.Fatalf("expected %d keys, got %d", len(keys), len(registered))
+	}
+	for i, key := range registered {
+		if key.PublicKey != keys[i].PublicKey {
+			t.Fatalf("expected key %v, got %v", keys[i].PublicKey, key.PublicKey)
+		} else if key.Address != types.PolicyPublicKey(key.PublicKey).Address() {
+			t.Fatalf("expected v2 address for key %d", i)
+		}
+	}
+
+	// registering is idempotent and the keys can sign
+	if _, err := client.RegisterKeys(context.Background(), meta.ID, keys); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.BlindSign(context.Background(), keys[2].PublicKey, frand.Entropy256()); err != nil {
+		t.Fatal(err)
+	}
+
+	if issues, err := client.CheckConsistency(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	var seeds []vault.SeedID
+	for range 2 {
+		meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := client.GenerateKeys(context.Background(), meta.ID, 5); err != nil {
+			t.Fatal(err)
+		}
+		seeds = append(seeds, meta.ID)
+	}
+
+	resp, err := client.Keys(context.Background(), vault.KeyFilter{}, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Total != 10 || len(resp.Keys) != 10 {
+		t.Fatalf("expected 10 keys, got %d of %d", len(resp.Keys), resp.Total)
+	} else if resp.Keys[0].SeedID != seeds[0] || resp.Keys[9].SeedID != seeds[1] || resp.Keys[9].Index != 4 {
+		t.Fatalf("expected keys sorted by seed and index, got %+v", resp.Keys)
+	} else if resp.Keys[0].CreatedAt.IsZero() {
+		t.Fatal("expected creation time")
+	}
+
+	// filter by seed and paginate
+	resp, err = client.Keys(context.Background(), vault.KeyFilter{SeedID: seeds[1]}, 2, 2, KeysWithPolicyType(PolicyTypePublicKey))
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Total != 5 || len(resp.Keys) != 2 {
+		t.Fatalf("expected 2 of 5 keys, got %d of %d", len(resp.Keys), resp.Total)
+	}
+	for i, key := range resp.Keys {
+		if key.SeedID != seeds[1] || key.Index != uint64(i+2) {
+			t.Fatalf("expected key %d of seed %d, got key %d of seed %d", i+2, seeds[1], key.Index, key.SeedID)
+		} else if key.Address != types.PolicyPublicKey(key.PublicKey).Address() {
+			t.Fatalf("expected v2 address for key %d", key.Index)
+		}
+	}
+
+	// filter by creation time
+	if resp, err := client.Keys(context.Background(), vault.KeyFilter{CreatedAfter: time.Now().Add(time.Hour)}, 0, 100); err != nil {
+		t.Fatal(err)
+	} else if resp.Total != 0 || len(resp.Keys) != 0 {
+		t.Fatalf("expected no keys, got %d", resp.Total)
+	} else if resp, err := client.Keys(context.Background(), vault.KeyFilter{CreatedAfter: time.Now().Add(-time.Hour)}, 0, 100); err != nil {
+		t.Fatal(err)
+	} else if resp.Total != 10 {
+		t.Fatalf("expected 10 keys, got %d", resp.Total)
+	} else if _, err := client.Keys(context.Background(), vault.KeyFilter{}, 0, 501); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+}
+
+func TestAddSeedEncrypted(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	meta, err := client.AddSeedEncrypted(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same seed added in plaintext matches
+	if plain, err := client.AddSeed(context.Background(), phrase); err != nil {
+		t.Fatal(err)
+	} else if plain.ID != meta.ID {
+		t.Fatalf("expected seed %d, got %d", meta.ID, plain.ID)
+	}
+
+	key, err := client.ImportKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := importkey.Seal(key.PublicKey, []byte(wallet.NewSeedPhrase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.c.POST(context.Background(), "/seeds", AddSeedRequest{Phrase: phrase, EncryptedPhrase: &e}, nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot both be set") {
+		t.Fatalf("expected error, got %v", err)
+	} else if err := client.c.POST(context.Background(), "/seeds", AddSeedRequest{EncryptedPhrase: &e}, nil); err != nil {
+		t.Fatal(err)
+	} else if err := client.c.POST(context.Background(), "/seeds", AddSeedRequest{EncryptedPhrase: &e}, nil); err == nil || !strings.Contains(err.Error(), importkey.ErrUnknownKey.Error()) {
+		t.Fatalf("expected %v, got %v", importkey.ErrUnknownKey, err)
+	}
+}
+
+func TestImportTokens(t *testing.T) {
+	store, err := sqlite.OpenDatabase(filepath.Join(t.TempDir(), "vaultd.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := vault.New(store)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	const password = "sia is cool"
+	server := httptest.NewServer(Authenticate(password, Handler(&chain{}, v, zap.NewNop())))
+	defer server.Close()
+	admin := NewClient(server.URL, password)
+	anon := NewClient(server.URL, "")
+
+	if _, err := anon.CreateImportToken(context.Background(), 0); err == nil {
+		t.Fatal("expected minting a token without the password to fail")
+	} else if _, err := admin.CreateImportToken(context.Background(), 30*24*time.Hour); err == nil {
+		t.Fatal("expected lifetime to be limited")
+	}
+
+	token, err := admin.CreateImportToken(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token grants no other access
+	var seeds SeedsResponse
+	if err := anon.c.GET(context.Background(), "/seeds", &seeds); err == nil {
+		t.Fatal("expected listing seeds without the password to fail")
+	} else if _, err := anon.SubmitSeed(context.Background(), "bad", wallet.NewSeedPhrase()); err == nil || !strings.Contains(err.Error(), "unknown or expired") {
+		t.Fatalf("expected unknown token error, got %v", err)
+	}
+
+	// requesting keys with the token neither evicts other import keys nor
+	// binds more than one key to the token
+	adminKey, err := admin.ImportKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, last ImportKeyResponse
+	for i := range maxImportKeys + 1 {
+		if err := anon.c.GET(context.Background(), "/import/tokens/"+token.Token+"/key", &last); err != nil {
+			t.Fatal(err)
+		} else if i == 0 {
+			first = last
+		}
+	}
+	seal := func(pk importkey.PublicKey) *importkey.Envelope {
+		t.Helper()
+		e, err := importkey.Seal(pk, []byte(wallet.NewSeedPhrase()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &e
+	}
+	for _, pk := range []importkey.PublicKey{first.PublicKey, adminKey.PublicKey} {
+		err := anon.c.POST(context.Background(), "/import/tokens/"+token.Token+"/seed", AddSeedRequest{EncryptedPhrase: seal(pk)}, new(SeedResponse))
+		if err == nil || !strings.Contains(err.Error(), importkey.ErrUnknownKey.Error()) {
+			t.Fatalf("expected unknown key error, got %v", err)
+		}
+	}
+	if err := admin.c.POST(context.Background(), "/seeds", AddSeedRequest{EncryptedPhrase: seal(adminKey.PublicKey)}, new(SeedResponse)); err != nil {
+		t.Fatal(err)
+	}
+
+	// an invalid phrase does not use up the token
+	if _, err := anon.SubmitSeed(context.Background(), token.Token, "not a phrase"); err == nil || !strings.Contains(err.Error(), "invalid phrase") {
+		t.Fatalf("expected invalid phrase error, got %v", err)
+	}
+
+	meta, err := anon.SubmitSeed(context.Background(), token.Token, wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := anon.SubmitSeed(context.Background(), token.Token, wallet.NewSeedPhrase()); err == nil || !strings.Contains(err.Error(), "unknown or expired") {
+		t.Fatalf("expected token to be single use, got %v", err)
+	}
+
+	if err := admin.c.GET(context.Background(), "/seeds", &seeds); err != nil {
+		t.Fatal(err)
+	} else if len(seeds.Seeds) != 2 || seeds.Seeds[1].ID != meta.ID {
+		t.Fatalf("expected seed %d, got %+v", meta.ID, seeds.Seeds)
+	}
+}
+
+type signHook chan SignEvent
+
+func (h signHook) AfterSign(_ context.Context, e SignEvent) error {
+	h <- e
+	return nil
+}
+
+func TestSignHook(t *testing.T) {
+	hook := make(signHook, 1)
+	client := startServer(t, &chain{}, "foo bar baz", WithSignHook(hook))
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigHash := frand.Entropy256()
+	ctx := ContextWithRequestID(context.Background(), "hook-test")
+	err = client.c.POST(ctx, "/blind/sign", BlindSignRequest{
+		PublicKey: keys[0].PublicKey,
+		SigHash:   sigHash,
+		Metadata:  map[string]string{"ticket": "OPS-123"},
+	}, new(BlindSignResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-hook:
+		if e.Route != "POST /blind/sign" || e.SigHash != sigHash || e.PublicKey != keys[0].PublicKey || e.RequestID != "hook-test" || e.Metadata["ticket"] != "OPS-123" {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sign hook was not called")
+	}
+
+	// failed requests do not call the hook
+	err = client.c.POST(context.Background(), "/blind/sign", BlindSignRequest{
+		PublicKey: frand.Entropy256(),
+		SigHash:   sigHash,
+	}, new(BlindSignResponse))
+	if err == nil {
+		t.Fatal("expected signing with an unknown key to fail")
+	}
+	select {
+	case e := <-hook:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// v2 requests that add no signatures do not call the hook
+	cs := consensus.State{Network: &consensus.Network{}, Index: types.ChainIndex{Height: 5, ID: frand.Entropy256()}}
+	txn := types.V2Transaction{
+		SiacoinInputs: []types.V2SiacoinInput{{
+			Parent:          types.SiacoinElement{ID: frand.Entropy256()},
+			SatisfiedPolicy: types.SatisfiedPolicy{Policy: types.PolicyPublicKey(types.GeneratePrivateKey().PublicKey())},
+		}},
+	}
+	if _, _, err := client.SignV2(context.Background(), txn, SignV2WithState(cs)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-hook:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// offline sign requests call the hook
+	req := offline.SignRequest{
+		TransactionID: types.TransactionID(frand.Entropy256()),
+		SigHashes: []offline.SigHash{
+			{PublicKey: keys[0].PublicKey, SigHash: frand.Entropy256()},
+			{PublicKey: types.GeneratePrivateKey().PublicKey(), SigHash: frand.Entropy256()},
+		},
+	}
+	if _, err := client.OfflineSign(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-hook:
+		if e.Route != "POST /offline/sign" || e.TransactionID != req.TransactionID || e.FullySigned {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sign hook was not called")
+	}
+}
+
+type feeChain struct {
+	chain
+	fee atomic.Uint64
+}
+
+func (c *feeChain) RecommendedFee(context.Context) (types.Currency, error) {
+	return types.NewCurrency64(c.fee.Load()), nil
+}
+
+func TestFees(t *testing.T) {
+	// the route is disabled if the chain cannot estimate fees
+	client := startServer(t, &chain{}, "")
+	if _, err := client.Fees(context.Background()); err == nil {
+		t.Fatal("expected fees to be unavailable")
+	}
+
+	minFee, maxFee := types.NewCurrency64(10), types.NewCurrency64(100)
+	fc := &feeChain{}
+	client = startServer(t, fc, "", WithFeeBounds(minFee, maxFee))
+	tests := []struct {
+		recommended uint64
+		expected    types.Currency
+	}{
+		{1, minFee},
+		{50, types.NewCurrency64(50)},
+		{1000, maxFee},
+	}
+	for _, test := range tests {
+		fc.fee.Store(test.recommended)
+		resp, err := client.Fees(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		} else if !resp.Recommended.Equals(types.NewCurrency64(test.recommended)) || !resp.FeePerByte.Equals(test.expected) {
+			t.Fatalf("expected fee %v clamped to %v, got %+v", test.recommended, test.expected, resp)
+		}
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	pk := wallet.KeyFromSeed(&seed, 0).PublicKey()
+
+	assertSeeds := func(n int) {
+		t.Helper()
+		var seeds SeedsResponse
+		if err := client.c.GET(context.Background(), "/seeds", &seeds); err != nil {
+			t.Fatal(err)
+		} else if len(seeds.Seeds) != n {
+			t.Fatalf("expected %d seeds, got %d", n, len(seeds.Seeds))
+		}
+	}
+
+	if _, err := client.PreviewSeed(context.Background(), "not a valid phrase"); err == nil {
+		t.Fatal("expected invalid phrase to fail")
+	}
+
+	preview, err := client.PreviewSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if preview.Action != vault.ImportCreate || preview.Seed != nil {
+		t.Fatalf("unexpected preview %+v", preview)
+	} else if preview.PublicKey != pk || preview.Address != types.StandardUnlockHash(pk) {
+		t.Fatalf("unexpected first key %v", preview.PublicKey)
+	}
+	assertSeeds(0)
+
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview, err = client.PreviewSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if preview.Action != vault.ImportExists || preview.Seed == nil || preview.Seed.ID != meta.ID {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+
+	if _, err := client.PreviewRestoreSeed(context.Background(), meta.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	} else if err := client.DeleteSeed(context.Background(), meta.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err = client.PreviewSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if preview.Action != vault.ImportRestore || preview.Seed == nil || preview.Seed.ID != meta.ID {
+		t.Fatalf("unexpected preview %+v", preview)
+	} else if restore, err := client.PreviewRestoreSeed(context.Background(), meta.ID); err != nil {
+		t.Fatal(err)
+	} else if restore.ID != meta.ID {
+		t.Fatalf("unexpected restore preview %+v", restore)
+	}
+	// nothing was restored
+	assertSeeds(0)
+}
+
+func TestSeedDerivation(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.SeedDerivation(context.Background(), meta.ID+1); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	d, err := client.SeedDerivation(context.Background(), meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.NextIndex != 0 || len(d.Vectors) != 0 {
+		t.Fatalf("unexpected derivation %+v", d)
+	}
+	for _, p := range d.Phrases {
+		if p.Name == "bip39" && !slices.Equal(p.Words, []int{12, 15, 18, 21, 24}) {
+			t.Fatalf("unexpected bip39 word counts %v", p.Words)
+		}
+	}
+
+	if _, err := client.GenerateKeys(context.Background(), meta.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	d, err = client.SeedDerivation(context.Background(), meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.NextIndex != 3 || len(d.Vectors) != 1 {
+		t.Fatalf("unexpected derivation %+v", d)
+	}
+
+	// reserved indices are never derived by vaultd
+	if _, err := client.ReserveIndices(context.Background(), meta.ID, 5); err != nil {
+		t.Fatal(err)
+	} else if d, err := client.SeedDerivation(context.Background(), meta.ID); err != nil {
+		t.Fatal(err)
+	} else if d.NextIndex != 8 {
+		t.Fatalf("expected next index 8, got %d", d.NextIndex)
+	}
+
+	// the vector can be reproduced independently
+	v := d.Vectors[0]
+	pk := wallet.KeyFromSeed(&seed, v.Index).PublicKey()
+	if v.Index != d.FirstIndex || v.PublicKey != pk {
+		t.Fatalf("expected key %v at index %d, got %v at %d", pk, d.FirstIndex, v.PublicKey, v.Index)
+	} else if v.V1Address != types.StandardUnlockHash(pk) || v.V2Address != types.PolicyPublicKey(pk).Address() {
+		t.Fatal("unexpected vector addresses")
+	}
+}
+
+func TestStatsStateSource(t *testing.T) {
+	cs := consensus.State{
+		Network: &consensus.Network{},
+		Index:   types.ChainIndex{Height: 5, ID: frand.Entropy256()},
+	}
+	cs.Network.HardforkV2.AllowHeight = 10
+	cs.Network.HardforkV2.RequireHeight = 20
+	client := startServer(t, &chain{cs}, "foo bar baz")
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.GenerateKeys(context.Background(), meta.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	newTxn := func() types.Transaction {
+		txn := types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{{
+				ParentID: frand.Entropy256(),
+				UnlockConditions: types.UnlockConditions{
+					PublicKeys:         []types.UnlockKey{wallet.KeyFromSeed(&seed, 0).PublicKey().UnlockKey()},
+					SignaturesRequired: 1,
+				},
+			}},
+		}
+		txn.Signatures = []types.TransactionSignature{{
+			ParentID:      types.Hash256(txn.SiacoinInputs[0].ParentID),
+			CoveredFields: types.CoveredFields{WholeTransaction: true},
+		}}
+		return txn
+	}
+
+	if _, _, err := client.Sign(context.Background(), newTxn()); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, _, err := client.Sign(context.Background(), newTxn(), SignWithState(cs)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if stats.SignedTransactions[StateSourceExplorer] != 1 {
+		t.Fatalf("expected 1 transaction signed with explorer state, got %d", stats.SignedTransactions[StateSourceExplorer])
+	} else if stats.SignedTransactions[StateSourceRequest] != 2 {
+		t.Fatalf("expected 2 transactions signed with request state, got %d", stats.SignedTransactions[StateSourceRequest])
+	}
+}
+
+type divergentChain struct {
+	chain
+	err error
+}
+
+func (c *divergentChain) Divergence() error {
+	return c.err
+}
+
+func TestStateExplorerDivergence(t *testing.T) {
+	client := startServer(t, &divergentChain{err: errors.New("tips differ")}, "foo bar baz")
+
+	state, err := client.State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if state.ExplorerDivergence != "tips differ" {
+		t.Fatalf("expected divergence to be reported, got %q", state.ExplorerDivergence)
+	}
+}
+
+func TestReplayProtection(t *testing.T) {
+	nonces, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nonces.Close()
+
+	client := startServer(t, &chain{}, "foo bar baz", WithReplayProtection(nonces, time.Minute))
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindSign := func(nonce string, timestamp time.Time) error {
+		return client.c.POST(context.Background(), "/blind/sign", BlindSignRequest{
+			PublicKey: keys[0].PublicKey,
+			SigHash:   frand.Entropy256(),
+			Nonce:     nonce,
+			Timestamp: timestamp,
+		}, nil)
+	}
+
+	if err := blindSign("", time.Now()); err == nil || !strings.Contains(err.Error(), "nonce is required") {
+		t.Fatalf("expected missing nonce error, got %v", err)
+	} else if err := blindSign("foo", time.Now().Add(-2*time.Minute)); err == nil || !strings.Contains(err.Error(), "timestamp must be within") {
+		t.Fatalf("expected timestamp error, got %v", err)
+	} else if err := blindSign("foo", time.Now()); err != nil {
+		t.Fatal(err)
+	} else if err := blindSign("foo", time.Now()); err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Fatalf("expected replay error, got %v", err)
+	}
+
+	// used nonces are shared by every server using the store
+	client2 := startServer(t, &chain{}, "foo bar baz", WithReplayProtection(nonces, time.Minute))
+	err = client2.c.POST(context.Background(), "/blind/sign", BlindSignRequest{Nonce: "foo", Timestamp: time.Now()}, nil)
+	if err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Fatalf("expected replay error, got %v", err)
+	}
+
+	// the client options set a fresh nonce; the empty transaction is
+	// rejected after the nonce check
+	if _, _, err := client.Sign(context.Background(), types.Transaction{}, SignWithNonce()); err == nil || !strings.Contains(err.Error(), "no signatures were added") {
+		t.Fatalf("expected signing error, got %v", err)
+	}
+
+	// offline sign requests are checked too; requests built by the vault
+	// carry a fresh nonce
+	req := offline.SignRequest{
+		TransactionID: frand.Entropy256(),
+		SigHashes:     []offline.SigHash{{PublicKey: keys[0].PublicKey, SigHash: frand.Entropy256()}},
+	}
+	if _, err := client.OfflineSign(context.Background(), req); err == nil || !strings.Contains(err.Error(), "nonce is required") {
+		t.Fatalf("expected missing nonce error, got %v", err)
+	}
+	req, err = client.OfflineRequest(context.Background(), types.Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	} else if req.Nonce == "" || req.Timestamp.IsZero() {
+		t.Fatal("expected offline request to have a nonce and timestamp")
+	} else if _, err := client.OfflineSign(context.Background(), req); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.OfflineSign(context.Background(), req); err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Fatalf("expected replay error, got %v", err)
+	}
+}
+
+func TestOwnershipProofs(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	var keys []types.PublicKey
+	for range 2 {
+		meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+		if err != nil {
+			t.Fatal(err)
+		}
+		seedKeys, err := client.GenerateKeys(context.Background(), meta.ID, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range seedKeys {
+			keys = append(keys, key.PublicKey)
+		}
+	}
+
+	const challenge = "auditor challenge 2026-10-18"
+	var calls int
+	proofs, err := client.ProveOwnership(context.Background(), challenge, 3, func(done, total int) {
+		calls++
+		if total != len(keys) {
+			t.Fatalf("expected total %d, got %d", len(keys), total)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(proofs) != len(keys) {
+		t.Fatalf("expected %d proofs, got %d", len(keys), len(proofs))
+	} else if calls != 4 {
+		t.Fatalf("expected 4 batches, got %d", calls)
+	}
+	for i, proof := range proofs {
+		if proof.PublicKey != keys[i] {
+			t.Fatalf("proof %d: expected key %v, got %v", i, keys[i], proof.PublicKey)
+		} else if !proof.Verify(challenge) {
+			t.Fatalf("proof %d: invalid signature", i)
+		} else if proof.Verify("other challenge") {
+			t.Fatalf("proof %d: signature valid for a different challenge", i)
+		}
+	}
+
+	resp, err := client.OwnershipProofs(context.Background(), challenge, keys[:1])
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Total != 1 || len(resp.Proofs) != 1 || !resp.Proofs[0].Verify(challenge) {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	if _, err := client.OwnershipProofs(context.Background(), challenge, []types.PublicKey{types.GeneratePrivateKey().PublicKey()}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	} else if _, err := client.OwnershipProofs(context.Background(), "", nil); err == nil || !strings.Contains(err.Error(), "challenge is required") {
+		t.Fatalf("expected missing challenge error, got %v", err)
+	}
+}
+
+// keyList is a custody.KeyLister over a fixed list of keys.
+type keyList []vault.KeyMeta
+
+func (kl *keyList) Keys(offset, limit int) ([]vault.KeyMeta, int, error) {
+	keys := *kl
+	offset = min(offset, len(keys))
+	return keys[offset:min(offset+limit, len(keys))], len(keys), nil
+}
+
+func TestCustodyProofs(t *testing.T) {
+	var keys keyList
+	committer := custody.NewCommitter(&keys)
+	client := startServer(t, &chain{}, "foo bar baz", WithCustody(committer))
+
+	if _, err := client.CustodyCommitment(context.Background()); err == nil || !strings.Contains(err.Error(), custody.ErrNoCommitment.Error()) {
+		t.Fatalf("expected %v, got %v", custody.ErrNoCommitment, err)
+	}
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedKeys, err := client.GenerateKeys(context.Background(), meta.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range seedKeys {
+		keys = append(keys, vault.KeyMeta{PublicKey: key.PublicKey, SeedID: meta.ID, Index: uint64(i)})
+	}
+	if _, err := committer.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	commitment, err := client.CustodyCommitment(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if commitment.Keys != len(seedKeys) {
+		t.Fatalf("expected %d keys, got %d", len(seedKeys), commitment.Keys)
+	}
+
+	for _, addr := range []types.Address{seedKeys[3].V1Address, seedKeys[7].V2Address} {
+		resp, err := client.CustodyProof(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		} else if resp.Commitment != commitment {
+			t.Fatal("proof is for a different commitment")
+		} else if resp.V1Address != addr && resp.V2Address != addr {
+			t.Fatalf("proof is for a different address")
+		} else if !resp.Proof.Verify(commitment) {
+			t.Fatal("invalid proof")
+		}
+	}
+
+	// keys derived after the commitment are not included
+	newKeys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.CustodyProof(context.Background(), newKeys[0].V1Address); err == nil || !strings.Contains(err.Error(), custody.ErrNotCommitted.Error()) {
+		t.Fatalf("expected %v, got %v", custody.ErrNotCommitted, err)
+	}
+}
+
+func TestContractGuardrails(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz", WithContractGuardrails(ContractGuardrails{
+		RevisionNumber: true,
+		HostPayout:     true,
+	}))
+
+	phrase := wallet.NewSeedPhrase()
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, phrase); err != nil {
+		t.Fatal(err)
+	}
+	pk := wallet.KeyFromSeed(&seed, 0).PublicKey()
+	meta, err := client.AddSeed(context.Background(), phrase)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.GenerateKeys(context.Background(), meta.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := consensus.State{
+		Network: &consensus.Network{},
+		Index: types.ChainIndex{
+			Height: 5,
+			ID:     frand.Entropy256(),
+		},
+	}
+
+	hostAddr := types.StandardAddress(frand.Entropy256())
+	parent := types.V2FileContractElement{
+		ID: frand.Entropy256(),
+		V2FileContract: types.V2FileContract{
+			RevisionNumber:  10,
+			HostOutput:      types.SiacoinOutput{Address: hostAddr, Value: types.Siacoins(100)},
+			MissedHostValue: types.Siacoins(100),
+			TotalCollateral: types.Siacoins(50),
+		},
+	}
+	newTxn := func(revise func(*types.V2FileContract)) types.V2Transaction {
+		rev := parent.V2FileContract
+		rev.RevisionNumber++
+		revise(&rev)
+		return types.V2Transaction{
+			SiacoinInputs: []types.V2SiacoinInput{{
+				Parent: types.SiacoinElement{ID: frand.Entropy256()},
+				SatisfiedPolicy: types.SatisfiedPolicy{
+					Policy: types.SpendPolicy{Type: types.PolicyTypePublicKey(pk)},
+				},
+			}},
+			FileContractRevisions: []types.V2FileContractRevision{{Parent: parent, Revision: rev}},
+		}
+	}
+
+	rejected := []struct {
+		name   string
+		revise func(*types.V2FileContract)
+		err    string
+	}{
+		{"revision number", func(fc *types.V2FileContract) { fc.RevisionNumber = 10 }, "does not increase the revision number"},
+		{"payout address", func(fc *types.V2FileContract) { fc.HostOutput.Address = types.VoidAddress }, "changes the host payout address"},
+		{"payout decrease", func(fc *types.V2FileContract) { fc.HostOutput.Value = types.Siacoins(90) }, "decreases the host payout"},
+		{"collateral", func(fc *types.V2FileContract) { fc.MissedHostValue = types.Siacoins(40) }, "risks more than the host's collateral"},
+	}
+	for _, test := range rejected {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := client.SignV2(context.Background(), newTxn(test.revise), SignV2WithState(cs))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+
+	// a revision paying the host and risking part of its collateral is
+	// signed
+	txn, signed, err := client.SignV2(context.Background(), newTxn(func(fc *types.V2FileContract) {
+		fc.HostOutput.Value = types.Siacoins(110)
+		fc.MissedHostValue = types.Siacoins(80)
+	}), SignV2WithState(cs))
+	if err != nil {
+		t.Fatal(err)
+	} else if !signed || len(txn.SiacoinInputs[0].SatisfiedPolicy.Signatures) != 1 {
+		t.Fatal("expected transaction to be signed")
+	}
+
+	// a renewal that shortchanges the host is rejected
+	txn = newTxn(func(*types.V2FileContract) {})
+	txn.FileContractRevisions = nil
+	txn.FileContractResolutions = []types.V2FileContractResolution{{
+		Parent: parent,
+		Resolution: &types.V2FileContractRenewal{
+			FinalHostOutput: types.SiacoinOutput{Address: hostAddr, Value: types.Siacoins(40)},
+			HostRollover:    types.Siacoins(50),
+		},
+	}}
+	if _, _, err := client.SignV2(context.Background(), txn, SignV2WithState(cs)); err == nil || !strings.Contains(err.Error(), "decreases the host payout") {
+		t.Fatalf("expected renewal to be rejected, got %v", err)
+	}
+}
+
+func TestStatsLatency(t *testing.T) {
+	// every request is slower than a nanosecond
+	client := startServer(t, &chain{}, "foo bar baz", WithSigningSLO(SLO{Latency: time.Nanosecond, Target: 0.99}))
+
+	for range 2 {
+		if _, err := client.State(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// failed requests count towards the objective
+	if err := client.c.POST(context.Background(), "/blind/sign", BlindSignRequest{PublicKey: frand.Entropy256()}, nil); err == nil {
+		t.Fatal("expected unknown key to fail")
+	}
+
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if s := stats.Latency["GET /state"]; s.Count != 2 || s.P50 <= 0 || s.P99 < s.P50 || s.Max < s.P99 {
+		t.Fatalf("unexpected state latency %+v", s)
+	} else if s := stats.Latency["POST /blind/sign"]; s.Count != 1 {
+		t.Fatalf("unexpected sign latency %+v", s)
+	} else if slo := stats.SigningSLO; slo == nil || slo.Requests != 1 || slo.Slow != 1 || math.Abs(slo.BurnRate-100) > 1e-9 {
+		t.Fatalf("unexpected signing SLO %+v", slo)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for _, test := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.5, 50},
+		{0.9, 90},
+		{0.99, 99},
+		{1, 100},
+		{0, 1},
+	} {
+		if got := percentile(sorted, test.p); got != test.want {
+			t.Fatalf("p%v: expected %v, got %v", test.p*100, test.want, got)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Fatalf("expected 0 for no samples, got %v", got)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// every optional route is enabled
+	opts := []ServerOption{WithAddressBook(store), WithAPITokens(store), WithUnlockApproval(func(context.Context) (string, error) { return "", nil }), WithCustody(&custody.Committer{}), WithSnapshots(&snapshot.Manager{}), WithWebhooks(&webhooks.Manager{}), WithMaintenanceWindows([]TimeWindow{{}}, time.Hour)}
+	routes := Routes(&feeChain{}, opts...)
+	all := newAPI(&feeChain{}, nil, zap.NewNop(), opts).routes()
+	if len(routes) != len(all) {
+		t.Fatalf("expected %d routes, got %d", len(all), len(routes))
+	} else if len(routeBodies) != len(all) {
+		t.Fatalf("expected body types for %d routes, got %d", len(all), len(routeBodies))
+	}
+	for _, r := range routes {
+		if _, ok := routeBodies[r.Method+" "+r.Path]; !ok {
+			t.Fatalf("missing body types for %s %s", r.Method, r.Path)
+		}
+	}
+	if !slices.IsSortedFunc(routes, func(a, b Route) int { return strings.Compare(a.Path, b.Path) }) {
+		t.Fatal("expected routes sorted by path")
+	}
+
+	i := slices.IndexFunc(routes, func(r Route) bool { return r.Method == "POST" && r.Path == "/v2/sign" })
+	if i < 0 {
+		t.Fatal("missing POST /v2/sign")
+	} else if routes[i].Request != reflect.TypeFor[SignV2Request]() || routes[i].Response != reflect.TypeFor[SignV2Response]() {
+		t.Fatalf("unexpected types %v %v", routes[i].Request, routes[i].Response)
+	} else if routes[i].Scope != apitoken.ScopeSign {
+		t.Fatalf("expected scope %q, got %q", apitoken.ScopeSign, routes[i].Scope)
+	}
+	for route := range routeScopes {
+		if _, ok := routeBodies[route]; !ok {
+			t.Fatalf("scope for unknown route %s", route)
+		}
+	}
+
+	// routes disabled by the options are excluded
+	for _, r := range Routes(&chain{}, WithWatchOnly(true)) {
+		if r.Path == "/v2/sign" || r.Path == "/fees" || r.Path == "/snapshots" {
+			t.Fatalf("unexpected route %s %s", r.Method, r.Path)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	client := startServer(t, &chain{}, "foo bar baz")
+
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.GenerateKeys(context.Background(), meta.ID, 500); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, encoding string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, client.c.baseURL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// setting the header disables transparent decompression
+		req.Header.Set("Accept-Encoding", encoding)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := get("/keys?limit=500", "gzip")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	} else if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", enc)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys KeysResponse
+	if err := json.NewDecoder(gz).Decode(&keys); err != nil {
+		t.Fatal(err)
+	} else if len(keys.Keys) != 500 {
+		t.Fatalf("expected 500 keys, got %d", len(keys.Keys))
+	}
+
+	// errors and clients that do not accept gzip are not compressed
+	if resp := get("/seeds/100/keys", "gzip"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", resp.StatusCode)
+	} else if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		t.Fatalf("expected no encoding, got %q", enc)
+	} else if resp := get("/keys", "gzip;q=0, identity"); resp.Header.Get("Content-Encoding") != "" {
+		t.Fatal("expected no encoding")
+	}
+
+	// the client decompresses transparently
+	if resp, err := client.Keys(context.Background(), vault.KeyFilter{}, 0, 500); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Keys) != 500 {
+		t.Fatalf("expected 500 keys, got %d", len(resp.Keys))
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := vault.New(store)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	const password = "sia is cool"
+	server := httptest.NewServer(AuthenticateTokens(password, store, Handler(&chain{}, v, zap.NewNop(), WithAPITokens(store))))
+	defer server.Close()
+	admin := NewClient(server.URL, password)
+
+	meta, err := admin.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := admin.CreateAPIToken(context.Background(), "empty"); err == nil {
+		t.Fatal("expected a token without scopes to be rejected")
+	} else if err := admin.c.POST(context.Background(), "/tokens", map[string]any{"name": "bad", "scopes": []string{"superuser"}}, nil); err == nil || !strings.Contains(err.Error(), "unknown scope") {
+		t.Fatalf("expected unknown scope error, got %v", err)
+	}
+
+	dashboardToken, err := admin.CreateAPIToken(context.Background(), "dashboard", apitoken.ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := admin.CreateAPIToken(context.Background(), "dashboard", apitoken.ScopeSign); err == nil || !strings.Contains(err.Error(), apitoken.ErrExists.Error()) {
+		t.Fatalf("expected %q, got %v", apitoken.ErrExists, err)
+	}
+	signerToken, err := admin.CreateAPIToken(context.Background(), "signer", apitoken.ScopeDerive, apitoken.ScopeSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := admin.APITokens(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	} else if tokens[0].Name != "dashboard" || !slices.Equal(tokens[0].Scopes, []apitoken.Scope{apitoken.ScopeRead}) {
+		t.Fatalf("unexpected token %+v", tokens[0])
+	}
+
+	// the read token can list seeds but not derive keys or sign
+	dashboard := NewClient(server.URL, dashboardToken.Secret)
+	if _, err := dashboard.Seeds(context.Background(), 0, 100); err != nil {
+		t.Fatal(err)
+	} else if _, err := dashboard.GenerateKeys(context.Background(), meta.ID, 1); err == nil || !strings.Contains(err.Error(), "derive") {
+		t.Fatalf("expected missing scope error, got %v", err)
+	} else if _, _, err := dashboard.SignV2(context.Background(), types.V2Transaction{}); err == nil || !strings.Contains(err.Error(), "sign") {
+		t.Fatalf("expected missing scope error, got %v", err)
+	} else if _, err := dashboard.CreateAPIToken(context.Background(), "escalate", apitoken.ScopeAdmin); err == nil || !strings.Contains(err.Error(), "admin") {
+		t.Fatalf("expected missing scope error, got %v", err)
+	}
+
+	// the signer token can derive keys but not list seeds
+	signer := NewClient(server.URL, signerToken.Secret)
+	if _, err := signer.GenerateKeys(context.Background(), meta.ID, 1); err != nil {
+		t.Fatal(err)
+	} else if _, err := signer.Seeds(context.Background(), 0, 100); err == nil || !strings.Contains(err.Error(), "read") {
+		t.Fatalf("expected missing scope error, got %v", err)
+	}
+
+	// unknown tokens are rejected
+	if _, err := NewClient(server.URL, "vaultd_unknown").Seeds(context.Background(), 0, 100); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+
+	// deleted tokens are rejected
+	if err := admin.DeleteAPIToken(context.Background(), dashboardToken.ID); err != nil {
+		t.Fatal(err)
+	} else if _, err := dashboard.Seeds(context.Background(), 0, 100); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	} else if err := admin.DeleteAPIToken(context.Background(), dashboardToken.ID); err == nil || !strings.Contains(err.Error(), apitoken.ErrNotFound.Error()) {
+		t.Fatalf("expected %q, got %v", apitoken.ErrNotFound, err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	hooks, err := webhooks.NewManager(store, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hooks.Close()
+
+	type delivery struct {
+		password string
+		event    webhooks.Event
+	}
+	received := make(chan delivery, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e webhooks.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, password, _ := r.BasicAuth()
+		received <- delivery{password, e}
+	}))
+	defer receiver.Close()
+
+	expectEvent := func(hook webhooks.Webhook, event string) webhooks.Event {
+		t.Helper()
+		select {
+		case d := <-received:
+			if d.event.Event != event {
+				t.Fatalf("expected event %q, got %q", event, d.event.Event)
+			} else if d.password != hook.SecretKey {
+				t.Fatal("expected the webhook's secret key as the password")
+			}
+			return d.event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", event)
+		}
+		panic("unreachable")
+	}
+
+	client := startServer(t, &chain{}, "foo bar baz", WithWebhooks(hooks))
+
+	if _, err := client.RegisterWebhook(context.Background(), "ftp://example.com", webhooks.ScopeAll); err == nil {
+		t.Fatal("expected an error for a non-HTTP callback")
+	} else if _, err := client.RegisterWebhook(context.Background(), receiver.URL, "foo"); err == nil {
+		t.Fatal("expected an error for an unknown scope")
+	}
+
+	hook, err := client.RegisterWebhook(context.Background(), receiver.URL, webhooks.ScopeVault)
+	if err != nil {
+		t.Fatal(err)
+	} else if hook.SecretKey == "" {
+		t.Fatal("expected a secret key")
+	}
+
+	if err := client.TestWebhook(context.Background(), hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(hook, webhooks.EventTest)
+
+	if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(hook, webhooks.EventLocked)
+	if err := client.Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(hook, webhooks.EventUnlocked)
+
+	// events outside the webhook's scopes are not sent
+	if _, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-received:
+		t.Fatalf("unexpected event %q", d.event.Event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := client.UpdateWebhook(context.Background(), hook.ID, receiver.URL, webhooks.ScopeSeeds, webhooks.ScopeSigning); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := expectEvent(hook, webhooks.EventSeedAdded)
+	if data, ok := e.Data.(map[string]any); !ok || data["id"] != float64(meta.ID) {
+		t.Fatalf("unexpected seed event data %v", e.Data)
+	}
+
+	keys, err := client.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.BlindSign(context.Background(), keys[0].PublicKey, frand.Entropy256()); err != nil {
+		t.Fatal(err)
+	}
+	e = expectEvent(hook, webhooks.EventSigned)
+	if data, ok := e.Data.(map[string]any); !ok || data["publicKey"] != keys[0].PublicKey.String() {
+		t.Fatalf("unexpected sign event data %v", e.Data)
+	}
+
+	if err := client.DeleteWebhook(context.Background(), hook.ID); err != nil {
+		t.Fatal(err)
+	} else if hooks, err := client.Webhooks(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if len(hooks) != 0 {
+		t.Fatalf("expected no webhooks, got %d", len(hooks))
+	} else if err := client.DeleteWebhook(context.Background(), hook.ID); err == nil {
+		t.Fatal("expected an error deleting a missing webhook")
+	}
+}
+
+func TestClientCertificates(t *testing.T) {
+	newCert := func(cn string, isCA bool, parent *x509.Certificate, parentKey ed25519.PrivateKey) (*x509.Certificate, ed25519.PrivateKey) {
+		t.Helper()
+		pub, priv, err := ed25519.GenerateKey(frand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber:          new(big.Int).SetUint64(frand.Uint64n(math.MaxInt64)),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  isCA,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		if parent == nil {
+			parent, parentKey = tmpl, priv
+		}
+		buf, err := x509.CreateCertificate(frand.Reader, tmpl, parent, pub, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, priv
+	}
+	ca, caKey := newCert("vaultd test CA", true, nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := vault.New(store)
+	defer v.Close()
+
+	az := SubjectAuthorizer{
+		"dashboard": {"GET /state", "GET /seeds"},
+		"signer":    {"*"},
+	}
+	srv := httptest.NewUnstartedServer(Handler(&chain{}, v, zap.NewNop(), WithAuthorizer(az)))
+	srv.TLS = &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	newClient := func(cn string) *Client {
+		cert, key := newCert(cn, false, ca, caKey)
+		return NewClient(srv.URL, "", WithTLSConfig(&tls.Config{
+			RootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  key,
+				Leaf:        cert,
+			}},
+		}))
+	}
+
+	dashboard := newClient("dashboard")
+	if _, err := dashboard.State(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := dashboard.Unlock(context.Background(), "foo bar baz"); err == nil || !strings.Contains(err.Error(), "not permitted to call POST /unlock") {
+		t.Fatalf("expected permission error, got %v", err)
+	}
+
+	if err := newClient("signer").Unlock(context.Background(), "foo bar baz"); err != nil {
+		t.Fatal(err)
+	} else if _, err := newClient("unknown").State(context.Background()); err == nil || !strings.Contains(err.Error(), "not permitted") {
+		t.Fatalf("expected permission error, got %v", err)
+	}
+
+	// clients without a certificate fail the handshake
+	if _, err := NewClient(srv.URL, "", WithTLSConfig(srv.Client().Transport.(*http.Transport).TLSClientConfig)).State(context.Background()); err == nil {
+		t.Fatal("expected handshake to fail without a client certificate")
+	}
+}
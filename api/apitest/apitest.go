@@ -0,0 +1,147 @@
+// Package apitest provides a vaultd API server for integration tests.
+package apitest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/coreutils/chain"
+	"go.sia.tech/vaultd/api"
+	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap"
+)
+
+// DefaultSecret is the secret used to unlock the test vault if no secret is
+// provided.
+const DefaultSecret = "vaultd test secret"
+
+type (
+	// A Chain is a stub [api.Chain] that returns a fixed consensus state.
+	Chain struct {
+		mu sync.Mutex
+		cs consensus.State
+	}
+
+	// A Vault is a running vaultd API server backed by a temporary
+	// database.
+	Vault struct {
+		*api.Client
+
+		Address string
+		Chain   *Chain
+		Store   *sqlite.Store
+		Vault   *vault.Vault
+	}
+
+	// An Option configures the test vault.
+	Option func(*options)
+
+	options struct {
+		secret     string
+		state      *consensus.State
+		serverOpts []api.ServerOption
+	}
+)
+
+// TipState implements api.Chain.
+func (c *Chain) TipState(context.Context) (consensus.State, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cs, nil
+}
+
+// SetState sets the consensus state returned by TipState.
+func (c *Chain) SetState(cs consensus.State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cs = cs
+}
+
+// WithSecret sets the secret used to unlock the vault. If the secret is
+// empty, the vault is left locked.
+func WithSecret(secret string) Option {
+	return func(o *options) {
+		o.secret = secret
+	}
+}
+
+// WithState sets the initial consensus state returned by the stub chain.
+// The default is the mainnet genesis state.
+func WithState(cs consensus.State) Option {
+	return func(o *options) {
+		o.state = &cs
+	}
+}
+
+// WithServerOptions passes additional options to the API handler.
+func WithServerOptions(opts ...api.ServerOption) Option {
+	return func(o *options) {
+		o.serverOpts = append(o.serverOpts, opts...)
+	}
+}
+
+// StartTestVault starts a vaultd API server with an unlocked vault, a stub
+// chain, and a temporary database. The server is stopped when the test
+// completes.
+func StartTestVault(tb testing.TB, opts ...Option) *Vault {
+	tb.Helper()
+
+	o := options{
+		secret: DefaultSecret,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.state == nil {
+		n, genesis := chain.Mainnet()
+		cs, _ := consensus.ApplyBlock(n.GenesisState(), genesis, consensus.V1BlockSupplement{Transactions: make([]consensus.V1TransactionSupplement, len(genesis.Transactions))}, genesis.Timestamp)
+		o.state = &cs
+	}
+
+	log := zap.NewNop()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { l.Close() })
+
+	store, err := sqlite.OpenDatabase(filepath.Join(tb.TempDir(), "vaultd.sqlite3"), sqlite.WithLogger(log))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { store.Close() })
+
+	v := vault.New(store)
+	tb.Cleanup(func() { v.Close() })
+	if o.secret != "" {
+		if err := v.Unlock(o.secret); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	cm := &Chain{cs: *o.state}
+	s := &http.Server{
+		Handler: api.Handler(cm, v, log, append([]api.ServerOption{api.WithAddressBook(store)}, o.serverOpts...)...),
+	}
+	tb.Cleanup(func() { s.Close() })
+	go func() {
+		if err := s.Serve(l); err != http.ErrServerClosed {
+			tb.Error(err)
+		}
+	}()
+
+	address := "http://" + l.Addr().String()
+	return &Vault{
+		Client:  api.NewClient(address, ""),
+		Address: address,
+		Chain:   cm,
+		Store:   store,
+		Vault:   v,
+	}
+}
@@ -0,0 +1,51 @@
+package apitest
+
+import (
+	"context"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/wallet"
+)
+
+func TestStartTestVault(t *testing.T) {
+	v := StartTestVault(t)
+
+	cs, err := v.Chain.TipState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if cs.Index.Height != 0 {
+		t.Fatalf("expected genesis state, got height %d", cs.Index.Height)
+	}
+
+	meta, err := v.AddSeed(context.Background(), wallet.NewSeedPhrase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := v.GenerateKeys(context.Background(), meta.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{
+			{ParentID: types.SiacoinOutputID{1}, UnlockConditions: types.StandardUnlockConditions(keys[0].PublicKey)},
+		},
+		Signatures: []types.TransactionSignature{
+			{ParentID: types.Hash256{1}, CoveredFields: types.CoveredFields{WholeTransaction: true}},
+		},
+	}
+	signed, ok, err := v.Sign(context.Background(), txn)
+	if err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected transaction to be fully signed")
+	} else if len(signed.Signatures[0].Signature) != 64 {
+		t.Fatal("expected signature")
+	}
+
+	locked := StartTestVault(t, WithSecret(""))
+	if _, err := locked.AddSeed(context.Background(), wallet.NewSeedPhrase()); err == nil {
+		t.Fatal("expected locked vault")
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/apitoken"
+	"go.uber.org/zap"
+)
+
+// maxAPITokenNameLen is the maximum length of an API token's name.
+const maxAPITokenNameLen = 64
+
+type apiTokenKey struct{}
+
+// apiToken returns the API token that authenticated the request, if any.
+func apiToken(ctx context.Context) (apitoken.Token, bool) {
+	t, ok := ctx.Value(apiTokenKey{}).(apitoken.Token)
+	return t, ok
+}
+
+// WithAPITokens adds the routes to manage the API tokens accepted by
+// [AuthenticateTokens].
+func WithAPITokens(s apitoken.Store) ServerOption {
+	return func(a *api) {
+		a.apiTokens = s
+	}
+}
+
+// AuthenticateTokens is like [Authenticate], but also accepts the secret
+// of an API token from the store as the password. Requests authenticated
+// by a token may only call the routes in its scopes. Requests
+// authenticated by the password may call every route.
+func AuthenticateTokens(password string, ts apitoken.Store, h http.Handler) http.Handler {
+	unauthorized := func(w http.ResponseWriter) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, importTokenPrefix) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		_, p, ok := r.BasicAuth()
+		if !ok {
+			unauthorized(w)
+			return
+		} else if p == password {
+			h.ServeHTTP(w, r)
+			return
+		} else if ts == nil {
+			unauthorized(w)
+			return
+		}
+
+		token, err := ts.APITokenByHash(apitoken.Hash(p))
+		if errors.Is(err, apitoken.ErrNotFound) {
+			unauthorized(w)
+			return
+		} else if err != nil {
+			http.Error(w, "failed to get API token", http.StatusInternalServerError)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, token)))
+	})
+}
+
+// requireScope rejects requests authenticated by an API token without the
+// route's scope.
+func (a *api) requireScope(route string, h jape.Handler) jape.Handler {
+	scope := routeScope(route)
+	return func(jc jape.Context) {
+		if token, ok := apiToken(jc.Request.Context()); ok && !token.Allows(scope) {
+			a.requestLog(jc.Request.Context()).Debug("request denied", zap.String("route", route), zap.String("token", token.Name))
+			jc.Error(fmt.Errorf("API token %q does not have the %q scope", token.Name, scope), http.StatusForbidden)
+			return
+		}
+		h(jc)
+	}
+}
+
+func (a *api) handleGETAPITokens(jc jape.Context) {
+	tokens, err := a.apiTokens.APITokens()
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(tokens)
+}
+
+func (a *api) handlePOSTAPITokens(jc jape.Context) {
+	var req APITokenRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if req.Name == "" || len(req.Name) > maxAPITokenNameLen {
+		jc.Error(fmt.Errorf("name must be between 1 and %d characters", maxAPITokenNameLen), http.StatusBadRequest)
+		return
+	} else if len(req.Scopes) == 0 {
+		jc.Error(errors.New("at least one scope is required"), http.StatusBadRequest)
+		return
+	}
+
+	secret, hash := apitoken.Generate()
+	token, err := a.apiTokens.AddAPIToken(req.Name, hash, req.Scopes)
+	if errors.Is(err, apitoken.ErrExists) {
+		jc.Error(err, http.StatusConflict)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("created API token", zap.String("name", token.Name), zap.Any("scopes", token.Scopes))
+	jc.Encode(APITokenResponse{
+		Token:  token,
+		Secret: secret,
+	})
+}
+
+func (a *api) handleDELETEAPITokensID(jc jape.Context) {
+	var id apitoken.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	err := a.apiTokens.DeleteAPIToken(id)
+	if errors.Is(err, apitoken.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("deleted API token", zap.Int64("id", int64(id)))
+	jc.Encode(nil)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/apitoken"
+	"go.uber.org/zap"
+)
+
+// maxAuthorizeBodySize is the maximum size of a request body buffered for
+// authorization.
+const maxAuthorizeBodySize = 32 << 20 // 32 MiB
+
+type (
+	// A Credential contains the credentials presented with a request.
+	Credential struct {
+		Username string
+		Password string
+		// Certificate is the verified TLS client certificate. It is nil
+		// if the client did not present one.
+		Certificate *x509.Certificate
+		// Token is the API token that authenticated the request. It is
+		// nil if the request was authenticated by the password.
+		Token *apitoken.Token
+	}
+
+	// An AuthRequest describes a request to be authorized.
+	AuthRequest struct {
+		Credential Credential
+		// Route is the matched route, for example "POST /seeds/:id/keys".
+		Route string
+		// Params contains the route's path parameters.
+		Params map[string]string
+		// Body is the JSON request body. It is nil if the request has no
+		// body. Implementations can decode it into the route's request
+		// type.
+		Body json.RawMessage
+	}
+
+	// An Authorizer decides whether a request is allowed. Authorize is
+	// called after the request has been authenticated and before it is
+	// handled. If it returns an error, the request is rejected with a
+	// 403 status code and the error's message.
+	Authorizer interface {
+		Authorize(context.Context, AuthRequest) error
+	}
+)
+
+// WithAuthorizer calls the authorizer before every request is handled so
+// embedders can enforce custom policies.
+func WithAuthorizer(az Authorizer) ServerOption {
+	return func(a *api) {
+		a.authorizer = az
+	}
+}
+
+// authorize wraps the handler with a call to the authorizer.
+func (a *api) authorize(route string, h jape.Handler) jape.Handler {
+	return func(jc jape.Context) {
+		req := AuthRequest{
+			Route:  route,
+			Params: make(map[string]string, len(jc.PathParams)),
+		}
+		req.Credential.Username, req.Credential.Password, _ = jc.Request.BasicAuth()
+		if tls := jc.Request.TLS; tls != nil && len(tls.VerifiedChains) > 0 && len(tls.VerifiedChains[0]) > 0 {
+			req.Credential.Certificate = tls.VerifiedChains[0][0]
+		}
+		if token, ok := apiToken(jc.Request.Context()); ok {
+			req.Credential.Token = &token
+		}
+		for _, p := range jc.PathParams {
+			req.Params[p.Key] = p.Value
+		}
+
+		if jc.Request.Body != nil && jc.Request.Body != http.NoBody {
+			buf, err := io.ReadAll(http.MaxBytesReader(jc.ResponseWriter, jc.Request.Body, maxAuthorizeBodySize))
+			if err != nil {
+				jc.Error(fmt.Errorf("failed to read request body: %w", err), http.StatusBadRequest)
+				return
+			}
+			if len(buf) > 0 {
+				req.Body = buf
+			}
+			// restore the body for the handler
+			jc.Request.Body = io.NopCloser(bytes.NewReader(buf))
+		}
+
+		if err := a.authorizer.Authorize(jc.Request.Context(), req); err != nil {
+			a.requestLog(jc.Request.Context()).Debug("request denied", zap.String("route", route), zap.Error(err))
+			jc.Error(err, http.StatusForbidden)
+			return
+		}
+		h(jc)
+	}
+}
+
+// A SubjectAuthorizer allows requests based on the common name of the
+// verified TLS client certificate. Each common name maps to the routes it
+// may call, for example "GET /seeds", or "*" for every route. Requests
+// without a certificate or from an unlisted common name are denied.
+type SubjectAuthorizer map[string][]string
+
+// Authorize implements Authorizer.
+func (sa SubjectAuthorizer) Authorize(_ context.Context, req AuthRequest) error {
+	cert := req.Credential.Certificate
+	if cert == nil {
+		return errors.New("a client certificate is required")
+	}
+	routes, ok := sa[cert.Subject.CommonName]
+	if !ok {
+		return fmt.Errorf("client certificate %q is not permitted", cert.Subject.CommonName)
+	} else if !slices.Contains(routes, "*") && !slices.Contains(routes, req.Route) {
+		return fmt.Errorf("client certificate %q is not permitted to call %s", cert.Subject.CommonName, req.Route)
+	}
+	return nil
+}
@@ -1,17 +1,101 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
-	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/addressbook"
+	"go.sia.tech/vaultd/apitoken"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/importkey"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/snapshot"
 	"go.sia.tech/vaultd/vault"
+	"go.sia.tech/vaultd/webhooks"
 )
 
+// maxPageSize is the largest limit accepted by the paginated routes.
+const maxPageSize = 500
+
 // A Client is an API client for the vaultd API.
 type Client struct {
-	c jape.Client
+	c httpClient
+}
+
+// State returns the state of the vaultd instance.
+func (c *Client) State(ctx context.Context) (resp StateResponse, err error) {
+	err = c.c.GET(ctx, "/state", &resp)
+	return
+}
+
+// Features returns which optional subsystems are enabled on the server.
+func (c *Client) Features(ctx context.Context) (resp FeaturesResponse, err error) {
+	err = c.c.GET(ctx, "/features", &resp)
+	return
+}
+
+// Build returns how the server was built, including the versions of the
+// go.sia.tech modules compiled into it.
+func (c *Client) Build(ctx context.Context) (resp BuildResponse, err error) {
+	err = c.c.GET(ctx, "/state/build", &resp)
+	return
+}
+
+// WaitState blocks until the lock state or tip differs from the state
+// identified by since, or the timeout elapses. An empty since returns
+// the current state immediately. A timeout of zero uses the server's
+// default.
+func (c *Client) WaitState(ctx context.Context, since string, timeout time.Duration) (resp StateWaitResponse, err error) {
+	v := url.Values{}
+	if since != "" {
+		v.Set("since", since)
+	}
+	if timeout > 0 {
+		v.Set("timeout", timeout.String())
+	}
+	path := "/state/wait"
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	wait := timeout
+	if wait <= 0 {
+		wait = defaultStateWaitTimeout
+	}
+	err = c.c.longPoll(ctx, path, wait, &resp)
+	return
+}
+
+// WaitForUnlock blocks until the vault is unlocked or the context is
+// canceled.
+func (c *Client) WaitForUnlock(ctx context.Context) error {
+	var since string
+	for {
+		state, err := c.WaitState(ctx, since, 0)
+		if err != nil {
+			return err
+		} else if state.Unlocked {
+			return nil
+		}
+		since = state.ID
+	}
+}
+
+// ExportDescriptor returns a descriptor of every derived key and spend
+// policy in the vault.
+func (c *Client) ExportDescriptor(ctx context.Context) (desc WalletDescriptor, err error) {
+	err = c.c.GET(ctx, "/export/descriptor", &desc)
+	return
 }
 
 // AddSeed adds a new seed to the vault.
@@ -23,23 +107,329 @@ func (c *Client) AddSeed(ctx context.Context, recoveryPhrase string) (resp SeedR
 	return
 }
 
-// SeedKeys returns the public keys derived from a seed.
-func (c *Client) SeedKeys(ctx context.Context, id vault.SeedID) ([]SeedKey, error) {
-	var resp SeedKeysResponse
-	err := c.c.GET(ctx, fmt.Sprintf("/seeds/%d/keys", id), &resp)
-	return resp.Keys, err
+// NewPhrase generates a 12 word BIP39 recovery phrase in the language, for
+// example "english" or "spanish". The phrase is not added to the vault.
+func (c *Client) NewPhrase(ctx context.Context, language string) (resp PhraseResponse, err error) {
+	err = c.c.GET(ctx, "/phrases/new?language="+url.QueryEscape(language), &resp)
+	return
+}
+
+// GenerateSeed generates a new seed inside the vault from a 12 word BIP39
+// phrase in the language, for example "english" or "spanish". The phrase
+// is only returned once; the caller must record it to recover the seed.
+func (c *Client) GenerateSeed(ctx context.Context, language string) (resp SeedGenerateResponse, err error) {
+	req := SeedGenerateRequest{
+		Language: language,
+		Confirm:  true,
+	}
+	err = c.c.POST(ctx, "/phrases/generate", req, &resp)
+	return
+}
+
+// ValidatePhrase checks a recovery phrase without adding it to the vault.
+func (c *Client) ValidatePhrase(ctx context.Context, recoveryPhrase string) (resp PhraseValidateResponse, err error) {
+	err = c.c.POST(ctx, "/phrases/validate", PhraseValidateRequest{Phrase: recoveryPhrase}, &resp)
+	return
+}
+
+// Fees returns the recommended fee per byte.
+func (c *Client) Fees(ctx context.Context) (resp FeesResponse, err error) {
+	err = c.c.GET(ctx, "/fees", &resp)
+	return
+}
+
+// ImportKey returns a new single-use key that a seed phrase can be
+// encrypted to.
+func (c *Client) ImportKey(ctx context.Context) (resp ImportKeyResponse, err error) {
+	err = c.c.GET(ctx, "/import/key", &resp)
+	return
+}
+
+// AddSeedEncrypted adds a seed to the vault. The phrase is encrypted to a
+// new import key so it is never sent in plaintext.
+func (c *Client) AddSeedEncrypted(ctx context.Context, recoveryPhrase string) (resp SeedResponse, err error) {
+	key, err := c.ImportKey(ctx)
+	if err != nil {
+		return SeedResponse{}, fmt.Errorf("failed to get import key: %w", err)
+	}
+	e, err := importkey.Seal(key.PublicKey, []byte(recoveryPhrase))
+	if err != nil {
+		return SeedResponse{}, fmt.Errorf("failed to encrypt phrase: %w", err)
+	}
+	err = c.c.POST(ctx, "/seeds", AddSeedRequest{EncryptedPhrase: &e}, &resp)
+	return
+}
+
+// CreateImportToken mints a single-use token that lets a party without the
+// API password submit one seed phrase with SubmitSeed.
+func (c *Client) CreateImportToken(ctx context.Context, lifetime time.Duration) (resp ImportTokenResponse, err error) {
+	err = c.c.POST(ctx, "/import/tokens", ImportTokenRequest{Lifetime: lifetime}, &resp)
+	return
+}
+
+// SubmitSeed adds a seed to the vault using an import token. The phrase is
+// encrypted to a new import key. The client does not need the API
+// password.
+func (c *Client) SubmitSeed(ctx context.Context, token, recoveryPhrase string) (resp SeedResponse, err error) {
+	var key ImportKeyResponse
+	if err := c.c.GET(ctx, fmt.Sprintf("/import/tokens/%s/key", token), &key); err != nil {
+		return SeedResponse{}, fmt.Errorf("failed to get import key: %w", err)
+	}
+	e, err := importkey.Seal(key.PublicKey, []byte(recoveryPhrase))
+	if err != nil {
+		return SeedResponse{}, fmt.Errorf("failed to encrypt phrase: %w", err)
+	}
+	err = c.c.POST(ctx, fmt.Sprintf("/import/tokens/%s/seed", token), AddSeedRequest{EncryptedPhrase: &e}, &resp)
+	return
+}
+
+// PreviewSeed validates a recovery phrase and returns the effect of
+// adding it without adding it.
+func (c *Client) PreviewSeed(ctx context.Context, recoveryPhrase string) (resp SeedImportPreview, err error) {
+	req := AddSeedRequest{
+		Phrase: recoveryPhrase,
+	}
+	err = c.c.POST(ctx, "/seeds?dryRun=true", req, &resp)
+	return
+}
+
+// DeleteSeed deletes a seed. It can be restored with RestoreSeed until the
+// retention period passes.
+func (c *Client) DeleteSeed(ctx context.Context, id vault.SeedID) error {
+	return c.c.DELETE(ctx, fmt.Sprintf("/seeds/%d", id))
+}
+
+// RestoreSeed restores a deleted seed.
+func (c *Client) RestoreSeed(ctx context.Context, id vault.SeedID) (resp SeedResponse, err error) {
+	err = c.c.POST(ctx, fmt.Sprintf("/seeds/%d/restore", id), nil, &resp)
+	return
+}
+
+// PreviewRestoreSeed returns the seed that RestoreSeed would restore
+// without restoring it.
+func (c *Client) PreviewRestoreSeed(ctx context.Context, id vault.SeedID) (resp SeedResponse, err error) {
+	err = c.c.POST(ctx, fmt.Sprintf("/seeds/%d/restore?dryRun=true", id), nil, &resp)
+	return
+}
+
+// withKeysOptions returns the path with the options encoded as query
+// parameters.
+func withKeysOptions(path string, opts []KeysOption) string {
+	v := make(url.Values)
+	for _, opt := range opts {
+		opt(v)
+	}
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	return path
+}
+
+// SeedKeys returns a page of the public keys derived from a seed and the
+// total number of keys derived from it. The limit must be between 1 and
+// 500.
+func (c *Client) SeedKeys(ctx context.Context, id vault.SeedID, offset, limit int, opts ...KeysOption) (resp SeedKeysResponse, err error) {
+	opts = append(opts, func(v url.Values) {
+		v.Set("offset", strconv.Itoa(offset))
+		v.Set("limit", strconv.Itoa(limit))
+	})
+	err = c.c.GET(ctx, withKeysOptions(fmt.Sprintf("/seeds/%d/keys", id), opts), &resp)
+	return
+}
+
+// AllSeedKeys returns an iterator over every key derived from a seed,
+// requesting them a page at a time. Iteration stops after the first error.
+func (c *Client) AllSeedKeys(ctx context.Context, id vault.SeedID, opts ...KeysOption) iter.Seq2[SeedKey, error] {
+	return func(yield func(SeedKey, error) bool) {
+		for offset := 0; ; offset += maxPageSize {
+			resp, err := c.SeedKeys(ctx, id, offset, maxPageSize, opts...)
+			if err != nil {
+				yield(SeedKey{}, err)
+				return
+			}
+			for _, key := range resp.Keys {
+				if !yield(key, nil) {
+					return
+				}
+			}
+			if len(resp.Keys) < maxPageSize || offset+len(resp.Keys) >= resp.Total {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns the keys of every seed matching the filter and the total
+// number of matching keys.
+func (c *Client) Keys(ctx context.Context, filter vault.KeyFilter, offset, limit int, opts ...KeysOption) (resp KeysResponse, err error) {
+	opts = append(opts, func(v url.Values) {
+		v.Set("offset", strconv.Itoa(offset))
+		v.Set("limit", strconv.Itoa(limit))
+		if filter.SeedID != 0 {
+			v.Set("seed", strconv.FormatInt(int64(filter.SeedID), 10))
+		}
+		if !filter.CreatedAfter.IsZero() {
+			v.Set("createdAfter", filter.CreatedAfter.Format(time.RFC3339Nano))
+		}
+	})
+	err = c.c.GET(ctx, withKeysOptions("/keys", opts), &resp)
+	return
+}
+
+// SeedDerivation returns a description of how the keys of a seed are
+// derived.
+func (c *Client) SeedDerivation(ctx context.Context, id vault.SeedID) (resp SeedDerivationResponse, err error) {
+	err = c.c.GET(ctx, fmt.Sprintf("/seeds/%d/derivation", id), &resp)
+	return
 }
 
 // GenerateKeys derives new keys from a seed.
-func (c *Client) GenerateKeys(ctx context.Context, id vault.SeedID, count uint64) ([]SeedKey, error) {
+func (c *Client) GenerateKeys(ctx context.Context, id vault.SeedID, count uint64, opts ...KeysOption) ([]SeedKey, error) {
 	req := SeedDeriveRequest{
 		Count: count,
 	}
 	var resp SeedKeysResponse
-	err := c.c.POST(ctx, fmt.Sprintf("/seeds/%d/keys", id), req, &resp)
+	err := c.c.POST(ctx, withKeysOptions(fmt.Sprintf("/seeds/%d/keys", id), opts), req, &resp)
+	return resp.Keys, err
+}
+
+// EnsureKeys derives keys from the seed until it has at least n and
+// returns its first n keys. It is not atomic: concurrent calls for the
+// same seed can derive more keys than needed.
+func (c *Client) EnsureKeys(ctx context.Context, id vault.SeedID, n uint64, opts ...KeysOption) ([]SeedKey, error) {
+	resp, err := c.SeedKeys(ctx, id, 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count keys: %w", err)
+	} else if existing := uint64(resp.Total); existing < n {
+		// derive in batches no larger than the server accepts
+		for missing := n - existing; missing > 0; {
+			count := min(missing, maxDeriveCount)
+			if _, err := c.GenerateKeys(ctx, id, count, opts...); err != nil {
+				return nil, fmt.Errorf("failed to derive keys: %w", err)
+			}
+			missing -= count
+		}
+	}
+
+	keys := make([]SeedKey, 0, n)
+	for uint64(len(keys)) < n {
+		page, err := c.SeedKeys(ctx, id, len(keys), maxPageSize, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get keys: %w", err)
+		} else if len(page.Keys) == 0 {
+			return nil, fmt.Errorf("expected %d keys, got %d", n, len(keys))
+		}
+		keys = append(keys, page.Keys...)
+	}
+	return keys[:n], nil
+}
+
+// ReserveIndices reserves the next count indices of a seed for keys
+// derived outside of the vault.
+func (c *Client) ReserveIndices(ctx context.Context, id vault.SeedID, count uint64) (resp SeedReserveResponse, err error) {
+	err = c.c.POST(ctx, fmt.Sprintf("/seeds/%d/reserve", id), SeedDeriveRequest{Count: count}, &resp)
+	return
+}
+
+// RegisterKeys registers keys derived outside of the vault from reserved
+// indices of a seed. The vault must be unlocked to verify the keys.
+func (c *Client) RegisterKeys(ctx context.Context, id vault.SeedID, keys []RegisterKey, opts ...KeysOption) ([]SeedKey, error) {
+	var resp SeedKeysResponse
+	err := c.c.POST(ctx, withKeysOptions(fmt.Sprintf("/seeds/%d/keys/register", id), opts), SeedRegisterRequest{Keys: keys}, &resp)
 	return resp.Keys, err
 }
 
+// Stats returns the server's counters.
+func (c *Client) Stats(ctx context.Context) (resp StatsResponse, err error) {
+	err = c.c.GET(ctx, "/stats", &resp)
+	return
+}
+
+// CheckConsistency runs a consistency check of the vault's store and
+// returns the issues found.
+func (c *Client) CheckConsistency(ctx context.Context) (issues []vault.ConsistencyIssue, err error) {
+	var resp SystemCheckResponse
+	err = c.c.POST(ctx, "/system/check", nil, &resp)
+	return resp.Issues, err
+}
+
+// AddressKey returns the key associated with a v1 or v2 address.
+func (c *Client) AddressKey(ctx context.Context, addr types.Address) (resp AddressKeyResponse, err error) {
+	err = c.c.GET(ctx, fmt.Sprintf("/addresses/%v/key", addr), &resp)
+	return
+}
+
+// CustodyCommitment returns the current custody commitment.
+func (c *Client) CustodyCommitment(ctx context.Context) (commitment custody.Commitment, err error) {
+	err = c.c.GET(ctx, "/custody/commitment", &commitment)
+	return
+}
+
+// CustodyProof returns a proof that the address's key is included in the
+// custody commitment.
+func (c *Client) CustodyProof(ctx context.Context, addr types.Address) (resp CustodyProofResponse, err error) {
+	err = c.c.GET(ctx, fmt.Sprintf("/custody/proofs/%v", addr), &resp)
+	return
+}
+
+// Snapshots returns every snapshot.
+func (c *Client) Snapshots(ctx context.Context) (snapshots []snapshot.Snapshot, err error) {
+	err = c.c.GET(ctx, "/snapshots", &snapshots)
+	return
+}
+
+// CreateSnapshot creates a snapshot of the vault's database.
+func (c *Client) CreateSnapshot(ctx context.Context, name string) (s snapshot.Snapshot, err error) {
+	err = c.c.POST(ctx, "/snapshots", SnapshotRequest{Name: name}, &s)
+	return
+}
+
+// RestoreSnapshot restores the vault's database from a snapshot. The
+// snapshot of the state before the restore is returned.
+func (c *Client) RestoreSnapshot(ctx context.Context, name string) (snapshot.Snapshot, error) {
+	var resp SnapshotRestoreResponse
+	err := c.c.POST(ctx, fmt.Sprintf("/snapshots/%s/restore", name), nil, &resp)
+	return resp.Backup, err
+}
+
+// PreviewSnapshot validates a snapshot and returns what restoring it
+// would change without restoring it.
+func (c *Client) PreviewSnapshot(ctx context.Context, name string) (resp snapshot.Preview, err error) {
+	err = c.c.POST(ctx, fmt.Sprintf("/snapshots/%s/restore?dryRun=true", name), nil, &resp)
+	return
+}
+
+// OwnershipProofs signs the challenge with the given keys.
+func (c *Client) OwnershipProofs(ctx context.Context, challenge string, keys []types.PublicKey) (resp OwnershipProofResponse, err error) {
+	err = c.c.POST(ctx, "/proofs/ownership", OwnershipProofRequest{Challenge: challenge, PublicKeys: keys}, &resp)
+	return
+}
+
+// ProveOwnership signs the challenge with every key in the vault in
+// batches of batchSize keys. If progress is not nil, it is called after
+// each batch with the number of keys signed so far and the total.
+func (c *Client) ProveOwnership(ctx context.Context, challenge string, batchSize int, progress func(done, total int)) ([]OwnershipProof, error) {
+	var proofs []OwnershipProof
+	for {
+		var resp OwnershipProofResponse
+		err := c.c.POST(ctx, "/proofs/ownership", OwnershipProofRequest{
+			Challenge: challenge,
+			Offset:    len(proofs),
+			Limit:     batchSize,
+		}, &resp)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, resp.Proofs...)
+		if progress != nil {
+			progress(len(proofs), resp.Total)
+		}
+		if len(resp.Proofs) == 0 || len(proofs) >= resp.Total {
+			return proofs, nil
+		}
+	}
+}
+
 // Sign signs a transaction using the vaultd.
 func (c *Client) Sign(ctx context.Context, txn types.Transaction, opts ...SignOption) (types.Transaction, bool, error) {
 	req := SignRequest{
@@ -53,6 +443,65 @@ func (c *Client) Sign(ctx context.Context, txn types.Transaction, opts ...SignOp
 	return resp.Transaction, resp.FullySigned, err
 }
 
+// SignAndVerify signs a v1 transaction like [Client.Sign] and verifies
+// every signature the vault added against the consensus state. An error is
+// returned if the vault changed the transaction or added an invalid
+// signature.
+func (c *Client) SignAndVerify(ctx context.Context, txn types.Transaction, cs consensus.State, opts ...SignOption) (types.Transaction, bool, error) {
+	signed, fullySigned, err := c.Sign(ctx, txn, append([]SignOption{SignWithState(cs)}, opts...)...)
+	if err != nil {
+		return types.Transaction{}, false, err
+	} else if signed.ID() != txn.ID() || len(signed.Signatures) != len(txn.Signatures) {
+		return types.Transaction{}, false, errors.New("vault returned a different transaction")
+	}
+
+	unlockConditions := make(map[types.Hash256]types.UnlockConditions)
+	for _, sci := range signed.SiacoinInputs {
+		unlockConditions[types.Hash256(sci.ParentID)] = sci.UnlockConditions
+	}
+	for _, sfi := range signed.SiafundInputs {
+		unlockConditions[types.Hash256(sfi.ParentID)] = sfi.UnlockConditions
+	}
+
+	for i, sig := range signed.Signatures {
+		if txn.Signatures[i].Signature != nil {
+			if !bytes.Equal(sig.Signature, txn.Signatures[i].Signature) {
+				return types.Transaction{}, false, fmt.Errorf("vault changed signature %d", i)
+			}
+			continue
+		} else if sig.Signature == nil {
+			continue // skipped
+		}
+
+		uc, ok := unlockConditions[sig.ParentID]
+		if !ok || sig.PublicKeyIndex >= uint64(len(uc.PublicKeys)) {
+			return types.Transaction{}, false, fmt.Errorf("signature %d has no public key", i)
+		}
+		uk := uc.PublicKeys[sig.PublicKeyIndex]
+		if uk.Algorithm != types.SpecifierEd25519 || len(uk.Key) != ed25519.PublicKeySize || len(sig.Signature) != ed25519.SignatureSize {
+			return types.Transaction{}, false, fmt.Errorf("signature %d is not an ed25519 signature", i)
+		}
+
+		var sigHash types.Hash256
+		if sig.CoveredFields.WholeTransaction {
+			sigHash = cs.WholeSigHash(signed, sig.ParentID, sig.PublicKeyIndex, sig.Timelock, nil)
+		} else {
+			sigHash = cs.PartialSigHash(signed, sig.CoveredFields)
+		}
+		if !types.PublicKey(uk.Key).VerifyHash(sigHash, types.Signature(sig.Signature)) {
+			return types.Transaction{}, false, fmt.Errorf("signature %d is invalid", i)
+		}
+	}
+	return signed, fullySigned, nil
+}
+
+// BlindSign signs a hash with the given public key.
+func (c *Client) BlindSign(ctx context.Context, pk types.PublicKey, sigHash types.Hash256) (types.Signature, error) {
+	var resp BlindSignResponse
+	err := c.c.POST(ctx, "/blind/sign", BlindSignRequest{PublicKey: pk, SigHash: sigHash}, &resp)
+	return resp.Signature, err
+}
+
 // SignV2 signs a v2 transaction using the vaultd.
 func (c *Client) SignV2(ctx context.Context, txn types.V2Transaction, opts ...SignV2Option) (types.V2Transaction, bool, error) {
 	req := SignV2Request{
@@ -78,6 +527,43 @@ func (c *Client) Unlock(ctx context.Context, secret string) error {
 	}, nil)
 }
 
+// SetSeedExpiration sets the time after which the seed's keys cannot sign.
+// A zero time removes the expiration.
+func (c *Client) SetSeedExpiration(ctx context.Context, id vault.SeedID, expiresAt time.Time) error {
+	return c.c.PUT(ctx, fmt.Sprintf("/seeds/%d/expiration", id), ExpirationRequest{ExpiresAt: expiresAt})
+}
+
+// SetKeyExpiration sets the time after which the key cannot sign. A zero
+// time removes the expiration.
+func (c *Client) SetKeyExpiration(ctx context.Context, pk types.PublicKey, expiresAt time.Time) error {
+	return c.c.PUT(ctx, fmt.Sprintf("/keys/%v/expiration", pk), ExpirationRequest{ExpiresAt: expiresAt})
+}
+
+// Expirations returns the seed and key expirations before the given time,
+// sorted by expiration.
+func (c *Client) Expirations(ctx context.Context, before time.Time) (exps []Expiration, err error) {
+	err = c.c.GET(ctx, "/expirations?before="+url.QueryEscape(before.Format(time.RFC3339)), &exps)
+	return
+}
+
+// SplitUnlockSecret initializes the vault with a random secret split into
+// n shares, any threshold of which unlock the vault. The vault is unlocked
+// and must not have been unlocked before.
+func (c *Client) SplitUnlockSecret(ctx context.Context, threshold, n int) (resp UnlockSplitResponse, err error) {
+	err = c.c.POST(ctx, "/unlock/split", UnlockSplitRequest{
+		Threshold: threshold,
+		Shares:    n,
+	}, &resp)
+	return
+}
+
+// UnlockShare submits a share of the vault secret. The vault is unlocked
+// once the threshold of shares has been submitted.
+func (c *Client) UnlockShare(ctx context.Context, share string) (resp UnlockShareResponse, err error) {
+	err = c.c.POST(ctx, "/unlock/share", UnlockShareRequest{Share: share}, &resp)
+	return
+}
+
 // Seed returns metadata about a seed. If the seed ID is not found,
 // [vault.ErrNotFound] is returned.
 func (c *Client) Seed(ctx context.Context, id vault.SeedID) (SeedResponse, error) {
@@ -93,12 +579,268 @@ func (c *Client) Seeds(ctx context.Context, offset, limit int) ([]vault.SeedMeta
 	return resp.Seeds, err
 }
 
+// AllSeeds returns an iterator over every seed in the vault, requesting
+// them a page at a time. Iteration stops after the first error.
+func (c *Client) AllSeeds(ctx context.Context) iter.Seq2[vault.SeedMeta, error] {
+	return func(yield func(vault.SeedMeta, error) bool) {
+		for offset := 0; ; offset += maxPageSize {
+			seeds, err := c.Seeds(ctx, offset, maxPageSize)
+			if err != nil {
+				yield(vault.SeedMeta{}, err)
+				return
+			}
+			for _, seed := range seeds {
+				if !yield(seed, nil) {
+					return
+				}
+			}
+			if len(seeds) < maxPageSize {
+				return
+			}
+		}
+	}
+}
+
+// OfflineRequest exports the sighashes of a transaction that need to be
+// signed by an offline signer.
+func (c *Client) OfflineRequest(ctx context.Context, txn types.Transaction, opts ...SignOption) (resp offline.SignRequest, err error) {
+	sr := SignRequest{Transaction: txn}
+	for _, opt := range opts {
+		opt(&sr)
+	}
+	req := OfflineRequest{
+		State:       sr.State,
+		Network:     sr.Network,
+		Transaction: txn,
+	}
+	err = c.c.POST(ctx, "/offline/requests", req, &resp)
+	return
+}
+
+// OfflineRequestV2 exports the sighashes of a v2 transaction that need to
+// be signed by an offline signer.
+func (c *Client) OfflineRequestV2(ctx context.Context, txn types.V2Transaction, opts ...SignV2Option) (resp offline.SignRequest, err error) {
+	sr := SignV2Request{Transaction: txn}
+	for _, opt := range opts {
+		opt(&sr)
+	}
+	req := OfflineV2Request{
+		State:       sr.State,
+		Network:     sr.Network,
+		Transaction: txn,
+	}
+	err = c.c.POST(ctx, "/v2/offline/requests", req, &resp)
+	return
+}
+
+// OfflineSign signs an exported sign request. It is called on the offline
+// signer.
+func (c *Client) OfflineSign(ctx context.Context, req offline.SignRequest) (resp offline.SignResponse, err error) {
+	err = c.c.POST(ctx, "/offline/sign", req, &resp)
+	return
+}
+
+// OfflineMerge adds the signatures from an offline signer to a
+// transaction.
+func (c *Client) OfflineMerge(ctx context.Context, txn types.Transaction, signatures offline.SignResponse, opts ...SignOption) (types.Transaction, bool, error) {
+	sr := SignRequest{Transaction: txn}
+	for _, opt := range opts {
+		opt(&sr)
+	}
+	req := OfflineMergeRequest{
+		State:       sr.State,
+		Network:     sr.Network,
+		Transaction: txn,
+		Response:    signatures,
+	}
+	var resp SignResponse
+	err := c.c.POST(ctx, "/offline/merge", req, &resp)
+	return resp.Transaction, resp.FullySigned, err
+}
+
+// OfflineMergeV2 adds the signatures from an offline signer to a v2
+// transaction.
+func (c *Client) OfflineMergeV2(ctx context.Context, txn types.V2Transaction, signatures offline.SignResponse, opts ...SignV2Option) (types.V2Transaction, bool, error) {
+	sr := SignV2Request{Transaction: txn}
+	for _, opt := range opts {
+		opt(&sr)
+	}
+	req := OfflineMergeV2Request{
+		State:       sr.State,
+		Network:     sr.Network,
+		Transaction: txn,
+		Response:    signatures,
+	}
+	var resp SignV2Response
+	err := c.c.POST(ctx, "/v2/offline/merge", req, &resp)
+	return resp.Transaction, resp.FullySigned, err
+}
+
+// AddressBook returns a paginated list of address book entries.
+func (c *Client) AddressBook(ctx context.Context, offset, limit int) (entries []addressbook.Entry, err error) {
+	err = c.c.GET(ctx, fmt.Sprintf("/addressbook?offset=%d&limit=%d", offset, limit), &entries)
+	return
+}
+
+// AddressBookEntry returns a single address book entry.
+func (c *Client) AddressBookEntry(ctx context.Context, id addressbook.ID) (entry addressbook.Entry, err error) {
+	err = c.c.GET(ctx, fmt.Sprintf("/addressbook/%d", id), &entry)
+	return
+}
+
+// AddAddressBookEntry adds an address to the address book.
+func (c *Client) AddAddressBookEntry(ctx context.Context, addr types.Address, label string, verified bool) (entry addressbook.Entry, err error) {
+	req := AddressBookRequest{
+		Address:  addr,
+		Label:    label,
+		Verified: verified,
+	}
+	err = c.c.POST(ctx, "/addressbook", req, &entry)
+	return
+}
+
+// UpdateAddressBookEntry updates the label and verification status of an
+// address book entry.
+func (c *Client) UpdateAddressBookEntry(ctx context.Context, id addressbook.ID, label string, verified bool) error {
+	req := AddressBookUpdateRequest{
+		Label:    label,
+		Verified: verified,
+	}
+	return c.c.PUT(ctx, fmt.Sprintf("/addressbook/%d", id), req)
+}
+
+// DeleteAddressBookEntry removes an entry from the address book.
+func (c *Client) DeleteAddressBookEntry(ctx context.Context, id addressbook.ID) error {
+	return c.c.DELETE(ctx, fmt.Sprintf("/addressbook/%d", id))
+}
+
+// RequestUnlock creates a request to unlock the vault with the secret from
+// the server's secret manager. An administrator must approve the returned
+// code with [Client.ApproveUnlock].
+func (c *Client) RequestUnlock(ctx context.Context) (req UnlockApproval, err error) {
+	err = c.c.POST(ctx, "/unlock/requests", nil, &req)
+	return
+}
+
+// UnlockRequest returns the state of an unlock request.
+func (c *Client) UnlockRequest(ctx context.Context, code string) (req UnlockApproval, err error) {
+	err = c.c.GET(ctx, "/unlock/requests/"+url.PathEscape(code), &req)
+	return
+}
+
+// ApproveUnlock approves an unlock request, unlocking the vault.
+func (c *Client) ApproveUnlock(ctx context.Context, code string) (req UnlockApproval, err error) {
+	err = c.c.POST(ctx, "/unlock/requests/"+url.PathEscape(code)+"/approve", nil, &req)
+	return
+}
+
+// Maintenance returns the maintenance state of the vault.
+func (c *Client) Maintenance(ctx context.Context) (resp MaintenanceResponse, err error) {
+	err = c.c.GET(ctx, "/maintenance", &resp)
+	return
+}
+
+// RequestMaintenanceOverride requests an emergency override of the
+// maintenance windows. An administrator must approve the returned code
+// with [Client.ApproveMaintenanceOverride].
+func (c *Client) RequestMaintenanceOverride(ctx context.Context, reason string) (override MaintenanceOverride, err error) {
+	err = c.c.POST(ctx, "/maintenance/overrides", MaintenanceOverrideRequest{Reason: reason}, &override)
+	return
+}
+
+// MaintenanceOverride returns the state of an override request.
+func (c *Client) MaintenanceOverride(ctx context.Context, code string) (override MaintenanceOverride, err error) {
+	err = c.c.GET(ctx, "/maintenance/overrides/"+url.PathEscape(code), &override)
+	return
+}
+
+// ApproveMaintenanceOverride approves an override request, allowing
+// signing during maintenance windows.
+func (c *Client) ApproveMaintenanceOverride(ctx context.Context, code string) (override MaintenanceOverride, err error) {
+	err = c.c.POST(ctx, "/maintenance/overrides/"+url.PathEscape(code)+"/approve", nil, &override)
+	return
+}
+
+// APITokens returns every API token.
+func (c *Client) APITokens(ctx context.Context) (tokens []apitoken.Token, err error) {
+	err = c.c.GET(ctx, "/tokens", &tokens)
+	return
+}
+
+// CreateAPIToken creates an API token with the given scopes. The returned
+// secret cannot be retrieved again.
+func (c *Client) CreateAPIToken(ctx context.Context, name string, scopes ...apitoken.Scope) (resp APITokenResponse, err error) {
+	err = c.c.POST(ctx, "/tokens", APITokenRequest{Name: name, Scopes: scopes}, &resp)
+	return
+}
+
+// DeleteAPIToken deletes an API token.
+func (c *Client) DeleteAPIToken(ctx context.Context, id apitoken.ID) error {
+	return c.c.DELETE(ctx, fmt.Sprintf("/tokens/%d", id))
+}
+
+// Webhooks returns the registered webhooks.
+func (c *Client) Webhooks(ctx context.Context) (hooks []webhooks.Webhook, err error) {
+	err = c.c.GET(ctx, "/webhooks", &hooks)
+	return
+}
+
+// RegisterWebhook registers a webhook that receives the events in the
+// given scopes.
+func (c *Client) RegisterWebhook(ctx context.Context, callbackURL string, scopes ...string) (hook webhooks.Webhook, err error) {
+	err = c.c.POST(ctx, "/webhooks", WebhookRequest{CallbackURL: callbackURL, Scopes: scopes}, &hook)
+	return
+}
+
+// UpdateWebhook updates the callback URL and scopes of a webhook.
+func (c *Client) UpdateWebhook(ctx context.Context, id webhooks.ID, callbackURL string, scopes ...string) error {
+	return c.c.PUT(ctx, fmt.Sprintf("/webhooks/%d", id), WebhookRequest{CallbackURL: callbackURL, Scopes: scopes})
+}
+
+// DeleteWebhook deletes a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, id webhooks.ID) error {
+	return c.c.DELETE(ctx, fmt.Sprintf("/webhooks/%d", id))
+}
+
+// TestWebhook sends a test event to a webhook.
+func (c *Client) TestWebhook(ctx context.Context, id webhooks.ID) error {
+	return c.c.POST(ctx, fmt.Sprintf("/webhooks/%d/test", id), nil, nil)
+}
+
+// A ClientOption configures a Client.
+type ClientOption func(*httpClient)
+
+// WithTLSConfig sets the TLS configuration used to connect to the API, for
+// example to present a client certificate or trust a private CA.
+func WithTLSConfig(tlsConfig *tls.Config) ClientOption {
+	return func(c *httpClient) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		c.client = &http.Client{Transport: transport}
+	}
+}
+
+// WithTimeout limits the duration of each request, including reading the
+// response, unless the request's context has an earlier deadline. Long
+// polls such as [Client.WaitState] are allowed their wait in addition to
+// the timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *httpClient) {
+		c.timeout = timeout
+	}
+}
+
 // NewClient creates a new API client.
-func NewClient(address, password string) *Client {
-	return &Client{
-		c: jape.Client{
-			BaseURL:  address,
-			Password: password,
+func NewClient(address, password string, opts ...ClientOption) *Client {
+	c := &Client{
+		c: httpClient{
+			baseURL:  address,
+			password: password,
+			client:   http.DefaultClient,
 		},
 	}
+	for _, opt := range opts {
+		opt(&c.c)
+	}
+	return c
 }
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"go.sia.tech/jape"
+)
+
+// compressedRoutes are the routes whose responses grow with the number of
+// seeds, keys, or entries in the vault. Their responses are compressed
+// when the client accepts gzip.
+var compressedRoutes = []string{
+	"GET /seeds",
+	"GET /seeds/:id/keys",
+	"GET /keys",
+	"GET /export/descriptor",
+	"POST /system/check",
+	"GET /addressbook",
+}
+
+// gzipResponseWriter compresses successful responses. Error responses are
+// written uncompressed because [http.Error] removes the Content-Encoding
+// header.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (gw *gzipResponseWriter) WriteHeader(status int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+	if status == http.StatusOK {
+		gw.Header().Set("Content-Encoding", "gzip")
+		gw.Header().Del("Content-Length")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(status)
+}
+
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !gw.wroteHeader {
+		gw.WriteHeader(http.StatusOK)
+	}
+	if gw.gz == nil {
+		return gw.ResponseWriter.Write(b)
+	}
+	return gw.gz.Write(b)
+}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// close flushes the compressed response, if any.
+func (gw *gzipResponseWriter) close() error {
+	if gw.gz == nil {
+		return nil
+	}
+	return gw.gz.Close()
+}
+
+// acceptsGzip returns true if the Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, enc := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		// a quality of zero means the encoding is not acceptable
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// compress gzips the route's response if the client accepts it. Go's HTTP
+// client, and so the API client, sends the header and decompresses the
+// response automatically.
+func compress(h jape.Handler) jape.Handler {
+	return func(jc jape.Context) {
+		jc.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(jc.Request.Header.Get("Accept-Encoding")) {
+			h(jc)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: jc.ResponseWriter}
+		defer gw.close()
+		jc.ResponseWriter = gw
+		h(jc)
+	}
+}
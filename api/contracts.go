@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+
+	"go.sia.tech/core/types"
+)
+
+// ContractGuardrails are optional checks on the file contract revisions
+// and resolutions of a v2 transaction. They protect hosts that delegate
+// signing to vaultd from buggy upstream software. A transaction that fails
+// a check is rejected before it is signed.
+type ContractGuardrails struct {
+	// RevisionNumber requires every revision to increase the contract's
+	// revision number.
+	RevisionNumber bool
+	// HostPayout requires every revision and renewal to pay the host at
+	// least as much as the parent contract and to the same address, and
+	// requires every revision to risk no more than the host's collateral
+	// if the storage proof is missed.
+	HostPayout bool
+}
+
+// WithContractGuardrails enables the contract checks on [POST] /v2/sign.
+func WithContractGuardrails(g ContractGuardrails) ServerOption {
+	return func(a *api) {
+		a.guardrails = g
+	}
+}
+
+// checkContracts returns an error if the transaction fails one of the
+// enabled contract guardrails.
+func (g ContractGuardrails) checkContracts(txn types.V2Transaction) error {
+	for i, fcr := range txn.FileContractRevisions {
+		parent, rev := fcr.Parent.V2FileContract, fcr.Revision
+		if g.RevisionNumber && rev.RevisionNumber <= parent.RevisionNumber {
+			return fmt.Errorf("file contract revision %d (%v) does not increase the revision number (%d <= %d)", i, fcr.Parent.ID, rev.RevisionNumber, parent.RevisionNumber)
+		}
+		if !g.HostPayout {
+			continue
+		}
+		// the request is untrusted, so sums must not panic on overflow
+		maxPayout, overflow := rev.MissedHostValue.AddWithOverflow(rev.TotalCollateral)
+		switch {
+		case rev.HostOutput.Address != parent.HostOutput.Address:
+			return fmt.Errorf("file contract revision %d (%v) changes the host payout address", i, fcr.Parent.ID)
+		case rev.HostOutput.Value.Cmp(parent.HostOutput.Value) < 0:
+			return fmt.Errorf("file contract revision %d (%v) decreases the host payout (%v < %v)", i, fcr.Parent.ID, rev.HostOutput.Value, parent.HostOutput.Value)
+		case !overflow && rev.HostOutput.Value.Cmp(maxPayout) > 0:
+			return fmt.Errorf("file contract revision %d (%v) risks more than the host's collateral of %v", i, fcr.Parent.ID, rev.TotalCollateral)
+		}
+	}
+
+	if !g.HostPayout {
+		return nil
+	}
+	for i, res := range txn.FileContractResolutions {
+		renewal, ok := res.Resolution.(*types.V2FileContractRenewal)
+		if !ok {
+			continue
+		}
+		parent := res.Parent.V2FileContract
+		payout, overflow := renewal.FinalHostOutput.Value.AddWithOverflow(renewal.HostRollover)
+		switch {
+		case renewal.FinalHostOutput.Address != parent.HostOutput.Address:
+			return fmt.Errorf("file contract renewal %d (%v) changes the host payout address", i, res.Parent.ID)
+		case !overflow && payout.Cmp(parent.HostOutput.Value) < 0:
+			return fmt.Errorf("file contract renewal %d (%v) decreases the host payout (%v < %v)", i, res.Parent.ID, payout, parent.HostOutput.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.sia.tech/jape"
+	"go.uber.org/zap"
+)
+
+// latencySamples is the number of recent requests per route used to
+// calculate latency percentiles.
+const latencySamples = 1024
+
+type (
+	// An SLO is a latency objective for signing requests: Target is the
+	// fraction of requests that should complete within Latency.
+	SLO struct {
+		Latency time.Duration
+		Target  float64
+	}
+
+	// routeLatency records the latency of requests to a single route.
+	routeLatency struct {
+		count   uint64
+		samples []time.Duration // ring buffer of the most recent requests
+		next    int
+		max     time.Duration
+	}
+
+	// latencyTracker records per-route latency and signing SLO burn.
+	latencyTracker struct {
+		mu     sync.Mutex
+		routes map[string]*routeLatency
+
+		signRequests uint64
+		signSlow     uint64
+	}
+)
+
+// WithSlowRequestThreshold logs a warning for every request that takes
+// longer than d. Zero disables the warnings.
+func WithSlowRequestThreshold(d time.Duration) ServerOption {
+	return func(a *api) {
+		a.slowRequestThreshold = d
+	}
+}
+
+// WithSigningSLO reports the burn of the latency objective for sign
+// requests in [GET] /stats.
+func WithSigningSLO(slo SLO) ServerOption {
+	return func(a *api) {
+		a.signingSLO = slo
+	}
+}
+
+// isSignRoute returns true if the route signs with the vault's keys.
+func isSignRoute(route string) bool {
+	return strings.HasSuffix(route, "/sign")
+}
+
+// record adds a request's latency to the route's samples.
+func (lt *latencyTracker) record(route string, elapsed time.Duration, slo SLO) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	rl, ok := lt.routes[route]
+	if !ok {
+		rl = &routeLatency{samples: make([]time.Duration, 0, latencySamples)}
+		lt.routes[route] = rl
+	}
+	rl.count++
+	rl.max = max(rl.max, elapsed)
+	if len(rl.samples) < latencySamples {
+		rl.samples = append(rl.samples, elapsed)
+	} else {
+		rl.samples[rl.next] = elapsed
+		rl.next = (rl.next + 1) % latencySamples
+	}
+
+	if slo.Latency > 0 && isSignRoute(route) {
+		lt.signRequests++
+		if elapsed > slo.Latency {
+			lt.signSlow++
+		}
+	}
+}
+
+// percentile returns the p-th percentile of the sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// stats returns the latency of each route and the signing SLO burn.
+func (lt *latencyTracker) stats(slo SLO) (map[string]LatencyStats, *SLOStats) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	routes := make(map[string]LatencyStats, len(lt.routes))
+	for route, rl := range lt.routes {
+		sorted := slices.Clone(rl.samples)
+		slices.Sort(sorted)
+		routes[route] = LatencyStats{
+			Count: rl.count,
+			P50:   percentile(sorted, 0.50),
+			P90:   percentile(sorted, 0.90),
+			P99:   percentile(sorted, 0.99),
+			Max:   rl.max,
+		}
+	}
+
+	if slo.Latency <= 0 {
+		return routes, nil
+	}
+	s := &SLOStats{
+		Latency:  slo.Latency,
+		Target:   slo.Target,
+		Requests: lt.signRequests,
+		Slow:     lt.signSlow,
+	}
+	// the burn rate is the fraction of the error budget consumed: 1 means
+	// slow requests are occurring exactly as often as the target allows
+	if s.Requests > 0 && slo.Target < 1 {
+		s.BurnRate = (float64(s.Slow) / float64(s.Requests)) / (1 - slo.Target)
+	}
+	return routes, s
+}
+
+// trackLatency records the latency of each request to the route and logs
+// requests slower than the configured threshold.
+func (a *api) trackLatency(route string, h jape.Handler) jape.Handler {
+	return func(jc jape.Context) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: jc.ResponseWriter, status: http.StatusOK}
+		jc.ResponseWriter = rec
+		h(jc)
+		elapsed := time.Since(start)
+
+		a.latency.record(route, elapsed, a.signingSLO)
+		if a.slowRequestThreshold > 0 && elapsed > a.slowRequestThreshold {
+			a.requestLog(jc.Request.Context()).Warn("slow request", zap.String("route", route), zap.Int("status", rec.status), zap.Duration("elapsed", elapsed), zap.Duration("threshold", a.slowRequestThreshold))
+		}
+	}
+}
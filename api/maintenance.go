@@ -0,0 +1,195 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/notify"
+	"go.uber.org/zap"
+)
+
+const (
+	// overrideRequestLifetime is how long a maintenance override request
+	// can be approved.
+	overrideRequestLifetime = 10 * time.Minute
+	// maxOverrideRequests is the maximum number of pending maintenance
+	// override requests.
+	maxOverrideRequests = 10
+)
+
+// WithMaintenanceWindows refuses sign requests during the windows. Signing
+// can be re-enabled during a window by approving an emergency override,
+// which lasts for overrideDuration.
+func WithMaintenanceWindows(windows []TimeWindow, overrideDuration time.Duration) ServerOption {
+	return func(a *api) {
+		a.maintenanceWindows = windows
+		a.overrideDuration = overrideDuration
+	}
+}
+
+// activeWindow returns whether any of the windows contains t and the
+// latest end of the windows that do.
+func activeWindow(windows []TimeWindow, t time.Time) (active bool, endsAt time.Time) {
+	for _, w := range windows {
+		if ok, end := w.contains(t); ok {
+			active = true
+			if end.After(endsAt) {
+				endsAt = end
+			}
+		}
+	}
+	return
+}
+
+// maintenanceError returns the error of a request refused during a
+// maintenance window that ends at endsAt.
+func maintenanceError(endsAt time.Time) error {
+	return fmt.Errorf("signing is disabled during a maintenance window until %s", endsAt.UTC().Format(time.RFC3339))
+}
+
+// refusesDuringMaintenance returns true if the route is disabled during a
+// maintenance window.
+func refusesDuringMaintenance(route string) bool {
+	return isSignRoute(route) || route == "POST /proofs/ownership"
+}
+
+// maintenance returns whether a maintenance window is active at t, the
+// latest end of the active windows, and the expiration of the approved
+// override, if any.
+func (a *api) maintenance(t time.Time) (active bool, endsAt, overrideExpiresAt time.Time) {
+	active, endsAt = activeWindow(a.maintenanceWindows, t)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if t.Before(a.overrideExpiresAt) {
+		overrideExpiresAt = a.overrideExpiresAt
+	}
+	return
+}
+
+// checkMaintenance refuses the request if a maintenance window is active
+// and no override has been approved.
+func (a *api) checkMaintenance(route string, h jape.Handler) jape.Handler {
+	return func(jc jape.Context) {
+		active, endsAt, overrideExpiresAt := a.maintenance(time.Now())
+		if !active {
+			h(jc)
+			return
+		} else if !overrideExpiresAt.IsZero() {
+			a.requestLog(jc.Request.Context()).Info("signing during maintenance window with override", zap.String("route", route), zap.Time("overrideExpires", overrideExpiresAt))
+			h(jc)
+			return
+		}
+
+		retry := math.Ceil(time.Until(endsAt).Seconds())
+		jc.ResponseWriter.Header().Set("Retry-After", strconv.Itoa(int(retry)))
+		jc.Error(maintenanceError(endsAt), http.StatusServiceUnavailable)
+	}
+}
+
+// pruneMaintenanceOverrides removes expired override requests.
+// It is expected that the caller holds the mutex.
+func (a *api) pruneMaintenanceOverrides() {
+	now := time.Now()
+	for code, req := range a.maintenanceOverrides {
+		if now.After(req.ExpiresAt) {
+			delete(a.maintenanceOverrides, code)
+		}
+	}
+}
+
+func (a *api) handleGETMaintenance(jc jape.Context) {
+	active, endsAt, overrideExpiresAt := a.maintenance(time.Now())
+	jc.Encode(MaintenanceResponse{
+		Active:            active,
+		EndsAt:            endsAt,
+		OverrideExpiresAt: overrideExpiresAt,
+	})
+}
+
+func (a *api) handlePOSTMaintenanceOverrides(jc jape.Context) {
+	var req MaintenanceOverrideRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if req.Reason == "" {
+		jc.Error(errors.New("a reason is required"), http.StatusBadRequest)
+		return
+	}
+
+	override := MaintenanceOverride{
+		Code:        newApprovalCode(),
+		Reason:      req.Reason,
+		RequestedBy: requestCredentials(jc.Request),
+		ExpiresAt:   time.Now().Add(overrideRequestLifetime),
+	}
+	a.mu.Lock()
+	a.pruneMaintenanceOverrides()
+	if len(a.maintenanceOverrides) >= maxOverrideRequests {
+		a.mu.Unlock()
+		jc.Error(fmt.Errorf("too many pending override requests, the limit is %d", maxOverrideRequests), http.StatusTooManyRequests)
+		return
+	}
+	a.maintenanceOverrides[override.Code] = override
+	a.mu.Unlock()
+
+	a.requestLog(jc.Request.Context()).Warn("maintenance override requested", zap.String("code", override.Code), zap.String("reason", override.Reason), zap.Strings("requestedBy", override.RequestedBy), zap.Time("expires", override.ExpiresAt))
+	jc.Encode(override)
+}
+
+func (a *api) handleGETMaintenanceOverridesCode(jc jape.Context) {
+	var code string
+	if err := jc.DecodeParam("code", &code); err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.pruneMaintenanceOverrides()
+	override, ok := a.maintenanceOverrides[code]
+	a.mu.Unlock()
+	if !ok {
+		jc.Error(errors.New("unknown or expired override request"), http.StatusNotFound)
+		return
+	}
+	jc.Encode(override)
+}
+
+func (a *api) handlePOSTMaintenanceOverridesApprove(jc jape.Context) {
+	var code string
+	if err := jc.DecodeParam("code", &code); err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.pruneMaintenanceOverrides()
+	override, ok := a.maintenanceOverrides[code]
+	if !ok {
+		a.mu.Unlock()
+		jc.Error(errors.New("unknown or expired override request"), http.StatusNotFound)
+		return
+	} else if override.Approved {
+		a.mu.Unlock()
+		jc.Error(errors.New("override request already approved"), http.StatusConflict)
+		return
+	} else if slices.ContainsFunc(requestCredentials(jc.Request), func(cred string) bool {
+		return slices.Contains(override.RequestedBy, cred)
+	}) {
+		a.mu.Unlock()
+		jc.Error(errors.New("an override request must be approved by a different credential than the one that requested it"), http.StatusForbidden)
+		return
+	}
+	override.Approved = true
+	a.maintenanceOverrides[code] = override
+	a.overrideExpiresAt = time.Now().Add(a.overrideDuration)
+	expires := a.overrideExpiresAt
+	a.mu.Unlock()
+
+	a.requestLog(jc.Request.Context()).Warn("maintenance override approved", zap.String("code", code), zap.String("reason", override.Reason), zap.Time("expires", expires))
+	a.notify(notify.EventMaintenanceOverride, "Maintenance override approved", fmt.Sprintf("Signing is enabled during maintenance windows until %s by approving request %s: %s", expires.UTC().Format(time.RFC3339), code, override.Reason))
+	jc.Encode(override)
+}
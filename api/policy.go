@@ -0,0 +1,199 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/vault"
+)
+
+// errSignPolicy is returned when a request does not meet the sign policies
+// of a key's seed.
+var errSignPolicy = errors.New("denied by sign policy")
+
+type (
+	// A TimeWindow is a weekly period of time.
+	TimeWindow struct {
+		// Days are the days of the week the window starts on. Empty starts
+		// the window every day.
+		Days []time.Weekday
+		// Start and End are the times since midnight the window starts and
+		// ends. A window that ends at or before its start ends the next
+		// day.
+		Start time.Duration
+		End   time.Duration
+		// Location is the time zone of Start and End. Nil is UTC.
+		Location *time.Location
+	}
+
+	// A SignPolicy restricts the requests that can sign with the keys of
+	// its seeds. Every condition that is set must be met; a seed covered
+	// by multiple policies must meet all of them.
+	SignPolicy struct {
+		// Seeds are the seeds the policy applies to. Empty applies to every
+		// seed.
+		Seeds []vault.SeedID
+		// Times are the windows during which signing is allowed.
+		Times []TimeWindow
+		// Networks are the networks the request's source address must be
+		// in.
+		Networks []netip.Prefix
+		// Credentials are the credentials allowed to sign: "password" for
+		// the API password, "token:<name>" for an API token, or
+		// "cert:<common name>" for a TLS client certificate.
+		Credentials []string
+	}
+
+	// A policySigner signs with the vault's keys if the request meets the
+	// sign policies of the key's seed.
+	policySigner struct {
+		a *api
+		r *http.Request
+	}
+
+	// A localSigner signs with the vault's keys if the sign policies of
+	// the key's seed allow requests without a source address or
+	// credentials.
+	localSigner struct {
+		a *api
+	}
+)
+
+// WithSignPolicies checks the policies before signing with the vault's
+// keys.
+func WithSignPolicies(policies []SignPolicy) ServerOption {
+	return func(a *api) {
+		a.signPolicies = policies
+	}
+}
+
+// at returns the time of day on the date in the window's location.
+func (w TimeWindow) at(year int, month time.Month, day int, d time.Duration) time.Time {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(year, month, day, int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// contains returns true and the end of the window if t is within it.
+func (w TimeWindow) contains(t time.Time) (bool, time.Time) {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	year, month, day := t.In(loc).Date()
+	// a window that crosses midnight may have started the day before
+	for _, offset := range []int{0, -1} {
+		start := w.at(year, month, day+offset, w.Start)
+		if len(w.Days) > 0 && !slices.Contains(w.Days, start.Weekday()) {
+			continue
+		}
+		end := w.at(year, month, day+offset, w.End)
+		if w.End <= w.Start {
+			end = w.at(year, month, day+offset+1, w.End)
+		}
+		if !t.Before(start) && t.Before(end) {
+			return true, end
+		}
+	}
+	return false, time.Time{}
+}
+
+// requestCredentials returns the credentials presented with the request,
+// in the form used by [SignPolicy].
+func requestCredentials(r *http.Request) []string {
+	var creds []string
+	// API tokens are sent in place of the password
+	if token, ok := apiToken(r.Context()); ok {
+		creds = append(creds, "token:"+token.Name)
+	} else if _, _, ok := r.BasicAuth(); ok {
+		creds = append(creds, "password")
+	}
+	if tls := r.TLS; tls != nil && len(tls.VerifiedChains) > 0 && len(tls.VerifiedChains[0]) > 0 {
+		creds = append(creds, "cert:"+tls.VerifiedChains[0][0].Subject.CommonName)
+	}
+	return creds
+}
+
+// requestAddr returns the request's source address, or the zero address if
+// it is unknown.
+func requestAddr(r *http.Request) netip.Addr {
+	addr, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Addr().Unmap()
+}
+
+// check returns an error if a request from addr with the credentials does
+// not meet the policy. A zero addr is an unknown address.
+func (p SignPolicy) check(addr netip.Addr, creds []string, id vault.SeedID, now time.Time) error {
+	if len(p.Seeds) > 0 && !slices.Contains(p.Seeds, id) {
+		return nil
+	}
+
+	if len(p.Times) > 0 && !slices.ContainsFunc(p.Times, func(w TimeWindow) bool {
+		ok, _ := w.contains(now)
+		return ok
+	}) {
+		return fmt.Errorf("%w: seed %d cannot sign at this time", errSignPolicy, id)
+	}
+
+	if len(p.Networks) > 0 {
+		if !addr.IsValid() {
+			return fmt.Errorf("%w: seed %d cannot sign from an unknown address", errSignPolicy, id)
+		} else if !slices.ContainsFunc(p.Networks, func(n netip.Prefix) bool { return n.Contains(addr) }) {
+			return fmt.Errorf("%w: seed %d cannot sign from %s", errSignPolicy, id, addr)
+		}
+	}
+
+	if len(p.Credentials) > 0 && !slices.ContainsFunc(creds, func(c string) bool {
+		return slices.Contains(p.Credentials, c)
+	}) {
+		return fmt.Errorf("%w: seed %d cannot sign with this credential", errSignPolicy, id)
+	}
+	return nil
+}
+
+// signChecked signs the hash with the key if a request from addr with the
+// credentials meets every policy of the key's seed.
+func signChecked(v *vault.Vault, policies []SignPolicy, addr netip.Addr, creds []string, pk types.PublicKey, hash types.Hash256) (types.Signature, error) {
+	if len(policies) == 0 {
+		return v.Sign(pk, hash)
+	}
+	now := time.Now()
+	return v.SignChecked(pk, hash, func(id vault.SeedID) error {
+		for _, p := range policies {
+			if err := p.check(addr, creds, id, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sign signs the hash with the key if the request meets the sign policies
+// of the key's seed.
+func (a *api) sign(r *http.Request, pk types.PublicKey, hash types.Hash256) (types.Signature, error) {
+	if len(a.signPolicies) == 0 {
+		return a.vault.Sign(pk, hash)
+	}
+	return signChecked(a.vault, a.signPolicies, requestAddr(r), requestCredentials(r), pk, hash)
+}
+
+// Sign implements offline.Signer.
+func (ps policySigner) Sign(pk types.PublicKey, hash types.Hash256) (types.Signature, error) {
+	return ps.a.sign(ps.r, pk, hash)
+}
+
+// Sign implements offline.Signer. Policies that restrict networks or
+// credentials deny every signature.
+func (ls localSigner) Sign(pk types.PublicKey, hash types.Hash256) (types.Signature, error) {
+	return signChecked(ls.a.vault, ls.a.signPolicies, netip.Addr{}, nil, pk, hash)
+}
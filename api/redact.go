@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.sia.tech/core/types"
+	"go.uber.org/zap"
+)
+
+// Redaction modes for public keys, addresses, and transaction IDs written
+// to logs.
+const (
+	// RedactNone logs values in full.
+	RedactNone RedactMode = ""
+	// RedactHash replaces values with a short hash. Equal values have equal
+	// hashes, so log entries can still be correlated.
+	RedactHash RedactMode = "hash"
+	// RedactTruncate logs only the first few characters of values.
+	RedactTruncate RedactMode = "truncate"
+)
+
+// redactPrefixLen is the number of characters kept by RedactTruncate.
+const redactPrefixLen = 8
+
+// A RedactMode controls how public keys, addresses, and transaction IDs
+// are written to logs, for operators whose log pipeline is less trusted
+// than the vault host.
+type RedactMode string
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *RedactMode) UnmarshalText(b []byte) error {
+	switch v := RedactMode(b); v {
+	case RedactNone, RedactHash, RedactTruncate:
+		*m = v
+		return nil
+	default:
+		return fmt.Errorf("unknown redaction mode %q", v)
+	}
+}
+
+// redact returns s redacted according to the mode. A type prefix, such as
+// "ed25519:", is preserved.
+func (m RedactMode) redact(s string) string {
+	prefix, value := "", s
+	if i := strings.LastIndexByte(s, ':'); i != -1 {
+		prefix, value = s[:i+1], s[i+1:]
+	}
+
+	switch m {
+	case RedactHash:
+		h := types.HashBytes([]byte(s))
+		return prefix + "h" + hex.EncodeToString(h[:6])
+	case RedactTruncate:
+		if len(value) > redactPrefixLen {
+			value = value[:redactPrefixLen] + "..."
+		}
+		return prefix + value
+	default:
+		return s
+	}
+}
+
+// redactPath redacts the path segments of r that are identifiers, such as
+// addresses, leaving the rest of the route intact.
+func (m RedactMode) redactPath(r *http.Request) string {
+	if m == RedactNone {
+		return r.URL.Path
+	}
+	segments := strings.Split(r.URL.Path, "/")
+	for i, seg := range segments {
+		if isIdentifier(seg) {
+			segments[i] = m.redact(seg)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isIdentifier returns true if s looks like a hex-encoded key, hash, or
+// address, with an optional type prefix.
+func isIdentifier(s string) bool {
+	if i := strings.LastIndexByte(s, ':'); i != -1 {
+		s = s[i+1:]
+	}
+	if len(s) < 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// WithLogRedaction redacts public keys, addresses, and transaction IDs in
+// the API's logs.
+func WithLogRedaction(mode RedactMode) ServerOption {
+	return func(a *api) {
+		a.redactMode = mode
+	}
+}
+
+// redactedField returns a log field for v, redacted according to the API's
+// redaction mode.
+func (a *api) redactedField(key string, v fmt.Stringer) zap.Field {
+	if a.redactMode == RedactNone {
+		return zap.Stringer(key, v)
+	}
+	return zap.String(key, a.redactMode.redact(v.String()))
+}
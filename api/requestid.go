@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+// RequestIDHeader is the header used to correlate a request across the
+// client's and the server's logs.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen is the maximum length of a request ID accepted from a
+// client. Longer or malformed IDs are replaced.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// statusRecorder records the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// ContextWithRequestID returns a context carrying the request ID. The
+// API client sends it instead of generating a new one.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by the context, or an empty
+// string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	return hex.EncodeToString(frand.Bytes(16))
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestLog returns a logger annotated with the request's ID.
+func (a *api) requestLog(ctx context.Context) *zap.Logger {
+	if id := RequestID(ctx); id != "" {
+		return a.log.With(zap.String("requestID", id))
+	}
+	return a.log
+}
+
+// requestIDMiddleware accepts the client's request ID, or generates one,
+// echoes it in the response, and logs the outcome of the request.
+func (a *api) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ContextWithRequestID(r.Context(), id)))
+
+		log := a.log.With(zap.String("requestID", id), zap.String("method", r.Method), zap.String("path", a.redactMode.redactPath(r)), zap.Int("status", rec.status), zap.Duration("elapsed", time.Since(start)))
+		switch {
+		case rec.status >= 500:
+			log.Warn("request failed")
+		case rec.status >= 400:
+			log.Debug("request failed")
+		default:
+			log.Debug("request completed")
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.sia.tech/vaultd/addressbook"
+	"go.sia.tech/vaultd/apitoken"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/snapshot"
+	"go.sia.tech/vaultd/vault"
+	"go.sia.tech/vaultd/webhooks"
+	"go.uber.org/zap"
+)
+
+// A Route describes a route served by [Handler].
+type Route struct {
+	Method string
+	// Path uses httprouter syntax, e.g. /seeds/:id.
+	Path string
+	// Request is the type of the JSON request body. It is nil if the
+	// route does not accept a body.
+	Request reflect.Type
+	// Response is the type of the JSON response body. It is nil if the
+	// route does not return a body.
+	Response reflect.Type
+	// Scope is the scope an API token needs to call the route.
+	Scope apitoken.Scope
+}
+
+// routeScopes are the scopes of the routes that do not require
+// [apitoken.ScopeAdmin].
+var routeScopes = map[string]apitoken.Scope{
+	"GET /state":       apitoken.ScopeRead,
+	"GET /state/build": apitoken.ScopeRead,
+	"GET /features":    apitoken.ScopeRead,
+	"GET /state/wait":  apitoken.ScopeRead,
+	"GET /stats":       apitoken.ScopeRead,
+	"GET /fees":        apitoken.ScopeRead,
+
+	"GET /seeds":                   apitoken.ScopeRead,
+	"GET /seeds/:id":               apitoken.ScopeRead,
+	"GET /seeds/:id/keys":          apitoken.ScopeRead,
+	"GET /seeds/:id/derivation":    apitoken.ScopeRead,
+	"GET /keys":                    apitoken.ScopeRead,
+	"GET /expirations":             apitoken.ScopeRead,
+	"GET /addresses/:address/key":  apitoken.ScopeRead,
+	"GET /export/descriptor":       apitoken.ScopeRead,
+	"POST /system/check":           apitoken.ScopeRead,
+	"GET /custody/commitment":      apitoken.ScopeRead,
+	"GET /custody/proofs/:address": apitoken.ScopeRead,
+	"GET /snapshots":               apitoken.ScopeRead,
+	"GET /addressbook":             apitoken.ScopeRead,
+	"GET /addressbook/:id":         apitoken.ScopeRead,
+
+	"POST /unlock/requests":      apitoken.ScopeRead,
+	"GET /unlock/requests/:code": apitoken.ScopeRead,
+
+	"GET /maintenance":                 apitoken.ScopeRead,
+	"POST /maintenance/overrides":      apitoken.ScopeSign,
+	"GET /maintenance/overrides/:code": apitoken.ScopeSign,
+
+	"POST /seeds/:id/keys":          apitoken.ScopeDerive,
+	"POST /seeds/:id/keys/register": apitoken.ScopeDerive,
+	"POST /seeds/:id/reserve":       apitoken.ScopeDerive,
+
+	"POST /sign":                apitoken.ScopeSign,
+	"POST /v2/sign":             apitoken.ScopeSign,
+	"POST /blind/sign":          apitoken.ScopeSign,
+	"POST /proofs/ownership":    apitoken.ScopeSign,
+	"POST /offline/requests":    apitoken.ScopeSign,
+	"POST /v2/offline/requests": apitoken.ScopeSign,
+	"POST /offline/sign":        apitoken.ScopeSign,
+	"POST /offline/merge":       apitoken.ScopeSign,
+	"POST /v2/offline/merge":    apitoken.ScopeSign,
+}
+
+// routeScope returns the scope an API token needs to call the route.
+func routeScope(route string) apitoken.Scope {
+	if scope, ok := routeScopes[route]; ok {
+		return scope
+	}
+	return apitoken.ScopeAdmin
+}
+
+// routeBodies are the request and response body types of every route,
+// keyed the same as [api.routes]. Routes that encode different types
+// depending on a query parameter list the default.
+var routeBodies = map[string][2]reflect.Type{
+	"GET /state":       {nil, reflect.TypeFor[StateResponse]()},
+	"GET /state/build": {nil, reflect.TypeFor[BuildResponse]()},
+	"GET /features":    {nil, reflect.TypeFor[FeaturesResponse]()},
+	"GET /stats":       {nil, reflect.TypeFor[StatsResponse]()},
+	"GET /fees":        {nil, reflect.TypeFor[FeesResponse]()},
+
+	"GET /state/wait": {nil, reflect.TypeFor[StateWaitResponse]()},
+
+	"GET /seeds":                    {nil, reflect.TypeFor[SeedsResponse]()},
+	"GET /phrases/new":              {nil, reflect.TypeFor[PhraseResponse]()},
+	"POST /phrases/validate":        {reflect.TypeFor[PhraseValidateRequest](), reflect.TypeFor[PhraseValidateResponse]()},
+	"POST /phrases/generate":        {reflect.TypeFor[SeedGenerateRequest](), reflect.TypeFor[SeedGenerateResponse]()},
+	"POST /seeds":                   {reflect.TypeFor[AddSeedRequest](), reflect.TypeFor[vault.SeedMeta]()},
+	"GET /seeds/:id":                {nil, reflect.TypeFor[SeedResponse]()},
+	"DELETE /seeds/:id":             {nil, nil},
+	"POST /seeds/:id/restore":       {nil, reflect.TypeFor[SeedResponse]()},
+	"GET /seeds/:id/keys":           {nil, reflect.TypeFor[SeedKeysResponse]()},
+	"POST /seeds/:id/keys":          {reflect.TypeFor[SeedDeriveRequest](), reflect.TypeFor[SeedKeysResponse]()},
+	"POST /seeds/:id/keys/register": {reflect.TypeFor[SeedRegisterRequest](), reflect.TypeFor[SeedKeysResponse]()},
+	"POST /seeds/:id/reserve":       {reflect.TypeFor[SeedDeriveRequest](), reflect.TypeFor[SeedReserveResponse]()},
+	"GET /seeds/:id/derivation":     {nil, reflect.TypeFor[SeedDerivationResponse]()},
+	"PUT /seeds/:id/expiration":     {reflect.TypeFor[ExpirationRequest](), nil},
+
+	"GET /keys":                   {nil, reflect.TypeFor[KeysResponse]()},
+	"GET /addresses/:address/key": {nil, reflect.TypeFor[AddressKeyResponse]()},
+	"PUT /keys/:key/expiration":   {reflect.TypeFor[ExpirationRequest](), nil},
+	"GET /expirations":            {nil, reflect.TypeFor[[]Expiration]()},
+	"GET /export/descriptor":      {nil, reflect.TypeFor[WalletDescriptor]()},
+	"POST /system/check":          {nil, reflect.TypeFor[SystemCheckResponse]()},
+
+	"POST /unlock":       {reflect.TypeFor[UnlockRequest](), nil},
+	"POST /unlock/split": {reflect.TypeFor[UnlockSplitRequest](), reflect.TypeFor[UnlockSplitResponse]()},
+	"POST /unlock/share": {reflect.TypeFor[UnlockShareRequest](), reflect.TypeFor[UnlockShareResponse]()},
+	"PUT /lock":          {nil, nil},
+
+	"GET /import/key":                 {nil, reflect.TypeFor[ImportKeyResponse]()},
+	"POST /import/tokens":             {reflect.TypeFor[ImportTokenRequest](), reflect.TypeFor[ImportTokenResponse]()},
+	"GET /import/tokens/:token/key":   {nil, reflect.TypeFor[ImportKeyResponse]()},
+	"POST /import/tokens/:token/seed": {reflect.TypeFor[AddSeedRequest](), reflect.TypeFor[SeedResponse]()},
+
+	"POST /sign":             {reflect.TypeFor[SignRequest](), reflect.TypeFor[SignResponse]()},
+	"POST /v2/sign":          {reflect.TypeFor[SignV2Request](), reflect.TypeFor[SignV2Response]()},
+	"POST /blind/sign":       {reflect.TypeFor[BlindSignRequest](), reflect.TypeFor[BlindSignResponse]()},
+	"POST /proofs/ownership": {reflect.TypeFor[OwnershipProofRequest](), reflect.TypeFor[OwnershipProofResponse]()},
+
+	"POST /offline/requests":    {reflect.TypeFor[OfflineRequest](), reflect.TypeFor[offline.SignRequest]()},
+	"POST /v2/offline/requests": {reflect.TypeFor[OfflineV2Request](), reflect.TypeFor[offline.SignRequest]()},
+	"POST /offline/sign":        {reflect.TypeFor[offline.SignRequest](), reflect.TypeFor[offline.SignResponse]()},
+	"POST /offline/merge":       {reflect.TypeFor[OfflineMergeRequest](), reflect.TypeFor[SignResponse]()},
+	"POST /v2/offline/merge":    {reflect.TypeFor[OfflineMergeV2Request](), reflect.TypeFor[SignV2Response]()},
+
+	"GET /custody/commitment":      {nil, reflect.TypeFor[custody.Commitment]()},
+	"GET /custody/proofs/:address": {nil, reflect.TypeFor[CustodyProofResponse]()},
+
+	"GET /snapshots":                {nil, reflect.TypeFor[[]snapshot.Snapshot]()},
+	"POST /snapshots":               {reflect.TypeFor[SnapshotRequest](), reflect.TypeFor[snapshot.Snapshot]()},
+	"POST /snapshots/:name/restore": {nil, reflect.TypeFor[SnapshotRestoreResponse]()},
+
+	"GET /addressbook":        {nil, reflect.TypeFor[[]addressbook.Entry]()},
+	"POST /addressbook":       {reflect.TypeFor[AddressBookRequest](), reflect.TypeFor[addressbook.Entry]()},
+	"GET /addressbook/:id":    {nil, reflect.TypeFor[addressbook.Entry]()},
+	"PUT /addressbook/:id":    {reflect.TypeFor[AddressBookUpdateRequest](), nil},
+	"DELETE /addressbook/:id": {nil, nil},
+
+	"POST /unlock/requests":               {nil, reflect.TypeFor[UnlockApproval]()},
+	"GET /unlock/requests/:code":          {nil, reflect.TypeFor[UnlockApproval]()},
+	"POST /unlock/requests/:code/approve": {nil, reflect.TypeFor[UnlockApproval]()},
+
+	"GET /maintenance":                          {nil, reflect.TypeFor[MaintenanceResponse]()},
+	"POST /maintenance/overrides":               {reflect.TypeFor[MaintenanceOverrideRequest](), reflect.TypeFor[MaintenanceOverride]()},
+	"GET /maintenance/overrides/:code":          {nil, reflect.TypeFor[MaintenanceOverride]()},
+	"POST /maintenance/overrides/:code/approve": {nil, reflect.TypeFor[MaintenanceOverride]()},
+
+	"GET /tokens":        {nil, reflect.TypeFor[[]apitoken.Token]()},
+	"POST /tokens":       {reflect.TypeFor[APITokenRequest](), reflect.TypeFor[APITokenResponse]()},
+	"DELETE /tokens/:id": {nil, nil},
+
+	"GET /webhooks":           {nil, reflect.TypeFor[[]webhooks.Webhook]()},
+	"POST /webhooks":          {reflect.TypeFor[WebhookRequest](), reflect.TypeFor[webhooks.Webhook]()},
+	"PUT /webhooks/:id":       {reflect.TypeFor[WebhookRequest](), nil},
+	"DELETE /webhooks/:id":    {nil, nil},
+	"POST /webhooks/:id/test": {nil, nil},
+}
+
+// Routes returns the routes [Handler] serves with the same chain and
+// options, sorted by path and method. Middleware, authorization matrices,
+// and API descriptions can be generated from the routes instead of
+// duplicating them.
+func Routes(c Chain, opts ...ServerOption) []Route {
+	a := newAPI(c, nil, zap.NewNop(), opts)
+	var routes []Route
+	for route := range a.routes() {
+		method, path, _ := strings.Cut(route, " ")
+		bodies := routeBodies[route]
+		routes = append(routes, Route{
+			Method:   method,
+			Path:     path,
+			Request:  bodies[0],
+			Response: bodies[1],
+			Scope:    routeScope(route),
+		})
+	}
+	slices.SortFunc(routes, func(a, b Route) int {
+		if n := strings.Compare(a.Path, b.Path); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return routes
+}
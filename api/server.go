@@ -3,23 +3,94 @@ package api
 import (
 	"context"
 	"crypto/ed25519"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
 	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/addressbook"
+	"go.sia.tech/vaultd/apitoken"
 	"go.sia.tech/vaultd/build"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/importkey"
+	"go.sia.tech/vaultd/internal/bip39"
 	"go.sia.tech/vaultd/internal/siad"
+	"go.sia.tech/vaultd/notify"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/snapshot"
 	"go.sia.tech/vaultd/vault"
+	"go.sia.tech/vaultd/webhooks"
 	"go.uber.org/zap"
+	"lukechampine.com/frand"
 )
 
+const (
+	// maxMetadataEntries is the maximum number of metadata entries
+	// attached to a sign request.
+	maxMetadataEntries = 16
+	// maxMetadataLen is the maximum length of a metadata key or value.
+	maxMetadataLen = 256
+	// maxNonceLen is the maximum length of a sign request nonce.
+	maxNonceLen = 128
+	// maxChallengeLen is the maximum length of an ownership challenge.
+	maxChallengeLen = 1024
+	// maxOwnershipProofs is the maximum number of keys signed by a single
+	// ownership proof request.
+	maxOwnershipProofs = 1000
+)
+
+// failedUnlockAlertThreshold is the number of consecutive failed unlock
+// attempts before operators are notified.
+const failedUnlockAlertThreshold = 3
+
+const (
+	// importKeyLifetime is how long an import key can be used.
+	importKeyLifetime = 10 * time.Minute
+	// maxImportKeys is the maximum number of unused import keys.
+	maxImportKeys = 100
+
+	// defaultImportTokenLifetime is how long an import token can be used
+	// if the request does not specify a lifetime.
+	defaultImportTokenLifetime = 24 * time.Hour
+	// maxImportTokenLifetime is the maximum lifetime of an import token.
+	maxImportTokenLifetime = 7 * 24 * time.Hour
+	// maxImportTokens is the maximum number of unused import tokens.
+	maxImportTokens = 1000
+	// maxDeriveCount is the maximum number of keys derived by a single
+	// request.
+	maxDeriveCount = 1000
+	// maxDescriptorKeys is the maximum number of keys in an exported
+	// wallet descriptor.
+	maxDescriptorKeys = 100000
+)
+
+// importTokenPrefix is the path prefix of the routes authenticated by an
+// import token instead of the API password.
+const importTokenPrefix = "/import/tokens/"
+
+const (
+	// defaultStateWaitTimeout is how long [GET] /state/wait blocks if the
+	// request does not specify a timeout.
+	defaultStateWaitTimeout = 30 * time.Second
+	// maxStateWaitTimeout is the maximum timeout of [GET] /state/wait.
+	maxStateWaitTimeout = 5 * time.Minute
+	// stateWaitWriteGrace is how long [GET] /state/wait has to write its
+	// response after the timeout.
+	stateWaitWriteGrace = 10 * time.Second
+)
+
+// stateWaitPollInterval is how often [GET] /state/wait checks the chain
+// for a new tip.
+var stateWaitPollInterval = 5 * time.Second
+
 var startTime = time.Now()
 
 type (
@@ -29,23 +100,460 @@ type (
 		TipState(ctx context.Context) (consensus.State, error)
 	}
 
+	// A DivergenceChecker is a Chain that cross-checks its state
+	// against an independent source. If the Chain passed to Handler
+	// implements it, divergence is reported by [GET] /state.
+	DivergenceChecker interface {
+		// Divergence returns the reason the sources disagree, or nil.
+		Divergence() error
+	}
+
+	// A FeeEstimator is a Chain that estimates transaction fees. If the
+	// Chain passed to Handler implements it, [GET] /fees is enabled.
+	FeeEstimator interface {
+		// RecommendedFee returns the recommended fee per byte.
+		RecommendedFee(ctx context.Context) (types.Currency, error)
+	}
+
+	// A NonceStore records the nonces of sign requests so they cannot
+	// be replayed.
+	NonceStore interface {
+		// UseNonce records a nonce until it expires. It returns false if
+		// the nonce has already been used and has not expired.
+		UseNonce(nonce string, expiration time.Time) (bool, error)
+	}
+
+	// A ServerOption is a functional option for configuring the API
+	// handler.
+	ServerOption func(*api)
+
+	// A Server serves the API over HTTP and signs offline sign requests
+	// received by other transports. Both share the server's
+	// configuration and state.
+	Server struct {
+		a *api
+		h http.Handler
+	}
+
+	// An importToken is an unused import token.
+	importToken struct {
+		expires time.Time
+		// keys holds the token's import key. A token has at most one
+		// key, which is discarded with the token.
+		keys *importkey.Keyring
+	}
+
 	api struct {
-		vault *vault.Vault
-		log   *zap.Logger
-		chain Chain
+		vault       *vault.Vault
+		log         *zap.Logger
+		chain       Chain
+		addressBook addressbook.Store
+		apiTokens   apitoken.Store
+		custody     *custody.Committer
+		snapshots   *snapshot.Manager
+		notifier    notify.Notifier
+		webhooks    *webhooks.Manager
+		authorizer  Authorizer
+		signHook    SignHook
+		guardrails  ContractGuardrails
+		watchOnly   bool
+		redactMode  RedactMode
+
+		minFee types.Currency
+		maxFee types.Currency
+
+		importKeys *importkey.Keyring
+		// importTokens maps the hash of each unused import token to the
+		// token. It is guarded by mu.
+		importTokens map[types.Hash256]importToken
+
+		unlockSecret SecretSource
+		// unlockRequests maps the code of each unexpired unlock request
+		// to its state. It is guarded by mu.
+		unlockRequests map[string]UnlockApproval
+		// unlockShares are the shares submitted towards unlocking the
+		// vault. It is guarded by mu.
+		unlockShares pendingShares
+
+		signPolicies []SignPolicy
+
+		maintenanceWindows []TimeWindow
+		overrideDuration   time.Duration
+		// maintenanceOverrides maps the code of each unexpired override
+		// request to its state. It is guarded by mu.
+		maintenanceOverrides map[string]MaintenanceOverride
+		// overrideExpiresAt is when the approved override stops allowing
+		// signing during maintenance windows. It is guarded by mu.
+		overrideExpiresAt time.Time
+
+		nonces      NonceStore
+		nonceWindow time.Duration
+
+		minClientVersion int
+
+		latency              latencyTracker
+		slowRequestThreshold time.Duration
+		signingSLO           SLO
+
+		mu             sync.Mutex
+		failedUnlocks  int
+		signedBySource map[StateSource]uint64
 	}
 )
 
+// WithNotifier sends alerts for critical events, such as repeated failed
+// unlock attempts, to the notifier.
+func WithNotifier(n notify.Notifier) ServerOption {
+	return func(a *api) {
+		a.notifier = n
+	}
+}
+
+// WithFeeBounds bounds the fee per byte returned by [GET] /fees. A zero
+// max disables the upper bound.
+func WithFeeBounds(minFee, maxFee types.Currency) ServerOption {
+	return func(a *api) {
+		a.minFee = minFee
+		a.maxFee = maxFee
+	}
+}
+
+// WithWatchOnly disables every route that requires seed material. A
+// watch-only instance can still export sign requests for an offline
+// signer and merge the returned signatures.
+func WithWatchOnly(watchOnly bool) ServerOption {
+	return func(a *api) {
+		a.watchOnly = watchOnly
+	}
+}
+
+// WithAddressBook enables the address book endpoints using the provided
+// store.
+func WithAddressBook(s addressbook.Store) ServerOption {
+	return func(a *api) {
+		a.addressBook = s
+	}
+}
+
+// WithCustody enables the custody commitment endpoints using the provided
+// committer. The committer is refreshed by the caller.
+func WithCustody(c *custody.Committer) ServerOption {
+	return func(a *api) {
+		a.custody = c
+	}
+}
+
+// WithSnapshots enables the snapshot endpoints using the provided
+// manager.
+func WithSnapshots(m *snapshot.Manager) ServerOption {
+	return func(a *api) {
+		a.snapshots = m
+	}
+}
+
+// WithReplayProtection requires a unique nonce and a timestamp within
+// window of the current time on every sign request. Used nonces are
+// recorded in the store so captured requests cannot be replayed, even
+// across restarts.
+func WithReplayProtection(s NonceStore, window time.Duration) ServerOption {
+	return func(a *api) {
+		a.nonces = s
+		a.nonceWindow = window
+	}
+}
+
+// useNonce returns an error, and its status code, if replay protection is
+// enabled and the nonce is missing, the timestamp is outside the window,
+// or the nonce has already been used.
+func (a *api) useNonce(ctx context.Context, nonce string, timestamp time.Time) (int, error) {
+	if a.nonces == nil {
+		return 0, nil
+	}
+
+	switch {
+	case nonce == "":
+		return http.StatusBadRequest, errors.New("nonce is required")
+	case len(nonce) > maxNonceLen:
+		return http.StatusBadRequest, fmt.Errorf("nonce must be at most %d characters", maxNonceLen)
+	case timestamp.IsZero():
+		return http.StatusBadRequest, errors.New("timestamp is required")
+	}
+	if d := time.Since(timestamp); d > a.nonceWindow || d < -a.nonceWindow {
+		return http.StatusBadRequest, fmt.Errorf("timestamp must be within %v of the server time", a.nonceWindow)
+	}
+
+	// the nonce only needs to be remembered until its timestamp leaves
+	// the window
+	ok, err := a.nonces.UseNonce(nonce, timestamp.Add(a.nonceWindow))
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to record nonce: %w", err)
+	} else if !ok {
+		a.requestLog(ctx).Warn("rejected replayed sign request", zap.String("nonce", nonce))
+		return http.StatusConflict, errors.New("nonce has already been used")
+	}
+	return 0, nil
+}
+
+// checkNonce rejects the request if replay protection is enabled and the
+// nonce is missing, the timestamp is outside the window, or the nonce has
+// already been used. It returns false if the request was rejected.
+func (a *api) checkNonce(jc jape.Context, nonce string, timestamp time.Time) bool {
+	if status, err := a.useNonce(jc.Request.Context(), nonce, timestamp); err != nil {
+		jc.Error(err, status)
+		return false
+	}
+	return true
+}
+
+// notify delivers the event in the background. Delivery failures are
+// logged.
+func (a *api) notify(eventType, subject, message string) {
+	if a.notifier == nil {
+		return
+	}
+	e := notify.Event{
+		Type:      eventType,
+		Subject:   subject,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+	go func() {
+		if err := a.notifier.Notify(e); err != nil {
+			a.log.Warn("failed to send notification", zap.String("type", e.Type), zap.Error(err))
+		}
+	}()
+}
+
+// seedKey returns the key with its standard spend policy of the given type
+// and the policy's address.
+func seedKey(pk types.PublicKey, pt PolicyType) SeedKey {
+	sp := pt.SpendPolicy(pk)
+	return SeedKey{
+		PublicKey:   pk,
+		Address:     sp.Address(),
+		SpendPolicy: sp,
+		V1Address:   types.StandardUnlockHash(pk),
+		V2Address:   types.PolicyPublicKey(pk).Address(),
+	}
+}
+
+// errInvalidPhraseLength is returned when a phrase's word count does not
+// match a supported type.
+var errInvalidPhraseLength = errors.New("invalid phrase length, must be BIP39 12, 15, 18, 21, or 24 word seed or 28 or 29 word Sia seed")
+
+// seedFromPhrase decodes a BIP39 or siad recovery phrase and returns the
+// phrase's language.
+func seedFromPhrase(seed *[32]byte, phrase string) (string, error) {
+	switch n := len(strings.Fields(phrase)); {
+	case n == 28 || n == 29:
+		lang, err := siad.DetectLanguage(phrase)
+		if err != nil {
+			return "", err
+		}
+		return string(lang), siad.SeedFromPhrase(seed, phrase)
+	case bip39.ValidWordCount(n):
+		lang, err := bip39.SeedFromPhrase(seed, phrase)
+		return string(lang), err
+	default:
+		return "", errInvalidPhraseLength
+	}
+}
+
+// validatePhrase checks a recovery phrase without storing it.
+func validatePhrase(phrase string) (resp PhraseValidateResponse) {
+	words := strings.Fields(phrase)
+	resp.WordCount = len(words)
+
+	isWord := bip39.IsWord
+	switch n := len(words); {
+	case bip39.ValidWordCount(n):
+		resp.Type = PhraseTypeBIP39
+	case n == 28 || n == 29:
+		resp.Type = PhraseTypeSiad
+		isWord = siad.IsWord
+	default:
+		resp.Error = errInvalidPhraseLength.Error()
+		return
+	}
+
+	for i, w := range words {
+		if !isWord(w) {
+			resp.UnknownWords = append(resp.UnknownWords, i)
+		}
+	}
+	if len(resp.UnknownWords) > 0 {
+		resp.Error = fmt.Sprintf("%d words are not in the %s word list", len(resp.UnknownWords), resp.Type)
+		return
+	}
+
+	var seed [32]byte
+	defer clear(seed[:])
+	lang, err := seedFromPhrase(&seed, phrase)
+	if err != nil {
+		resp.Error = err.Error()
+		return
+	}
+	sk := wallet.KeyFromSeed(&seed, 0)
+	defer clear(sk[:])
+	resp.Valid = true
+	resp.Language = lang
+	resp.Fingerprint = seedFingerprint(sk.PublicKey())
+	resp.Address = types.StandardUnlockHash(sk.PublicKey())
+	return
+}
+
+// seedFingerprint returns a short identifier for a seed derived from its
+// first public key. It reveals no secret material.
+func seedFingerprint(first types.PublicKey) string {
+	h := types.HashBytes(first[:])
+	return hex.EncodeToString(h[:4])
+}
+
+// validateMetadata checks that sign request metadata is within limits.
+func validateMetadata(md map[string]string) error {
+	if len(md) > maxMetadataEntries {
+		return fmt.Errorf("metadata has %d entries, maximum is %d", len(md), maxMetadataEntries)
+	}
+	for k, v := range md {
+		if k == "" {
+			return errors.New("metadata keys must not be empty")
+		} else if len(k) > maxMetadataLen || len(v) > maxMetadataLen {
+			return fmt.Errorf("metadata %q exceeds %d characters", k, maxMetadataLen)
+		}
+	}
+	return nil
+}
+
 func (a *api) handleGETState(jc jape.Context) {
-	jc.Encode(StateResponse{
+	resp := StateResponse{
 		Version:   build.Version(),
 		Commit:    build.Commit(),
 		OS:        runtime.GOOS,
 		BuildTime: build.Time(),
 		StartTime: startTime,
+		Unlocked:  a.vault.Unlocked(),
+
+		APIVersions:      supportedVersions,
+		MinClientVersion: a.minClientVersion,
+	}
+	if dc, ok := a.chain.(DivergenceChecker); ok {
+		if err := dc.Divergence(); err != nil {
+			resp.ExplorerDivergence = err.Error()
+		}
+	}
+	if err := a.vault.ReadOnly(); err != nil {
+		resp.ReadOnly = err.Error()
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handleGETStateBuild(jc jape.Context) {
+	tags := build.Tags()
+	if tags == nil {
+		tags = []string{}
+	}
+	jc.Encode(BuildResponse{
+		Version:      build.Version(),
+		Commit:       build.Commit(),
+		BuildTime:    build.Time(),
+		GoVersion:    build.GoVersion(),
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Dependencies: build.Dependencies("go.sia.tech/"),
+		Tags:         tags,
 	})
 }
 
+func (a *api) handleGETFeatures(jc jape.Context) {
+	_, fees := a.chain.(FeeEstimator)
+	jc.Encode(FeaturesResponse{
+		Signing:            !a.watchOnly,
+		Fees:               fees,
+		AddressBook:        a.addressBook != nil,
+		Custody:            a.custody != nil,
+		Snapshots:          a.snapshots != nil,
+		UnlockApproval:     a.unlockSecret != nil,
+		MaintenanceWindows: len(a.maintenanceWindows) > 0,
+		APITokens:          a.apiTokens != nil,
+		Webhooks:           a.webhooks != nil,
+		SignPolicies:       len(a.signPolicies) > 0,
+		ReplayProtection:   a.nonces != nil,
+	})
+}
+
+// waitState returns the current lock state and tip. If the chain cannot
+// be reached, the last known tip is returned.
+func (a *api) waitState(ctx context.Context, last types.ChainIndex) StateWaitResponse {
+	tip := last
+	if cs, err := a.chain.TipState(ctx); err == nil {
+		tip = cs.Index
+	} else if ctx.Err() == nil {
+		a.requestLog(ctx).Debug("failed to get tip state", zap.Error(err))
+	}
+	unlocked := a.vault.Unlocked()
+	return StateWaitResponse{
+		ID:       fmt.Sprintf("%t:%s", unlocked, tip),
+		Unlocked: unlocked,
+		Tip:      tip,
+	}
+}
+
+func (a *api) handleGETStateWait(jc jape.Context) {
+	var since, timeoutStr string
+	if jc.DecodeForm("since", &since) != nil {
+		return
+	} else if jc.DecodeForm("timeout", &timeoutStr) != nil {
+		return
+	}
+
+	timeout := defaultStateWaitTimeout
+	if timeoutStr != "" {
+		d, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			jc.Error(fmt.Errorf("invalid timeout: %w", err), http.StatusBadRequest)
+			return
+		} else if d < 0 || d > maxStateWaitTimeout {
+			jc.Error(fmt.Errorf("timeout must be between 0 and %v", maxStateWaitTimeout), http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	// the wait can outlast the server's write timeout, so the deadline is
+	// extended past it
+	rc := http.NewResponseController(jc.ResponseWriter)
+	if err := rc.SetWriteDeadline(time.Now().Add(timeout + stateWaitWriteGrace)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		jc.Error(fmt.Errorf("failed to extend write deadline: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	ctx := jc.Request.Context()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(stateWaitPollInterval)
+	defer poll.Stop()
+
+	// subscribe before reading the state so a change between the two is
+	// not missed
+	lockChanged := a.vault.LockChanged()
+	state := a.waitState(ctx, types.ChainIndex{})
+	for state.ID == since {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			jc.Encode(state)
+			return
+		case <-lockChanged:
+			lockChanged = a.vault.LockChanged()
+		case <-poll.C:
+		}
+		state = a.waitState(ctx, state.Tip)
+	}
+	state.Changed = since != ""
+	jc.Encode(state)
+}
+
 func (a *api) handleGETSeeds(jc jape.Context) {
 	var (
 		limit  = 100
@@ -76,377 +584,1760 @@ func (a *api) handleGETSeeds(jc jape.Context) {
 	})
 }
 
-func (a *api) handlePOSTSeeds(jc jape.Context) {
-	var req AddSeedRequest
-	if err := jc.Decode(&req); err != nil {
+func (a *api) handleGETImportKey(jc jape.Context) {
+	pk, expires, err := a.importKeys.Generate()
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
 		return
 	}
+	jc.Encode(ImportKeyResponse{
+		PublicKey: pk,
+		ExpiresAt: expires,
+	})
+}
 
-	var seed [32]byte
-	defer clear(seed[:])
-	switch len(strings.Fields(req.Phrase)) {
-	case 28, 29:
-		if err := siad.SeedFromPhrase(&seed, req.Phrase); err != nil {
-			jc.Error(err, http.StatusBadRequest)
-			return
-		}
-	case 12:
-		if err := wallet.SeedFromPhrase(&seed, req.Phrase); err != nil {
-			jc.Error(err, http.StatusBadRequest)
-			return
-		}
-	default:
-		jc.Error(errors.New("invalid phrase length, must be BIP39 12 word seed or 28 word Sia seed"), http.StatusBadRequest)
+// takeImportToken removes an unexpired import token. It returns false if
+// the token is unknown, expired, or already in use.
+func (a *api) takeImportToken(token string) (importToken, bool) {
+	h := types.HashBytes([]byte(token))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	it, ok := a.importTokens[h]
+	delete(a.importTokens, h)
+	return it, ok && time.Now().Before(it.expires)
+}
+
+// getImportToken returns an unused, unexpired import token.
+func (a *api) getImportToken(token string) (importToken, bool) {
+	h := types.HashBytes([]byte(token))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	it, ok := a.importTokens[h]
+	return it, ok && time.Now().Before(it.expires)
+}
+
+func (a *api) handlePOSTImportTokens(jc jape.Context) {
+	var req ImportTokenRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if req.Lifetime == 0 {
+		req.Lifetime = defaultImportTokenLifetime
+	} else if req.Lifetime < 0 || req.Lifetime > maxImportTokenLifetime {
+		jc.Error(fmt.Errorf("lifetime must be between 0 and %v", maxImportTokenLifetime), http.StatusBadRequest)
 		return
 	}
 
-	meta, err := a.vault.AddSeed(&seed)
-	if err != nil {
-		jc.Error(err, http.StatusInternalServerError)
+	token := hex.EncodeToString(frand.Bytes(32))
+	expires := time.Now().Add(req.Lifetime)
+
+	a.mu.Lock()
+	now := time.Now()
+	for h, it := range a.importTokens {
+		if now.After(it.expires) {
+			delete(a.importTokens, h)
+		}
+	}
+	if len(a.importTokens) >= maxImportTokens {
+		a.mu.Unlock()
+		jc.Error(fmt.Errorf("too many unused import tokens, the limit is %d", maxImportTokens), http.StatusTooManyRequests)
 		return
 	}
-	jc.Encode(meta)
+	a.importTokens[types.HashBytes([]byte(token))] = importToken{
+		expires: expires,
+		keys:    importkey.NewKeyring(importKeyLifetime, 1),
+	}
+	a.mu.Unlock()
+
+	a.requestLog(jc.Request.Context()).Info("created import token", zap.Time("expires", expires))
+	jc.Encode(ImportTokenResponse{
+		Token:     token,
+		ExpiresAt: expires,
+	})
 }
 
-func (a *api) handleGETSeedsID(jc jape.Context) {
-	var id vault.SeedID
-	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+func (a *api) handleGETImportTokensKey(jc jape.Context) {
+	var token string
+	if err := jc.DecodeParam("token", &token); err != nil {
+		return
+	}
+	it, ok := a.getImportToken(token)
+	if !ok {
+		jc.Error(errors.New("unknown or expired import token"), http.StatusUnauthorized)
 		return
 	}
 
-	meta, err := a.vault.SeedMeta(id)
-	if errors.Is(err, vault.ErrNotFound) {
-		jc.Error(err, http.StatusNotFound)
-	} else if err != nil {
+	// the key is bound to the token instead of the shared keyring, so a
+	// token can neither hold more than one key nor evict other keys
+	pk, expires, err := it.keys.Generate()
+	if err != nil {
 		jc.Error(err, http.StatusInternalServerError)
 		return
+	} else if it.expires.Before(expires) {
+		// the key cannot be used after the token expires
+		expires = it.expires
 	}
-	jc.Encode(SeedResponse{
-		ID:        meta.ID,
-		LastIndex: meta.LastIndex,
-		CreatedAt: meta.CreatedAt,
+	jc.Encode(ImportKeyResponse{
+		PublicKey: pk,
+		ExpiresAt: expires,
 	})
 }
 
-func (a *api) handleGETSeedsKeys(jc jape.Context) {
-	limit := 100
-	offset := 0
-	if err := jc.DecodeForm("limit", &limit); err != nil {
-		return
-	} else if err := jc.DecodeForm("offset", &offset); err != nil {
+func (a *api) handlePOSTImportTokensSeed(jc jape.Context) {
+	var token string
+	var req AddSeedRequest
+	if err := jc.DecodeParam("token", &token); err != nil {
 		return
-	} else if limit < 1 || limit > 500 {
-		jc.Error(errors.New("limit must be between 1 and 500"), http.StatusBadRequest)
+	} else if err := jc.Decode(&req); err != nil {
 		return
-	} else if offset < 0 {
-		jc.Error(errors.New("offset must be non-negative"), http.StatusBadRequest)
+	} else if req.EncryptedPhrase == nil || req.Phrase != "" {
+		jc.Error(errors.New("the phrase must be encrypted"), http.StatusBadRequest)
 		return
 	}
 
-	var id vault.SeedID
-	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+	it, ok := a.takeImportToken(token)
+	if !ok {
+		jc.Error(errors.New("unknown or expired import token"), http.StatusUnauthorized)
 		return
 	}
+	// the token is only used up by a successful submission
+	var added bool
+	defer func() {
+		if !added {
+			a.mu.Lock()
+			a.importTokens[types.HashBytes([]byte(token))] = it
+			a.mu.Unlock()
+		}
+	}()
 
-	keys, err := a.vault.SeedKeys(id, offset, limit)
-	if errors.Is(err, vault.ErrNotFound) {
-		jc.Error(err, http.StatusNotFound)
+	buf, err := it.keys.Open(*req.EncryptedPhrase)
+	if errors.Is(err, importkey.ErrUnknownKey) || errors.Is(err, importkey.ErrDecrypt) {
+		jc.Error(err, http.StatusBadRequest)
+		return
 	} else if err != nil {
 		jc.Error(err, http.StatusInternalServerError)
 		return
 	}
+	defer clear(buf)
 
-	resp := SeedKeysResponse{
-		Keys: make([]SeedKey, len(keys)),
+	var seed [32]byte
+	defer clear(seed[:])
+	lang, err := seedFromPhrase(&seed, string(buf))
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
 	}
 
-	for i, key := range keys {
-		resp.Keys[i].PublicKey = key
-		resp.Keys[i].SpendPolicy = types.SpendPolicy{
-			Type: types.PolicyTypeUnlockConditions(types.StandardUnlockConditions(key)),
-		}
-		resp.Keys[i].Address = resp.Keys[i].SpendPolicy.Address()
+	meta, err := a.vault.AddSeedWithLanguage(&seed, lang)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	added = true
+	a.requestLog(jc.Request.Context()).Info("added seed with import token", zap.Int64("seedID", int64(meta.ID)))
+	resp := SeedResponse{
+		ID:        meta.ID,
+		LastIndex: meta.LastIndex,
+		Language:  meta.Language,
+		ExpiresAt: meta.ExpiresAt,
+		CreatedAt: meta.CreatedAt,
 	}
+	a.broadcast(webhooks.EventSeedAdded, webhooks.ScopeSeeds, resp)
 	jc.Encode(resp)
 }
 
-func (a *api) handlePOSTSeedsKeys(jc jape.Context) {
-	var req SeedDeriveRequest
+func (a *api) handlePOSTSeeds(jc jape.Context) {
+	var req AddSeedRequest
 	if err := jc.Decode(&req); err != nil {
 		return
 	}
-	var id vault.SeedID
-	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
-		return
-	}
 
-	resp := SeedKeysResponse{
-		Keys: make([]SeedKey, req.Count),
-	}
-	for i := uint64(0); i < req.Count; i++ {
-		key, err := a.vault.NextKey(id)
-		if err != nil {
-			jc.Error(err, http.StatusInternalServerError)
+	phrase := req.Phrase
+	if req.EncryptedPhrase != nil {
+		if req.Phrase != "" {
+			jc.Error(errors.New("phrase and encryptedPhrase cannot both be set"), http.StatusBadRequest)
 			return
 		}
-		resp.Keys[i].PublicKey = key
-		resp.Keys[i].SpendPolicy = types.SpendPolicy{
-			Type: types.PolicyTypeUnlockConditions(types.StandardUnlockConditions(key)),
+		buf, err := a.importKeys.Open(*req.EncryptedPhrase)
+		if errors.Is(err, importkey.ErrUnknownKey) || errors.Is(err, importkey.ErrDecrypt) {
+			jc.Error(err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			jc.Error(err, http.StatusInternalServerError)
+			return
 		}
-		resp.Keys[i].Address = resp.Keys[i].SpendPolicy.Address()
-	}
-	jc.Encode(resp)
-}
-
-func (a *api) getConsensusState(ctx context.Context, state *consensus.State, network *consensus.Network) (consensus.State, error) {
-	if state != nil && network != nil {
-		cs := *state
-		cs.Network = network
-		return cs, nil
-	} else if state == nil && network == nil {
-		a.log.Debug("getting consensus state from chain")
-		return a.chain.TipState(ctx)
-	} else if state == nil {
-		return consensus.State{}, errors.New("state must be provided if network is provided")
-	}
-	return consensus.State{}, errors.New("network must be provided if state is provided")
-}
-
-func (a *api) handlePOSTSign(jc jape.Context) {
-	var req SignRequest
-	if err := jc.Decode(&req); err != nil {
-		return
+		defer clear(buf)
+		phrase = string(buf)
 	}
 
-	cs, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	var seed [32]byte
+	defer clear(seed[:])
+	lang, err := seedFromPhrase(&seed, phrase)
 	if err != nil {
 		jc.Error(err, http.StatusBadRequest)
 		return
 	}
 
-	txn := req.Transaction
-
-	publicKeyForSigning := func(id types.Hash256, pubKeyIndex uint64) (types.PublicKey, bool) {
-		getUnlockConditions := func(id types.Hash256) (types.UnlockConditions, bool) {
+	var dryRun bool
+	if err := jc.DecodeForm("dryRun", &dryRun); err != nil {
+		return
+	} else if dryRun {
+		preview, err := a.vault.PreviewSeed(&seed)
+		if err != nil {
+			jc.Error(err, http.StatusInternalServerError)
+			return
+		}
+		sk := wallet.KeyFromSeed(&seed, 0)
+		defer clear(sk[:])
+		resp := SeedImportPreview{
+			Action:    preview.Action,
+			PublicKey: sk.PublicKey(),
+			Address:   types.StandardUnlockHash(sk.PublicKey()),
+		}
+		if preview.Action != vault.ImportCreate {
+			resp.Seed = &SeedResponse{
+				ID:        preview.Seed.ID,
+				LastIndex: preview.Seed.LastIndex,
+				Language:  preview.Seed.Language,
+				ExpiresAt: preview.Seed.ExpiresAt,
+				CreatedAt: preview.Seed.CreatedAt,
+			}
+		}
+		jc.Encode(resp)
+		return
+	}
+
+	meta, err := a.vault.AddSeedWithLanguage(&seed, lang)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.broadcast(webhooks.EventSeedAdded, webhooks.ScopeSeeds, SeedResponse{
+		ID:        meta.ID,
+		LastIndex: meta.LastIndex,
+		Language:  meta.Language,
+		ExpiresAt: meta.ExpiresAt,
+		CreatedAt: meta.CreatedAt,
+	})
+	jc.Encode(meta)
+}
+
+func (a *api) handleGETPhrasesNew(jc jape.Context) {
+	lang := bip39.English
+	if err := jc.DecodeForm("language", &lang); err != nil {
+		return
+	}
+	phrase, err := bip39.NewPhrase(lang)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(PhraseResponse{
+		Phrase:   phrase,
+		Language: string(lang),
+	})
+}
+
+func (a *api) handlePOSTPhrasesValidate(jc jape.Context) {
+	var req PhraseValidateRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+	jc.Encode(validatePhrase(req.Phrase))
+}
+
+func (a *api) handlePOSTPhrasesGenerate(jc jape.Context) {
+	var req SeedGenerateRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if !req.Confirm {
+		jc.Error(errors.New("confirm must be true to acknowledge that the phrase is only returned once"), http.StatusBadRequest)
+		return
+	}
+	lang := bip39.English
+	if req.Language != "" {
+		if err := lang.UnmarshalText([]byte(req.Language)); err != nil {
+			jc.Error(err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	phrase, err := bip39.NewPhrase(lang)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	var seed [32]byte
+	defer clear(seed[:])
+	if _, err := bip39.SeedFromPhrase(&seed, phrase); err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	meta, err := a.vault.AddSeedWithLanguage(&seed, string(lang))
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := SeedResponse{
+		ID:        meta.ID,
+		LastIndex: meta.LastIndex,
+		Language:  meta.Language,
+		ExpiresAt: meta.ExpiresAt,
+		CreatedAt: meta.CreatedAt,
+	}
+	a.requestLog(jc.Request.Context()).Info("generated seed", zap.Int64("seedID", int64(meta.ID)))
+	a.broadcast(webhooks.EventSeedAdded, webhooks.ScopeSeeds, resp)
+	// the phrase cannot be shown again, so it must not be cached either
+	jc.ResponseWriter.Header().Set("Cache-Control", "no-store")
+	jc.Encode(SeedGenerateResponse{
+		Seed:   resp,
+		Phrase: phrase,
+	})
+}
+
+func (a *api) handleGETSeedsID(jc jape.Context) {
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	meta, err := a.vault.SeedMeta(id)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(SeedResponse{
+		ID:        meta.ID,
+		LastIndex: meta.LastIndex,
+		Language:  meta.Language,
+		ExpiresAt: meta.ExpiresAt,
+		CreatedAt: meta.CreatedAt,
+	})
+}
+
+func (a *api) handlePUTSeedsExpiration(jc jape.Context) {
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	var req ExpirationRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+
+	err := a.vault.SetSeedExpiration(id, req.ExpiresAt)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("set seed expiration", zap.Int64("seedID", int64(id)), zap.Time("expiresAt", req.ExpiresAt))
+	jc.Encode(nil)
+}
+
+func (a *api) handlePUTKeysExpiration(jc jape.Context) {
+	var pk types.PublicKey
+	if err := jc.DecodeParam("key", &pk); err != nil {
+		return
+	}
+	var req ExpirationRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+
+	err := a.vault.SetKeyExpiration(pk, req.ExpiresAt)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("set key expiration", a.redactedField("publicKey", pk), zap.Time("expiresAt", req.ExpiresAt))
+	jc.Encode(nil)
+}
+
+func (a *api) handleGETExpirations(jc jape.Context) {
+	// by default, every expiration is returned
+	before := time.Unix(1<<40, 0)
+	if err := jc.DecodeForm("before", &before); err != nil {
+		return
+	}
+
+	exps, err := a.vault.Expirations(before)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	resp := make([]Expiration, 0, len(exps))
+	for _, exp := range exps {
+		resp = append(resp, Expiration{
+			SeedID:    exp.SeedID,
+			PublicKey: exp.PublicKey,
+			ExpiresAt: exp.ExpiresAt,
+			Expired:   !now.Before(exp.ExpiresAt),
+		})
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handleDELETESeedsID(jc jape.Context) {
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	err := a.vault.DeleteSeed(id)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("deleted seed", zap.Int64("seedID", int64(id)))
+	a.notify(notify.EventSeedDeleted, "Seed deleted", fmt.Sprintf("Seed %d was deleted. It can be restored until it is purged.", id))
+	jc.Encode(nil)
+}
+
+func (a *api) handlePOSTSeedsRestore(jc jape.Context) {
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	var dryRun bool
+	if err := jc.DecodeForm("dryRun", &dryRun); err != nil {
+		return
+	}
+
+	var meta vault.SeedMeta
+	var err error
+	if dryRun {
+		meta, err = a.vault.PreviewRestoreSeed(id)
+	} else {
+		err = a.vault.RestoreSeed(id)
+	}
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(fmt.Errorf("no deleted seed %d within the retention period: %w", id, err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	} else if dryRun {
+		jc.Encode(SeedResponse{
+			ID:        meta.ID,
+			LastIndex: meta.LastIndex,
+			Language:  meta.Language,
+			ExpiresAt: meta.ExpiresAt,
+			CreatedAt: meta.CreatedAt,
+		})
+		return
+	}
+
+	meta, err = a.vault.SeedMeta(id)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("restored seed", zap.Int64("seedID", int64(id)))
+	a.notify(notify.EventSeedRestored, "Seed restored", fmt.Sprintf("Seed %d was restored.", id))
+	jc.Encode(SeedResponse{
+		ID:        meta.ID,
+		LastIndex: meta.LastIndex,
+		Language:  meta.Language,
+		ExpiresAt: meta.ExpiresAt,
+		CreatedAt: meta.CreatedAt,
+	})
+}
+
+func (a *api) handleGETSeedsDerivation(jc jape.Context) {
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	keys, _, err := a.vault.SeedKeys(id, 0, 1)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	// reserved indices are skipped, so the next index can be past the
+	// last derived key
+	next, err := a.vault.NextIndex(id)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := SeedDerivationResponse{
+		SeedID: id,
+		Scheme: "sia",
+		Phrases: []PhraseEncoding{
+			{Name: "bip39", Words: []int{12, 15, 18, 21, 24}, Seed: "blake2b-256(bip39_entropy)"},
+			{Name: "siad", Words: []int{28, 29}, Seed: "siad_mnemonic_decode(phrase)[:32]"},
+		},
+		Key:           "ed25519_from_seed(blake2b-256(seed || uint64le(index)))",
+		Hardened:      true,
+		IndexEncoding: "uint64-le",
+		FirstIndex:    0,
+		NextIndex:     next,
+		V1Address:     "standard unlock conditions: timelock 0, one ed25519 public key, 1 required signature",
+		V2Address:     "public key spend policy",
+		Vectors:       []DerivationVector{},
+	}
+	if len(keys) > 0 {
+		km, err := a.vault.KeyByAddress(types.StandardUnlockHash(keys[0]))
+		if err != nil {
+			jc.Error(err, http.StatusInternalServerError)
+			return
+		}
+		resp.Vectors = append(resp.Vectors, DerivationVector{
+			Index:     km.Index,
+			PublicKey: keys[0],
+			V1Address: types.StandardUnlockHash(keys[0]),
+			V2Address: types.PolicyPublicKey(keys[0]).Address(),
+		})
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handleGETSeedsKeys(jc jape.Context) {
+	limit := 100
+	offset := 0
+	policyType := PolicyTypeUnlockConditions
+	if err := jc.DecodeForm("limit", &limit); err != nil {
+		return
+	} else if err := jc.DecodeForm("offset", &offset); err != nil {
+		return
+	} else if err := jc.DecodeForm("policyType", &policyType); err != nil {
+		return
+	} else if limit < 1 || limit > 500 {
+		jc.Error(errors.New("limit must be between 1 and 500"), http.StatusBadRequest)
+		return
+	} else if offset < 0 {
+		jc.Error(errors.New("offset must be non-negative"), http.StatusBadRequest)
+		return
+	}
+
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	keys, total, err := a.vault.SeedKeys(id, offset, limit)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := SeedKeysResponse{
+		Keys:  make([]SeedKey, len(keys)),
+		Total: total,
+	}
+
+	for i, key := range keys {
+		resp.Keys[i] = seedKey(key, policyType)
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handleGETKeys(jc jape.Context) {
+	limit := 100
+	offset := 0
+	policyType := PolicyTypeUnlockConditions
+	var filter vault.KeyFilter
+	if err := jc.DecodeForm("limit", &limit); err != nil {
+		return
+	} else if err := jc.DecodeForm("offset", &offset); err != nil {
+		return
+	} else if err := jc.DecodeForm("policyType", &policyType); err != nil {
+		return
+	} else if err := jc.DecodeForm("seed", (*int64)(&filter.SeedID)); err != nil {
+		return
+	} else if err := jc.DecodeForm("createdAfter", &filter.CreatedAfter); err != nil {
+		return
+	} else if limit < 1 || limit > 500 {
+		jc.Error(errors.New("limit must be between 1 and 500"), http.StatusBadRequest)
+		return
+	} else if offset < 0 {
+		jc.Error(errors.New("offset must be non-negative"), http.StatusBadRequest)
+		return
+	}
+
+	keys, total, err := a.vault.FilterKeys(filter, offset, limit)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	resp := KeysResponse{
+		Keys:  make([]VaultKey, len(keys)),
+		Total: total,
+	}
+	for i, key := range keys {
+		resp.Keys[i] = VaultKey{
+			SeedKey:   seedKey(key.PublicKey, policyType),
+			SeedID:    key.SeedID,
+			Index:     key.Index,
+			CreatedAt: key.CreatedAt,
+		}
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handleGETAddressesKey(jc jape.Context) {
+	var addr types.Address
+	if err := jc.DecodeParam("address", &addr); err != nil {
+		return
+	}
+
+	key, err := a.vault.KeyByAddress(addr)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	// report the policy whose address was requested
+	policyType := PolicyTypeUnlockConditions
+	if addr != types.StandardUnlockHash(key.PublicKey) {
+		policyType = PolicyTypePublicKey
+	}
+	jc.Encode(AddressKeyResponse{
+		SeedID: key.SeedID,
+		Index:  key.Index,
+		Key:    seedKey(key.PublicKey, policyType),
+	})
+}
+
+func (a *api) handlePOSTSystemCheck(jc jape.Context) {
+	issues, err := a.vault.CheckConsistency()
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	for _, issue := range issues {
+		a.requestLog(jc.Request.Context()).Warn("consistency check found an issue", zap.Int64("seedID", int64(issue.SeedID)), zap.String("description", issue.Description))
+	}
+	if issues == nil {
+		issues = []vault.ConsistencyIssue{}
+	}
+	jc.Encode(SystemCheckResponse{Issues: issues})
+}
+
+func (a *api) handleGETStats(jc jape.Context) {
+	a.mu.Lock()
+	signed := make(map[StateSource]uint64, len(a.signedBySource))
+	for source, n := range a.signedBySource {
+		signed[source] = n
+	}
+	a.mu.Unlock()
+
+	latency, slo := a.latency.stats(a.signingSLO)
+	jc.Encode(StatsResponse{
+		SignedTransactions: signed,
+		Latency:            latency,
+		SigningSLO:         slo,
+	})
+}
+
+func (a *api) handleGETExportDescriptor(jc jape.Context) {
+	seeds, err := a.vault.ExportSeeds(maxDescriptorKeys)
+	if errors.Is(err, vault.ErrExportLimit) {
+		jc.Error(err, http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	desc := WalletDescriptor{
+		Version: descriptorVersion,
+		Seeds:   make([]SeedDescriptor, 0, len(seeds)),
+	}
+	for _, seed := range seeds {
+		sd := SeedDescriptor{
+			ID:               seed.ID,
+			DerivationScheme: DerivationSchemeSia,
+			DerivedCount:     seed.NextIndex,
+			Keys:             make([]SeedKey, 0, len(seed.Keys)),
+		}
+		if seed.FirstKey != (types.PublicKey{}) {
+			sd.Fingerprint = seedFingerprint(seed.FirstKey)
+		}
+		for _, key := range seed.Keys {
+			sd.Keys = append(sd.Keys, seedKey(key.PublicKey, PolicyTypeUnlockConditions))
+		}
+		desc.Seeds = append(desc.Seeds, sd)
+	}
+	jc.Encode(desc)
+}
+
+func (a *api) handlePOSTSeedsKeys(jc jape.Context) {
+	var req SeedDeriveRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	policyType := PolicyTypeUnlockConditions
+	if err := jc.DecodeForm("policyType", &policyType); err != nil {
+		return
+	} else if req.Count == 0 {
+		jc.Error(errors.New("count must be greater than zero"), http.StatusBadRequest)
+		return
+	} else if req.Count > maxDeriveCount {
+		jc.Error(fmt.Errorf("count must not exceed %d", maxDeriveCount), http.StatusBadRequest)
+		return
+	}
+
+	keys, err := a.vault.NextKeys(id, req.Count)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(fmt.Errorf("seed %d not found: %w", id, err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	resp := SeedKeysResponse{
+		Keys: make([]SeedKey, len(keys)),
+	}
+	for i, key := range keys {
+		resp.Keys[i] = seedKey(key, policyType)
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handlePOSTSeedsReserve(jc jape.Context) {
+	var req SeedDeriveRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	} else if req.Count == 0 {
+		jc.Error(errors.New("count must be greater than zero"), http.StatusBadRequest)
+		return
+	}
+
+	start, err := a.vault.ReserveIndices(id, req.Count)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(fmt.Errorf("seed %d not found: %w", id, err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(SeedReserveResponse{
+		Start: start,
+		End:   start + req.Count,
+	})
+}
+
+func (a *api) handlePOSTSeedsKeysRegister(jc jape.Context) {
+	var req SeedRegisterRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+	var id vault.SeedID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	policyType := PolicyTypeUnlockConditions
+	if err := jc.DecodeForm("policyType", &policyType); err != nil {
+		return
+	} else if len(req.Keys) == 0 {
+		jc.Error(errors.New("no keys to register"), http.StatusBadRequest)
+		return
+	}
+
+	keys := make([]vault.KeyMeta, len(req.Keys))
+	for i, key := range req.Keys {
+		keys[i] = vault.KeyMeta{PublicKey: key.PublicKey, SeedID: id, Index: key.Index}
+	}
+	err := a.vault.RegisterKeys(keys)
+	switch {
+	case errors.Is(err, vault.ErrNotFound):
+		jc.Error(fmt.Errorf("seed %d not found: %w", id, err), http.StatusNotFound)
+		return
+	case errors.Is(err, vault.ErrNotReserved), errors.Is(err, vault.ErrKeyMismatch):
+		jc.Error(err, http.StatusBadRequest)
+		return
+	case err != nil:
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := SeedKeysResponse{
+		Keys: make([]SeedKey, len(keys)),
+	}
+	for i, key := range keys {
+		resp.Keys[i] = seedKey(key.PublicKey, policyType)
+	}
+	jc.Encode(resp)
+}
+
+// getConsensusState returns the consensus state provided in the request or,
+// if none was provided, the chain's tip state, along with its source.
+func (a *api) getConsensusState(ctx context.Context, state *consensus.State, network *consensus.Network) (consensus.State, StateSource, error) {
+	if state != nil && network != nil {
+		cs := *state
+		cs.Network = network
+		return cs, StateSourceRequest, nil
+	} else if state == nil && network == nil {
+		a.requestLog(ctx).Debug("getting consensus state from chain")
+		cs, err := a.chain.TipState(ctx)
+		return cs, StateSourceExplorer, err
+	} else if state == nil {
+		return consensus.State{}, "", errors.New("state must be provided if network is provided")
+	}
+	return consensus.State{}, "", errors.New("network must be provided if state is provided")
+}
+
+// recordSigned counts a transaction signed with consensus state from
+// the given source.
+func (a *api) recordSigned(source StateSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.signedBySource[source]++
+}
+
+func (a *api) handleGETFees(jc jape.Context, fe FeeEstimator) {
+	fee, err := fe.RecommendedFee(jc.Request.Context())
+	if err != nil {
+		jc.Error(fmt.Errorf("failed to get recommended fee: %w", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	resp := FeesResponse{
+		Recommended: fee,
+		FeePerByte:  fee,
+		Min:         a.minFee,
+		Max:         a.maxFee,
+	}
+	if resp.FeePerByte.Cmp(a.minFee) < 0 {
+		resp.FeePerByte = a.minFee
+	}
+	if !a.maxFee.IsZero() && resp.FeePerByte.Cmp(a.maxFee) > 0 {
+		resp.FeePerByte = a.maxFee
+	}
+	jc.Encode(resp)
+}
+
+func (a *api) handlePOSTSign(jc jape.Context) {
+	var req SignRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if err := validateMetadata(req.Metadata); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	if !a.checkNonce(jc, req.Nonce, req.Timestamp) {
+		return
+	}
+
+	cs, source, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	txn := req.Transaction
+
+	publicKeyForSigning := func(id types.Hash256, pubKeyIndex uint64) (types.PublicKey, SkippedSignature, bool) {
+		getUnlockConditions := func(id types.Hash256) (types.UnlockConditions, bool) {
 			for _, input := range txn.SiacoinInputs {
 				if types.Hash256(input.ParentID) == id {
 					return input.UnlockConditions, true
 				}
 			}
-			for _, input := range txn.SiafundInputs {
-				if types.Hash256(input.ParentID) == id {
-					return input.UnlockConditions, true
+			for _, input := range txn.SiafundInputs {
+				if types.Hash256(input.ParentID) == id {
+					return input.UnlockConditions, true
+				}
+			}
+			return types.UnlockConditions{}, false
+		}
+
+		uc, ok := getUnlockConditions(id)
+		if !ok {
+			return types.PublicKey{}, SkippedSignature{Reason: SkipReasonUnknownParent, Message: fmt.Sprintf("no input with parent ID %v", id)}, false
+		} else if pubKeyIndex >= uint64(len(uc.PublicKeys)) {
+			return types.PublicKey{}, SkippedSignature{Reason: SkipReasonInvalidKey, Message: fmt.Sprintf("public key index %d out of range", pubKeyIndex)}, false
+		}
+
+		uk := uc.PublicKeys[pubKeyIndex]
+		if uk.Algorithm != types.SpecifierEd25519 {
+			return types.PublicKey{}, SkippedSignature{Reason: SkipReasonUnsupportedAlgorithm, Message: fmt.Sprintf("unsupported public key algorithm %q", uk.Algorithm.String())}, false
+		} else if len(uk.Key) != ed25519.PublicKeySize {
+			return types.PublicKey{}, SkippedSignature{Reason: SkipReasonInvalidKey, Message: fmt.Sprintf("invalid ed25519 key length %d", len(uk.Key))}, false
+		}
+		return types.PublicKey(uk.Key), SkippedSignature{}, true
+	}
+
+	var signed int
+	var skipped []SkippedSignature
+	for i, sig := range txn.Signatures {
+		if sig.Signature != nil {
+			signed++
+			continue
+		}
+
+		pk, skip, ok := publicKeyForSigning(sig.ParentID, sig.PublicKeyIndex)
+		if !ok {
+			skip.Index = i
+			skipped = append(skipped, skip)
+			continue
+		}
+
+		var sigHash types.Hash256
+		if sig.CoveredFields.WholeTransaction {
+			sigHash = cs.WholeSigHash(txn, sig.ParentID, sig.PublicKeyIndex, sig.Timelock, nil)
+		} else {
+			sigHash = cs.PartialSigHash(txn, sig.CoveredFields)
+		}
+
+		signature, err := a.sign(jc.Request, pk, sigHash)
+		if errors.Is(err, vault.ErrNotFound) {
+			skipped = append(skipped, SkippedSignature{Index: i, Reason: SkipReasonNotFound, Message: fmt.Sprintf("key %v not found", pk)})
+			continue
+		} else if errors.Is(err, vault.ErrExpired) {
+			skipped = append(skipped, SkippedSignature{Index: i, Reason: SkipReasonExpired, Message: fmt.Sprintf("key %v: %v", pk, err)})
+			continue
+		} else if errors.Is(err, errSignPolicy) {
+			skipped = append(skipped, SkippedSignature{Index: i, Reason: SkipReasonPolicy, Message: fmt.Sprintf("key %v: %v", pk, err)})
+			continue
+		} else if err != nil {
+			jc.Error(err, http.StatusInternalServerError)
+			return
+		}
+		txn.Signatures[i].Signature = signature[:]
+		signed++
+	}
+
+	if signed == 0 {
+		if len(skipped) > 0 {
+			jc.Error(fmt.Errorf("no signatures were added: signature %d: %s", skipped[0].Index, skipped[0].Message), http.StatusBadRequest)
+			return
+		}
+		jc.Error(errors.New("no signatures were added"), http.StatusBadRequest)
+		return
+	}
+	a.recordSigned(source)
+	a.requestLog(jc.Request.Context()).Info("signed transaction", a.redactedField("transactionID", txn.ID()), zap.String("stateSource", string(source)), zap.Int("signatures", signed), zap.Int("skipped", len(skipped)), zap.Any("metadata", req.Metadata))
+	var fee types.Currency
+	for _, c := range txn.MinerFees {
+		fee = fee.Add(c)
+	}
+	a.afterSign(jc.Request.Context(), SignEvent{
+		Route:          "POST /sign",
+		TransactionID:  txn.ID(),
+		SiacoinOutputs: txn.SiacoinOutputs,
+		MinerFee:       fee,
+		FullySigned:    signed == len(txn.Signatures),
+		Metadata:       req.Metadata,
+	})
+	jc.Encode(SignResponse{Transaction: txn, FullySigned: signed == len(txn.Signatures), Skipped: skipped, Metadata: req.Metadata})
+}
+
+func (a *api) handlePOSTSignV2(jc jape.Context) {
+	var req SignV2Request
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if err := validateMetadata(req.Metadata); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	if !a.checkNonce(jc, req.Nonce, req.Timestamp) {
+		return
+	}
+
+	txn := req.Transaction
+
+	cs, source, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	if cs.Index.Height < cs.Network.HardforkV2.AllowHeight {
+		jc.Error(errors.New("v2 transactions are not supported until after the allow height"), http.StatusBadRequest)
+		return
+	} else if err := a.guardrails.checkContracts(txn); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	sigHash := cs.InputSigHash(txn)
+
+	var added int
+	var signPolicy func(policy types.SpendPolicy, signatures *[]types.Signature) error
+	signPolicy = func(policy types.SpendPolicy, signatures *[]types.Signature) error {
+		switch policy := policy.Type.(type) {
+		case types.PolicyTypeThreshold:
+			var signed uint8
+			for _, sub := range policy.Of {
+				if signed == policy.N {
+					break
+				}
+
+				if err := signPolicy(sub, signatures); err != nil {
+					return err
+				}
+				signed++
+			}
+			if signed < policy.N {
+				return fmt.Errorf("policy %q threshold not met %d != %d", policy, signed, policy.N)
+			}
+		case types.PolicyTypePublicKey:
+			sig, err := a.sign(jc.Request, types.PublicKey(policy), sigHash)
+			if errors.Is(err, vault.ErrNotFound) || errors.Is(err, vault.ErrExpired) || errors.Is(err, errSignPolicy) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to sign policy %v: %w", policy, err)
+			}
+			*signatures = append(*signatures, sig)
+			added++
+		case types.PolicyTypeUnlockConditions:
+			var signed uint64
+			for i := range policy.PublicKeys {
+				if signed == policy.SignaturesRequired {
+					break
+				} else if policy.PublicKeys[i].Algorithm != types.SpecifierEd25519 || len(policy.PublicKeys[i].Key) != ed25519.PublicKeySize {
+					return fmt.Errorf("unsupported public key algorithm %v", policy.PublicKeys[i].Algorithm)
+				}
+				pk := types.PublicKey(policy.PublicKeys[i].Key)
+
+				sig, err := a.sign(jc.Request, pk, sigHash)
+				if errors.Is(err, vault.ErrNotFound) || errors.Is(err, vault.ErrExpired) || errors.Is(err, errSignPolicy) {
+					continue
+				} else if err != nil {
+					return fmt.Errorf("failed to sign policy %v: %w", policy, err)
 				}
+				*signatures = append(*signatures, sig)
+				added++
+				signed++
 			}
-			return types.UnlockConditions{}, false
+			if signed < policy.SignaturesRequired {
+				return fmt.Errorf("policy %v required signatures not met %d != %d", policy, signed, policy.SignaturesRequired)
+			}
+		}
+		return nil
+	}
+
+	signed := true
+	for i := range txn.SiacoinInputs {
+		if err := signPolicy(txn.SiacoinInputs[i].SatisfiedPolicy.Policy, &txn.SiacoinInputs[i].SatisfiedPolicy.Signatures); err != nil {
+			signed = false
+		}
+	}
+	for i := range txn.SiafundInputs {
+		if err := signPolicy(txn.SiafundInputs[i].SatisfiedPolicy.Policy, &txn.SiafundInputs[i].SatisfiedPolicy.Signatures); err != nil {
+			signed = false
 		}
+	}
+
+	// the response is returned even if no signatures were added, but
+	// only a request that added signatures counts as signed
+	if added > 0 {
+		a.recordSigned(source)
+		a.requestLog(jc.Request.Context()).Info("signed v2 transaction", a.redactedField("transactionID", txn.ID()), zap.String("stateSource", string(source)), zap.Int("signatures", added), zap.Bool("fullySigned", signed), zap.Any("metadata", req.Metadata))
+		a.afterSign(jc.Request.Context(), SignEvent{
+			Route:          "POST /v2/sign",
+			TransactionID:  txn.ID(),
+			SiacoinOutputs: txn.SiacoinOutputs,
+			MinerFee:       txn.MinerFee,
+			FullySigned:    signed,
+			Metadata:       req.Metadata,
+		})
+	}
+	jc.Encode(SignV2Response{
+		Transaction: txn,
+		FullySigned: signed,
+		Metadata:    req.Metadata,
+	})
+}
+
+func (a *api) handlePOSTBlindSign(jc jape.Context) {
+	var req BlindSignRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if err := validateMetadata(req.Metadata); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	if !a.checkNonce(jc, req.Nonce, req.Timestamp) {
+		return
+	}
+
+	sig, err := a.sign(jc.Request, req.PublicKey, req.SigHash)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if errors.Is(err, vault.ErrExpired) || errors.Is(err, errSignPolicy) {
+		jc.Error(err, http.StatusForbidden)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("blind signed hash", a.redactedField("publicKey", req.PublicKey), zap.Stringer("sigHash", req.SigHash), zap.Any("metadata", req.Metadata))
+	a.afterSign(jc.Request.Context(), SignEvent{
+		Route:       "POST /blind/sign",
+		PublicKey:   req.PublicKey,
+		SigHash:     req.SigHash,
+		FullySigned: true,
+		Metadata:    req.Metadata,
+	})
+	jc.Encode(BlindSignResponse{Signature: sig, Metadata: req.Metadata})
+}
+
+func (a *api) handlePOSTProofsOwnership(jc jape.Context) {
+	var req OwnershipProofRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+
+	switch {
+	case req.Challenge == "":
+		jc.Error(errors.New("challenge is required"), http.StatusBadRequest)
+		return
+	case len(req.Challenge) > maxChallengeLen:
+		jc.Error(fmt.Errorf("challenge must be at most %d characters", maxChallengeLen), http.StatusBadRequest)
+		return
+	case len(req.PublicKeys) > maxOwnershipProofs:
+		jc.Error(fmt.Errorf("at most %d public keys can be proven per request", maxOwnershipProofs), http.StatusBadRequest)
+		return
+	case req.Offset < 0:
+		jc.Error(errors.New("offset must be non-negative"), http.StatusBadRequest)
+		return
+	case req.Limit < 0 || req.Limit > maxOwnershipProofs:
+		jc.Error(fmt.Errorf("limit must be between 0 and %d", maxOwnershipProofs), http.StatusBadRequest)
+		return
+	case req.Limit == 0:
+		req.Limit = maxOwnershipProofs
+	}
+
+	keys, total := req.PublicKeys, len(req.PublicKeys)
+	if len(keys) == 0 {
+		metas, n, err := a.vault.Keys(req.Offset, req.Limit)
+		if err != nil {
+			jc.Error(fmt.Errorf("failed to get keys: %w", err), http.StatusInternalServerError)
+			return
+		}
+		for _, meta := range metas {
+			keys = append(keys, meta.PublicKey)
+		}
+		total = n
+	}
+
+	sigHash := OwnershipChallengeHash(req.Challenge)
+	proofs := make([]OwnershipProof, 0, len(keys))
+	for _, pk := range keys {
+		sig, err := a.sign(jc.Request, pk, sigHash)
+		if errors.Is(err, vault.ErrNotFound) {
+			jc.Error(fmt.Errorf("key %v not found", pk), http.StatusNotFound)
+			return
+		} else if errors.Is(err, vault.ErrExpired) || errors.Is(err, errSignPolicy) {
+			jc.Error(fmt.Errorf("key %v: %w", pk, err), http.StatusForbidden)
+			return
+		} else if err != nil {
+			jc.Error(fmt.Errorf("failed to sign with key %v: %w", pk, err), http.StatusInternalServerError)
+			return
+		}
+		proofs = append(proofs, OwnershipProof{
+			PublicKey: pk,
+			V1Address: types.StandardUnlockHash(pk),
+			V2Address: types.PolicyPublicKey(pk).Address(),
+			Signature: sig,
+		})
+	}
+	a.requestLog(jc.Request.Context()).Info("signed ownership proofs", zap.Stringer("sigHash", sigHash), zap.Int("proofs", len(proofs)), zap.Int("total", total))
+	jc.Encode(OwnershipProofResponse{
+		Challenge: req.Challenge,
+		SigHash:   sigHash,
+		Proofs:    proofs,
+		Total:     total,
+	})
+}
+
+func (a *api) handleGETCustodyCommitment(jc jape.Context) {
+	commitment, err := a.custody.Commitment()
+	if errors.Is(err, custody.ErrNoCommitment) {
+		jc.Error(err, http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(commitment)
+}
+
+func (a *api) handleGETCustodyProofsAddress(jc jape.Context) {
+	var addr types.Address
+	if err := jc.DecodeParam("address", &addr); err != nil {
+		return
+	}
+
+	key, err := a.vault.KeyByAddress(addr)
+	if errors.Is(err, vault.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	proof, commitment, err := a.custody.Proof(key.PublicKey)
+	switch {
+	case errors.Is(err, custody.ErrNoCommitment):
+		jc.Error(err, http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, custody.ErrNotCommitted):
+		jc.Error(err, http.StatusNotFound)
+		return
+	case err != nil:
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(CustodyProofResponse{
+		Commitment: commitment,
+		Proof:      proof,
+		V1Address:  types.StandardUnlockHash(key.PublicKey),
+		V2Address:  types.PolicyPublicKey(key.PublicKey).Address(),
+	})
+}
+
+func (a *api) handleGETSnapshots(jc jape.Context) {
+	snapshots, err := a.snapshots.Snapshots()
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(snapshots)
+}
+
+func (a *api) handlePOSTSnapshots(jc jape.Context) {
+	var req SnapshotRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+
+	s, err := a.snapshots.Create(req.Name)
+	switch {
+	case errors.Is(err, snapshot.ErrInvalidName):
+		jc.Error(err, http.StatusBadRequest)
+		return
+	case errors.Is(err, snapshot.ErrExists):
+		jc.Error(err, http.StatusConflict)
+		return
+	case err != nil:
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("created snapshot", zap.String("name", s.Name), zap.Int64("size", s.Size))
+	jc.Encode(s)
+}
+
+func (a *api) handlePOSTSnapshotsRestore(jc jape.Context) {
+	var name string
+	var dryRun bool
+	if err := jc.DecodeParam("name", &name); err != nil {
+		return
+	} else if err := jc.DecodeForm("dryRun", &dryRun); err != nil {
+		return
+	} else if dryRun {
+		preview, err := a.snapshots.Preview(name)
+		switch {
+		case errors.Is(err, snapshot.ErrInvalidName):
+			jc.Error(err, http.StatusBadRequest)
+		case errors.Is(err, snapshot.ErrNotFound):
+			jc.Error(err, http.StatusNotFound)
+		case err != nil:
+			jc.Error(err, http.StatusInternalServerError)
+		default:
+			jc.Encode(preview)
+		}
+		return
+	}
+
+	backup, err := a.snapshots.Restore(name)
+	switch {
+	case errors.Is(err, snapshot.ErrInvalidName):
+		jc.Error(err, http.StatusBadRequest)
+		return
+	case errors.Is(err, snapshot.ErrNotFound):
+		jc.Error(err, http.StatusNotFound)
+		return
+	case err != nil:
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	if err := a.vault.SyncKeyVersion(); err != nil {
+		a.requestLog(jc.Request.Context()).Error("failed to sync key version", zap.Error(err))
+	}
+	a.requestLog(jc.Request.Context()).Warn("restored snapshot", zap.String("name", name), zap.String("backup", backup.Name))
+	a.notify(notify.EventSnapshotRestored, "Snapshot restored", fmt.Sprintf("The vault was restored from snapshot %q. The previous state was saved as snapshot %q.", name, backup.Name))
+	jc.Encode(SnapshotRestoreResponse{Backup: backup})
+}
+
+// unlockVault unlocks the vault with the secret. If the vault cannot be
+// unlocked, the error is written to the response and false is returned.
+func (a *api) unlockVault(jc jape.Context, secret string) bool {
+	switch err := a.vault.Unlock(secret); {
+	case err == nil:
+		a.mu.Lock()
+		a.failedUnlocks = 0
+		a.mu.Unlock()
+		a.notify(notify.EventUnlocked, "Vault unlocked", "The vault was unlocked.")
+		a.broadcast(webhooks.EventUnlocked, webhooks.ScopeVault, nil)
+		return true
+	case errors.Is(err, vault.ErrUnlocked):
+		jc.Error(err, http.StatusBadRequest)
+	case errors.Is(err, vault.ErrTooManyAttempts):
+		jc.Error(err, http.StatusTooManyRequests)
+	case errors.Is(err, vault.ErrHardwareToken):
+		jc.Error(err, http.StatusServiceUnavailable)
+	case errors.Is(err, vault.ErrIncorrectSecret):
+		a.mu.Lock()
+		a.failedUnlocks++
+		failed := a.failedUnlocks
+		a.mu.Unlock()
+		if failed%failedUnlockAlertThreshold == 0 {
+			a.notify(notify.EventUnlockFailed, "Repeated failed unlock attempts", fmt.Sprintf("%d consecutive attempts to unlock the vault used an incorrect secret.", failed))
+		}
+		jc.Error(err, http.StatusUnauthorized)
+	default:
+		jc.Error(err, http.StatusInternalServerError)
+	}
+	return false
+}
 
-		uc, ok := getUnlockConditions(id)
-		if !ok {
-			return types.PublicKey{}, false
-		} else if pubKeyIndex >= uint64(len(uc.PublicKeys)) {
-			return types.PublicKey{}, false
-		}
+func (a *api) handlePOSTUnlock(jc jape.Context) {
+	var req UnlockRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
 
-		uk := uc.PublicKeys[pubKeyIndex]
-		if uk.Algorithm != types.SpecifierEd25519 {
-			return types.PublicKey{}, false
-		} else if len(uk.Key) != ed25519.PublicKeySize {
-			return types.PublicKey{}, false
-		}
-		return types.PublicKey(uk.Key), true
+	if a.unlockVault(jc, req.Secret) {
+		jc.Encode(nil)
 	}
+}
 
-	var signed int
-	for i, sig := range txn.Signatures {
-		if sig.Signature != nil {
-			signed++
-			continue
-		}
+func (a *api) handlePUTLock(jc jape.Context) {
+	a.vault.Lock()
+	a.notify(notify.EventLocked, "Vault locked", "The vault was locked.")
+	a.broadcast(webhooks.EventLocked, webhooks.ScopeVault, nil)
+	jc.Encode(nil)
+}
 
-		pk, ok := publicKeyForSigning(sig.ParentID, sig.PublicKeyIndex)
-		if !ok {
-			continue
-		}
+func (a *api) handlePOSTOfflineRequests(jc jape.Context) {
+	var req OfflineRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
 
-		var sigHash types.Hash256
-		if sig.CoveredFields.WholeTransaction {
-			sigHash = cs.WholeSigHash(txn, sig.ParentID, sig.PublicKeyIndex, sig.Timelock, nil)
-		} else {
-			sigHash = cs.PartialSigHash(txn, sig.CoveredFields)
-		}
+	cs, _, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	jc.Encode(offline.NewV1Request(cs, req.Transaction))
+}
 
-		signature, err := a.vault.Sign(pk, sigHash)
-		if errors.Is(err, vault.ErrNotFound) {
-			continue
-		} else if err != nil {
-			jc.Error(err, http.StatusInternalServerError)
-			return
-		}
-		txn.Signatures[i].Signature = signature[:]
-		signed++
+func (a *api) handlePOSTOfflineRequestsV2(jc jape.Context) {
+	var req OfflineV2Request
+	if err := jc.Decode(&req); err != nil {
+		return
 	}
 
-	if signed == 0 {
-		jc.Error(errors.New("no signatures were added"), http.StatusBadRequest)
+	cs, _, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	} else if cs.Index.Height < cs.Network.HardforkV2.AllowHeight {
+		jc.Error(errors.New("v2 transactions are not supported until after the allow height"), http.StatusBadRequest)
 		return
 	}
-	jc.Encode(SignResponse{Transaction: txn, FullySigned: signed == len(txn.Signatures)})
+	jc.Encode(offline.NewV2Request(cs, req.Transaction))
 }
 
-func (a *api) handlePOSTSignV2(jc jape.Context) {
-	var req SignV2Request
+func (a *api) handlePOSTOfflineSign(jc jape.Context) {
+	var req offline.SignRequest
 	if err := jc.Decode(&req); err != nil {
 		return
+	} else if !a.checkNonce(jc, req.Nonce, req.Timestamp) {
+		return
 	}
 
-	txn := req.Transaction
+	resp, err := offline.Sign(policySigner{a, jc.Request}, req)
+	if errors.Is(err, errSignPolicy) {
+		jc.Error(err, http.StatusForbidden)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.afterOfflineSign(jc.Request.Context(), "POST /offline/sign", req, resp)
+	jc.Encode(resp)
+}
+
+func (a *api) handlePOSTOfflineMerge(jc jape.Context) {
+	var req OfflineMergeRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
 
-	cs, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	cs, _, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
 	if err != nil {
 		jc.Error(err, http.StatusBadRequest)
 		return
 	}
 
-	if cs.Index.Height < cs.Network.HardforkV2.AllowHeight {
-		jc.Error(errors.New("v2 transactions are not supported until after the allow height"), http.StatusBadRequest)
+	txn := req.Transaction
+	signed, err := offline.MergeV1(cs, &txn, req.Response)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
 		return
 	}
+	jc.Encode(SignResponse{Transaction: txn, FullySigned: signed})
+}
 
-	sigHash := cs.InputSigHash(txn)
+func (a *api) handlePOSTOfflineMergeV2(jc jape.Context) {
+	var req OfflineMergeV2Request
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
 
-	var signPolicy func(policy types.SpendPolicy, signatures *[]types.Signature) error
-	signPolicy = func(policy types.SpendPolicy, signatures *[]types.Signature) error {
-		switch policy := policy.Type.(type) {
-		case types.PolicyTypeThreshold:
-			var signed uint8
-			for _, sub := range policy.Of {
-				if signed == policy.N {
-					break
-				}
+	cs, _, err := a.getConsensusState(jc.Request.Context(), req.State, req.Network)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
 
-				if err := signPolicy(sub, signatures); err != nil {
-					return err
-				}
-				signed++
-			}
-			if signed < policy.N {
-				return fmt.Errorf("policy %q threshold not met %d != %d", policy, signed, policy.N)
-			}
-		case types.PolicyTypePublicKey:
-			sig, err := a.vault.Sign(types.PublicKey(policy), sigHash)
-			if errors.Is(err, vault.ErrNotFound) {
-				return nil
-			} else if err != nil {
-				return fmt.Errorf("failed to sign policy %v: %w", policy, err)
-			}
-			*signatures = append(*signatures, sig)
-		case types.PolicyTypeUnlockConditions:
-			var signed uint64
-			for i := range policy.PublicKeys {
-				if signed == policy.SignaturesRequired {
-					break
-				} else if policy.PublicKeys[i].Algorithm != types.SpecifierEd25519 || len(policy.PublicKeys[i].Key) != ed25519.PublicKeySize {
-					return fmt.Errorf("unsupported public key algorithm %v", policy.PublicKeys[i].Algorithm)
-				}
-				pk := types.PublicKey(policy.PublicKeys[i].Key)
+	txn := req.Transaction
+	signed, err := offline.MergeV2(cs, &txn, req.Response)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	jc.Encode(SignV2Response{Transaction: txn, FullySigned: signed})
+}
 
-				sig, err := a.vault.Sign(pk, sigHash)
-				if errors.Is(err, vault.ErrNotFound) {
-					continue
-				} else if err != nil {
-					return fmt.Errorf("failed to sign policy %v: %w", policy, err)
-				}
-				*signatures = append(*signatures, sig)
-				signed++
-			}
-			if signed < policy.SignaturesRequired {
-				return fmt.Errorf("policy %v required signatures not met %d != %d", policy, signed, policy.SignaturesRequired)
-			}
-		}
-		return nil
+func (a *api) handleGETAddressBook(jc jape.Context) {
+	limit := 100
+	offset := 0
+	if err := jc.DecodeForm("limit", &limit); err != nil {
+		return
+	} else if err := jc.DecodeForm("offset", &offset); err != nil {
+		return
+	} else if limit < 1 || limit > 500 {
+		jc.Error(errors.New("limit must be between 1 and 500"), http.StatusBadRequest)
+		return
+	} else if offset < 0 {
+		jc.Error(errors.New("offset must be non-negative"), http.StatusBadRequest)
+		return
 	}
 
-	signed := true
-	for i := range txn.SiacoinInputs {
-		if err := signPolicy(txn.SiacoinInputs[i].SatisfiedPolicy.Policy, &txn.SiacoinInputs[i].SatisfiedPolicy.Signatures); err != nil {
-			signed = false
-		}
+	entries, err := a.addressBook.AddressBookEntries(limit, offset)
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
 	}
-	for i := range txn.SiafundInputs {
-		if err := signPolicy(txn.SiafundInputs[i].SatisfiedPolicy.Policy, &txn.SiafundInputs[i].SatisfiedPolicy.Signatures); err != nil {
-			signed = false
-		}
+	jc.Encode(entries)
+}
+
+func (a *api) handlePOSTAddressBook(jc jape.Context) {
+	var req AddressBookRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	} else if req.Label == "" {
+		jc.Error(errors.New("label is required"), http.StatusBadRequest)
+		return
 	}
 
-	jc.Encode(SignV2Response{
-		Transaction: txn,
-		FullySigned: signed,
-	})
+	entry, err := a.addressBook.AddAddressBookEntry(req.Address, req.Label, req.Verified)
+	if errors.Is(err, addressbook.ErrExists) {
+		jc.Error(err, http.StatusConflict)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(entry)
 }
 
-func (a *api) handlePOSTBlindSign(jc jape.Context) {
-	var req BlindSignRequest
-	if err := jc.Decode(&req); err != nil {
+func (a *api) handleGETAddressBookID(jc jape.Context) {
+	var id addressbook.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
 		return
 	}
 
-	sig, err := a.vault.Sign(req.PublicKey, req.SigHash)
-	if errors.Is(err, vault.ErrNotFound) {
+	entry, err := a.addressBook.AddressBookEntry(id)
+	if errors.Is(err, addressbook.ErrNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
 	} else if err != nil {
 		jc.Error(err, http.StatusInternalServerError)
 		return
 	}
-	jc.Encode(BlindSignResponse{Signature: sig})
+	jc.Encode(entry)
 }
 
-func (a *api) handlePOSTUnlock(jc jape.Context) {
-	var req UnlockRequest
+func (a *api) handlePUTAddressBookID(jc jape.Context) {
+	var id addressbook.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	var req AddressBookUpdateRequest
 	if err := jc.Decode(&req); err != nil {
 		return
+	} else if req.Label == "" {
+		jc.Error(errors.New("label is required"), http.StatusBadRequest)
+		return
 	}
 
-	switch err := a.vault.Unlock(req.Secret); err {
-	case nil:
-		jc.Encode(nil)
-	case vault.ErrUnlocked:
-		jc.Error(err, http.StatusBadRequest)
-	case vault.ErrIncorrectSecret:
-		jc.Error(err, http.StatusUnauthorized)
-	default:
+	_, err := a.addressBook.UpdateAddressBookEntry(id, req.Label, req.Verified)
+	if errors.Is(err, addressbook.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
 		jc.Error(err, http.StatusInternalServerError)
+		return
 	}
+	jc.Encode(nil)
 }
 
-func (a *api) handlePUTLock(jc jape.Context) {
-	a.vault.Lock()
+func (a *api) handleDELETEAddressBookID(jc jape.Context) {
+	var id addressbook.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+
+	err := a.addressBook.DeleteAddressBookEntry(id)
+	if errors.Is(err, addressbook.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
 	jc.Encode(nil)
 }
 
-// Handler returns an HTTP handler for the vaultd API.
-func Handler(c Chain, v *vault.Vault, log *zap.Logger) http.Handler {
+// Authenticate requires HTTP basic authentication with the password for
+// every request except those authenticated by an import token.
+func Authenticate(password string, h http.Handler) http.Handler {
+	return AuthenticateTokens(password, nil, h)
+}
+
+// newAPI returns an api with the options applied.
+func newAPI(c Chain, v *vault.Vault, log *zap.Logger, opts []ServerOption) *api {
 	a := &api{
 		chain: c,
 		vault: v,
 		log:   log,
+
+		importKeys:           importkey.NewKeyring(importKeyLifetime, maxImportKeys),
+		importTokens:         make(map[types.Hash256]importToken),
+		unlockRequests:       make(map[string]UnlockApproval),
+		maintenanceOverrides: make(map[string]MaintenanceOverride),
+		signedBySource:       make(map[StateSource]uint64),
+		latency: latencyTracker{
+			routes: make(map[string]*routeLatency),
+		},
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
-	return jape.Mux(map[string]jape.Handler{
-		"GET /state": a.handleGETState,
+	return a
+}
 
-		"GET /seeds":           a.handleGETSeeds,
-		"POST /seeds":          a.handlePOSTSeeds,
-		"GET /seeds/:id":       a.handleGETSeedsID,
-		"GET /seeds/:id/keys":  a.handleGETSeedsKeys,
-		"POST /seeds/:id/keys": a.handlePOSTSeedsKeys,
+// routes returns the handlers of the routes enabled by the api's options,
+// keyed by method and path.
+func (a *api) routes() map[string]jape.Handler {
+	routes := map[string]jape.Handler{
+		"GET /state":       a.handleGETState,
+		"GET /state/build": a.handleGETStateBuild,
+		"GET /stats":       a.handleGETStats,
+		"GET /features":    a.handleGETFeatures,
 
-		"POST /unlock": a.handlePOSTUnlock,
-		"PUT /lock":    a.handlePUTLock,
+		"GET /state/wait": a.handleGETStateWait,
 
-		"POST /sign":    a.handlePOSTSign,
-		"POST /v2/sign": a.handlePOSTSignV2,
+		"GET /seeds":                a.handleGETSeeds,
+		"GET /seeds/:id":            a.handleGETSeedsID,
+		"GET /seeds/:id/keys":       a.handleGETSeedsKeys,
+		"GET /seeds/:id/derivation": a.handleGETSeedsDerivation,
 
-		"POST /blind/sign": a.handlePOSTBlindSign,
-	})
+		"GET /keys":                   a.handleGETKeys,
+		"GET /addresses/:address/key": a.handleGETAddressesKey,
+
+		"GET /export/descriptor": a.handleGETExportDescriptor,
+
+		"POST /system/check": a.handlePOSTSystemCheck,
+
+		"POST /unlock":       a.handlePOSTUnlock,
+		"POST /unlock/split": a.handlePOSTUnlockSplit,
+		"POST /unlock/share": a.handlePOSTUnlockShare,
+		"PUT /lock":          a.handlePUTLock,
+
+		"POST /offline/requests":    a.handlePOSTOfflineRequests,
+		"POST /v2/offline/requests": a.handlePOSTOfflineRequestsV2,
+		"POST /offline/merge":       a.handlePOSTOfflineMerge,
+		"POST /v2/offline/merge":    a.handlePOSTOfflineMergeV2,
+	}
+
+	if fe, ok := a.chain.(FeeEstimator); ok {
+		routes["GET /fees"] = func(jc jape.Context) { a.handleGETFees(jc, fe) }
+	}
+
+	if !a.watchOnly {
+		routes["GET /phrases/new"] = a.handleGETPhrasesNew
+		routes["POST /phrases/validate"] = a.handlePOSTPhrasesValidate
+		routes["POST /phrases/generate"] = a.handlePOSTPhrasesGenerate
+		routes["GET /import/key"] = a.handleGETImportKey
+		routes["POST /import/tokens"] = a.handlePOSTImportTokens
+		routes["GET /import/tokens/:token/key"] = a.handleGETImportTokensKey
+		routes["POST /import/tokens/:token/seed"] = a.handlePOSTImportTokensSeed
+		routes["POST /seeds"] = a.handlePOSTSeeds
+		routes["POST /seeds/:id/keys"] = a.handlePOSTSeedsKeys
+		routes["POST /seeds/:id/reserve"] = a.handlePOSTSeedsReserve
+		routes["POST /seeds/:id/keys/register"] = a.handlePOSTSeedsKeysRegister
+		routes["DELETE /seeds/:id"] = a.handleDELETESeedsID
+		routes["POST /seeds/:id/restore"] = a.handlePOSTSeedsRestore
+		routes["PUT /seeds/:id/expiration"] = a.handlePUTSeedsExpiration
+		routes["PUT /keys/:key/expiration"] = a.handlePUTKeysExpiration
+		routes["GET /expirations"] = a.handleGETExpirations
+
+		routes["POST /sign"] = a.handlePOSTSign
+		routes["POST /v2/sign"] = a.handlePOSTSignV2
+		routes["POST /blind/sign"] = a.handlePOSTBlindSign
+		routes["POST /proofs/ownership"] = a.handlePOSTProofsOwnership
+		routes["POST /offline/sign"] = a.handlePOSTOfflineSign
+	}
+
+	if a.custody != nil {
+		routes["GET /custody/commitment"] = a.handleGETCustodyCommitment
+		routes["GET /custody/proofs/:address"] = a.handleGETCustodyProofsAddress
+	}
+	if a.snapshots != nil {
+		routes["GET /snapshots"] = a.handleGETSnapshots
+		routes["POST /snapshots"] = a.handlePOSTSnapshots
+		routes["POST /snapshots/:name/restore"] = a.handlePOSTSnapshotsRestore
+	}
+	if a.addressBook != nil {
+		routes["GET /addressbook"] = a.handleGETAddressBook
+		routes["POST /addressbook"] = a.handlePOSTAddressBook
+		routes["GET /addressbook/:id"] = a.handleGETAddressBookID
+		routes["PUT /addressbook/:id"] = a.handlePUTAddressBookID
+		routes["DELETE /addressbook/:id"] = a.handleDELETEAddressBookID
+	}
+	if a.unlockSecret != nil {
+		routes["POST /unlock/requests"] = a.handlePOSTUnlockRequests
+		routes["GET /unlock/requests/:code"] = a.handleGETUnlockRequestsCode
+		routes["POST /unlock/requests/:code/approve"] = a.handlePOSTUnlockRequestsApprove
+	}
+	if len(a.maintenanceWindows) > 0 {
+		routes["GET /maintenance"] = a.handleGETMaintenance
+		routes["POST /maintenance/overrides"] = a.handlePOSTMaintenanceOverrides
+		routes["GET /maintenance/overrides/:code"] = a.handleGETMaintenanceOverridesCode
+		routes["POST /maintenance/overrides/:code/approve"] = a.handlePOSTMaintenanceOverridesApprove
+	}
+	if a.apiTokens != nil {
+		routes["GET /tokens"] = a.handleGETAPITokens
+		routes["POST /tokens"] = a.handlePOSTAPITokens
+		routes["DELETE /tokens/:id"] = a.handleDELETEAPITokensID
+	}
+
+	if a.webhooks != nil {
+		routes["GET /webhooks"] = a.handleGETWebhooks
+		routes["POST /webhooks"] = a.handlePOSTWebhooks
+		routes["PUT /webhooks/:id"] = a.handlePUTWebhooksID
+		routes["DELETE /webhooks/:id"] = a.handleDELETEWebhooksID
+		routes["POST /webhooks/:id/test"] = a.handlePOSTWebhooksIDTest
+	}
+	return routes
+}
+
+// Handler returns an HTTP handler for the vaultd API.
+func Handler(c Chain, v *vault.Vault, log *zap.Logger, opts ...ServerOption) http.Handler {
+	return NewServer(c, v, log, opts...)
+}
+
+// NewServer returns a Server configured by the options.
+func NewServer(c Chain, v *vault.Vault, log *zap.Logger, opts ...ServerOption) *Server {
+	a := newAPI(c, v, log, opts)
+	return &Server{a: a, h: a.handler()}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.h.ServeHTTP(w, r)
+}
+
+// SignRequest implements offline.RequestSigner. It signs requests that do
+// not go through the HTTP API, such as those received over the serial
+// transport. The request is refused during maintenance windows unless an
+// override has been approved through the HTTP API, its nonce is checked if
+// replay protection is enabled, and the sign policies apply without a
+// source address or credentials.
+func (s *Server) SignRequest(req offline.SignRequest) (offline.SignResponse, error) {
+	a := s.a
+	if active, endsAt, overrideExpiresAt := a.maintenance(time.Now()); active && overrideExpiresAt.IsZero() {
+		return offline.SignResponse{}, maintenanceError(endsAt)
+	} else if _, err := a.useNonce(context.Background(), req.Nonce, req.Timestamp); err != nil {
+		return offline.SignResponse{}, err
+	}
+	resp, err := offline.Sign(localSigner{a}, req)
+	if err != nil {
+		return offline.SignResponse{}, err
+	}
+	a.afterOfflineSign(context.Background(), "serial", req, resp)
+	return resp, nil
+}
+
+// handler returns the HTTP handler of the api's routes.
+func (a *api) handler() http.Handler {
+	routes := a.routes()
+	if len(a.maintenanceWindows) > 0 {
+		for route, h := range routes {
+			if refusesDuringMaintenance(route) {
+				routes[route] = a.checkMaintenance(route, h)
+			}
+		}
+	}
+	for _, route := range compressedRoutes {
+		if h, ok := routes[route]; ok {
+			routes[route] = compress(h)
+		}
+	}
+	if a.authorizer != nil {
+		for route, h := range routes {
+			routes[route] = a.authorize(route, h)
+		}
+	}
+	for route, h := range routes {
+		routes[route] = a.requireScope(route, h)
+	}
+	for route, h := range routes {
+		routes[route] = a.trackLatency(route, h)
+	}
+	return a.requestIDMiddleware(a.versionMiddleware(jape.Mux(routes)))
 }
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/webhooks"
+	"go.uber.org/zap"
+)
+
+type (
+	// A SignEvent summarizes a successful sign request.
+	SignEvent struct {
+		// Route is the route of the request, for example "POST /sign",
+		// or "serial" for requests over the serial transport.
+		Route     string `json:"route"`
+		RequestID string `json:"requestID,omitempty"`
+		// TransactionID, SiacoinOutputs, and MinerFee are set for
+		// transaction signing requests.
+		TransactionID  types.TransactionID   `json:"transactionID,omitzero"`
+		SiacoinOutputs []types.SiacoinOutput `json:"siacoinOutputs,omitempty"`
+		MinerFee       types.Currency        `json:"minerFee,omitzero"`
+		// PublicKey and SigHash are set for blind signing requests.
+		PublicKey types.PublicKey `json:"publicKey,omitzero"`
+		SigHash   types.Hash256   `json:"sigHash,omitzero"`
+		// FullySigned is true for offline sign requests if every
+		// requested signature was produced.
+		FullySigned bool              `json:"fullySigned"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		Timestamp   time.Time         `json:"timestamp"`
+	}
+
+	// A SignHook is called in the background after each successful sign
+	// request so embedders can trigger downstream automation. Errors are
+	// logged and do not affect the response.
+	SignHook interface {
+		AfterSign(context.Context, SignEvent) error
+	}
+)
+
+// WithSignHook calls the hook after each successful sign request.
+func WithSignHook(h SignHook) ServerOption {
+	return func(a *api) {
+		a.signHook = h
+	}
+}
+
+// afterSign calls the sign hook and broadcasts the event to webhooks in the
+// background.
+func (a *api) afterSign(ctx context.Context, e SignEvent) {
+	e.RequestID = RequestID(ctx)
+	e.Timestamp = time.Now()
+	a.broadcast(webhooks.EventSigned, webhooks.ScopeSigning, e)
+	if a.signHook == nil {
+		return
+	}
+	// the hook outlives the request
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := a.signHook.AfterSign(ctx, e); err != nil {
+			a.requestLog(ctx).Warn("sign hook failed", zap.String("route", e.Route), zap.Error(err))
+		}
+	}()
+}
+
+// afterOfflineSign calls afterSign for an offline sign request that
+// produced at least one signature.
+func (a *api) afterOfflineSign(ctx context.Context, route string, req offline.SignRequest, resp offline.SignResponse) {
+	if len(resp.Signatures) == 0 {
+		return
+	}
+	a.requestLog(ctx).Info("signed offline request", a.redactedField("transactionID", req.TransactionID), zap.String("route", route), zap.Int("signatures", len(resp.Signatures)))
+	a.afterSign(ctx, SignEvent{
+		Route:         route,
+		TransactionID: req.TransactionID,
+		FullySigned:   len(resp.Signatures) == len(req.SigHashes),
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpClient is a JSON HTTP client for the vaultd API. It behaves like
+// jape.Client but declares the client's API version and a request ID on
+// every request.
+type httpClient struct {
+	baseURL  string
+	password string
+	client   *http.Client
+	// timeout limits the duration of each request, including reading
+	// the response. Zero relies on the context alone.
+	timeout time.Duration
+}
+
+// req performs a request. wait is added to the client's timeout for
+// requests the server holds open, such as long polls.
+func (c *httpClient) req(ctx context.Context, method string, route string, data, resp any, wait time.Duration) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout+wait)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if data != nil {
+		js, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(js)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+route, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(APIVersionHeader, strconv.Itoa(APIVersion))
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	} else {
+		req.Header.Set(RequestIDHeader, newRequestID())
+	}
+	if c.password != "" {
+		req.SetBasicAuth("", c.password)
+	}
+
+	r, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer io.Copy(io.Discard, r.Body)
+	defer r.Body.Close()
+
+	switch {
+	case r.StatusCode == http.StatusUpgradeRequired:
+		var ur UnsupportedVersionResponse
+		if err := json.NewDecoder(r.Body).Decode(&ur); err != nil {
+			return ErrUnsupportedVersion
+		}
+		return fmt.Errorf("%w: client version %d is below the minimum version %d", ErrUnsupportedVersion, ur.ClientVersion, ur.MinClientVersion)
+	case r.StatusCode < 200 || r.StatusCode >= 300:
+		msg, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read error response: %w", err)
+		}
+		return errors.New(strings.TrimSpace(string(msg)))
+	case resp == nil:
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+		// a body read interrupted by the context is reported as a
+		// malformed response
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("failed to read response: %w", ctxErr)
+		}
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// GET performs a GET request, decoding the response into r.
+func (c *httpClient) GET(ctx context.Context, route string, r any) error {
+	return c.req(ctx, http.MethodGet, route, nil, r, 0)
+}
+
+// longPoll performs a GET request that the server may hold open for up to
+// wait before responding, decoding the response into r.
+func (c *httpClient) longPoll(ctx context.Context, route string, wait time.Duration, r any) error {
+	return c.req(ctx, http.MethodGet, route, nil, r, wait)
+}
+
+// POST performs a POST request. If d is non-nil, it is encoded as the
+// request body. If r is non-nil, the response is decoded into it.
+func (c *httpClient) POST(ctx context.Context, route string, d, r any) error {
+	return c.req(ctx, http.MethodPost, route, d, r, 0)
+}
+
+// PUT performs a PUT request, encoding d as the request body.
+func (c *httpClient) PUT(ctx context.Context, route string, d any) error {
+	return c.req(ctx, http.MethodPut, route, d, nil, 0)
+}
+
+// DELETE performs a DELETE request.
+func (c *httpClient) DELETE(ctx context.Context, route string) error {
+	return c.req(ctx, http.MethodDelete, route, nil, nil, 0)
+}
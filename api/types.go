@@ -1,14 +1,106 @@
 package api
 
 import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
 	"time"
 
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/apitoken"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/importkey"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/snapshot"
 	"go.sia.tech/vaultd/vault"
+	"lukechampine.com/frand"
+)
+
+// DerivationSchemeSia is the derivation scheme used for every seed. Key i
+// is the ed25519 key with the seed blake2b(seed || uint64le(i)).
+const DerivationSchemeSia = "sia"
+
+// descriptorVersion is the version of the wallet descriptor format.
+const descriptorVersion = 1
+
+// ownershipProofPrefix domain-separates ownership challenges from
+// transaction sighashes.
+const ownershipProofPrefix = "vaultd ownership proof\n"
+
+// Spend policy types used to compute key addresses.
+const (
+	// PolicyTypeUnlockConditions is the v1 standard unlock conditions
+	// policy. Its address is the same as the v1 standard address.
+	PolicyTypeUnlockConditions PolicyType = "unlockConditions"
+	// PolicyTypePublicKey is the v2 standard public key policy used by
+	// v2-native wallets. Its address differs from the v1 address.
+	PolicyTypePublicKey PolicyType = "publicKey"
+)
+
+// Sources of the consensus state used to compute sighashes.
+const (
+	// StateSourceExplorer is the tip state reported by the explorer.
+	StateSourceExplorer StateSource = "explorer"
+	// StateSourceRequest is a state provided by the caller.
+	StateSourceRequest StateSource = "request"
+)
+
+// Types of recovery phrases.
+const (
+	// PhraseTypeBIP39 is a 12, 15, 18, 21, or 24 word BIP39 phrase.
+	PhraseTypeBIP39 PhraseType = "bip39"
+	// PhraseTypeSiad is a 28 or 29 word siad phrase.
+	PhraseTypeSiad PhraseType = "siad"
+)
+
+// Reasons a v1 signature was skipped.
+const (
+	// SkipReasonUnknownParent indicates that no input spends the
+	// signature's parent ID.
+	SkipReasonUnknownParent SkipReason = "unknownParent"
+	// SkipReasonInvalidKey indicates that the signature's public key index
+	// is out of range or the key is malformed.
+	SkipReasonInvalidKey SkipReason = "invalidKey"
+	// SkipReasonUnsupportedAlgorithm indicates that the unlock key uses an
+	// algorithm other than ed25519, such as entropy.
+	SkipReasonUnsupportedAlgorithm SkipReason = "unsupportedAlgorithm"
+	// SkipReasonNotFound indicates that the key is not held by the vault.
+	SkipReasonNotFound SkipReason = "notFound"
+	// SkipReasonExpired indicates that the key or its seed has expired.
+	SkipReasonExpired SkipReason = "expired"
+	// SkipReasonPolicy indicates that the request does not meet the sign
+	// policies of the key's seed.
+	SkipReasonPolicy SkipReason = "policy"
 )
 
 type (
+	// A StateSource is the source of the consensus state used to
+	// compute sighashes.
+	StateSource string
+
+	// A PolicyType selects the spend policy used to compute key addresses.
+	PolicyType string
+
+	// A SkipReason explains why a signature was not added.
+	SkipReason string
+
+	// A PhraseType is the type of a recovery phrase.
+	PhraseType string
+
+	// A StateWaitResponse is the lock state and tip returned by
+	// [GET] /state/wait.
+	StateWaitResponse struct {
+		// ID identifies the state. It is opaque and should be passed
+		// as the since parameter of the next request.
+		ID       string           `json:"id"`
+		Unlocked bool             `json:"unlocked"`
+		Tip      types.ChainIndex `json:"tip"`
+		// Changed is true if the state differs from the since
+		// parameter. It is false if the request timed out.
+		Changed bool `json:"changed"`
+	}
+
 	// A StateResponse returns information about the current state of the walletd
 	// daemon.
 	StateResponse struct {
@@ -17,11 +109,100 @@ type (
 		OS        string    `json:"os"`
 		BuildTime time.Time `json:"buildTime"`
 		StartTime time.Time `json:"startTime"`
+		Unlocked  bool      `json:"unlocked"`
+
+		APIVersions      []int `json:"apiVersions"`
+		MinClientVersion int   `json:"minClientVersion"`
+
+		// ExplorerDivergence is set when the explorers used to
+		// cross-check the tip state disagree.
+		ExplorerDivergence string `json:"explorerDivergence,omitempty"`
+		// ReadOnly is the reason the vault refuses to sign or derive
+		// keys after repeated seed decryption failures.
+		ReadOnly string `json:"readOnly,omitempty"`
+	}
+
+	// A BuildResponse describes how the server was built, to diagnose
+	// encoding differences between vaultd and its callers.
+	BuildResponse struct {
+		Version   string    `json:"version"`
+		Commit    string    `json:"commit"`
+		BuildTime time.Time `json:"buildTime"`
+		GoVersion string    `json:"goVersion"`
+		OS        string    `json:"os"`
+		Arch      string    `json:"arch"`
+		// Dependencies are the versions of the go.sia.tech modules
+		// compiled into the server, keyed by module path.
+		Dependencies map[string]string `json:"dependencies"`
+		// Tags are the build tags the server was built with.
+		Tags []string `json:"tags"`
+	}
+
+	// A FeaturesResponse reports which optional subsystems are enabled, so
+	// clients can avoid calling the routes of disabled ones.
+	FeaturesResponse struct {
+		// Signing is false in watch-only mode, which disables signing,
+		// blind signing, ownership proofs, and adding seeds.
+		Signing            bool `json:"signing"`
+		Fees               bool `json:"fees"`
+		AddressBook        bool `json:"addressBook"`
+		Custody            bool `json:"custody"`
+		Snapshots          bool `json:"snapshots"`
+		UnlockApproval     bool `json:"unlockApproval"`
+		MaintenanceWindows bool `json:"maintenanceWindows"`
+		APITokens          bool `json:"apiTokens"`
+		Webhooks           bool `json:"webhooks"`
+		// SignPolicies is true if sign requests can be denied by a
+		// policy.
+		SignPolicies bool `json:"signPolicies"`
+		// ReplayProtection is true if sign requests must include a
+		// nonce and timestamp.
+		ReplayProtection bool `json:"replayProtection"`
+	}
+
+	// An UnsupportedVersionResponse is returned with a 426 status code when
+	// the client's API version is below the server's minimum.
+	UnsupportedVersionResponse struct {
+		Error             string `json:"error"`
+		ClientVersion     int    `json:"clientVersion"`
+		MinClientVersion  int    `json:"minClientVersion"`
+		SupportedVersions []int  `json:"supportedVersions"`
 	}
 
-	// An AddSeedRequest is a request to add a seed to the vault.
+	// An AddSeedRequest is a request to add a seed to the vault. The
+	// phrase is either sent in plaintext or encrypted to an import key.
 	AddSeedRequest struct {
-		Phrase string `json:"phrase"`
+		Phrase          string              `json:"phrase,omitempty"`
+		EncryptedPhrase *importkey.Envelope `json:"encryptedPhrase,omitempty"`
+	}
+
+	// A FeesResponse is the recommended transaction fee. FeePerByte is
+	// the explorer's recommended fee clamped to the server's bounds.
+	FeesResponse struct {
+		Recommended types.Currency `json:"recommended"`
+		FeePerByte  types.Currency `json:"feePerByte"`
+		Min         types.Currency `json:"min"`
+		Max         types.Currency `json:"max"`
+	}
+
+	// An ImportTokenRequest is a request to mint an import token. Zero
+	// Lifetime uses the default of 24 hours.
+	ImportTokenRequest struct {
+		Lifetime time.Duration `json:"lifetime,omitempty"`
+	}
+
+	// An ImportTokenResponse is a single-use token that lets a party
+	// without the API password submit one encrypted seed phrase.
+	ImportTokenResponse struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+
+	// An ImportKeyResponse is an ephemeral key that a seed phrase can be
+	// encrypted to. The key can be used once before it expires.
+	ImportKeyResponse struct {
+		PublicKey importkey.PublicKey `json:"publicKey"`
+		ExpiresAt time.Time           `json:"expiresAt"`
 	}
 
 	// SeedsResponse is a response to a seeds request.
@@ -33,7 +214,93 @@ type (
 	SeedResponse struct {
 		ID        vault.SeedID `json:"id"`
 		LastIndex uint64       `json:"lastIndex"`
-		CreatedAt time.Time    `json:"createdAt"`
+		// Language is the language of the phrase the seed was imported
+		// from. It is empty if unknown.
+		Language string `json:"language,omitempty"`
+		// ExpiresAt is the time after which the seed's keys cannot sign.
+		// It is omitted if the seed does not expire.
+		ExpiresAt time.Time `json:"expiresAt,omitzero"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	// An ExpirationRequest sets the time after which a seed or key cannot
+	// sign. A zero or null ExpiresAt removes the expiration.
+	ExpirationRequest struct {
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+
+	// An Expiration is the time after which a seed's keys, or a single
+	// key, cannot sign. PublicKey is omitted for a seed's expiration.
+	Expiration struct {
+		SeedID    vault.SeedID    `json:"seedID"`
+		PublicKey types.PublicKey `json:"publicKey,omitzero"`
+		ExpiresAt time.Time       `json:"expiresAt"`
+		Expired   bool            `json:"expired"`
+	}
+
+	// A SeedImportPreview describes the effect of importing a seed
+	// without importing it. Seed is the matching existing seed and is
+	// nil if the import would create a new seed. PublicKey and Address
+	// are derived from the first index of the seed so the phrase can be
+	// verified.
+	SeedImportPreview struct {
+		Action    vault.ImportAction `json:"action"`
+		Seed      *SeedResponse      `json:"seed,omitempty"`
+		PublicKey types.PublicKey    `json:"publicKey"`
+		Address   types.Address      `json:"address"`
+	}
+
+	// A WebhookRequest registers or updates a webhook.
+	WebhookRequest struct {
+		CallbackURL string   `json:"callbackURL"`
+		Scopes      []string `json:"scopes"`
+	}
+
+	// A PhraseResponse is a newly generated recovery phrase. The phrase
+	// is not stored.
+	PhraseResponse struct {
+		Phrase   string `json:"phrase"`
+		Language string `json:"language"`
+	}
+
+	// A SeedGenerateRequest is a request to generate a new seed inside
+	// the vault. The vault stores the seed, not its phrase, so the phrase
+	// is only returned once; Confirm must be true to acknowledge this.
+	SeedGenerateRequest struct {
+		// Language is the language of the phrase. Empty is English.
+		Language string `json:"language,omitempty"`
+		Confirm  bool   `json:"confirm"`
+	}
+
+	// A SeedGenerateResponse is a seed generated inside the vault and the
+	// phrase it was derived from. The phrase is not stored.
+	SeedGenerateResponse struct {
+		Seed   SeedResponse `json:"seed"`
+		Phrase string       `json:"phrase"`
+	}
+
+	// A PhraseValidateRequest is a recovery phrase to validate.
+	PhraseValidateRequest struct {
+		Phrase string `json:"phrase"`
+	}
+
+	// A PhraseValidateResponse describes a recovery phrase. Fingerprint
+	// and Address are derived from the first index of the seed and are
+	// only set if the phrase is valid.
+	PhraseValidateResponse struct {
+		Valid bool `json:"valid"`
+		// Type is the detected type of the phrase. It is empty if the
+		// word count does not match a supported type.
+		Type      PhraseType `json:"type,omitempty"`
+		WordCount int        `json:"wordCount"`
+		// UnknownWords are the zero-based positions of the words that
+		// are not in the type's word list.
+		UnknownWords []int `json:"unknownWords,omitempty"`
+		// Language is the detected language of a valid phrase.
+		Language    string        `json:"language,omitempty"`
+		Error       string        `json:"error,omitempty"`
+		Fingerprint string        `json:"fingerprint,omitempty"`
+		Address     types.Address `json:"address,omitzero"`
 	}
 
 	// SeedKey is a public key and its associated standard address.
@@ -41,11 +308,204 @@ type (
 		PublicKey   types.PublicKey   `json:"publicKey"`
 		Address     types.Address     `json:"address"`
 		SpendPolicy types.SpendPolicy `json:"spendPolicy"`
+		// V1Address is the v1 standard unlock hash of the key.
+		V1Address types.Address `json:"v1Address"`
+		// V2Address is the address of the key's v2 public key policy.
+		V2Address types.Address `json:"v2Address"`
+	}
+
+	// LatencyStats summarizes the latency of requests to a route. The
+	// percentiles are calculated from the most recent requests.
+	LatencyStats struct {
+		Count uint64        `json:"count"`
+		P50   time.Duration `json:"p50"`
+		P90   time.Duration `json:"p90"`
+		P99   time.Duration `json:"p99"`
+		Max   time.Duration `json:"max"`
+	}
+
+	// SLOStats reports the burn of the signing latency objective since
+	// the server started.
+	SLOStats struct {
+		Latency  time.Duration `json:"latency"`
+		Target   float64       `json:"target"`
+		Requests uint64        `json:"requests"`
+		// Slow is the number of requests slower than Latency.
+		Slow uint64 `json:"slow"`
+		// BurnRate is the rate at which the error budget is consumed.
+		// Above 1, slow requests occur more often than Target allows.
+		BurnRate float64 `json:"burnRate"`
+	}
+
+	// A StatsResponse contains counters since the server started.
+	StatsResponse struct {
+		// SignedTransactions is the number of transactions signed with
+		// consensus state from each source.
+		SignedTransactions map[StateSource]uint64 `json:"signedTransactions"`
+		// Latency is the latency of each route, keyed by method and path.
+		Latency map[string]LatencyStats `json:"latency"`
+		// SigningSLO is set if a signing latency objective is configured.
+		SigningSLO *SLOStats `json:"signingSLO,omitempty"`
+	}
+
+	// A SystemCheckResponse is the result of a consistency check of the
+	// vault's store.
+	SystemCheckResponse struct {
+		Issues []vault.ConsistencyIssue `json:"issues"`
+	}
+
+	// An AddressKeyResponse is the key associated with an address.
+	AddressKeyResponse struct {
+		SeedID vault.SeedID `json:"seedID"`
+		Index  uint64       `json:"index"`
+		Key    SeedKey      `json:"key"`
+	}
+
+	// An OwnershipProofRequest is a request to sign a challenge with a set
+	// of vault keys.
+	OwnershipProofRequest struct {
+		Challenge string `json:"challenge"`
+		// PublicKeys selects the keys to sign with. If empty, every key
+		// in the vault is selected and Offset and Limit page through
+		// them.
+		PublicKeys []types.PublicKey `json:"publicKeys,omitempty"`
+		Offset     int               `json:"offset,omitempty"`
+		Limit      int               `json:"limit,omitempty"`
+	}
+
+	// An OwnershipProof is a signature of an ownership challenge by a key.
+	// It can be verified with [OwnershipChallengeHash].
+	OwnershipProof struct {
+		PublicKey types.PublicKey `json:"publicKey"`
+		V1Address types.Address   `json:"v1Address"`
+		V2Address types.Address   `json:"v2Address"`
+		Signature types.Signature `json:"signature"`
+	}
+
+	// An OwnershipProofResponse is a batch of ownership proofs.
+	OwnershipProofResponse struct {
+		Challenge string `json:"challenge"`
+		// SigHash is the hash of the challenge signed by every key.
+		SigHash types.Hash256    `json:"sigHash"`
+		Proofs  []OwnershipProof `json:"proofs"`
+		// Total is the number of selected keys. Callers paging through
+		// every key can report progress as Offset+len(Proofs) of Total.
+		Total int `json:"total"`
+	}
+
+	// A CustodyProofResponse proves that an address's key is included in
+	// the custody commitment. The address is either V1Address or
+	// V2Address.
+	CustodyProofResponse struct {
+		Commitment custody.Commitment `json:"commitment"`
+		Proof      custody.Proof      `json:"proof"`
+		V1Address  types.Address      `json:"v1Address"`
+		V2Address  types.Address      `json:"v2Address"`
+	}
+
+	// A SnapshotRequest is a request to create a snapshot.
+	SnapshotRequest struct {
+		Name string `json:"name"`
+	}
+
+	// A SnapshotRestoreResponse is the response to restoring a snapshot.
+	// Backup is the snapshot of the state before the restore.
+	SnapshotRestoreResponse struct {
+		Backup snapshot.Snapshot `json:"backup"`
+	}
+
+	// A VaultKey is a key of any seed in the vault.
+	VaultKey struct {
+		SeedKey
+		SeedID    vault.SeedID `json:"seedID"`
+		Index     uint64       `json:"index"`
+		CreatedAt time.Time    `json:"createdAt"`
+	}
+
+	// KeysResponse is a response to a request for the keys of every seed.
+	KeysResponse struct {
+		Keys  []VaultKey `json:"keys"`
+		Total int        `json:"total"`
 	}
 
 	// SeedKeysResponse is a response to a seed keys request.
 	SeedKeysResponse struct {
 		Keys []SeedKey `json:"keys"`
+		// Total is the number of keys derived from the seed. It is only
+		// set when listing the seed's keys.
+		Total int `json:"total,omitempty"`
+	}
+
+	// A SeedDescriptor describes the keys derived from a seed without
+	// any secret material.
+	SeedDescriptor struct {
+		ID vault.SeedID `json:"id"`
+		// Fingerprint identifies the seed. It is derived from the public
+		// key at index 0 and is empty if that key has not been derived
+		// and the vault is locked.
+		Fingerprint      string `json:"fingerprint"`
+		DerivationScheme string `json:"derivationScheme"`
+		// DerivedCount is the seed's next index. Every lower index has
+		// been derived or reserved, so a wallet scanning the seed should
+		// cover it even if some keys are missing from Keys.
+		DerivedCount uint64    `json:"derivedCount"`
+		Keys         []SeedKey `json:"keys"`
+	}
+
+	// A WalletDescriptor contains every derived key and spend policy in
+	// the vault. It is sufficient for a watch-only wallet to track
+	// balances.
+	WalletDescriptor struct {
+		Version int              `json:"version"`
+		Seeds   []SeedDescriptor `json:"seeds"`
+	}
+
+	// A PhraseEncoding describes a recovery phrase format that can be
+	// imported as a seed.
+	PhraseEncoding struct {
+		Name  string `json:"name"`
+		Words []int  `json:"words"`
+		// Seed describes how the 32-byte seed is computed from the
+		// phrase.
+		Seed string `json:"seed"`
+	}
+
+	// A DerivationVector is a key derived from a seed that can be used to
+	// verify an independent implementation of the derivation.
+	DerivationVector struct {
+		Index     uint64          `json:"index"`
+		PublicKey types.PublicKey `json:"publicKey"`
+		V1Address types.Address   `json:"v1Address"`
+		V2Address types.Address   `json:"v2Address"`
+	}
+
+	// A SeedDerivationResponse describes how the keys of a seed are
+	// derived so they can be recovered without vaultd.
+	SeedDerivationResponse struct {
+		SeedID vault.SeedID `json:"seedID"`
+		Scheme string       `json:"scheme"`
+		// Phrases lists the phrase formats that produce a seed. vaultd
+		// does not record which format a seed was imported from.
+		Phrases []PhraseEncoding `json:"phrases"`
+		// Key describes how the private key at an index is derived from
+		// the seed.
+		Key string `json:"key"`
+		// Hardened is true because every key is derived from the seed
+		// directly. Public keys cannot be derived from other public
+		// keys.
+		Hardened bool `json:"hardened"`
+		// IndexEncoding is the encoding of the index in the key
+		// derivation.
+		IndexEncoding string `json:"indexEncoding"`
+		// FirstIndex is the index of the first key of every seed.
+		FirstIndex uint64 `json:"firstIndex"`
+		// NextIndex is the next index vaultd will derive. Keys below it
+		// may have been shared and should be scanned when recovering.
+		NextIndex uint64 `json:"nextIndex"`
+		V1Address string `json:"v1Address"`
+		V2Address string `json:"v2Address"`
+		// Vectors contains the first key derived from the seed, if any.
+		Vectors []DerivationVector `json:"vectors"`
 	}
 
 	// SeedDeriveRequest is a request to derive a set of keys from a seed.
@@ -53,17 +513,55 @@ type (
 		Count uint64 `json:"count"`
 	}
 
+	// SeedReserveResponse is the range of indices reserved for keys
+	// derived outside of the vault. End is exclusive.
+	SeedReserveResponse struct {
+		Start uint64 `json:"start"`
+		End   uint64 `json:"end"`
+	}
+
+	// A RegisterKey is a key derived outside of the vault from a
+	// reserved index.
+	RegisterKey struct {
+		Index     uint64          `json:"index"`
+		PublicKey types.PublicKey `json:"publicKey"`
+	}
+
+	// SeedRegisterRequest is a request to register keys derived outside
+	// of the vault.
+	SeedRegisterRequest struct {
+		Keys []RegisterKey `json:"keys"`
+	}
+
 	// SignRequest is a request to sign a transaction.
 	SignRequest struct {
 		State       *consensus.State   `json:"state"`
 		Network     *consensus.Network `json:"network"`
 		Transaction types.Transaction  `json:"transaction"`
+		// Metadata describes why the transaction is being signed. It is
+		// logged and echoed in the response.
+		Metadata map[string]string `json:"metadata,omitempty"`
+		// Nonce and Timestamp are required if the server has replay
+		// protection enabled. A nonce can only be used once.
+		Nonce     string    `json:"nonce,omitempty"`
+		Timestamp time.Time `json:"timestamp,omitzero"`
+	}
+
+	// A SkippedSignature describes a signature in a v1 transaction that
+	// the vault did not add.
+	SkippedSignature struct {
+		// Index is the index of the signature in the transaction.
+		Index   int        `json:"index"`
+		Reason  SkipReason `json:"reason"`
+		Message string     `json:"message"`
 	}
 
 	// SignResponse is a response to a sign request.
 	SignResponse struct {
-		Transaction types.Transaction `json:"transaction"`
-		FullySigned bool              `json:"fullySigned"`
+		Transaction types.Transaction  `json:"transaction"`
+		FullySigned bool               `json:"fullySigned"`
+		Skipped     []SkippedSignature `json:"skipped,omitempty"`
+		Metadata    map[string]string  `json:"metadata,omitempty"`
 	}
 
 	// SignV2Request is a request to sign a v2 transaction.
@@ -71,12 +569,20 @@ type (
 		State       *consensus.State    `json:"state"`
 		Network     *consensus.Network  `json:"network"`
 		Transaction types.V2Transaction `json:"transaction"`
+		// Metadata describes why the transaction is being signed. It is
+		// logged and echoed in the response.
+		Metadata map[string]string `json:"metadata,omitempty"`
+		// Nonce and Timestamp are required if the server has replay
+		// protection enabled. A nonce can only be used once.
+		Nonce     string    `json:"nonce,omitempty"`
+		Timestamp time.Time `json:"timestamp,omitzero"`
 	}
 
 	// SignV2Response is a response to a sign v2 request.
 	SignV2Response struct {
 		Transaction types.V2Transaction `json:"transaction"`
 		FullySigned bool                `json:"fullySigned"`
+		Metadata    map[string]string   `json:"metadata,omitempty"`
 	}
 
 	// An UnlockRequest is a request to unlock the vault.
@@ -89,14 +595,181 @@ type (
 	BlindSignRequest struct {
 		PublicKey types.PublicKey `json:"publicKey"`
 		SigHash   types.Hash256   `json:"sigHash"`
+		// Metadata describes why the hash is being signed. It is logged
+		// and echoed in the response.
+		Metadata map[string]string `json:"metadata,omitempty"`
+		// Nonce and Timestamp are required if the server has replay
+		// protection enabled. A nonce can only be used once.
+		Nonce     string    `json:"nonce,omitempty"`
+		Timestamp time.Time `json:"timestamp,omitzero"`
 	}
 
 	// A BlindSignResponse is a response to a blind sign request.
 	BlindSignResponse struct {
-		Signature types.Signature `json:"signature"`
+		Signature types.Signature   `json:"signature"`
+		Metadata  map[string]string `json:"metadata,omitempty"`
+	}
+
+	// An OfflineRequest is a request to export the sighashes of a
+	// transaction for an offline signer.
+	OfflineRequest struct {
+		State       *consensus.State   `json:"state"`
+		Network     *consensus.Network `json:"network"`
+		Transaction types.Transaction  `json:"transaction"`
+	}
+
+	// An OfflineV2Request is a request to export the sighashes of a v2
+	// transaction for an offline signer.
+	OfflineV2Request struct {
+		State       *consensus.State    `json:"state"`
+		Network     *consensus.Network  `json:"network"`
+		Transaction types.V2Transaction `json:"transaction"`
+	}
+
+	// An OfflineMergeRequest is a request to add the signatures from an
+	// offline signer to a transaction.
+	OfflineMergeRequest struct {
+		State       *consensus.State     `json:"state"`
+		Network     *consensus.Network   `json:"network"`
+		Transaction types.Transaction    `json:"transaction"`
+		Response    offline.SignResponse `json:"response"`
+	}
+
+	// An OfflineMergeV2Request is a request to add the signatures from an
+	// offline signer to a v2 transaction.
+	OfflineMergeV2Request struct {
+		State       *consensus.State     `json:"state"`
+		Network     *consensus.Network   `json:"network"`
+		Transaction types.V2Transaction  `json:"transaction"`
+		Response    offline.SignResponse `json:"response"`
+	}
+
+	// An AddressBookRequest is a request to add an address to the
+	// address book.
+	AddressBookRequest struct {
+		Address  types.Address `json:"address"`
+		Label    string        `json:"label"`
+		Verified bool          `json:"verified"`
+	}
+
+	// An AddressBookUpdateRequest is a request to update the label and
+	// verification status of an address book entry.
+	AddressBookUpdateRequest struct {
+		Label    string `json:"label"`
+		Verified bool   `json:"verified"`
+	}
+
+	// An UnlockSplitRequest initializes the vault with a random secret
+	// split into Shares shares, any Threshold of which unlock the vault.
+	UnlockSplitRequest struct {
+		Threshold int `json:"threshold"`
+		Shares    int `json:"shares"`
+	}
+
+	// An UnlockSplitResponse contains the shares of the vault secret.
+	// The shares are not stored and cannot be retrieved again.
+	UnlockSplitResponse struct {
+		Threshold int      `json:"threshold"`
+		Shares    []string `json:"shares"`
+	}
+
+	// An UnlockShareRequest submits one share of the vault secret.
+	UnlockShareRequest struct {
+		Share string `json:"share"`
+	}
+
+	// An UnlockShareResponse reports the progress of unlocking the vault
+	// with shares. Received is the number of distinct shares submitted.
+	UnlockShareResponse struct {
+		Received  int  `json:"received"`
+		Threshold int  `json:"threshold"`
+		Unlocked  bool `json:"unlocked"`
+	}
+
+	// An UnlockApproval is a request to unlock the vault with the secret
+	// from the configured secret manager. The code is shown to the
+	// administrator approving the request.
+	UnlockApproval struct {
+		Code      string    `json:"code"`
+		ExpiresAt time.Time `json:"expiresAt"`
+		Approved  bool      `json:"approved"`
+	}
+
+	// A MaintenanceResponse is the maintenance state of the vault.
+	MaintenanceResponse struct {
+		// Active is true if a maintenance window is in progress.
+		Active bool      `json:"active"`
+		EndsAt time.Time `json:"endsAt,omitzero"`
+		// OverrideExpiresAt is when the approved emergency override stops
+		// allowing signing during maintenance windows.
+		OverrideExpiresAt time.Time `json:"overrideExpiresAt,omitzero"`
+	}
+
+	// A MaintenanceOverrideRequest is a request for an emergency override
+	// of the maintenance windows.
+	MaintenanceOverrideRequest struct {
+		Reason string `json:"reason"`
+	}
+
+	// A MaintenanceOverride is a request to allow signing during
+	// maintenance windows. The code is shown to the administrator
+	// approving the request. RequestedBy lists the credentials of the
+	// request, which cannot approve it.
+	MaintenanceOverride struct {
+		Code        string    `json:"code"`
+		Reason      string    `json:"reason"`
+		RequestedBy []string  `json:"requestedBy,omitempty"`
+		ExpiresAt   time.Time `json:"expiresAt"`
+		Approved    bool      `json:"approved"`
+	}
+
+	// An APITokenRequest is a request to create an API token.
+	APITokenRequest struct {
+		Name   string           `json:"name"`
+		Scopes []apitoken.Scope `json:"scopes"`
+	}
+
+	// An APITokenResponse is a newly created API token. The secret is
+	// used as the basic auth password and cannot be retrieved again.
+	APITokenResponse struct {
+		apitoken.Token
+		Secret string `json:"secret"`
 	}
 )
 
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (pt *PolicyType) UnmarshalText(b []byte) error {
+	switch v := PolicyType(b); v {
+	case PolicyTypeUnlockConditions, PolicyTypePublicKey:
+		*pt = v
+		return nil
+	default:
+		return fmt.Errorf("unknown policy type %q", v)
+	}
+}
+
+// SpendPolicy returns the spend policy of the given type for pk.
+func (pt PolicyType) SpendPolicy(pk types.PublicKey) types.SpendPolicy {
+	if pt == PolicyTypePublicKey {
+		return types.PolicyPublicKey(pk)
+	}
+	return types.SpendPolicy{
+		Type: types.PolicyTypeUnlockConditions(types.StandardUnlockConditions(pk)),
+	}
+}
+
+// A KeysOption is a functional option for key listing and derivation
+// requests.
+type KeysOption func(url.Values)
+
+// KeysWithPolicyType sets the spend policy used to compute the addresses
+// of the returned keys.
+func KeysWithPolicyType(pt PolicyType) KeysOption {
+	return func(v url.Values) {
+		v.Set("policyType", string(pt))
+	}
+}
+
 // A SignOption is a functional option for the SignRequest.
 type SignOption func(*SignRequest)
 
@@ -109,6 +782,22 @@ func SignWithState(cs consensus.State) SignOption {
 	}
 }
 
+// SignWithMetadata is an option for the SignRequest that attaches
+// metadata describing why the transaction is being signed.
+func SignWithMetadata(md map[string]string) SignOption {
+	return func(req *SignRequest) {
+		req.Metadata = md
+	}
+}
+
+// SignWithNonce is an option for the SignRequest that sets a random nonce
+// and the current time for servers with replay protection enabled.
+func SignWithNonce() SignOption {
+	return func(req *SignRequest) {
+		req.Nonce, req.Timestamp = newNonce()
+	}
+}
+
 // A SignV2Option is a functional option for the SignV2Request.
 type SignV2Option func(*SignV2Request)
 
@@ -120,3 +809,38 @@ func SignV2WithState(cs consensus.State) SignV2Option {
 		req.Network = cs.Network
 	}
 }
+
+// SignV2WithMetadata is an option for the SignV2Request that attaches
+// metadata describing why the transaction is being signed.
+func SignV2WithMetadata(md map[string]string) SignV2Option {
+	return func(req *SignV2Request) {
+		req.Metadata = md
+	}
+}
+
+// SignV2WithNonce is an option for the SignV2Request that sets a random
+// nonce and the current time for servers with replay protection enabled.
+func SignV2WithNonce() SignV2Option {
+	return func(req *SignV2Request) {
+		req.Nonce, req.Timestamp = newNonce()
+	}
+}
+
+// newNonce returns a random sign request nonce and the current time.
+func newNonce() (string, time.Time) {
+	return hex.EncodeToString(frand.Bytes(16)), time.Now()
+}
+
+// OwnershipChallengeHash returns the hash signed by ownership proofs for
+// the challenge. The challenge is prefixed so a proof can never be a valid
+// transaction signature.
+func OwnershipChallengeHash(challenge string) types.Hash256 {
+	return types.HashBytes([]byte(ownershipProofPrefix + challenge))
+}
+
+// Verify returns true if the proof's signature of the challenge is valid.
+func (p OwnershipProof) Verify(challenge string) bool {
+	return p.PublicKey.VerifyHash(OwnershipChallengeHash(challenge), p.Signature) &&
+		p.V1Address == types.StandardUnlockHash(p.PublicKey) &&
+		p.V2Address == types.PolicyPublicKey(p.PublicKey).Address()
+}
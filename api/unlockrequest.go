@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+const (
+	// unlockRequestLifetime is how long an unlock request can be
+	// approved.
+	unlockRequestLifetime = 10 * time.Minute
+	// maxUnlockRequests is the maximum number of pending unlock requests.
+	maxUnlockRequests = 10
+
+	// approvalCodeAlphabet omits vowels and characters that are easily
+	// confused so codes can be read aloud and compared at a glance.
+	approvalCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+)
+
+// A SecretSource fetches the vault secret from a secret manager when an
+// unlock request is approved.
+type SecretSource func(context.Context) (string, error)
+
+// WithUnlockApproval adds the routes of the approval unlock flow. An
+// unlock request is created without the vault secret; once an
+// administrator approves its code, the secret is fetched from the source
+// and the vault is unlocked.
+func WithUnlockApproval(src SecretSource) ServerOption {
+	return func(a *api) {
+		a.unlockSecret = src
+	}
+}
+
+// newApprovalCode returns a random code in the form XXXX-XXXX for a
+// request that must be approved, such as an unlock request.
+func newApprovalCode() string {
+	var sb strings.Builder
+	for i := range 8 {
+		if i == 4 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(approvalCodeAlphabet[frand.Intn(len(approvalCodeAlphabet))])
+	}
+	return sb.String()
+}
+
+// pruneUnlockRequests removes expired unlock requests.
+// It is expected that the caller holds the mutex.
+func (a *api) pruneUnlockRequests() {
+	now := time.Now()
+	for code, req := range a.unlockRequests {
+		if now.After(req.ExpiresAt) {
+			delete(a.unlockRequests, code)
+		}
+	}
+}
+
+func (a *api) handlePOSTUnlockRequests(jc jape.Context) {
+	if a.vault.Unlocked() {
+		jc.Error(vault.ErrUnlocked, http.StatusBadRequest)
+		return
+	}
+
+	req := UnlockApproval{
+		Code:      newApprovalCode(),
+		ExpiresAt: time.Now().Add(unlockRequestLifetime),
+	}
+	a.mu.Lock()
+	a.pruneUnlockRequests()
+	if len(a.unlockRequests) >= maxUnlockRequests {
+		a.mu.Unlock()
+		jc.Error(fmt.Errorf("too many pending unlock requests, the limit is %d", maxUnlockRequests), http.StatusTooManyRequests)
+		return
+	}
+	a.unlockRequests[req.Code] = req
+	a.mu.Unlock()
+
+	a.requestLog(jc.Request.Context()).Info("unlock requested", zap.String("code", req.Code), zap.Time("expires", req.ExpiresAt))
+	jc.Encode(req)
+}
+
+func (a *api) handleGETUnlockRequestsCode(jc jape.Context) {
+	var code string
+	if err := jc.DecodeParam("code", &code); err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.pruneUnlockRequests()
+	req, ok := a.unlockRequests[code]
+	a.mu.Unlock()
+	if !ok {
+		jc.Error(errors.New("unknown or expired unlock request"), http.StatusNotFound)
+		return
+	}
+	jc.Encode(req)
+}
+
+func (a *api) handlePOSTUnlockRequestsApprove(jc jape.Context) {
+	var code string
+	if err := jc.DecodeParam("code", &code); err != nil {
+		return
+	}
+
+	// the request is removed before the secret is fetched so it cannot be
+	// approved twice
+	a.mu.Lock()
+	a.pruneUnlockRequests()
+	req, ok := a.unlockRequests[code]
+	if ok && !req.Approved {
+		delete(a.unlockRequests, code)
+	}
+	a.mu.Unlock()
+	if !ok {
+		jc.Error(errors.New("unknown or expired unlock request"), http.StatusNotFound)
+		return
+	} else if req.Approved {
+		jc.Error(errors.New("unlock request already approved"), http.StatusConflict)
+		return
+	}
+
+	log := a.requestLog(jc.Request.Context()).With(zap.String("code", code))
+	secret, err := a.unlockSecret(jc.Request.Context())
+	if err != nil {
+		log.Error("failed to fetch vault secret", zap.Error(err))
+		jc.Error(fmt.Errorf("failed to fetch vault secret: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !a.unlockVault(jc, secret) {
+		// restore the request so it can be approved again, for example
+		// once the unlock backoff has passed
+		log.Warn("failed to unlock vault with fetched secret")
+		a.mu.Lock()
+		a.unlockRequests[code] = req
+		a.mu.Unlock()
+		return
+	}
+
+	req.Approved = true
+	a.mu.Lock()
+	a.unlockRequests[code] = req
+	a.mu.Unlock()
+
+	log.Info("unlock request approved")
+	jc.Encode(req)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/internal/shamir"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+// unlockSecretSize is the number of random bytes in a vault secret that is
+// split into shares.
+const unlockSecretSize = 32
+
+type (
+	// An unlockShare is a decoded share of the vault secret. SplitID
+	// identifies the shares of the same split so shares from different
+	// splits are not combined.
+	unlockShare struct {
+		SplitID   [4]byte
+		Threshold int
+		Share     shamir.Share
+	}
+
+	// pendingShares are the shares submitted towards unlocking the vault.
+	pendingShares struct {
+		splitID   [4]byte
+		threshold int
+		shares    []shamir.Share
+		expiresAt time.Time
+	}
+)
+
+// reset discards the pending shares.
+func (p *pendingShares) reset() {
+	for _, s := range p.shares {
+		clear(s.Y)
+	}
+	*p = pendingShares{}
+}
+
+// encodeUnlockShare encodes a share as hex: the split ID, the threshold,
+// the share's x coordinate, and its y values.
+func encodeUnlockShare(s unlockShare) string {
+	buf := make([]byte, 0, 6+len(s.Share.Y))
+	buf = append(buf, s.SplitID[:]...)
+	buf = append(buf, byte(s.Threshold), s.Share.X)
+	buf = append(buf, s.Share.Y...)
+	return hex.EncodeToString(buf)
+}
+
+// decodeUnlockShare decodes a share encoded by encodeUnlockShare.
+func decodeUnlockShare(str string) (unlockShare, error) {
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		return unlockShare{}, fmt.Errorf("failed to decode share: %w", err)
+	} else if len(buf) != 6+unlockSecretSize {
+		return unlockShare{}, errors.New("invalid share length")
+	}
+	s := unlockShare{
+		Threshold: int(buf[4]),
+		Share: shamir.Share{
+			X: buf[5],
+			Y: buf[6:],
+		},
+	}
+	copy(s.SplitID[:], buf[:4])
+	if s.Threshold < 2 || s.Share.X == 0 {
+		return unlockShare{}, errors.New("invalid share")
+	}
+	return s, nil
+}
+
+func (a *api) handlePOSTUnlockSplit(jc jape.Context) {
+	var req UnlockSplitRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+
+	// the secret can only be split before it encrypts any seeds, since
+	// whoever chose an existing secret could still unlock the vault alone
+	if ok, err := a.vault.Initialized(); err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	} else if ok {
+		jc.Error(errors.New("vault is already initialized, the secret can only be split before the first unlock"), http.StatusConflict)
+		return
+	}
+
+	secret := frand.Bytes(unlockSecretSize)
+	defer clear(secret)
+	shares, err := shamir.Split(secret, req.Threshold, req.Shares)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	encoded := hex.EncodeToString(secret)
+	if !a.unlockVault(jc, encoded) {
+		return
+	}
+
+	resp := UnlockSplitResponse{
+		Threshold: req.Threshold,
+		Shares:    make([]string, 0, len(shares)),
+	}
+	var splitID [4]byte
+	frand.Read(splitID[:])
+	for _, share := range shares {
+		resp.Shares = append(resp.Shares, encodeUnlockShare(unlockShare{
+			SplitID:   splitID,
+			Threshold: req.Threshold,
+			Share:     share,
+		}))
+		clear(share.Y)
+	}
+	a.requestLog(jc.Request.Context()).Info("initialized vault with split secret", zap.Int("threshold", req.Threshold), zap.Int("shares", req.Shares))
+	jc.Encode(resp)
+}
+
+func (a *api) handlePOSTUnlockShare(jc jape.Context) {
+	var req UnlockShareRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+
+	if a.vault.Unlocked() {
+		jc.Error(vault.ErrUnlocked, http.StatusBadRequest)
+		return
+	}
+
+	share, err := decodeUnlockShare(req.Share)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	pending := &a.unlockShares
+	if len(pending.shares) == 0 || time.Now().After(pending.expiresAt) {
+		pending.reset()
+		pending.splitID = share.SplitID
+		pending.threshold = share.Threshold
+		pending.expiresAt = time.Now().Add(unlockRequestLifetime)
+	}
+	if share.SplitID != pending.splitID || share.Threshold != pending.threshold {
+		a.mu.Unlock()
+		jc.Error(errors.New("share is from a different split than the pending shares"), http.StatusBadRequest)
+		return
+	} else if slices.ContainsFunc(pending.shares, func(s shamir.Share) bool { return s.X == share.Share.X }) {
+		a.mu.Unlock()
+		jc.Error(errors.New("share already submitted"), http.StatusConflict)
+		return
+	}
+	pending.shares = append(pending.shares, share.Share)
+	resp := UnlockShareResponse{
+		Received:  len(pending.shares),
+		Threshold: pending.threshold,
+	}
+	if resp.Received < resp.Threshold {
+		a.mu.Unlock()
+		a.requestLog(jc.Request.Context()).Info("unlock share submitted", zap.Int("received", resp.Received), zap.Int("threshold", resp.Threshold))
+		jc.Encode(resp)
+		return
+	}
+	// the shares are discarded before unlocking so a bad share cannot
+	// block the next attempt
+	secret, err := shamir.Combine(pending.shares)
+	pending.reset()
+	a.mu.Unlock()
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	defer clear(secret)
+
+	if !a.unlockVault(jc, hex.EncodeToString(secret)) {
+		return
+	}
+	resp.Unlocked = true
+	jc.Encode(resp)
+}
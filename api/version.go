@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// APIVersion is the version of the API implemented by this package.
+	// It is incremented when a change could break existing clients.
+	APIVersion = 1
+
+	// APIVersionHeader is the header used by clients to declare the API
+	// version they implement and by the server to declare its own.
+	APIVersionHeader = "X-Vaultd-Api-Version"
+)
+
+// ErrUnsupportedVersion is returned when the client's API version is below
+// the minimum required by the server.
+var ErrUnsupportedVersion = errors.New("unsupported API version")
+
+// supportedVersions are the API versions the server can serve.
+var supportedVersions = []int{APIVersion}
+
+// WithMinClientVersion rejects requests from clients that declare an API
+// version below v, or no version at all, with a 426 status code.
+func WithMinClientVersion(v int) ServerOption {
+	return func(a *api) {
+		a.minClientVersion = v
+	}
+}
+
+// versionMiddleware declares the server's API version on every response
+// and enforces the minimum client version.
+func (a *api) versionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(APIVersion))
+		if a.minClientVersion > 0 {
+			// a missing or invalid version is treated as version 0
+			version, _ := strconv.Atoi(r.Header.Get(APIVersionHeader))
+			if version < a.minClientVersion {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUpgradeRequired)
+				json.NewEncoder(w).Encode(UnsupportedVersionResponse{
+					Error:             fmt.Sprintf("%s: client version %d is below the minimum version %d", ErrUnsupportedVersion, version, a.minClientVersion),
+					ClientVersion:     version,
+					MinClientVersion:  a.minClientVersion,
+					SupportedVersions: supportedVersions,
+				})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/vaultd/webhooks"
+	"go.uber.org/zap"
+)
+
+// WithWebhooks adds the routes to manage webhooks and broadcasts unlock,
+// lock, seed, and signing events to them.
+func WithWebhooks(m *webhooks.Manager) ServerOption {
+	return func(a *api) {
+		a.webhooks = m
+	}
+}
+
+// broadcast sends the event to the registered webhooks in the background.
+func (a *api) broadcast(event, scope string, data any) {
+	if a.webhooks == nil {
+		return
+	}
+	a.webhooks.BroadcastEvent(event, scope, data)
+}
+
+func (a *api) handleGETWebhooks(jc jape.Context) {
+	jc.Encode(a.webhooks.Webhooks())
+}
+
+func (a *api) handlePOSTWebhooks(jc jape.Context) {
+	var req WebhookRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+	hook, err := a.webhooks.Register(req.CallbackURL, req.Scopes)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("registered webhook", zap.Int64("id", int64(hook.ID)), zap.String("callbackURL", hook.CallbackURL), zap.Strings("scopes", hook.Scopes))
+	jc.Encode(hook)
+}
+
+func (a *api) handlePUTWebhooksID(jc jape.Context) {
+	var id webhooks.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	var req WebhookRequest
+	if err := jc.Decode(&req); err != nil {
+		return
+	}
+	_, err := a.webhooks.Update(id, req.CallbackURL, req.Scopes)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	jc.Encode(nil)
+}
+
+func (a *api) handleDELETEWebhooksID(jc jape.Context) {
+	var id webhooks.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	err := a.webhooks.Delete(id)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	a.requestLog(jc.Request.Context()).Info("deleted webhook", zap.Int64("id", int64(id)))
+	jc.Encode(nil)
+}
+
+func (a *api) handlePOSTWebhooksIDTest(jc jape.Context) {
+	var id webhooks.ID
+	if err := jc.DecodeParam("id", (*int64)(&id)); err != nil {
+		return
+	}
+	err := a.webhooks.Test(id)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusBadGateway)
+		return
+	}
+	jc.Encode(nil)
+}
@@ -3,7 +3,12 @@ package build
 
 //go:generate go run gen.go
 
-import "time"
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
 
 // Commit returns the commit hash of walletd
 func Commit() string {
@@ -19,3 +24,49 @@ func Version() string {
 func Time() time.Time {
 	return time.Unix(buildTime, 0)
 }
+
+// GoVersion returns the version of Go the binary was built with.
+func GoVersion() string {
+	return runtime.Version()
+}
+
+// Dependencies returns the versions of the modules compiled into the binary
+// whose paths start with the prefix, keyed by module path. A replaced module
+// reports the version of its replacement, or its directory if it is replaced
+// with a local copy.
+func Dependencies(prefix string) map[string]string {
+	deps := make(map[string]string)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return deps
+	}
+	for _, dep := range info.Deps {
+		if !strings.HasPrefix(dep.Path, prefix) {
+			continue
+		}
+		version := dep.Version
+		if r := dep.Replace; r != nil {
+			// local replacements have a path but no version
+			version = r.Version
+			if version == "" {
+				version = r.Path
+			}
+		}
+		deps[dep.Path] = version
+	}
+	return deps
+}
+
+// Tags returns the build tags the binary was built with.
+func Tags() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	for _, s := range info.Settings {
+		if s.Key == "-tags" && s.Value != "" {
+			return strings.Split(s.Value, ",")
+		}
+	}
+	return nil
+}
@@ -2,35 +2,65 @@ package chain
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/threadgroup"
 	"go.uber.org/zap"
 )
 
+// ErrDiverged is returned when the tip states of the primary and
+// verification explorers disagree.
+var ErrDiverged = errors.New("explorers diverged")
+
+// ErrPinMismatch is returned when the explorer's TLS certificate chain
+// does not contain a pinned public key.
+var ErrPinMismatch = errors.New("explorer certificate does not match a pinned public key")
+
 // An Option is a functional option for configuring a Manager
 type Option func(*Manager)
 
+// A Pin is the SHA-256 hash of a certificate's DER-encoded
+// SubjectPublicKeyInfo.
+type Pin [32]byte
+
+// An explorer is an explorer API, the headers added to its requests, and
+// the public keys pinned for its connections.
+type explorer struct {
+	url     string
+	headers http.Header
+	pins    []Pin
+	client  *http.Client
+}
+
 // A Manager manages the consensus state of a blockchain by periodically
 // polling an explorer API.
 type Manager struct {
 	tg  *threadgroup.ThreadGroup
 	log *zap.Logger
 
-	baseURL      string
+	primary      explorer
 	pollInterval time.Duration
 
-	mu sync.Mutex
-	cs consensus.State
-}
+	// verify is the optional second explorer used to cross-check the
+	// primary explorer's tip state.
+	verify          explorer
+	verifyTolerance uint64
 
-var client = &http.Client{
-	Timeout: 10 * time.Second,
+	mu         sync.Mutex
+	cs         consensus.State
+	divergence error
 }
 
 // pollConsensusState periodically polls the explorer for the latest consensus state.
@@ -55,12 +85,18 @@ func (m *Manager) pollConsensusState() {
 		case <-ticker.C:
 		}
 		// reuse existing network
-		cs, err := getConsensusState(ctx, m.cs.Network, m.baseURL)
-		if err != nil {
+		cs, err := m.fetchState(ctx, m.cs.Network)
+		if errors.Is(err, ErrDiverged) {
+			m.mu.Lock()
+			m.divergence = err
+			m.mu.Unlock()
+			continue
+		} else if err != nil {
 			fmt.Printf("failed to get consensus state: %v\n", err)
 			continue
 		}
 		m.mu.Lock()
+		m.divergence = nil
 		if m.cs.Index != cs.Index {
 			log.Debug("consensus state updated", zap.Stringer("tip", cs.Index), zap.Stringer("prev", m.cs.Index), zap.String("network", cs.Network.Name))
 		} else {
@@ -77,37 +113,122 @@ func (m *Manager) TipState(ctx context.Context) (consensus.State, error) {
 	defer m.mu.Unlock()
 	if m.cs.Network != nil {
 		// fast path if the consensus state has already been initialized
+		if m.divergence != nil {
+			return consensus.State{}, m.divergence
+		}
 		return m.cs, nil
 	}
 
 	// initialize the consensus state if it hasn't been done yet
-	network, err := getNetwork(ctx, m.baseURL)
+	network, err := m.getNetwork(ctx, m.primary)
 	if err != nil {
 		return consensus.State{}, fmt.Errorf("failed to get network: %w", err)
+	} else if m.verify.url != "" {
+		other, err := m.getNetwork(ctx, m.verify)
+		if err != nil {
+			return consensus.State{}, fmt.Errorf("failed to get verification network: %w", err)
+		} else if other.Name != network.Name {
+			m.divergence = fmt.Errorf("%w: networks %q and %q differ", ErrDiverged, network.Name, other.Name)
+			return consensus.State{}, m.divergence
+		}
+	}
+	cs, err := m.fetchState(ctx, &network)
+	if errors.Is(err, ErrDiverged) {
+		m.divergence = err
 	}
-	cs, err := getConsensusState(ctx, &network, m.baseURL)
 	if err != nil {
 		return consensus.State{}, fmt.Errorf("failed to get consensus state: %w", err)
 	}
 	m.log.Debug("initial consensus state retrieved", zap.Stringer("tip", cs.Index), zap.String("network", network.Name))
 	m.cs = cs
+	m.divergence = nil
 	go m.pollConsensusState()
 	return m.cs, nil
 }
 
+// Divergence returns the reason the explorers' tip states disagree, or nil
+// if they agree or no verification explorer is configured.
+func (m *Manager) Divergence() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.divergence
+}
+
+// fetchState retrieves the tip state from the explorer. If a verification
+// explorer is configured, its tip state must agree within the tolerance;
+// the lower of the two states is returned.
+func (m *Manager) fetchState(ctx context.Context, network *consensus.Network) (consensus.State, error) {
+	cs, err := m.getConsensusState(ctx, network, m.primary)
+	if err != nil || m.verify.url == "" {
+		return cs, err
+	}
+
+	other, err := m.getConsensusState(ctx, network, m.verify)
+	if err != nil {
+		return consensus.State{}, fmt.Errorf("failed to get verification state: %w", err)
+	}
+
+	if err := m.crossCheck(ctx, cs, other); err != nil {
+		m.log.Warn("explorers diverged", zap.Error(err), zap.Stringer("primary", cs.Index), zap.Stringer("verify", other.Index))
+		return consensus.State{}, err
+	} else if other.Index.Height < cs.Index.Height {
+		return other, nil
+	}
+	return cs, nil
+}
+
+// crossCheck returns an error wrapping [ErrDiverged] if the two tip states
+// are not on the same chain within the tolerance.
+func (m *Manager) crossCheck(ctx context.Context, a, b consensus.State) error {
+	lower, higher := a, m.verify
+	if b.Index.Height < a.Index.Height {
+		lower, higher = b, m.primary
+	}
+	diff := max(a.Index.Height, b.Index.Height) - lower.Index.Height
+	if diff > m.verifyTolerance {
+		return fmt.Errorf("%w: tips %v and %v are %d blocks apart", ErrDiverged, a.Index, b.Index, diff)
+	} else if diff == 0 {
+		if a.Index != b.Index {
+			return fmt.Errorf("%w: tips %v and %v differ", ErrDiverged, a.Index, b.Index)
+		}
+		return nil
+	}
+
+	// check that the higher explorer's chain contains the lower tip
+	var index types.ChainIndex
+	if err := m.makeGETRequest(ctx, higher, fmt.Sprintf("/consensus/tip/%d", lower.Index.Height), &index); err != nil {
+		return fmt.Errorf("failed to get index at height %d: %w", lower.Index.Height, err)
+	} else if index != lower.Index {
+		return fmt.Errorf("%w: index %v is not in the chain of the other explorer", ErrDiverged, lower.Index)
+	}
+	return nil
+}
+
+// RecommendedFee returns the explorer's recommended fee per byte for a
+// transaction to be included in the next few blocks.
+func (m *Manager) RecommendedFee(ctx context.Context) (fee types.Currency, err error) {
+	err = m.makeGETRequest(ctx, m.primary, "/txpool/fee", &fee)
+	return
+}
+
 // Close stops the chain's thread group and cleans up resources.
 func (m *Manager) Close() error {
 	m.tg.Stop()
 	return nil
 }
 
-func makeGETRequest(ctx context.Context, url string, obj any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+func (m *Manager) makeGETRequest(ctx context.Context, e explorer, path string, obj any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+path, http.NoBody)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
+	} else if len(e.pins) > 0 && req.URL.Scheme != "https" {
+		return errors.New("explorer URL must use https when public keys are pinned")
+	}
+	for k, v := range e.headers {
+		req.Header[k] = v
 	}
 
-	resp, err := client.Do(req)
+	resp, err := e.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -124,14 +245,14 @@ func makeGETRequest(ctx context.Context, url string, obj any) error {
 // getNetwork retrieves the network information from the explorer.
 // This only needs to be called once, as the network information is cached.
 // It is assumed that the caller will hold the mutex before calling this method.
-func getNetwork(ctx context.Context, baseURL string) (network consensus.Network, err error) {
-	err = makeGETRequest(ctx, baseURL+"/consensus/network", &network)
+func (m *Manager) getNetwork(ctx context.Context, e explorer) (network consensus.Network, err error) {
+	err = m.makeGETRequest(ctx, e, "/consensus/network", &network)
 	return
 }
 
 // getConsensusState retrieves the current consensus state from the explorer.
-func getConsensusState(ctx context.Context, network *consensus.Network, baseURL string) (cs consensus.State, err error) {
-	err = makeGETRequest(ctx, baseURL+"/consensus/state", &cs)
+func (m *Manager) getConsensusState(ctx context.Context, network *consensus.Network, e explorer) (cs consensus.State, err error) {
+	err = m.makeGETRequest(ctx, e, "/consensus/state", &cs)
 	cs.Network = network
 	return
 }
@@ -150,16 +271,128 @@ func WithPollInterval(interval time.Duration) Option {
 	}
 }
 
+// WithPinnedKeys refuses to connect to the primary explorer unless its TLS
+// certificate chain contains one of the pinned public keys. The consensus
+// state feeds directly into sighashes, so its transport deserves more
+// trust than the system's certificate authorities alone provide. The pins
+// do not apply to the verification explorer; use
+// [WithVerificationPinnedKeys] instead.
+func WithPinnedKeys(pins ...Pin) Option {
+	return func(m *Manager) {
+		m.primary.pins = append(m.primary.pins, pins...)
+	}
+}
+
+// WithVerificationPinnedKeys refuses to connect to the verification
+// explorer unless its TLS certificate chain contains one of the pinned
+// public keys.
+func WithVerificationPinnedKeys(pins ...Pin) Option {
+	return func(m *Manager) {
+		m.verify.pins = append(m.verify.pins, pins...)
+	}
+}
+
+// WithVerification cross-checks the tip state of the explorer against a
+// second, independent explorer. The state is only served when both
+// explorers are on the same chain and their tips are at most tolerance
+// blocks apart. This protects against a single compromised explorer
+// feeding crafted state into sighashes.
+func WithVerification(baseURL string, tolerance uint64) Option {
+	return func(m *Manager) {
+		m.verify.url = baseURL
+		m.verifyTolerance = tolerance
+	}
+}
+
+// WithHeader adds a header, such as an API key, to every request to the
+// primary explorer. Self-hosted explorers are often behind authenticated
+// gateways. The header is not sent to the verification explorer, which
+// is usually run by someone else; use [WithVerificationHeader] instead.
+func WithHeader(key, value string) Option {
+	return func(m *Manager) {
+		if m.primary.headers == nil {
+			m.primary.headers = make(http.Header)
+		}
+		m.primary.headers.Add(key, value)
+	}
+}
+
+// WithVerificationHeader adds a header to every request to the
+// verification explorer.
+func WithVerificationHeader(key, value string) Option {
+	return func(m *Manager) {
+		if m.verify.headers == nil {
+			m.verify.headers = make(http.Header)
+		}
+		m.verify.headers.Add(key, value)
+	}
+}
+
+// ParsePin parses a base64-encoded SHA-256 hash of a certificate's
+// SubjectPublicKeyInfo, as produced by
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// An optional "sha256//" prefix is accepted.
+func ParsePin(s string) (Pin, error) {
+	buf, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, "sha256//"))
+	if err != nil {
+		return Pin{}, fmt.Errorf("failed to decode pin: %w", err)
+	} else if len(buf) != len(Pin{}) {
+		return Pin{}, fmt.Errorf("pin must be %d bytes, got %d", len(Pin{}), len(buf))
+	}
+	return Pin(buf), nil
+}
+
+// verifyPins returns a function that checks that a verified certificate
+// chain contains one of the pinned public keys. The certificates sent by
+// the server are not checked directly: any server could include a pinned
+// certificate that does not chain to its own.
+func verifyPins(pins []Pin) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if slices.Contains(pins, Pin(h)) {
+					return nil
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+}
+
 // New creates a new Explorer instance with the given base URL.
 func New(baseURL string, opts ...Option) *Manager {
 	m := &Manager{
 		tg:           threadgroup.New(),
 		log:          zap.NewNop(),
-		baseURL:      baseURL,
+		primary:      explorer{url: baseURL},
 		pollInterval: time.Minute,
 	}
 	for _, opt := range opts {
 		opt(m)
 	}
+
+	// each explorer has its own client so a connection is only checked
+	// against its explorer's pins
+	m.primary.client = newClient(m.primary.pins)
+	m.verify.client = newClient(m.verify.pins)
 	return m
 }
+
+// newClient returns an HTTP client that checks its connections against the
+// pins, if any.
+func newClient(pins []Pin) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(pins) > 0 {
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			VerifyConnection: verifyPins(pins),
+		}
+	}
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+}
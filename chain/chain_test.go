@@ -2,11 +2,22 @@ package chain
 
 import (
 	"context"
-	"encoding/json"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"errors"
+	"math/big"
 	"net"
 	"net/http"
-	"sync"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"testing"
 	"time"
 
@@ -14,62 +25,11 @@ import (
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils"
 	"go.sia.tech/coreutils/testutil"
+	"go.sia.tech/vaultd/chain/chaintest"
 )
 
-func startConsensusServer(tb testing.TB) (string, func(consensus.State)) {
-	tb.Helper()
-
-	l, err := net.Listen("tcp", ":0")
-	if err != nil {
-		tb.Fatalf("failed to start consensus server: %v", err)
-	}
-	tb.Cleanup(func() { l.Close() })
-
-	var mu sync.Mutex
-	var cs consensus.State
-	updateConsensusFunc := func(newState consensus.State) {
-		mu.Lock()
-		cs = newState
-		mu.Unlock()
-	}
-
-	s := &http.Server{
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.Method != http.MethodGet {
-				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-				return
-			}
-			mu.Lock()
-			defer mu.Unlock()
-			switch r.URL.Path {
-			case "/consensus/network":
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				if err := json.NewEncoder(w).Encode(cs.Network); err != nil {
-					panic(err)
-				}
-			case "/consensus/state":
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				if err := json.NewEncoder(w).Encode(cs); err != nil {
-					panic(err)
-				}
-			default:
-				http.NotFound(w, r)
-			}
-		}),
-	}
-	tb.Cleanup(func() { s.Close() })
-	go func() {
-		if err := s.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			panic(err)
-		}
-	}()
-	return "http://" + l.Addr().String(), updateConsensusFunc
-}
-
 func TestChainPolling(t *testing.T) {
-	addr, updateFn := startConsensusServer(t)
+	addr, updateFn := chaintest.StartConsensusServer(t)
 
 	n, genesis := testutil.Network()
 	cs, _ := consensus.ApplyBlock(n.GenesisState(), genesis, consensus.V1BlockSupplement{Transactions: make([]consensus.V1TransactionSupplement, len(genesis.Transactions))}, time.Time{})
@@ -106,3 +66,241 @@ func TestChainPolling(t *testing.T) {
 		t.Fatalf("expected updated tip index %v, got %v", cs.Index, tip.Index)
 	}
 }
+
+func TestPinnedKeys(t *testing.T) {
+	addr, cert, updateFn := chaintest.StartTLSConsensusServer(t)
+	updateFn(chaintest.AllowHeightState())
+
+	newManager := func(pins ...Pin) *Manager {
+		m := New(addr, WithPinnedKeys(pins...))
+		roots := x509.NewCertPool()
+		roots.AddCert(cert)
+		m.primary.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		t.Cleanup(func() { m.Close() })
+		return m
+	}
+
+	pin := Pin(sha256.Sum256(cert.RawSubjectPublicKeyInfo))
+	parsed, err := ParsePin("sha256//" + base64.StdEncoding.EncodeToString(pin[:]))
+	if err != nil {
+		t.Fatal(err)
+	} else if parsed != pin {
+		t.Fatal("parsed pin does not match")
+	}
+
+	if _, err := newManager(pin).TipState(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newManager(Pin{1}).TipState(context.Background()); !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("expected ErrPinMismatch, got %v", err)
+	}
+}
+
+// newCertificate returns a certificate for 127.0.0.1 signed by parent, or
+// a self-signed certificate if parent is nil.
+func newCertificate(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPinnedKeysUnchained(t *testing.T) {
+	ca, caKey := newCertificate(t, "trusted CA", true, nil, nil)
+	leaf, leafKey := newCertificate(t, "explorer", false, ca, caKey)
+	pinned, _ := newCertificate(t, "pinned", true, nil, nil)
+
+	// the server's chain includes the pinned certificate, but it does not
+	// sign the leaf
+	addr, updateFn := chaintest.StartTLSConsensusServerWithCertificate(t, tls.Certificate{
+		Certificate: [][]byte{leaf.Raw, pinned.Raw},
+		PrivateKey:  leafKey,
+	})
+	updateFn(chaintest.AllowHeightState())
+
+	newManager := func(pins ...Pin) *Manager {
+		m := New(addr, WithPinnedKeys(pins...))
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		roots.AddCert(pinned)
+		m.primary.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		t.Cleanup(func() { m.Close() })
+		return m
+	}
+
+	if _, err := newManager(Pin(sha256.Sum256(pinned.RawSubjectPublicKeyInfo))).TipState(context.Background()); !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("expected ErrPinMismatch, got %v", err)
+	} else if _, err := newManager(Pin(sha256.Sum256(ca.RawSubjectPublicKeyInfo))).TipState(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerificationPinnedKeys(t *testing.T) {
+	primaryCert, primaryKey := newCertificate(t, "primary", true, nil, nil)
+	verifyCert, verifyKey := newCertificate(t, "verify", true, nil, nil)
+	primary, updatePrimary := chaintest.StartTLSConsensusServerWithCertificate(t, tls.Certificate{Certificate: [][]byte{primaryCert.Raw}, PrivateKey: primaryKey})
+	verify, updateVerify := chaintest.StartTLSConsensusServerWithCertificate(t, tls.Certificate{Certificate: [][]byte{verifyCert.Raw}, PrivateKey: verifyKey})
+	cs := chaintest.AllowHeightState()
+	updatePrimary(cs)
+	updateVerify(cs)
+
+	primaryPin := Pin(sha256.Sum256(primaryCert.RawSubjectPublicKeyInfo))
+	verifyPin := Pin(sha256.Sum256(verifyCert.RawSubjectPublicKeyInfo))
+	newManager := func(pins ...Pin) *Manager {
+		m := New(primary, WithPinnedKeys(primaryPin), WithVerification(verify, 0), WithVerificationPinnedKeys(pins...))
+		roots := x509.NewCertPool()
+		roots.AddCert(primaryCert)
+		roots.AddCert(verifyCert)
+		m.primary.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		m.verify.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		t.Cleanup(func() { m.Close() })
+		return m
+	}
+
+	// each explorer is checked against its own pins
+	if _, err := newManager(verifyPin).TipState(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := newManager(primaryPin).TipState(context.Background()); !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("expected ErrPinMismatch, got %v", err)
+	}
+}
+
+func TestVerification(t *testing.T) {
+	primary, updatePrimary := chaintest.StartConsensusServer(t)
+	verify, updateVerify := chaintest.StartConsensusServer(t)
+
+	cs := chaintest.AllowHeightState()
+	updatePrimary(cs)
+	updateVerify(cs)
+
+	m := New(primary, WithVerification(verify, 1), WithPollInterval(100*time.Millisecond))
+	defer m.Close()
+
+	tip, err := m.TipState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if tip.Index != cs.Index {
+		t.Fatalf("expected tip %v, got %v", cs.Index, tip.Index)
+	}
+
+	// a different block at the same height
+	forked := cs
+	forked.Index.ID = types.BlockID{1}
+	updateVerify(forked)
+	time.Sleep(200 * time.Millisecond) // wait for polling to catch up
+
+	if _, err := m.TipState(context.Background()); !errors.Is(err, ErrDiverged) {
+		t.Fatalf("expected ErrDiverged, got %v", err)
+	} else if !errors.Is(m.Divergence(), ErrDiverged) {
+		t.Fatalf("expected divergence to be reported, got %v", m.Divergence())
+	}
+
+	// tips too far apart
+	ahead := chaintest.State(chaintest.V2AllowHeight + 5)
+	updateVerify(ahead)
+	time.Sleep(200 * time.Millisecond)
+	if _, err := m.TipState(context.Background()); !errors.Is(err, ErrDiverged) {
+		t.Fatalf("expected ErrDiverged, got %v", err)
+	}
+
+	updateVerify(cs)
+	time.Sleep(200 * time.Millisecond)
+	if _, err := m.TipState(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if m.Divergence() != nil {
+		t.Fatalf("expected no divergence, got %v", m.Divergence())
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	addr, updateFn := chaintest.StartConsensusServer(t)
+	updateFn(chaintest.AllowHeightState())
+
+	// require an API key in front of the explorer
+	target, err := url.Parse(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "foo" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer gateway.Close()
+
+	m := New(gateway.URL)
+	defer m.Close()
+	if _, err := m.TipState(context.Background()); err == nil {
+		t.Fatal("expected request without API key to fail")
+	}
+
+	m = New(gateway.URL, WithHeader("X-Api-Key", "foo"))
+	defer m.Close()
+	if _, err := m.TipState(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the primary explorer's headers are not sent to the verification
+	// explorer, which has its own
+	verify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "" || r.Header.Get("X-Verify-Key") != "bar" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer verify.Close()
+
+	m = New(gateway.URL, WithHeader("X-Api-Key", "foo"), WithVerification(verify.URL, 0))
+	defer m.Close()
+	if _, err := m.TipState(context.Background()); err == nil {
+		t.Fatal("expected verification request without its header to fail")
+	}
+
+	m = New(gateway.URL, WithHeader("X-Api-Key", "foo"), WithVerification(verify.URL, 0), WithVerificationHeader("X-Verify-Key", "bar"))
+	defer m.Close()
+	if _, err := m.TipState(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecommendedFee(t *testing.T) {
+	addr, updateFn := chaintest.StartConsensusServer(t)
+	updateFn(chaintest.AllowHeightState())
+
+	m := New(addr)
+	defer m.Close()
+	if fee, err := m.RecommendedFee(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !fee.Equals(chaintest.RecommendedFee) {
+		t.Fatalf("expected %v, got %v", chaintest.RecommendedFee, fee)
+	}
+}
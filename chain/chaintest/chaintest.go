@@ -0,0 +1,189 @@
+// Package chaintest provides a stub explorer consensus server and canned
+// consensus states for testing code that depends on the hardfork heights.
+package chaintest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+)
+
+// Hardfork heights of the test network.
+const (
+	FoundationHeight = 10
+	V2AllowHeight    = 20
+	V2RequireHeight  = 30
+	V2FinalCutHeight = 40
+)
+
+// RecommendedFee is the fee per byte served by the stub explorer.
+var RecommendedFee = types.Siacoins(1).Div64(1e6)
+
+// Network returns a test network with every hardfork before the Foundation
+// hardfork activated at height 1 and the remaining hardforks at the heights
+// above.
+func Network() *consensus.Network {
+	n, _ := chain.TestnetZen()
+	n.Name = "chaintest"
+	n.HardforkDevAddr.Height = 1
+	n.HardforkTax.Height = 1
+	n.HardforkStorageProof.Height = 1
+	n.HardforkOak.Height = 1
+	n.HardforkASIC.Height = 1
+	n.HardforkFoundation.Height = FoundationHeight
+	n.HardforkV2.AllowHeight = V2AllowHeight
+	n.HardforkV2.RequireHeight = V2RequireHeight
+	n.HardforkV2.FinalCutHeight = V2FinalCutHeight
+	return n
+}
+
+// State returns a consensus state of the test network at the given height.
+// The block ID is derived from the height so states are deterministic.
+func State(height uint64) consensus.State {
+	var id types.BlockID
+	binary.BigEndian.PutUint64(id[:], height)
+	return consensus.State{
+		Network: Network(),
+		Index: types.ChainIndex{
+			Height: height,
+			ID:     id,
+		},
+	}
+}
+
+// PreFoundationState returns a state before the Foundation hardfork. v1
+// signatures use replay prefix 0.
+func PreFoundationState() consensus.State {
+	return State(FoundationHeight - 1)
+}
+
+// PreHardforkState returns a state after the Foundation hardfork but
+// before v2 transactions are allowed. v1 signatures use replay prefix 1.
+func PreHardforkState() consensus.State {
+	return State(V2AllowHeight - 1)
+}
+
+// AllowHeightState returns a state at the v2 allow height. Both v1 and v2
+// transactions are valid and v1 signatures use replay prefix 2.
+func AllowHeightState() consensus.State {
+	return State(V2AllowHeight)
+}
+
+// RequireHeightState returns a state at the v2 require height. Only v2
+// transactions are valid.
+func RequireHeightState() consensus.State {
+	return State(V2RequireHeight)
+}
+
+// consensusHandler returns a handler implementing the explorer's consensus
+// endpoints and a function to update the consensus state it serves.
+func consensusHandler() (http.Handler, func(consensus.State)) {
+	var mu sync.Mutex
+	var cs consensus.State
+	updateConsensusFunc := func(newState consensus.State) {
+		mu.Lock()
+		cs = newState
+		mu.Unlock()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/consensus/network":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			if err := json.NewEncoder(w).Encode(cs.Network); err != nil {
+				panic(err)
+			}
+		case "/consensus/state":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			if err := json.NewEncoder(w).Encode(cs); err != nil {
+				panic(err)
+			}
+		case "/txpool/fee":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			if err := json.NewEncoder(w).Encode(RecommendedFee); err != nil {
+				panic(err)
+			}
+		case fmt.Sprintf("/consensus/tip/%d", cs.Index.Height):
+			// only the current tip is known
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			if err := json.NewEncoder(w).Encode(cs.Index); err != nil {
+				panic(err)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}), updateConsensusFunc
+}
+
+// StartConsensusServer starts an HTTP server implementing the explorer's
+// consensus endpoints. It returns the server's URL and a function to update
+// the consensus state it serves. The server is stopped when the test
+// completes.
+func StartConsensusServer(tb testing.TB) (string, func(consensus.State)) {
+	tb.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		tb.Fatalf("failed to start consensus server: %v", err)
+	}
+	tb.Cleanup(func() { l.Close() })
+
+	handler, updateConsensusFunc := consensusHandler()
+	s := &http.Server{
+		Handler: handler,
+	}
+	tb.Cleanup(func() { s.Close() })
+	go func() {
+		if err := s.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+	return "http://" + l.Addr().String(), updateConsensusFunc
+}
+
+// StartTLSConsensusServer is like StartConsensusServer, but the server uses
+// TLS with a self-signed certificate, which is also returned.
+func StartTLSConsensusServer(tb testing.TB) (string, *x509.Certificate, func(consensus.State)) {
+	tb.Helper()
+
+	handler, updateConsensusFunc := consensusHandler()
+	s := httptest.NewTLSServer(handler)
+	tb.Cleanup(s.Close)
+	return s.URL, s.Certificate(), updateConsensusFunc
+}
+
+// StartTLSConsensusServerWithCertificate is like StartTLSConsensusServer,
+// but the server presents cert, including any extra certificates in its
+// chain.
+func StartTLSConsensusServerWithCertificate(tb testing.TB, cert tls.Certificate) (string, func(consensus.State)) {
+	tb.Helper()
+
+	handler, updateConsensusFunc := consensusHandler()
+	s := httptest.NewUnstartedServer(handler)
+	s.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	s.StartTLS()
+	tb.Cleanup(s.Close)
+	return s.URL, updateConsensusFunc
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	coreutils "go.sia.tech/coreutils/chain"
+	"go.sia.tech/vaultd/api"
+	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+type (
+	// benchOptions configures a signing benchmark.
+	benchOptions struct {
+		Keys        uint64
+		Requests    int
+		Concurrency int
+		Paths       []string
+	}
+
+	// benchResult summarizes the requests to a single signing path.
+	benchResult struct {
+		Path      string
+		Requests  int
+		Errors    int
+		Elapsed   time.Duration
+		Latencies []time.Duration
+	}
+
+	// benchChain serves a fixed consensus state so signing does not depend
+	// on an explorer.
+	benchChain struct {
+		cs consensus.State
+	}
+)
+
+// benchPaths are the signing paths measured by the benchmark.
+var benchPaths = []string{"v1", "v2", "blind"}
+
+// TipState implements api.Chain.
+func (bc benchChain) TipState(context.Context) (consensus.State, error) {
+	return bc.cs, nil
+}
+
+// benchSigner returns a function that sends a single sign request for
+// the given path using the key.
+func benchSigner(client *api.Client, path string) (func(ctx context.Context, pk types.PublicKey) error, error) {
+	switch path {
+	case "v1":
+		return func(ctx context.Context, pk types.PublicKey) error {
+			txn := types.Transaction{
+				SiacoinInputs: []types.SiacoinInput{{
+					ParentID:         frand.Entropy256(),
+					UnlockConditions: types.StandardUnlockConditions(pk),
+				}},
+				SiacoinOutputs: []types.SiacoinOutput{{Address: types.VoidAddress, Value: types.Siacoins(1)}},
+			}
+			txn.Signatures = []types.TransactionSignature{{
+				ParentID:      types.Hash256(txn.SiacoinInputs[0].ParentID),
+				CoveredFields: types.CoveredFields{WholeTransaction: true},
+			}}
+			_, signed, err := client.Sign(ctx, txn)
+			if err == nil && !signed {
+				err = errors.New("transaction not fully signed")
+			}
+			return err
+		}, nil
+	case "v2":
+		return func(ctx context.Context, pk types.PublicKey) error {
+			txn := types.V2Transaction{
+				SiacoinInputs: []types.V2SiacoinInput{{
+					Parent: types.SiacoinElement{ID: frand.Entropy256()},
+					SatisfiedPolicy: types.SatisfiedPolicy{
+						Policy: types.PolicyPublicKey(pk),
+					},
+				}},
+				SiacoinOutputs: []types.SiacoinOutput{{Address: types.VoidAddress, Value: types.Siacoins(1)}},
+			}
+			_, signed, err := client.SignV2(ctx, txn)
+			if err == nil && !signed {
+				err = errors.New("transaction not fully signed")
+			}
+			return err
+		}, nil
+	case "blind":
+		return func(ctx context.Context, pk types.PublicKey) error {
+			_, err := client.BlindSign(ctx, pk, frand.Entropy256())
+			return err
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown path %q, expected one of %s", path, strings.Join(benchPaths, ", "))
+	}
+}
+
+// runBench starts an in-memory vault with a random seed, derives the
+// keys, and measures the signing throughput of each path through the
+// HTTP API.
+func runBench(ctx context.Context, opts benchOptions) ([]benchResult, error) {
+	switch {
+	case opts.Keys == 0:
+		return nil, errors.New("at least one key is required")
+	case opts.Requests <= 0:
+		return nil, errors.New("at least one request is required")
+	case opts.Concurrency <= 0:
+		return nil, errors.New("concurrency must be positive")
+	}
+	for _, path := range opts.Paths {
+		if !slices.Contains(benchPaths, path) {
+			return nil, fmt.Errorf("unknown path %q, expected one of %s", path, strings.Join(benchPaths, ", "))
+		}
+	}
+
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	v := vault.New(store)
+	defer v.Close()
+	if err := v.Unlock(string(frand.Bytes(16))); err != nil {
+		return nil, fmt.Errorf("failed to unlock vault: %w", err)
+	}
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	clear(seed[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to add seed: %w", err)
+	}
+
+	start := time.Now()
+	keys, err := v.NextKeys(meta.ID, opts.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keys: %w", err)
+	}
+	fmt.Printf("Derived %d keys in %s\n", len(keys), time.Since(start).Round(time.Millisecond))
+
+	// sign at the allow height so both v1 and v2 transactions are valid
+	network, _ := coreutils.Mainnet()
+	cs := consensus.State{
+		Network: network,
+		Index:   types.ChainIndex{Height: network.HardforkV2.AllowHeight},
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	defer l.Close()
+	s := &http.Server{Handler: api.Handler(benchChain{cs}, v, zap.NewNop())}
+	defer s.Close()
+	go s.Serve(l)
+
+	client := api.NewClient("http://"+l.Addr().String(), "")
+	var results []benchResult
+	for _, path := range opts.Paths {
+		sign, err := benchSigner(client, path)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, benchPath(ctx, path, sign, keys, opts))
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// benchPath sends the configured number of sign requests from concurrent
+// workers, cycling through the keys.
+func benchPath(ctx context.Context, path string, sign func(context.Context, types.PublicKey) error, keys []types.PublicKey, opts benchOptions) benchResult {
+	var next, errs atomic.Int64
+	latencies := make([]time.Duration, opts.Requests)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= opts.Requests {
+					return
+				}
+				reqStart := time.Now()
+				if err := sign(ctx, keys[i%len(keys)]); err != nil {
+					errs.Add(1)
+				}
+				latencies[i] = time.Since(reqStart)
+			}
+		}()
+	}
+	wg.Wait()
+
+	r := benchResult{
+		Path:      path,
+		Requests:  min(int(next.Load()), opts.Requests),
+		Errors:    int(errs.Load()),
+		Elapsed:   time.Since(start),
+		Latencies: latencies[:min(int(next.Load()), opts.Requests)],
+	}
+	slices.Sort(r.Latencies)
+	return r
+}
+
+// benchPercentile returns the p-th percentile of the sorted latencies.
+func benchPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// printBench prints a report of the benchmark results.
+func printBench(results []benchResult, opts benchOptions) error {
+	fmt.Printf("%d requests per path, concurrency %d\n", opts.Requests, opts.Concurrency)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tREQUESTS\tERRORS\tELAPSED\tREQ/S\tP50\tP90\tP99\tMAX")
+	for _, r := range results {
+		var rate float64
+		if r.Elapsed > 0 {
+			rate = float64(r.Requests) / r.Elapsed.Seconds()
+		}
+		var maxLatency time.Duration
+		if len(r.Latencies) > 0 {
+			maxLatency = r.Latencies[len(r.Latencies)-1]
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%.1f\t%s\t%s\t%s\t%s\n", r.Path, r.Requests, r.Errors, r.Elapsed.Round(time.Millisecond), rate,
+			benchPercentile(r.Latencies, 0.50).Round(time.Microsecond),
+			benchPercentile(r.Latencies, 0.90).Round(time.Microsecond),
+			benchPercentile(r.Latencies, 0.99).Round(time.Microsecond),
+			maxLatency.Round(time.Microsecond))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	rpprof "runtime/pprof"
+	"syscall"
+	"time"
+
+	"go.sia.tech/jape"
+	"go.uber.org/zap"
+)
+
+// debugHandler returns a handler serving the pprof endpoints.
+func debugHandler(password string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return jape.BasicAuth(password)(mux)
+}
+
+// serveDebug serves the pprof endpoints on l until the listener is
+// closed.
+func serveDebug(l net.Listener, password string, log *zap.Logger) {
+	server := &http.Server{
+		ReadTimeout: 5 * time.Second,
+		// CPU profiles and traces stream for the requested duration
+		WriteTimeout: 5 * time.Minute,
+		Handler:      debugHandler(password),
+	}
+	if err := server.Serve(l); !errors.Is(err, net.ErrClosed) {
+		log.Error("debug server failed", zap.Error(err))
+	}
+}
+
+// dumpGoroutines writes the stack of every goroutine to the log when the
+// process receives SIGQUIT, instead of exiting. It blocks until the
+// context is canceled.
+func dumpGoroutines(ctx context.Context, log *zap.Logger) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGQUIT)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+
+		var buf bytes.Buffer
+		if err := rpprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+			log.Error("failed to dump goroutines", zap.Error(err))
+			continue
+		}
+		log.Warn("goroutine dump", zap.String("goroutines", buf.String()))
+	}
+}
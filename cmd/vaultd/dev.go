@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/wallet"
+	"go.sia.tech/vaultd/vault"
+)
+
+const (
+	// devSecret is the well-known secret used to unlock the vault in dev
+	// mode.
+	devSecret = "vaultd dev secret"
+	// devPassword is the API password used in dev mode if no password is
+	// configured.
+	devPassword = "vaultd dev password"
+	// devPhrase is the well-known recovery phrase loaded in dev mode. It
+	// must never be used to hold real funds.
+	devPhrase = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	// devKeys is the number of keys derived from the dev seed.
+	devKeys = 5
+)
+
+// loadDevSeed adds the well-known dev seed to the vault, derives its first
+// keys, and prints their addresses.
+func loadDevSeed(v *vault.Vault) error {
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, devPhrase); err != nil {
+		return fmt.Errorf("failed to parse dev phrase: %w", err)
+	}
+	defer clear(seed[:])
+
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		return fmt.Errorf("failed to add dev seed: %w", err)
+	}
+
+	fmt.Println("Dev mode is enabled. Do not send real funds to these addresses.")
+	fmt.Printf("API password: %s\n", cfg.HTTP.Password)
+	fmt.Printf("Recovery phrase: %s\n", devPhrase)
+	fmt.Printf("Seed %d:\n", meta.ID)
+	for i := 0; i < devKeys; i++ {
+		pk, err := v.NextKey(meta.ID)
+		if err != nil {
+			return fmt.Errorf("failed to derive dev key: %w", err)
+		}
+		fmt.Printf("  %d: %v\n", i, types.StandardUnlockHash(pk))
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/config"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/notify"
+	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap"
+)
+
+// purgeSeeds periodically removes seeds whose retention period has passed.
+// It blocks until the context is canceled.
+func purgeSeeds(ctx context.Context, v *vault.Vault, log *zap.Logger) {
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		n, err := v.PurgeSeeds()
+		if err != nil {
+			log.Error("failed to purge deleted seeds", zap.Error(err))
+		} else if n > 0 {
+			log.Info("purged deleted seeds", zap.Int("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// refreshCommitment periodically recomputes the custody commitment. It
+// blocks until the context is canceled.
+func refreshCommitment(ctx context.Context, c *custody.Committer, interval time.Duration, log *zap.Logger) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if commitment, err := c.Refresh(); err != nil {
+			log.Error("failed to compute custody commitment", zap.Error(err))
+		} else {
+			log.Debug("computed custody commitment", zap.Stringer("root", commitment.Root), zap.Int("keys", commitment.Keys))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// checkStorage periodically compares the size of the database against the
+// configured soft limits. Operators are notified when a limit is first
+// exceeded. It blocks until the context is canceled.
+func checkStorage(ctx context.Context, store *sqlite.Store, cfg config.Storage, notifier notify.Notifier, log *zap.Logger) {
+	alert := func(message string) {
+		if notifier == nil {
+			return
+		}
+		err := notifier.Notify(notify.Event{
+			Type:      notify.EventStorageLimit,
+			Subject:   "Storage limit exceeded",
+			Message:   message,
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			log.Warn("failed to send notification", zap.Error(err))
+		}
+	}
+
+	var sizeExceeded, keysExceeded bool
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		usage, err := store.Usage()
+		if err != nil {
+			log.Error("failed to get database usage", zap.Error(err))
+		} else {
+			log.Debug("database usage", zap.Int64("size", usage.Size), zap.Int("seeds", usage.Seeds), zap.Int("keys", usage.Keys), zap.Int("nonces", usage.Nonces))
+
+			exceeded := cfg.MaxSize > 0 && usage.Size > cfg.MaxSize
+			if exceeded {
+				log.Warn("database size exceeds limit", zap.Int64("size", usage.Size), zap.Int64("limit", cfg.MaxSize))
+				if !sizeExceeded {
+					alert(fmt.Sprintf("The database is %d bytes, exceeding the limit of %d bytes. Free disk space or archive unused seeds before writes fail.", usage.Size, cfg.MaxSize))
+				}
+			}
+			sizeExceeded = exceeded
+
+			exceeded = cfg.MaxKeys > 0 && usage.Keys > cfg.MaxKeys
+			if exceeded {
+				log.Warn("derived keys exceed limit", zap.Int("keys", usage.Keys), zap.Int("limit", cfg.MaxKeys))
+				if !keysExceeded {
+					alert(fmt.Sprintf("The database contains %d derived keys, exceeding the limit of %d.", usage.Keys, cfg.MaxKeys))
+				}
+			}
+			keysExceeded = exceeded
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// auditKeys periodically re-derives a sample of stored keys while the
+// vault is unlocked and alerts operators on a mismatch. It blocks until
+// the context is canceled.
+func auditKeys(ctx context.Context, v *vault.Vault, cfg config.KeyAudit, notifier notify.Notifier, log *zap.Logger) {
+	alert := func(message string) {
+		if notifier == nil {
+			return
+		}
+		err := notifier.Notify(notify.Event{
+			Type:      notify.EventKeyMismatch,
+			Subject:   "Key audit failed",
+			Message:   message,
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			log.Warn("failed to send notification", zap.Error(err))
+		}
+	}
+
+	t := time.NewTicker(cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		mismatched, err := v.AuditKeys(cfg.SampleSize)
+		if errors.Is(err, vault.ErrLocked) {
+			log.Debug("skipping key audit while locked")
+			continue
+		} else if err != nil {
+			log.Error("key audit failed", zap.Error(err))
+			alert(fmt.Sprintf("The key audit could not re-derive the sampled keys: %v", err))
+			continue
+		}
+
+		for _, key := range mismatched {
+			log.Error("stored key does not match derived key", zap.Int64("seedID", int64(key.SeedID)), zap.Uint64("index", key.Index))
+		}
+		if len(mismatched) > 0 {
+			alert(fmt.Sprintf("%d of the sampled keys do not match the keys derived from their seed. The database may be corrupt.", len(mismatched)))
+		} else {
+			log.Debug("key audit passed", zap.Int("sampled", cfg.SampleSize))
+		}
+	}
+}
+
+// warnExpirations periodically notifies operators of seeds and keys that
+// expire within the warning period. Each expiration is notified once per
+// run. It blocks until the context is canceled.
+func warnExpirations(ctx context.Context, v *vault.Vault, warning time.Duration, notifier notify.Notifier, log *zap.Logger) {
+	alert := func(message string) {
+		if notifier == nil {
+			return
+		}
+		err := notifier.Notify(notify.Event{
+			Type:      notify.EventKeyExpiring,
+			Subject:   "Keys expiring soon",
+			Message:   message,
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			log.Warn("failed to send notification", zap.Error(err))
+		}
+	}
+
+	notified := make(map[vault.Expiration]bool)
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		now := time.Now()
+		exps, err := v.Expirations(now.Add(warning))
+		if err != nil {
+			log.Error("failed to get expirations", zap.Error(err))
+		} else {
+			current := make(map[vault.Expiration]bool, len(exps))
+			var lines []string
+			for _, exp := range exps {
+				current[exp] = true
+				if notified[exp] || !exp.ExpiresAt.After(now) {
+					continue
+				}
+				if exp.PublicKey == (types.PublicKey{}) {
+					log.Warn("seed expiring", zap.Int64("seedID", int64(exp.SeedID)), zap.Time("expiresAt", exp.ExpiresAt))
+					lines = append(lines, fmt.Sprintf("seed %d expires at %s", exp.SeedID, exp.ExpiresAt.Format(time.RFC3339)))
+				} else {
+					log.Warn("key expiring", zap.Int64("seedID", int64(exp.SeedID)), zap.Stringer("publicKey", exp.PublicKey), zap.Time("expiresAt", exp.ExpiresAt))
+					lines = append(lines, fmt.Sprintf("key %v of seed %d expires at %s", exp.PublicKey, exp.SeedID, exp.ExpiresAt.Format(time.RFC3339)))
+				}
+			}
+			if len(lines) > 0 {
+				alert(fmt.Sprintf("Signing will be refused after the following expire. Rotate to new keys before then.\n\n%s", strings.Join(lines, "\n")))
+			}
+			// forget expirations that were removed or changed
+			notified = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
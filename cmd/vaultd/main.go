@@ -8,9 +8,14 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
+	"go.sia.tech/vaultd/api"
 	"go.sia.tech/vaultd/config"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/vault"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -24,6 +29,76 @@ const (
 	secretEnvVar      = "VAULTD_SECRET"
 )
 
+const (
+	statusUsage = `Usage:
+    vaultd status [flags]
+
+Checks that a vaultd instance is reachable and prints a short summary.
+Exits with a non-zero status code if the instance is unreachable.
+`
+	configUsage = `Usage:
+    vaultd config [command]
+
+Manages the vaultd config file.
+
+Commands:
+    upgrade    upgrade the config file to the current version
+`
+	configUpgradeUsage = `Usage:
+    vaultd config upgrade [file]
+
+Upgrades the config file, or the loaded config file if none is given, to the
+current version in place. Deprecated keys are replaced and comments are
+preserved where possible.
+`
+	snapshotUsage = `Usage:
+    vaultd snapshot [flags] [command]
+
+Manages snapshots of a running vaultd instance's database.
+
+Commands:
+    list              list snapshots
+    create [name]     create a snapshot
+    restore [name]    restore a snapshot, saving the current state first
+`
+	offlineUsage = `Usage:
+    vaultd offline [command]
+
+Converts offline sign requests and responses to and from a compact
+format that can be transferred to an air-gapped signer as text or QR codes.
+
+Commands:
+    encode    split a JSON request or response into text chunks
+    decode    join text chunks into a JSON request or response
+    qr        display a JSON request or response as animated QR codes
+`
+	offlineEncodeUsage = `Usage:
+    vaultd offline encode [file]
+
+Reads a JSON sign request or response from file, or stdin, and writes one
+chunk per line to stdout.
+`
+	offlineDecodeUsage = `Usage:
+    vaultd offline decode [file]
+
+Reads chunks, one per line in any order, from file, or stdin, and writes the
+JSON sign request or response to stdout.
+`
+	offlineQRUsage = `Usage:
+    vaultd offline qr [file]
+
+Reads a JSON sign request or response from file, or stdin, and displays its
+chunks as QR codes in the terminal until interrupted.
+`
+	benchUsage = `Usage:
+    vaultd bench [flags]
+
+Starts an in-memory vault with a random seed, derives keys, and measures the
+throughput and latency of the v1, v2, and blind signing paths through the
+HTTP API. Nothing is written to the data directory.
+`
+)
+
 func tryConfigPaths() []string {
 	if str := os.Getenv(configFileEnvVar); str != "" {
 		return []string{str}
@@ -87,16 +162,16 @@ func checkFatalError(context string, err error) {
 // based on GOOS starting with PWD/vaultd.yml. If the file does not exist, it will
 // try the next location. If an error occurs while loading the file, it will
 // print the error and exit. If the config is successfully loaded, the path to
-// the config file is returned.
-func tryLoadConfig() string {
+// the config file and any deprecated keys it uses are returned.
+func tryLoadConfig() (string, []string) {
 	for _, fp := range tryConfigPaths() {
-		if err := config.LoadFile(fp, &cfg); err == nil {
-			return fp
+		if deprecated, err := config.LoadFile(fp, &cfg); err == nil {
+			return fp, deprecated
 		} else if !errors.Is(err, os.ErrNotExist) {
 			checkFatalError("failed to load config file", err)
 		}
 	}
-	return ""
+	return "", nil
 }
 
 // jsonEncoder returns a zapcore.Encoder that encodes logs as JSON intended for
@@ -126,12 +201,21 @@ func humanEncoder(showColors bool) zapcore.Encoder {
 	return zapcore.NewConsoleEncoder(cfg)
 }
 
+// devMode starts vaultd with an in-memory store and a well-known test seed.
+var devMode bool
+
+// migrateOnly applies database migrations and exits without serving.
+var migrateOnly bool
+
 var cfg = config.Config{
-	Secret:    os.Getenv(secretEnvVar),
-	Directory: os.Getenv(dataDirEnvVar),
+	Secret:        os.Getenv(secretEnvVar),
+	Directory:     os.Getenv(dataDirEnvVar),
+	SeedRetention: vault.DefaultSeedRetention,
 	HTTP: config.HTTP{
-		Address:  "localhost:9980",
-		Password: os.Getenv(apiPasswordEnvVar),
+		Address:      config.Addresses{"localhost:9980"},
+		Password:     os.Getenv(apiPasswordEnvVar),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: time.Minute,
 	},
 	Log: config.Log{
 		File: config.LogFile{
@@ -149,23 +233,112 @@ var cfg = config.Config{
 	Explorer: config.Explorer{
 		Network: "mainnet",
 	},
+	Serial: config.Serial{
+		Baud: 115200,
+	},
+	Vault: config.Vault{
+		ExpiryWarning: 7 * 24 * time.Hour,
+	},
+	Maintenance: config.Maintenance{
+		OverrideDuration: time.Hour,
+	},
+	KeyAudit: config.KeyAudit{
+		SampleSize: 100,
+	},
+	SLO: config.SLO{
+		Target: 0.99,
+	},
 }
 
 func main() {
 	// attempt to load the config file, command line flags will override any
 	// values set in the config file
-	tryLoadConfig()
+	configPath, deprecatedKeys := tryLoadConfig()
+	if configPath == "" {
+		// loading a config file resolves every secret, including those
+		// set by environment variables, so they only need to be resolved
+		// here if there is none
+		checkFatalError("failed to resolve config secrets", cfg.ResolveSecrets())
+	}
 	// set the data directory to the default if it is not set
 	cfg.Directory = defaultDataDirectory(cfg.Directory)
 
 	rootCmd := flagg.Root
 	rootCmd.TextVar(&cfg.Log.StdOut.Level, "log.level", cfg.Log.StdOut.Level, "the log level for stdout")
-	rootCmd.StringVar(&cfg.HTTP.Address, "http.addr", cfg.HTTP.Address, "the address to listen on for the HTTP API")
+	rootCmd.TextVar(&cfg.HTTP.Address, "http.addr", cfg.HTTP.Address, "the comma-separated addresses to listen on for the HTTP API")
 	rootCmd.StringVar(&cfg.Explorer.Network, "network", cfg.Explorer.Network, "the network to use for the explorer")
+	rootCmd.BoolVar(&cfg.WatchOnly, "watch-only", cfg.WatchOnly, "disable seed import and signing")
+	rootCmd.BoolVar(&devMode, "dev", false, "start with an in-memory store and a well-known test seed")
+	rootCmd.BoolVar(&migrateOnly, "migrate-only", false, "apply database migrations and exit")
 	rootCmd.Usage = flagg.SimpleUsage(rootCmd, ``)
 
+	statusCmd := flagg.New("status", statusUsage)
+	statusAddr := "http://localhost:9980"
+	if len(cfg.HTTP.Address) > 0 {
+		statusAddr = "http://" + cfg.HTTP.Address[0]
+		if cfg.HTTP.TLS.CertFile != "" || len(cfg.HTTP.TLS.ACME.Domains) > 0 {
+			statusAddr = "https://" + cfg.HTTP.Address[0]
+		}
+	}
+	statusPassword := cfg.HTTP.Password
+	statusTimeout := 5 * time.Second
+	statusCmd.StringVar(&statusAddr, "addr", statusAddr, "the address of the vaultd API")
+	statusCmd.StringVar(&statusPassword, "password", statusPassword, "the API password")
+	statusCmd.DurationVar(&statusTimeout, "timeout", statusTimeout, "the maximum time to wait for a response")
+
+	snapshotCmd := flagg.New("snapshot", snapshotUsage)
+	snapshotAddr := statusAddr
+	snapshotPassword := statusPassword
+	snapshotCmd.StringVar(&snapshotAddr, "addr", snapshotAddr, "the address of the vaultd API")
+	snapshotCmd.StringVar(&snapshotPassword, "password", snapshotPassword, "the API password")
+
+	configCmd := flagg.New("config", configUsage)
+	configUpgradeCmd := flagg.New("upgrade", configUpgradeUsage)
+
+	offlineCmd := flagg.New("offline", offlineUsage)
+	offlineEncodeCmd := flagg.New("encode", offlineEncodeUsage)
+	offlineDecodeCmd := flagg.New("decode", offlineDecodeUsage)
+	offlineQRCmd := flagg.New("qr", offlineQRUsage)
+
+	benchCmd := flagg.New("bench", benchUsage)
+	benchOpts := benchOptions{
+		Keys:        1000,
+		Requests:    10000,
+		Concurrency: runtime.NumCPU(),
+	}
+	benchPathList := strings.Join(benchPaths, ",")
+	benchCmd.Uint64Var(&benchOpts.Keys, "keys", benchOpts.Keys, "the number of keys to derive and sign with")
+	benchCmd.IntVar(&benchOpts.Requests, "requests", benchOpts.Requests, "the number of sign requests per path")
+	benchCmd.IntVar(&benchOpts.Concurrency, "concurrency", benchOpts.Concurrency, "the number of concurrent requests")
+	benchCmd.StringVar(&benchPathList, "paths", benchPathList, "the comma-separated signing paths to measure (v1, v2, blind)")
+
+	var chunkSize int
+	var qrInterval time.Duration
+	offlineEncodeCmd.IntVar(&chunkSize, "chunk", 300, "the maximum number of characters per chunk")
+	offlineQRCmd.IntVar(&chunkSize, "chunk", 300, "the maximum number of characters per chunk")
+	offlineQRCmd.DurationVar(&qrInterval, "interval", 500*time.Millisecond, "the time to display each chunk")
+
 	cmd := flagg.Parse(flagg.Tree{
 		Cmd: rootCmd,
+		Sub: []flagg.Tree{
+			{Cmd: statusCmd},
+			{Cmd: snapshotCmd},
+			{Cmd: benchCmd},
+			{
+				Cmd: configCmd,
+				Sub: []flagg.Tree{
+					{Cmd: configUpgradeCmd},
+				},
+			},
+			{
+				Cmd: offlineCmd,
+				Sub: []flagg.Tree{
+					{Cmd: offlineEncodeCmd},
+					{Cmd: offlineDecodeCmd},
+					{Cmd: offlineQRCmd},
+				},
+			},
+		},
 	})
 
 	switch cmd {
@@ -178,9 +351,21 @@ func main() {
 		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
 		defer cancel()
 
-		if cfg.Directory != "" {
+		if devMode && migrateOnly {
+			checkFatalError("invalid flags", errors.New("-migrate-only cannot be used with -dev"))
+		}
+
+		if devMode {
+			// dev mode never touches the data directory
+			cfg.Secret = devSecret
+			cfg.WatchOnly = false
+			cfg.Log.File.Enabled = false
+			if cfg.HTTP.Password == "" {
+				cfg.HTTP.Password = devPassword
+			}
+		} else if cfg.Directory != "" {
 			checkFatalError("failed to create data directory", os.MkdirAll(cfg.Directory, 0700))
-		} else if cfg.HTTP.Password == "" {
+		} else if cfg.HTTP.Password == "" && !migrateOnly {
 			checkFatalError("missing password", errors.New("HTTP auth password must be set using ENV variable or config file"))
 		}
 
@@ -232,7 +417,98 @@ func main() {
 		// redirect stdlib log to zap
 		zap.RedirectStdLog(log.Named("stdlib"))
 
+		if len(deprecatedKeys) > 0 {
+			log.Warn("config file uses deprecated keys, run \"vaultd config upgrade\" to update it", zap.String("path", configPath), zap.Strings("keys", deprecatedKeys))
+		}
+
+		if migrateOnly {
+			checkFatalError("failed to migrate database", migrateDatabase(log))
+			return
+		}
 		checkFatalError("failed to run node", run(ctx, log))
+	case statusCmd:
+		if len(cmd.Args()) != 0 {
+			cmd.Usage()
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+		defer cancel()
+		checkFatalError("vaultd is unhealthy", printStatus(ctx, statusAddr, statusPassword))
+	case snapshotCmd:
+		args := cmd.Args()
+		if len(args) == 0 {
+			cmd.Usage()
+			return
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		client := api.NewClient(snapshotAddr, snapshotPassword)
+		switch {
+		case args[0] == "list" && len(args) == 1:
+			checkFatalError("failed to list snapshots", printSnapshots(ctx, client))
+		case args[0] == "create" && len(args) == 2:
+			checkFatalError("failed to create snapshot", createSnapshot(ctx, client, args[1]))
+		case args[0] == "restore" && len(args) == 2:
+			checkFatalError("failed to restore snapshot", restoreSnapshot(ctx, client, args[1]))
+		default:
+			cmd.Usage()
+		}
+	case configUpgradeCmd:
+		if len(cmd.Args()) > 1 {
+			cmd.Usage()
+			return
+		}
+		fp := configPath
+		if len(cmd.Args()) == 1 {
+			fp = cmd.Args()[0]
+		}
+		if fp == "" {
+			checkFatalError("failed to upgrade config file", errors.New("no config file found"))
+		}
+		checkFatalError("failed to upgrade config file", upgradeConfigFile(fp))
+	case offlineEncodeCmd:
+		if len(cmd.Args()) > 1 || chunkSize <= 0 {
+			cmd.Usage()
+			return
+		}
+		r, err := openInput(cmd.Args())
+		checkFatalError("failed to open input", err)
+		defer r.Close()
+		checkFatalError("failed to encode chunks", encodeChunks(r, os.Stdout, chunkSize))
+	case offlineDecodeCmd:
+		if len(cmd.Args()) > 1 {
+			cmd.Usage()
+			return
+		}
+		r, err := openInput(cmd.Args())
+		checkFatalError("failed to open input", err)
+		defer r.Close()
+		checkFatalError("failed to decode chunks", decodeChunks(r, os.Stdout))
+	case offlineQRCmd:
+		if len(cmd.Args()) > 1 || chunkSize <= 0 || qrInterval <= 0 {
+			cmd.Usage()
+			return
+		}
+		r, err := openInput(cmd.Args())
+		checkFatalError("failed to open input", err)
+		defer r.Close()
+		buf, err := readEnvelope(r)
+		checkFatalError("failed to read input", err)
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		checkFatalError("failed to display QR codes", showQR(ctx, os.Stdout, offline.Chunk(buf, chunkSize), qrInterval))
+	case benchCmd:
+		if len(cmd.Args()) != 0 {
+			cmd.Usage()
+			return
+		}
+		benchOpts.Paths = strings.Split(benchPathList, ",")
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		results, err := runBench(ctx, benchOpts)
+		checkFatalError("failed to run benchmark", err)
+		checkFatalError("failed to print report", printBench(results, benchOpts))
 	default:
 		cmd.Usage()
 	}
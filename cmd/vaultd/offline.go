@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.sia.tech/vaultd/offline"
+	"rsc.io/qr"
+)
+
+// openInput returns the file named by the first argument or stdin if no
+// argument is provided.
+func openInput(args []string) (io.ReadCloser, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(args[0])
+}
+
+// readEnvelope reads a JSON sign request or sign response and returns it
+// as a binary envelope.
+func readEnvelope(r io.Reader) ([]byte, error) {
+	var v struct {
+		offline.SignRequest
+		Signatures []offline.Signature `json:"signatures"`
+	}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	} else if v.Signatures != nil {
+		return offline.EncodeResponse(offline.SignResponse{
+			TransactionID: v.TransactionID,
+			Signatures:    v.Signatures,
+		}), nil
+	}
+	return offline.EncodeRequest(v.SignRequest), nil
+}
+
+// encodeChunks reads a JSON sign request or response from r and writes one
+// chunk per line to w.
+func encodeChunks(r io.Reader, w io.Writer, size int) error {
+	buf, err := readEnvelope(r)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range offline.Chunk(buf, size) {
+		if _, err := fmt.Fprintln(w, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeChunks reads chunks, one per line, from r and writes the decoded
+// sign request or response to w as JSON.
+func decodeChunks(r io.Reader, w io.Writer) error {
+	var chunks []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			chunks = append(chunks, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("failed to read chunks: %w", err)
+	}
+
+	buf, err := offline.Unchunk(chunks)
+	if err != nil {
+		return err
+	}
+	kind, err := offline.EnvelopeKind(buf)
+	if err != nil {
+		return err
+	}
+
+	var v any
+	switch kind {
+	case offline.KindRequest:
+		v, err = offline.DecodeRequest(buf)
+	case offline.KindResponse:
+		v, err = offline.DecodeResponse(buf)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// renderQR writes a QR code to w using half block characters so each line
+// of text holds two rows of modules. Light modules are drawn so the code
+// scans on terminals with a dark background.
+func renderQR(w io.Writer, code *qr.Code) {
+	const quiet = 2
+	light := func(x, y int) bool { return !code.Black(x, y) }
+
+	var sb strings.Builder
+	for y := -quiet; y < code.Size+quiet; y += 2 {
+		for x := -quiet; x < code.Size+quiet; x++ {
+			top, bottom := light(x, y), light(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	io.WriteString(w, sb.String())
+}
+
+// showQR cycles through the chunks, rendering each as a QR code, until the
+// context is canceled.
+func showQR(ctx context.Context, w io.Writer, chunks []string, interval time.Duration) error {
+	codes := make([]*qr.Code, 0, len(chunks))
+	for _, chunk := range chunks {
+		// the chunks are case sensitive so they are always encoded in
+		// byte mode
+		code, err := qr.Encode(chunk, qr.L)
+		if err != nil {
+			return fmt.Errorf("failed to encode QR code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for i := 0; ; i = (i + 1) % len(codes) {
+		io.WriteString(w, "\033[H\033[2J") // clear the terminal
+		renderQR(w, codes[i])
+		fmt.Fprintf(w, "%d/%d\n", i+1, len(codes))
+
+		// a single code does not need to be animated
+		if len(codes) == 1 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
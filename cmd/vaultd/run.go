@@ -2,74 +2,349 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
+	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"go.sia.tech/jape"
 	"go.sia.tech/vaultd/api"
 	"go.sia.tech/vaultd/chain"
+	"go.sia.tech/vaultd/config"
+	"go.sia.tech/vaultd/custody"
+	"go.sia.tech/vaultd/hook"
+	"go.sia.tech/vaultd/notify"
 	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/serial"
+	"go.sia.tech/vaultd/snapshot"
+	"go.sia.tech/vaultd/tor"
 	"go.sia.tech/vaultd/vault"
+	"go.sia.tech/vaultd/webhooks"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // run runs the vault daemon. It blocks until the context is canceled or
 // an error occurs.
 func run(ctx context.Context, log *zap.Logger) error {
-	httpListener, err := net.Listen("tcp", cfg.HTTP.Address)
-	if err != nil {
-		return fmt.Errorf("failed to listen on %q: %w", cfg.HTTP.Address, err)
+	if len(cfg.HTTP.Address) == 0 {
+		return errors.New("no HTTP address configured")
+	}
+	var httpListeners []net.Listener
+	for _, addr := range cfg.HTTP.Address {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %q: %w", addr, err)
+		}
+		defer l.Close()
+		httpListeners = append(httpListeners, l)
 	}
-	defer httpListener.Close()
 
-	store, err := sqlite.OpenDatabase(filepath.Join(cfg.Directory, "vaultd.sqlite3"),
-		sqlite.WithLogger(log.Named("sqlite3")),
-		sqlite.WithBusyTimeout(15*time.Second))
+	var debugListener net.Listener
+	if cfg.Debug.Address != "" {
+		l, err := net.Listen("tcp", cfg.Debug.Address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %q: %w", cfg.Debug.Address, err)
+		}
+		defer l.Close()
+		debugListener = l
+	}
+
+	var store *sqlite.Store
+	var err error
+	if devMode {
+		store, err = sqlite.OpenMemoryDatabase(sqlite.WithLogger(log.Named("sqlite3")))
+	} else {
+		store, err = sqlite.OpenDatabase(filepath.Join(cfg.Directory, "vaultd.sqlite3"),
+			sqlite.WithLogger(log.Named("sqlite3")),
+			sqlite.WithBusyTimeout(15*time.Second))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to open wallet database: %w", err)
 	}
 	defer store.Close()
 
-	vault := vault.New(store)
+	var notifier notify.Notifier
+	if cfg.SMTP.Address != "" {
+		notifier, err = notify.NewSMTPNotifier(cfg.SMTP.Address, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From, cfg.SMTP.To)
+		if err != nil {
+			return fmt.Errorf("failed to create SMTP notifier: %w", err)
+		}
+	}
+
+	hooks, err := webhooks.NewManager(store, log.Named("webhooks"))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook manager: %w", err)
+	}
+	defer hooks.Close()
+
+	vaultOpts := []vault.Option{vault.WithLookAhead(cfg.LookAhead), vault.WithSeedRetention(cfg.SeedRetention),
+		vault.WithAutoLock(cfg.Vault.AutoLockAfter, func() {
+			log.Info("vault locked after inactivity", zap.Duration("after", cfg.Vault.AutoLockAfter))
+			hooks.BroadcastEvent(webhooks.EventLocked, webhooks.ScopeVault, nil)
+			if notifier == nil {
+				return
+			}
+			err := notifier.Notify(notify.Event{
+				Type:      notify.EventLocked,
+				Subject:   "Vault locked",
+				Message:   fmt.Sprintf("The vault was locked after %s of inactivity.", cfg.Vault.AutoLockAfter),
+				Timestamp: time.Now(),
+			})
+			if err != nil {
+				log.Warn("failed to send notification", zap.Error(err))
+			}
+		}),
+		vault.WithReadOnlyHandler(func(reason error) {
+			log.Error("vault is read-only", zap.Error(reason))
+			if notifier == nil {
+				return
+			}
+			err := notifier.Notify(notify.Event{
+				Type:      notify.EventReadOnly,
+				Subject:   "Vault is read-only",
+				Message:   fmt.Sprintf("Signing and key derivation are disabled until vaultd is restarted: %v. The database may be corrupted or the vault was unlocked with an incorrect secret.", reason),
+				Timestamp: time.Now(),
+			})
+			if err != nil {
+				log.Warn("failed to send notification", zap.Error(err))
+			}
+		}),
+	}
+	if cfg.Vault.HardwareToken != "" {
+		token, err := hook.NewChallenge(cfg.Vault.HardwareToken, 0)
+		if err != nil {
+			return fmt.Errorf("invalid hardware token command: %w", err)
+		}
+		vaultOpts = append(vaultOpts, vault.WithHardwareToken(token.Respond))
+	}
+	vault := vault.New(store, vaultOpts...)
 	defer vault.Close()
 
+	issues, err := vault.CheckConsistency()
+	if err != nil {
+		return fmt.Errorf("failed to check database consistency: %w", err)
+	}
+	for _, issue := range issues {
+		log.Error("database consistency issue", zap.Int64("seedID", int64(issue.SeedID)), zap.String("description", issue.Description))
+	}
+
+	go dumpGoroutines(ctx, log.Named("debug"))
+	go purgeSeeds(ctx, vault, log.Named("purge"))
+	if cfg.Storage.MaxSize > 0 || cfg.Storage.MaxKeys > 0 {
+		go checkStorage(ctx, store, cfg.Storage, notifier, log.Named("storage"))
+	}
+	if cfg.KeyAudit.Interval > 0 {
+		go auditKeys(ctx, vault, cfg.KeyAudit, notifier, log.Named("audit"))
+	}
+	if cfg.Vault.ExpiryWarning > 0 {
+		go warnExpirations(ctx, vault, cfg.Vault.ExpiryWarning, notifier, log.Named("expiry"))
+	}
+
 	if cfg.Secret != "" {
 		if err := vault.Unlock(cfg.Secret); err != nil {
 			return fmt.Errorf("failed to unlock vault: %w", err)
 		}
 	}
 
+	if devMode {
+		if err := loadDevSeed(vault); err != nil {
+			return err
+		}
+	}
+
+	chainOpts := []chain.Option{chain.WithLog(log.Named("chain"))}
+	for _, s := range cfg.Explorer.PinnedKeys {
+		pin, err := chain.ParsePin(s)
+		if err != nil {
+			return fmt.Errorf("invalid explorer pin %q: %w", s, err)
+		}
+		chainOpts = append(chainOpts, chain.WithPinnedKeys(pin))
+	}
+	for _, s := range cfg.Explorer.VerifyPinnedKeys {
+		pin, err := chain.ParsePin(s)
+		if err != nil {
+			return fmt.Errorf("invalid verification explorer pin %q: %w", s, err)
+		}
+		chainOpts = append(chainOpts, chain.WithVerificationPinnedKeys(pin))
+	}
+	for k, v := range cfg.Explorer.Headers {
+		chainOpts = append(chainOpts, chain.WithHeader(k, v))
+	}
+	for k, v := range cfg.Explorer.VerifyHeaders {
+		chainOpts = append(chainOpts, chain.WithVerificationHeader(k, v))
+	}
+	if cfg.Explorer.VerifyURL != "" {
+		chainOpts = append(chainOpts, chain.WithVerification(cfg.Explorer.VerifyURL, cfg.Explorer.VerifyTolerance))
+	}
+
 	var manager *chain.Manager
 	if cfg.Explorer.URL != "" {
-		manager = chain.New(cfg.Explorer.URL, chain.WithLog(log.Named("chain")))
+		manager = chain.New(cfg.Explorer.URL, chainOpts...)
 	} else {
 		switch cfg.Explorer.Network {
 		case "mainnet":
-			manager = chain.New("https://api.siascan.com", chain.WithLog(log.Named("chain")))
+			manager = chain.New("https://api.siascan.com", chainOpts...)
 		case "zen":
-			manager = chain.New("https://api.siascan.com/zen", chain.WithLog(log.Named("chain")))
+			manager = chain.New("https://api.siascan.com/zen", chainOpts...)
 		default:
 			return fmt.Errorf("unknown explorer network %q", cfg.Explorer.Network)
 		}
 	}
 
+	var redactMode api.RedactMode
+	if err := redactMode.UnmarshalText([]byte(cfg.Log.Redact)); err != nil {
+		return fmt.Errorf("invalid log redaction mode: %w", err)
+	} else if cfg.HTTP.ReadTimeout < 0 || cfg.HTTP.WriteTimeout < 0 || cfg.HTTP.IdleTimeout < 0 || cfg.HTTP.MaxHeaderBytes < 0 {
+		return errors.New("HTTP timeouts and max header size must not be negative")
+	} else if cfg.SLO.SignLatency > 0 && (cfg.SLO.Target <= 0 || cfg.SLO.Target >= 1) {
+		return fmt.Errorf("invalid slo target %v: must be between 0 and 1", cfg.SLO.Target)
+	}
+
+	tlsConfig, err := loadTLSConfig(cfg.HTTP.TLS)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	apiOpts := []api.ServerOption{
+		api.WithAddressBook(store),
+		api.WithAPITokens(store),
+		api.WithWebhooks(hooks),
+		api.WithWatchOnly(cfg.WatchOnly),
+		api.WithMinClientVersion(cfg.HTTP.MinClientVersion),
+		api.WithSlowRequestThreshold(cfg.HTTP.SlowRequestThreshold),
+		api.WithSigningSLO(api.SLO{Latency: cfg.SLO.SignLatency, Target: cfg.SLO.Target}),
+		api.WithLogRedaction(redactMode),
+		api.WithFeeBounds(cfg.Fees.Min, cfg.Fees.Max),
+		api.WithContractGuardrails(api.ContractGuardrails{
+			RevisionNumber: cfg.Contracts.CheckRevisionNumber,
+			HostPayout:     cfg.Contracts.CheckHostPayout,
+		}),
+	}
+	if notifier != nil {
+		apiOpts = append(apiOpts, api.WithNotifier(notifier))
+	}
+	if len(cfg.HTTP.TLS.ClientPermissions) > 0 {
+		apiOpts = append(apiOpts, api.WithAuthorizer(api.SubjectAuthorizer(cfg.HTTP.TLS.ClientPermissions)))
+	}
+	if ref := cfg.Vault.ApprovalSecret; ref != "" {
+		if !config.IsSecretReference(ref) {
+			return errors.New("vault.approvalSecret must be a secret reference, such as exec:")
+		}
+		apiOpts = append(apiOpts, api.WithUnlockApproval(func(context.Context) (string, error) {
+			return config.ResolveSecret(ref)
+		}))
+	}
+	if cfg.Custody.Interval > 0 {
+		committer := custody.NewCommitter(vault)
+		go refreshCommitment(ctx, committer, cfg.Custody.Interval, log.Named("custody"))
+		apiOpts = append(apiOpts, api.WithCustody(committer))
+	}
+	if !devMode {
+		// dev mode never touches the data directory
+		snapshots, err := snapshot.NewManager(filepath.Join(cfg.Directory, "snapshots"), store)
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot manager: %w", err)
+		}
+		apiOpts = append(apiOpts, api.WithSnapshots(snapshots))
+	}
+	if cfg.Hooks.Sign != "" {
+		h, err := hook.NewExec(cfg.Hooks.Sign, cfg.Hooks.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid sign hook: %w", err)
+		}
+		apiOpts = append(apiOpts, api.WithSignHook(h))
+	}
+	if len(cfg.Maintenance.Windows) > 0 {
+		windows, err := parseTimeWindows(cfg.Maintenance.Windows)
+		if err != nil {
+			return fmt.Errorf("invalid maintenance window: %w", err)
+		}
+		apiOpts = append(apiOpts, api.WithMaintenanceWindows(windows, cfg.Maintenance.OverrideDuration))
+	}
+	if len(cfg.SignPolicies) > 0 {
+		policies, err := parseSignPolicies(cfg.SignPolicies)
+		if err != nil {
+			return fmt.Errorf("invalid sign policy: %w", err)
+		}
+		apiOpts = append(apiOpts, api.WithSignPolicies(policies))
+	}
+	if cfg.ReplayWindow > 0 {
+		apiOpts = append(apiOpts, api.WithReplayProtection(store, cfg.ReplayWindow))
+	}
+	srv := api.NewServer(manager, vault, log.Named("api"), apiOpts...)
+
+	if cfg.Serial.Device != "" {
+		// the serial transport does not go through the HTTP API, but its
+		// requests are checked the same way
+		port, err := serial.Open(cfg.Serial.Device, cfg.Serial.Baud)
+		if err != nil {
+			return fmt.Errorf("failed to open serial device %q: %w", cfg.Serial.Device, err)
+		}
+		defer port.Close()
+		go func() {
+			if err := serial.Serve(port, srv, log.Named("serial")); err != nil && !errors.Is(err, os.ErrClosed) {
+				log.Error("serial transport failed", zap.Error(err))
+			}
+		}()
+		log.Info("serving sign requests over serial", zap.String("device", cfg.Serial.Device), zap.Int("baud", cfg.Serial.Baud))
+	}
+
 	server := &http.Server{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: time.Minute,
-		Handler:      jape.BasicAuth(cfg.HTTP.Password)(api.Handler(manager, vault, log.Named("api"))),
+		TLSConfig:      tlsConfig,
+		ReadTimeout:    cfg.HTTP.ReadTimeout,
+		WriteTimeout:   cfg.HTTP.WriteTimeout,
+		IdleTimeout:    cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes: cfg.HTTP.MaxHeaderBytes,
+		Handler:        api.AuthenticateTokens(cfg.HTTP.Password, store, srv),
 	}
 	defer server.Close()
-	go func() {
-		if err := server.Serve(httpListener); !errors.Is(err, http.ErrServerClosed) {
-			log.Error("HTTP server failed", zap.Error(err))
+	for _, l := range httpListeners {
+		go func() {
+			serve := server.Serve
+			if tlsConfig != nil {
+				serve = func(l net.Listener) error { return server.ServeTLS(l, "", "") }
+			}
+			if err := serve(l); !errors.Is(err, http.ErrServerClosed) {
+				log.Error("HTTP server failed", zap.String("address", l.Addr().String()), zap.Error(err))
+			}
+		}()
+	}
+
+	if debugListener != nil {
+		go serveDebug(debugListener, cfg.HTTP.Password, log.Named("debug"))
+		log.Info("serving pprof endpoints", zap.String("address", debugListener.Addr().String()))
+	}
+
+	if cfg.Tor.ControlAddress != "" {
+		keyPath := filepath.Join(cfg.Directory, "onion.key")
+		if devMode {
+			keyPath = "" // dev mode never touches the data directory
 		}
-	}()
+		service, err := tor.Publish(tor.Options{
+			ControlAddress:  cfg.Tor.ControlAddress,
+			ControlPassword: cfg.Tor.ControlPassword,
+			KeyPath:         keyPath,
+			Port:            cfg.Tor.Port,
+			Target:          localAddr(httpListeners[0].Addr()),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish onion service: %w", err)
+		}
+		defer service.Close()
+		log.Info("published onion service", zap.String("address", service.Address()))
+	}
 
-	log.Info("vaultd started", zap.String("http", cfg.HTTP.Address))
+	log.Info("vaultd started", zap.Strings("http", cfg.HTTP.Address), zap.Bool("tls", tlsConfig != nil))
 	<-ctx.Done()
 	log.Debug("shutting down")
 	time.AfterFunc(10*time.Second, func() {
@@ -77,3 +352,182 @@ func run(ctx context.Context, log *zap.Logger) error {
 	})
 	return nil
 }
+
+// migrateDatabase opens the database, applying any pending migrations, and
+// closes it.
+func migrateDatabase(log *zap.Logger) error {
+	start := time.Now()
+	store, err := sqlite.OpenDatabase(filepath.Join(cfg.Directory, "vaultd.sqlite3"), sqlite.WithLogger(log.Named("sqlite3")))
+	if err != nil {
+		return err
+	} else if err := store.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	log.Info("database is up to date", zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// loadTLSConfig returns the TLS configuration of the HTTP API, or nil if
+// TLS is disabled.
+func loadTLSConfig(c config.TLS) (*tls.Config, error) {
+	tlsConfig, err := loadServerTLSConfig(c)
+	switch {
+	case err != nil:
+		return nil, err
+	case c.ClientCAFile == "" && len(c.ClientPermissions) > 0:
+		return nil, errors.New("client permissions require a client CA")
+	case c.ClientCAFile == "":
+		return tlsConfig, nil
+	case tlsConfig == nil:
+		return nil, errors.New("client certificates require a server certificate or ACME")
+	}
+
+	buf, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, fmt.Errorf("no certificates found in %q", c.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(c.ACME.Domains) > 0 {
+		// the certificate authority cannot present a client certificate
+		// when completing the TLS-ALPN challenge
+		challengeConfig := tlsConfig.Clone()
+		challengeConfig.ClientAuth = tls.NoClientCert
+		tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			if slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+				return challengeConfig, nil
+			}
+			return nil, nil
+		}
+	}
+	return tlsConfig, nil
+}
+
+// loadServerTLSConfig returns the TLS configuration for the server's
+// certificate, or nil if TLS is disabled.
+func loadServerTLSConfig(c config.TLS) (*tls.Config, error) {
+	switch {
+	case c.CertFile != "" && len(c.ACME.Domains) > 0:
+		return nil, errors.New("a certificate and ACME domains cannot both be configured")
+	case c.CertFile != "" || c.KeyFile != "":
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("both a certificate and key file are required")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	case len(c.ACME.Domains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(c.ACME.Domains...),
+			Email:      c.ACME.Email,
+		}
+		if c.ACME.DirectoryURL != "" {
+			m.Client = &acme.Client{DirectoryURL: c.ACME.DirectoryURL}
+		}
+		if !devMode {
+			// dev mode never touches the data directory
+			m.Cache = autocert.DirCache(filepath.Join(cfg.Directory, "acme"))
+		}
+		tlsConfig := m.TLSConfig()
+		tlsConfig.MinVersion = tls.VersionTLS12
+		return tlsConfig, nil
+	default:
+		return nil, nil
+	}
+}
+
+// localAddr returns an address that connects to the listener address from
+// the local host.
+func localAddr(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(tcp.Port))
+}
+
+// parseTimeWindows converts the configured time windows to their API form.
+func parseTimeWindows(windows []config.TimeWindow) ([]api.TimeWindow, error) {
+	parseTime := func(s string) (time.Duration, error) {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+		}
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+	parseDay := func(s string) (time.Weekday, error) {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if name := d.String(); strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+				return d, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown day %q", s)
+	}
+
+	parsed := make([]api.TimeWindow, 0, len(windows))
+	for i, w := range windows {
+		var mw api.TimeWindow
+		for _, s := range w.Days {
+			day, err := parseDay(s)
+			if err != nil {
+				return nil, fmt.Errorf("window %d: %w", i, err)
+			}
+			mw.Days = append(mw.Days, day)
+		}
+		var err error
+		if mw.Start, err = parseTime(w.Start); err != nil {
+			return nil, fmt.Errorf("window %d: start: %w", i, err)
+		} else if mw.End, err = parseTime(w.End); err != nil {
+			return nil, fmt.Errorf("window %d: end: %w", i, err)
+		}
+		if w.Timezone != "" {
+			if mw.Location, err = time.LoadLocation(w.Timezone); err != nil {
+				return nil, fmt.Errorf("window %d: invalid timezone: %w", i, err)
+			}
+		}
+		parsed = append(parsed, mw)
+	}
+	return parsed, nil
+}
+
+// parseSignPolicies converts the configured sign policies to their API
+// form.
+func parseSignPolicies(policies []config.SignPolicy) ([]api.SignPolicy, error) {
+	parsed := make([]api.SignPolicy, 0, len(policies))
+	for i, p := range policies {
+		var sp api.SignPolicy
+		for _, id := range p.Seeds {
+			sp.Seeds = append(sp.Seeds, vault.SeedID(id))
+		}
+		times, err := parseTimeWindows(p.Times)
+		if err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+		sp.Times = times
+		for _, s := range p.Networks {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("policy %d: invalid network: %w", i, err)
+			}
+			sp.Networks = append(sp.Networks, prefix.Masked())
+		}
+		for _, c := range p.Credentials {
+			if kind, name, _ := strings.Cut(c, ":"); !(c == "password" || ((kind == "token" || kind == "cert") && name != "")) {
+				return nil, fmt.Errorf(`policy %d: invalid credential %q, expected "password", "token:<name>", or "cert:<common name>"`, i, c)
+			}
+		}
+		sp.Credentials = p.Credentials
+		parsed = append(parsed, sp)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go.sia.tech/vaultd/api"
+)
+
+// printSnapshots prints the snapshots of a running vaultd instance.
+func printSnapshots(ctx context.Context, client *api.Client) error {
+	snapshots, err := client.Snapshots(ctx)
+	if err != nil {
+		return err
+	} else if len(snapshots) == 0 {
+		fmt.Println("no snapshots")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tSIZE")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", s.Name, s.CreatedAt.Format(time.RFC3339), s.Size)
+	}
+	return w.Flush()
+}
+
+// createSnapshot creates a snapshot on a running vaultd instance.
+func createSnapshot(ctx context.Context, client *api.Client, name string) error {
+	s, err := client.CreateSnapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created snapshot %q (%d bytes)\n", s.Name, s.Size)
+	return nil
+}
+
+// restoreSnapshot restores a snapshot on a running vaultd instance.
+func restoreSnapshot(ctx context.Context, client *api.Client, name string) error {
+	backup, err := client.RestoreSnapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored snapshot %q, the previous state was saved as %q\n", name, backup.Name)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.sia.tech/vaultd/api"
+)
+
+// printStatus queries the state of a running vaultd instance and prints a
+// one line summary. An error is returned if the instance is unreachable or
+// read-only.
+func printStatus(ctx context.Context, addr, password string) error {
+	client := api.NewClient(addr, password)
+	state, err := client.State(ctx)
+	if err != nil {
+		return err
+	}
+
+	locked := "locked"
+	if state.Unlocked {
+		locked = "unlocked"
+	}
+	fmt.Printf("vaultd %s (%s) %s, up %s\n", state.Version, state.Commit, locked, time.Since(state.StartTime).Round(time.Second))
+	if state.ReadOnly != "" {
+		return errors.New(state.ReadOnly)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.sia.tech/vaultd/config"
+)
+
+// upgradeConfigFile upgrades the config file at fp to the current version
+// in place. The file is replaced atomically and keeps its permissions.
+func upgradeConfigFile(fp string) error {
+	info, err := os.Stat(fp)
+	if err != nil {
+		return err
+	}
+	buf, err := os.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+	upgraded, deprecated, err := config.Upgrade(buf)
+	if err != nil {
+		return err
+	} else if bytes.Equal(upgraded, buf) {
+		fmt.Printf("%s is already up to date\n", fp)
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fp), filepath.Base(fp)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(upgraded); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	} else if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	} else if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	} else if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	} else if err := os.Rename(tmp.Name(), fp); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+
+	fmt.Printf("upgraded %s to version %d\n", fp, config.CurrentVersion)
+	for _, key := range deprecated {
+		fmt.Printf("replaced deprecated key %q\n", key)
+	}
+	return nil
+}
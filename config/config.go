@@ -2,18 +2,81 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"go.sia.tech/core/types"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
 
 type (
+	// Addresses is a list of addresses. In YAML it can be a single address
+	// or a list; as text it is a comma-separated list.
+	Addresses []string
+
 	// HTTP contains the configuration for the HTTP server.
 	HTTP struct {
-		Address  string `yaml:"address,omitempty"`
-		Password string `yaml:"password,omitempty"`
+		// Address is the address, or list of addresses, the HTTP API
+		// listens on.
+		Address  Addresses `yaml:"address,omitempty"`
+		Password string    `yaml:"password,omitempty"`
+		// MinClientVersion rejects clients that declare an older API
+		// version. Zero accepts every client.
+		MinClientVersion int `yaml:"minClientVersion,omitempty"`
+		// SlowRequestThreshold logs a warning for requests that take
+		// longer. Zero disables the warnings.
+		SlowRequestThreshold time.Duration `yaml:"slowRequestThreshold,omitempty"`
+		// ReadTimeout is the maximum duration for reading a request,
+		// including the body. Zero disables the timeout.
+		ReadTimeout time.Duration `yaml:"readTimeout,omitempty"`
+		// WriteTimeout is the maximum duration before timing out the
+		// response of a request. Zero disables the timeout.
+		WriteTimeout time.Duration `yaml:"writeTimeout,omitempty"`
+		// IdleTimeout is the maximum duration to wait for the next
+		// request on a keep-alive connection. Zero uses ReadTimeout.
+		IdleTimeout time.Duration `yaml:"idleTimeout,omitempty"`
+		// MaxHeaderBytes is the maximum size of request headers. Zero
+		// uses the net/http default of 1 MiB.
+		MaxHeaderBytes int `yaml:"maxHeaderBytes,omitempty"`
+		// TLS serves the API over HTTPS.
+		TLS TLS `yaml:"tls,omitempty"`
+	}
+
+	// TLS configures HTTPS for the HTTP API using either a certificate
+	// and key or certificates obtained automatically with ACME.
+	TLS struct {
+		// CertFile and KeyFile are the paths of a PEM encoded certificate
+		// chain and private key.
+		CertFile string `yaml:"certFile,omitempty"`
+		KeyFile  string `yaml:"keyFile,omitempty"`
+		// ACME obtains and renews certificates for the domains. The
+		// TLS-ALPN challenge requires the API to be reachable on port
+		// 443.
+		ACME ACME `yaml:"acme,omitempty"`
+		// ClientCAFile is the path of a PEM bundle of certificate
+		// authorities. If set, every client must present a certificate
+		// signed by one of them.
+		ClientCAFile string `yaml:"clientCAFile,omitempty"`
+		// ClientPermissions maps the common names of client certificates
+		// to the routes they may call, e.g. "GET /seeds", or "*" for
+		// every route. If empty, every verified certificate may call
+		// every route.
+		ClientPermissions map[string][]string `yaml:"clientPermissions,omitempty"`
+	}
+
+	// ACME configures automatic certificates.
+	ACME struct {
+		Domains []string `yaml:"domains,omitempty"`
+		// Email is the optional contact address registered with the
+		// certificate authority.
+		Email string `yaml:"email,omitempty"`
+		// DirectoryURL is the ACME directory of the certificate
+		// authority. Empty uses Let's Encrypt.
+		DirectoryURL string `yaml:"directoryURL,omitempty"`
 	}
 
 	// LogFile configures the file output of the logger.
@@ -37,34 +100,294 @@ type (
 	Log struct {
 		StdOut StdOut  `yaml:"stdout,omitempty"`
 		File   LogFile `yaml:"file,omitempty"`
+		// Redact controls how public keys, addresses, and transaction IDs
+		// are logged: "" logs them in full, "hash" replaces them with a
+		// short hash, and "truncate" keeps only a short prefix.
+		Redact string `yaml:"redact,omitempty"`
 	}
 
 	// Explorer contains the configuration for the optional blockchain explorer.
 	Explorer struct {
 		Network string `yaml:"network,omitempty"`
 		URL     string `yaml:"url,omitempty"`
+		// PinnedKeys are base64-encoded SHA-256 hashes of the primary
+		// explorer certificate's SubjectPublicKeyInfo. If set, the
+		// explorer's certificate chain must contain one of them.
+		PinnedKeys []string `yaml:"pinnedKeys,omitempty"`
+		// VerifyURL is an optional second explorer whose tip state must
+		// agree with the primary explorer's within VerifyTolerance blocks.
+		// VerifyPinnedKeys are pinned for it like PinnedKeys.
+		VerifyURL        string   `yaml:"verifyURL,omitempty"`
+		VerifyTolerance  uint64   `yaml:"verifyTolerance,omitempty"`
+		VerifyPinnedKeys []string `yaml:"verifyPinnedKeys,omitempty"`
+		// Headers are added to every request to the primary explorer,
+		// for example to authenticate with a gateway. VerifyHeaders are
+		// added to every request to the verification explorer.
+		Headers       map[string]string `yaml:"headers,omitempty"`
+		VerifyHeaders map[string]string `yaml:"verifyHeaders,omitempty"`
+	}
+
+	// Serial contains the configuration for the optional serial signing
+	// transport.
+	Serial struct {
+		Device string `yaml:"device,omitempty"`
+		Baud   int    `yaml:"baud,omitempty"`
+	}
+
+	// SMTP contains the configuration for the optional email notifier.
+	SMTP struct {
+		Address  string   `yaml:"address,omitempty"`
+		Username string   `yaml:"username,omitempty"`
+		Password string   `yaml:"password,omitempty"`
+		From     string   `yaml:"from,omitempty"`
+		To       []string `yaml:"to,omitempty"`
+	}
+
+	// Tor configures the optional onion service published through the
+	// control port of an external Tor daemon.
+	Tor struct {
+		ControlAddress  string `yaml:"controlAddress,omitempty"`
+		ControlPassword string `yaml:"controlPassword,omitempty"`
+		// Port is the virtual port of the onion service.
+		Port int `yaml:"port,omitempty"`
+	}
+
+	// KeyAudit configures the optional background job that re-derives a
+	// random sample of stored keys while the vault is unlocked.
+	KeyAudit struct {
+		// Interval is the time between audits. Zero disables the audit.
+		Interval   time.Duration `yaml:"interval,omitempty"`
+		SampleSize int           `yaml:"sampleSize,omitempty"`
+	}
+
+	// Custody configures the optional Merkle commitment over the vault's
+	// keys.
+	Custody struct {
+		// Interval is the time between commitments. Zero disables the
+		// commitment endpoints.
+		Interval time.Duration `yaml:"interval,omitempty"`
+	}
+
+	// Fees bounds the recommended fee per byte returned by the API. A
+	// zero Max disables the upper bound.
+	Fees struct {
+		Min types.Currency `yaml:"min,omitempty"`
+		Max types.Currency `yaml:"max,omitempty"`
+	}
+
+	// Vault configures the vault's key handling.
+	Vault struct {
+		// AutoLockAfter locks the vault when no key has been used to sign
+		// or been derived for the duration. Zero disables auto-locking.
+		AutoLockAfter time.Duration `yaml:"autoLockAfter,omitempty"`
+		// ApprovalSecret is a "file:", "env:", or "exec:" reference to
+		// the vault secret, typically a secret manager's CLI. It is only
+		// resolved when an administrator approves an unlock request, so
+		// the secret never passes through the machine requesting the
+		// unlock. Empty disables the approval unlock flow.
+		ApprovalSecret string `yaml:"approvalSecret,omitempty"`
+		// ExpiryWarning is how long before a seed or key expires that
+		// operators are notified. Zero disables the notifications.
+		ExpiryWarning time.Duration `yaml:"expiryWarning,omitempty"`
+		// HardwareToken is a command, such as "ykchalresp -2 -x", that
+		// answers a hardware token challenge. Unlocking then requires
+		// both the secret and the token. It must be set before the vault
+		// is first unlocked. Empty disables the hardware token.
+		HardwareToken string `yaml:"hardwareToken,omitempty"`
+	}
+
+	// A TimeWindow is a weekly period of time.
+	TimeWindow struct {
+		// Days are the days of the week the window starts on, such as
+		// "sat". Empty starts the window every day.
+		Days []string `yaml:"days,omitempty"`
+		// Start and End are the times of day, such as "18:00", the
+		// window starts and ends. A window that ends at or before its
+		// start ends the next day.
+		Start string `yaml:"start"`
+		End   string `yaml:"end"`
+		// Timezone is the IANA time zone of Start and End. Empty is UTC.
+		Timezone string `yaml:"timezone,omitempty"`
+	}
+
+	// Maintenance configures scheduled periods during which the vault
+	// refuses to sign.
+	Maintenance struct {
+		Windows []TimeWindow `yaml:"windows,omitempty"`
+		// OverrideDuration is how long signing is allowed during a
+		// window after an emergency override is approved.
+		OverrideDuration time.Duration `yaml:"overrideDuration,omitempty"`
+	}
+
+	// A SignPolicy restricts the requests that can sign with the keys of
+	// its seeds. Every condition that is set must be met.
+	SignPolicy struct {
+		// Seeds are the IDs of the seeds the policy applies to. Empty
+		// applies to every seed.
+		Seeds []int64 `yaml:"seeds,omitempty"`
+		// Times are the windows during which signing is allowed.
+		Times []TimeWindow `yaml:"times,omitempty"`
+		// Networks are the CIDR ranges the request's source address must
+		// be in.
+		Networks []string `yaml:"networks,omitempty"`
+		// Credentials are the credentials allowed to sign: "password",
+		// "token:<name>", or "cert:<common name>".
+		Credentials []string `yaml:"credentials,omitempty"`
+	}
+
+	// Contracts configures checks on the file contracts of v2
+	// transactions before they are signed.
+	Contracts struct {
+		// CheckRevisionNumber requires revisions to increase the
+		// revision number.
+		CheckRevisionNumber bool `yaml:"checkRevisionNumber,omitempty"`
+		// CheckHostPayout requires revisions and renewals not to
+		// decrease the host's payout or risk more than its collateral.
+		CheckHostPayout bool `yaml:"checkHostPayout,omitempty"`
+	}
+
+	// SLO configures the latency objective for sign requests reported by
+	// the API's stats. A zero SignLatency disables the objective.
+	SLO struct {
+		SignLatency time.Duration `yaml:"signLatency,omitempty"`
+		// Target is the fraction of sign requests that should complete
+		// within SignLatency.
+		Target float64 `yaml:"target,omitempty"`
+	}
+
+	// Storage configures soft limits on the size of the database. A
+	// warning is logged and operators are notified when a limit is
+	// exceeded. Zero disables a limit.
+	Storage struct {
+		// MaxSize is the size of the database in bytes.
+		MaxSize int64 `yaml:"maxSize,omitempty"`
+		// MaxKeys is the number of derived keys.
+		MaxKeys int `yaml:"maxKeys,omitempty"`
+	}
+
+	// Hooks configures commands run in response to vault events.
+	Hooks struct {
+		// Sign is run after each successful sign request with the event
+		// as JSON on stdin. Arguments are split on whitespace.
+		Sign string `yaml:"sign,omitempty"`
+		// Timeout is the time a command can run before it is killed.
+		Timeout time.Duration `yaml:"timeout,omitempty"`
+	}
+
+	// Debug configures the optional pprof listener.
+	Debug struct {
+		// Address is the address of a separate listener serving the
+		// pprof endpoints. The endpoints require the API password. Empty
+		// disables the listener.
+		Address string `yaml:"address,omitempty"`
 	}
 
 	// Config contains the configuration for the host.
 	Config struct {
+		// Version is the version of the config file format. Older files
+		// are upgraded when they are loaded.
+		Version       int    `yaml:"version,omitempty"`
 		Secret        string `yaml:"secret,omitempty"`
 		Directory     string `yaml:"directory,omitempty"`
 		AutoOpenWebUI bool   `yaml:"autoOpenWebUI,omitempty"`
+		// WatchOnly disables seed import and signing. A watch-only
+		// instance exports sign requests for an offline signer.
+		WatchOnly bool `yaml:"watchOnly,omitempty"`
+		// LookAhead is the number of keys past the last derived index of
+		// each seed searched when a sign request references an unknown
+		// key. Zero disables the search.
+		LookAhead uint64 `yaml:"lookAhead,omitempty"`
+		// SeedRetention is how long a deleted seed can be restored before
+		// it is purged.
+		SeedRetention time.Duration `yaml:"seedRetention,omitempty"`
+		// ReplayWindow enables replay protection. Sign requests must
+		// include a unique nonce and a timestamp within the window of the
+		// current time. Zero disables replay protection.
+		ReplayWindow time.Duration `yaml:"replayWindow,omitempty"`
+		// SignPolicies restrict the requests that can sign with each
+		// seed's keys.
+		SignPolicies []SignPolicy `yaml:"signPolicies,omitempty"`
 
-		HTTP     HTTP     `yaml:"http,omitempty"`
-		Log      Log      `yaml:"log,omitempty"`
-		Explorer Explorer `yaml:"explorer,omitempty"`
+		HTTP        HTTP        `yaml:"http,omitempty"`
+		Log         Log         `yaml:"log,omitempty"`
+		Explorer    Explorer    `yaml:"explorer,omitempty"`
+		Vault       Vault       `yaml:"vault,omitempty"`
+		Maintenance Maintenance `yaml:"maintenance,omitempty"`
+		Serial      Serial      `yaml:"serial,omitempty"`
+		SMTP        SMTP        `yaml:"smtp,omitempty"`
+		KeyAudit    KeyAudit    `yaml:"keyAudit,omitempty"`
+		Tor         Tor         `yaml:"tor,omitempty"`
+		Custody     Custody     `yaml:"custody,omitempty"`
+		Fees        Fees        `yaml:"fees,omitempty"`
+		Contracts   Contracts   `yaml:"contracts,omitempty"`
+		SLO         SLO         `yaml:"slo,omitempty"`
+		Storage     Storage     `yaml:"storage,omitempty"`
+		Hooks       Hooks       `yaml:"hooks,omitempty"`
+		Debug       Debug       `yaml:"debug,omitempty"`
 	}
 )
 
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (a *Addresses) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*a = Addresses{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var addrs []string
+		if err := value.Decode(&addrs); err != nil {
+			return err
+		}
+		*a = addrs
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected an address or a list of addresses", value.Line)
+	}
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (a Addresses) MarshalYAML() (any, error) {
+	if len(a) == 1 {
+		return a[0], nil
+	}
+	return []string(a), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Addresses) UnmarshalText(b []byte) error {
+	var addrs Addresses
+	for s := range strings.SplitSeq(string(b), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			addrs = append(addrs, s)
+		}
+	}
+	if len(addrs) == 0 {
+		return errors.New("no addresses")
+	}
+	*a = addrs
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Addresses) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(a, ",")), nil
+}
+
 // LoadFile loads the configuration from the provided file path.
 // If the file does not exist, an error is returned.
-// If the file exists but cannot be decoded, the function will attempt
-// to upgrade the config file.
-func LoadFile(fp string, cfg *Config) error {
+// Secret fields can reference a "file:", "env:", or "exec:" indirection
+// that is resolved after the file is decoded.
+// Config files from older versions are upgraded in memory; the deprecated
+// keys they use are returned so they can be reported to the user.
+func LoadFile(fp string, cfg *Config) (deprecated []string, err error) {
 	buf, err := os.ReadFile(fp)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	buf, deprecated, err = Upgrade(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade config file: %w", err)
 	}
 
 	r := bytes.NewReader(buf)
@@ -72,7 +395,9 @@ func LoadFile(fp string, cfg *Config) error {
 	dec.KnownFields(true)
 
 	if err := dec.Decode(cfg); err != nil {
-		return fmt.Errorf("failed to decode config file: %w", err)
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	} else if err := cfg.ResolveSecrets(); err != nil {
+		return nil, fmt.Errorf("failed to resolve config secrets: %w", err)
 	}
-	return nil
+	return deprecated, nil
 }
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAddresses(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want Addresses
+	}{
+		{"address: localhost:9980", Addresses{"localhost:9980"}},
+		{"address: [localhost:9980, 100.64.0.1:9980]", Addresses{"localhost:9980", "100.64.0.1:9980"}},
+	}
+	for _, test := range tests {
+		var h HTTP
+		if err := yaml.Unmarshal([]byte(test.yaml), &h); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(h.Address, test.want) {
+			t.Fatalf("expected %v, got %v", test.want, h.Address)
+		}
+	}
+
+	var a Addresses
+	if err := a.UnmarshalText([]byte("localhost:9980, 100.64.0.1:9980")); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(a, Addresses{"localhost:9980", "100.64.0.1:9980"}) {
+		t.Fatalf("unexpected addresses %v", a)
+	} else if err := a.UnmarshalText([]byte(" , ")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestFees(t *testing.T) {
+	var c Config
+	if err := yaml.Unmarshal([]byte("fees:\n  min: 10 nS\n  max: 1 uS\n"), &c); err != nil {
+		t.Fatal(err)
+	} else if !c.Fees.Min.Equals(types.Siacoins(10).Div64(1e9)) || !c.Fees.Max.Equals(types.Siacoins(1).Div64(1e6)) {
+		t.Fatalf("unexpected fees %+v", c.Fees)
+	}
+}
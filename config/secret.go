@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// secretPrefixes are the prefixes of secret references.
+var secretPrefixes = []string{"file:", "env:", "exec:", "systemd:", "vaultkv:", "gcp:", "aws:"}
+
+// resolveSecret resolves a secret indirection. Values prefixed with "file:"
+// are read from the named file, "env:" from the named environment variable,
+// and "exec:" from the standard output of the command. "systemd:" reads a
+// systemd service credential, "vaultkv:" a HashiCorp Vault KV secret,
+// "gcp:" a GCP Secret Manager secret, and "aws:" an AWS Secrets Manager
+// secret. Trailing newlines are removed. Other values are returned
+// unchanged.
+func resolveSecret(s string) (string, error) {
+	var value string
+	var err error
+	switch {
+	case strings.HasPrefix(s, "file:"):
+		buf, err := os.ReadFile(strings.TrimPrefix(s, "file:"))
+		if err != nil {
+			// not wrapped so a missing secret file is not mistaken for a
+			// missing config file
+			return "", fmt.Errorf("failed to read secret file: %v", err)
+		}
+		value = string(buf)
+	case strings.HasPrefix(s, "env:"):
+		name := strings.TrimPrefix(s, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q is not set", name)
+		}
+		value = v
+	case strings.HasPrefix(s, "exec:"):
+		args := strings.Fields(strings.TrimPrefix(s, "exec:"))
+		if len(args) == 0 {
+			return "", errors.New("missing command")
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stderr = os.Stderr
+		buf, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to run %q: %v", args[0], err)
+		}
+		value = string(buf)
+	case strings.HasPrefix(s, "systemd:"):
+		value, err = systemdCredential(strings.TrimPrefix(s, "systemd:"))
+	case strings.HasPrefix(s, "vaultkv:"):
+		value, err = vaultKVSecret(strings.TrimPrefix(s, "vaultkv:"))
+	case strings.HasPrefix(s, "gcp:"):
+		value, err = gcpSecret(strings.TrimPrefix(s, "gcp:"))
+	case strings.HasPrefix(s, "aws:"):
+		value, err = awsSecret(strings.TrimPrefix(s, "aws:"))
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(value, "\r\n"), nil
+}
+
+// IsSecretReference returns true if s is a secret reference, such as
+// "file:" or "exec:".
+func IsSecretReference(s string) bool {
+	return slices.ContainsFunc(secretPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(s, prefix)
+	})
+}
+
+// ResolveSecret resolves a secret reference. Values that are not a
+// reference are an error.
+func ResolveSecret(s string) (string, error) {
+	if !IsSecretReference(s) {
+		return "", fmt.Errorf("secret must be a reference starting with one of %s", strings.Join(secretPrefixes, ", "))
+	}
+	return resolveSecret(s)
+}
+
+// ResolveSecrets resolves the secret indirections of every field that can
+// contain a secret. [LoadFile] resolves them after decoding the file, so it
+// only needs to be called for values set another way, such as from the
+// environment when there is no config file.
+func (c *Config) ResolveSecrets() error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"secret", &c.Secret},
+		{"http.password", &c.HTTP.Password},
+		{"smtp.password", &c.SMTP.Password},
+		{"tor.controlPassword", &c.Tor.ControlPassword},
+	}
+	for _, f := range fields {
+		v, err := resolveSecret(*f.value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", f.name, err)
+		}
+		*f.value = v
+	}
+	for k, v := range c.Explorer.Headers {
+		v, err := resolveSecret(v)
+		if err != nil {
+			return fmt.Errorf("failed to resolve explorer header %q: %w", k, err)
+		}
+		c.Explorer.Headers[k] = v
+	}
+	for k, v := range c.Explorer.VerifyHeaders {
+		v, err := resolveSecret(v)
+		if err != nil {
+			return fmt.Errorf("failed to resolve explorer verification header %q: %w", k, err)
+		}
+		c.Explorer.VerifyHeaders[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretPath, []byte("file secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULTD_TEST_PASSWORD", "env password")
+
+	cfgPath := filepath.Join(dir, "vaultd.yml")
+	err := os.WriteFile(cfgPath, []byte(`secret: file:`+secretPath+`
+http:
+  password: env:VAULTD_TEST_PASSWORD
+smtp:
+  password: exec:echo exec password
+explorer:
+  headers:
+    X-Api-Key: plain
+  verifyHeaders:
+    X-Api-Key: env:VAULTD_TEST_PASSWORD
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg Config
+	if _, err := LoadFile(cfgPath, &cfg); err != nil {
+		t.Fatal(err)
+	} else if cfg.Secret != "file secret" {
+		t.Fatalf("expected file secret, got %q", cfg.Secret)
+	} else if cfg.HTTP.Password != "env password" {
+		t.Fatalf("expected env password, got %q", cfg.HTTP.Password)
+	} else if cfg.SMTP.Password != "exec password" {
+		t.Fatalf("expected exec password, got %q", cfg.SMTP.Password)
+	} else if cfg.Explorer.Headers["X-Api-Key"] != "plain" {
+		t.Fatalf("expected plain header, got %q", cfg.Explorer.Headers["X-Api-Key"])
+	} else if cfg.Explorer.VerifyHeaders["X-Api-Key"] != "env password" {
+		t.Fatalf("expected env verification header, got %q", cfg.Explorer.VerifyHeaders["X-Api-Key"])
+	}
+
+	// a missing secret file must not look like a missing config file
+	if err := os.WriteFile(cfgPath, []byte("secret: file:"+filepath.Join(dir, "missing")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(cfgPath, &cfg); err == nil {
+		t.Fatal("expected error")
+	} else if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("missing secret file reported as missing config file")
+	}
+
+	// values set without a config file, such as from the environment, are
+	// resolved by ResolveSecrets
+	cfg = Config{Secret: "file:" + secretPath}
+	cfg.HTTP.Password = "env:VAULTD_TEST_PASSWORD"
+	if err := cfg.ResolveSecrets(); err != nil {
+		t.Fatal(err)
+	} else if cfg.Secret != "file secret" {
+		t.Fatalf("expected file secret, got %q", cfg.Secret)
+	} else if cfg.HTTP.Password != "env password" {
+		t.Fatalf("expected env password, got %q", cfg.HTTP.Password)
+	}
+}
+
+func TestSecretSources(t *testing.T) {
+	// systemd credentials
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "vaultd-secret"), []byte("systemd secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	if v, err := resolveSecret("systemd:vaultd-secret"); err != nil {
+		t.Fatal(err)
+	} else if v != "systemd secret" {
+		t.Fatalf("expected systemd secret, got %q", v)
+	} else if _, err := resolveSecret("systemd:../vaultd-secret"); err == nil {
+		t.Fatal("expected path traversal to be rejected")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/secret/data/vaultd", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"secret":"vault kv secret"},"metadata":{"version":1}}}`))
+	})
+	mux.HandleFunc("GET /computeMetadata/v1/instance/service-accounts/default/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"access_token":"gcp token","expires_in":3599,"token_type":"Bearer"}`))
+	})
+	mux.HandleFunc("GET /gcp/projects/p/secrets/vaultd/versions/latest:access", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gcp token" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name":"projects/p/secrets/vaultd/versions/1","payload":{"data":"` + base64.StdEncoding.EncodeToString([]byte("gcp secret")) + `"}}`))
+	})
+	mux.HandleFunc("POST /aws/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(r.Header.Get("Authorization"), "/us-west-2/secretsmanager/aws4_request") {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		var req struct {
+			SecretID string `json:"SecretId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SecretID != "vaultd" {
+			http.Error(w, "secret not found", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"Name":"vaultd","SecretString":"{\"secret\":\"aws secret\"}"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// HashiCorp Vault KV
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "vault token")
+	if v, err := resolveSecret("vaultkv:secret/vaultd#secret"); err != nil {
+		t.Fatal(err)
+	} else if v != "vault kv secret" {
+		t.Fatalf("expected vault kv secret, got %q", v)
+	} else if _, err := resolveSecret("vaultkv:secret/vaultd#missing"); err == nil {
+		t.Fatal("expected missing field to be rejected")
+	}
+
+	// GCP Secret Manager with a token from the metadata server
+	defaultGCPURL, defaultAWSURL := gcpSecretManagerURL, awsSecretsManagerURL
+	t.Cleanup(func() { gcpSecretManagerURL, awsSecretsManagerURL = defaultGCPURL, defaultAWSURL })
+	gcpSecretManagerURL = srv.URL + "/gcp/"
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	if v, err := resolveSecret("gcp:projects/p/secrets/vaultd"); err != nil {
+		t.Fatal(err)
+	} else if v != "gcp secret" {
+		t.Fatalf("expected gcp secret, got %q", v)
+	}
+
+	// AWS Secrets Manager
+	awsSecretsManagerURL = func(string) string { return srv.URL + "/aws/" }
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	if v, err := resolveSecret("aws:vaultd#secret"); err != nil {
+		t.Fatal(err)
+	} else if v != "aws secret" {
+		t.Fatalf("expected aws secret, got %q", v)
+	}
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	// the get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signAWSRequest(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	const expected = "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
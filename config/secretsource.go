@@ -0,0 +1,320 @@
+package config
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// secretSourceTimeout is the maximum time to fetch a secret from a secret
+// manager.
+const secretSourceTimeout = 30 * time.Second
+
+var (
+	// awsSecretsManagerURL returns the AWS Secrets Manager endpoint of
+	// the region.
+	awsSecretsManagerURL = func(region string) string {
+		return "https://secretsmanager." + region + ".amazonaws.com/"
+	}
+	// gcpSecretManagerURL is the base URL of the GCP Secret Manager API.
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+)
+
+var secretSourceClient = &http.Client{Timeout: secretSourceTimeout}
+
+// cutKey splits a reference into the secret's name and the optional JSON
+// key after a '#'.
+func cutKey(ref string) (name, key string) {
+	name, key, _ = strings.Cut(ref, "#")
+	return
+}
+
+// jsonField returns the string field of a JSON object.
+func jsonField(buf []byte, key string) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		return "", fmt.Errorf("failed to decode secret as JSON: %w", err)
+	}
+	v, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string field %q", key)
+	}
+	return v, nil
+}
+
+// doSecretRequest sends the request and returns the response body. Non-2xx
+// responses are an error.
+func doSecretRequest(req *http.Request) ([]byte, error) {
+	resp, err := secretSourceClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+	return buf, nil
+}
+
+// systemdCredential reads a credential passed to the service with
+// LoadCredential= or LoadCredentialEncrypted=.
+func systemdCredential(name string) (string, error) {
+	dir, ok := os.LookupEnv("CREDENTIALS_DIRECTORY")
+	if !ok {
+		return "", errors.New("CREDENTIALS_DIRECTORY is not set, is vaultd running as a systemd service with LoadCredential=?")
+	} else if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid credential name %q", name)
+	}
+	buf, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential: %v", err)
+	}
+	return string(buf), nil
+}
+
+// vaultKVSecret reads a field of a HashiCorp Vault KV version 2 secret.
+// The reference is "mount/path#field". The server and token are read from
+// VAULT_ADDR and VAULT_TOKEN.
+func vaultKVSecret(ref string) (string, error) {
+	path, field := cutKey(ref)
+	mount, path, ok := strings.Cut(path, "/")
+	if !ok || mount == "" || path == "" || field == "" {
+		return "", errors.New(`reference must be in the form "mount/path#field"`)
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = "https://127.0.0.1:8200"
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", errors.New("VAULT_TOKEN is not set")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+mount+"/data/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	buf, err := doSecretRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+
+	var resp struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return jsonField(resp.Data.Data, field)
+}
+
+// gcpAccessToken returns an OAuth access token from GOOGLE_OAUTH_ACCESS_TOKEN
+// or, if it is not set, the service account of the instance from the
+// metadata server.
+func gcpAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	buf, err := doSecretRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token from metadata server: %w", err)
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	return resp.AccessToken, nil
+}
+
+// gcpSecret reads a GCP Secret Manager secret version. The reference is
+// "projects/P/secrets/S", which reads the latest version, or
+// "projects/P/secrets/S/versions/V", optionally followed by "#field" to read
+// a field of a JSON secret.
+func gcpSecret(ref string) (string, error) {
+	name, field := cutKey(ref)
+	parts := strings.Split(name, "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets":
+		name += "/versions/latest"
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions":
+	default:
+		return "", errors.New(`reference must be in the form "projects/P/secrets/S[/versions/V]"`)
+	}
+
+	token, err := gcpAccessToken()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, gcpSecretManagerURL+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	buf, err := doSecretRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret: %w", err)
+	}
+
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode payload: %w", err)
+	} else if field != "" {
+		return jsonField(payload, field)
+	}
+	return string(payload), nil
+}
+
+// awsSecret reads an AWS Secrets Manager secret. The reference is the
+// secret's name or ARN, optionally followed by "#field" to read a field of
+// a JSON secret. Credentials are read from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN. The region is read from
+// the ARN or AWS_REGION.
+func awsSecret(ref string) (string, error) {
+	id, field := cutKey(ref)
+	if id == "" {
+		return "", errors.New("missing secret ID")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if arn := strings.Split(id, ":"); len(arn) > 3 && arn[0] == "arn" {
+		region = arn[3]
+	}
+	if region == "" {
+		return "", errors.New("AWS_REGION is not set")
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, awsSecretsManagerURL(region), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWSRequest(req, body, accessKey, secretKey, region, "secretsmanager", time.Now())
+	buf, err := doSecretRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret value: %w", err)
+	}
+
+	var resp struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	} else if field != "" {
+		return jsonField([]byte(resp.SecretString), field)
+	}
+	return resp.SecretString, nil
+}
+
+// signAWSRequest adds an AWS Signature Version 4 Authorization header to
+// the request. Every header already set on the request is signed.
+func signAWSRequest(req *http.Request, body []byte, accessKey, secretKey, region, service string, now time.Time) {
+	hmacSHA256 := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	sha256Hex := func(b []byte) string {
+		h := sha256.Sum256(b)
+		return hex.EncodeToString(h[:])
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
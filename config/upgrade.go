@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the config file format.
+const CurrentVersion = 2
+
+// upgrades is a list of functions that upgrade the config file from one
+// version to the next. Each function returns the deprecated keys it
+// replaced. The upgrade from version N to N+1 is at index N-1.
+var upgrades = []func(root *yaml.Node) []string{
+	// version 2: replace the flat log level and directory with the stdout
+	// and file sections
+	func(root *yaml.Node) (deprecated []string) {
+		log := mappingValue(root, "log")
+		if log == nil || log.Kind != yaml.MappingNode {
+			return nil
+		}
+		if level := removeKey(log, "level"); level != nil {
+			deprecated = append(deprecated, "log.level")
+			for _, section := range []string{"stdout", "file"} {
+				m := ensureMapping(log, section)
+				if mappingValue(m, "level") == nil {
+					setKey(m, "level", &yaml.Node{Kind: yaml.ScalarNode, Value: level.Value})
+				}
+			}
+		}
+		if dir := removeKey(log, "path"); dir != nil {
+			deprecated = append(deprecated, "log.path")
+			m := ensureMapping(log, "file")
+			if mappingValue(m, "path") == nil {
+				setKey(m, "path", &yaml.Node{Kind: yaml.ScalarNode, Value: filepath.Join(dir.Value, "vaultd.log")})
+			}
+		}
+		return
+	},
+}
+
+// Upgrade upgrades a config file to the current version. Comments are
+// preserved where possible. The deprecated keys that were replaced are
+// returned so they can be reported to the user. If the file is already
+// current, buf is returned unchanged.
+func Upgrade(buf []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode config file: %w", err)
+	} else if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return buf, nil, nil // empty file
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, errors.New("config file must be a mapping")
+	}
+
+	version := 1
+	if v := mappingValue(root, "version"); v != nil {
+		n, err := strconv.Atoi(v.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid config version %q", v.Value)
+		}
+		version = n
+	}
+	switch {
+	case version == CurrentVersion:
+		return buf, nil, nil
+	case version < 1 || version > CurrentVersion:
+		return nil, nil, fmt.Errorf("unsupported config version %d", version)
+	}
+
+	var deprecated []string
+	for _, fn := range upgrades[version-1:] {
+		deprecated = append(deprecated, fn(root)...)
+	}
+	setKey(root, "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(CurrentVersion)})
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config file: %w", err)
+	} else if err := enc.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	return out.Bytes(), deprecated, nil
+}
+
+// mappingValue returns the value of key in the mapping node m, or nil if the
+// key does not exist.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setKey sets the value of key in the mapping node m, adding the key if it
+// does not exist.
+func setKey(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// removeKey removes key from the mapping node m and returns its value, or nil
+// if the key does not exist.
+func removeKey(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			value := m.Content[i+1]
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// ensureMapping returns the mapping value of key in m, adding an empty
+// mapping if the key does not exist.
+func ensureMapping(m *yaml.Node, key string) *yaml.Node {
+	if v := mappingValue(m, key); v != nil && v.Kind == yaml.MappingNode {
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setKey(m, key, v)
+	return v
+}
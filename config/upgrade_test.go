@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestUpgrade(t *testing.T) {
+	v1 := `# vaultd config
+directory: /etc/vaultd
+log:
+  level: debug # verbose logging
+  path: /var/log/vaultd
+  stdout:
+    level: warn
+`
+
+	upgraded, deprecated, err := Upgrade([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(deprecated, []string{"log.level", "log.path"}) {
+		t.Fatalf("unexpected deprecated keys %v", deprecated)
+	} else if !strings.Contains(string(upgraded), "# vaultd config") {
+		t.Fatalf("comment not preserved:\n%s", upgraded)
+	}
+
+	// upgrading again is a no-op
+	again, deprecated, err := Upgrade(upgraded)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(deprecated) != 0 || string(again) != string(upgraded) {
+		t.Fatalf("expected no changes, got %v:\n%s", deprecated, again)
+	}
+
+	fp := filepath.Join(t.TempDir(), "vaultd.yml")
+	if err := os.WriteFile(fp, []byte(v1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	deprecated, err = LoadFile(fp, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(deprecated) != 2 {
+		t.Fatalf("expected 2 deprecated keys, got %v", deprecated)
+	} else if cfg.Version != CurrentVersion {
+		t.Fatalf("expected version %d, got %d", CurrentVersion, cfg.Version)
+	} else if cfg.Log.StdOut.Level.Level() != zap.WarnLevel {
+		// existing values are not overwritten
+		t.Fatalf("expected stdout level warn, got %v", cfg.Log.StdOut.Level)
+	} else if cfg.Log.File.Level.Level() != zap.DebugLevel {
+		t.Fatalf("expected file level debug, got %v", cfg.Log.File.Level)
+	} else if cfg.Log.File.Path != filepath.Join("/var/log/vaultd", "vaultd.log") {
+		t.Fatalf("unexpected log path %q", cfg.Log.File.Path)
+	}
+
+	if _, _, err := Upgrade([]byte("version: 100\n")); err == nil {
+		t.Fatal("expected unsupported version error")
+	}
+}
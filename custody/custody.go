@@ -0,0 +1,137 @@
+// Package custody commits to the set of keys held by the vault so third
+// parties can verify that an address is under custody without access to
+// the vault.
+package custody
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/vault"
+)
+
+// keyBatchSize is the number of keys read from the vault at a time.
+const keyBatchSize = 1000
+
+var (
+	// ErrNoCommitment is returned when no commitment has been computed.
+	ErrNoCommitment = errors.New("no commitment has been computed")
+	// ErrNotCommitted is returned when a key is not in the current
+	// commitment, for example because it was derived after the
+	// commitment was computed.
+	ErrNotCommitted = errors.New("key is not in the current commitment")
+)
+
+type (
+	// A KeyLister lists the keys of the vault.
+	KeyLister interface {
+		// Keys returns a paginated list of keys, sorted by seed ID and
+		// index, and the total number of keys.
+		Keys(offset, limit int) ([]vault.KeyMeta, int, error)
+	}
+
+	// A Commitment is a Merkle root over the vault's public keys.
+	Commitment struct {
+		Root       types.Hash256 `json:"root"`
+		Keys       int           `json:"keys"`
+		ComputedAt time.Time     `json:"computedAt"`
+	}
+
+	// A Proof proves that a public key is included in a commitment.
+	Proof struct {
+		PublicKey types.PublicKey `json:"publicKey"`
+		// Index is the position of the key's leaf in the tree.
+		Index  int             `json:"index"`
+		Hashes []types.Hash256 `json:"hashes"`
+	}
+
+	// A Committer computes and caches the commitment over the vault's
+	// keys.
+	Committer struct {
+		keys KeyLister
+
+		mu         sync.Mutex
+		commitment Commitment
+		leaves     []types.Hash256
+		indices    map[types.PublicKey]int
+	}
+)
+
+// Verify returns true if the proof proves that its public key is included
+// in the commitment.
+func (p Proof) Verify(c Commitment) bool {
+	if p.Index < 0 || p.Index >= c.Keys {
+		return false
+	}
+	root, ok := proofRoot(LeafHash(p.PublicKey), p.Index, c.Keys, p.Hashes)
+	return ok && root == c.Root
+}
+
+// Refresh recomputes the commitment over every key in the vault.
+func (c *Committer) Refresh() (Commitment, error) {
+	var leaves []types.Hash256
+	indices := make(map[types.PublicKey]int)
+	for {
+		keys, _, err := c.keys.Keys(len(leaves), keyBatchSize)
+		if err != nil {
+			return Commitment{}, fmt.Errorf("failed to get keys: %w", err)
+		}
+		for _, key := range keys {
+			indices[key.PublicKey] = len(leaves)
+			leaves = append(leaves, LeafHash(key.PublicKey))
+		}
+		if len(keys) < keyBatchSize {
+			break
+		}
+	}
+
+	commitment := Commitment{
+		Root:       merkleRoot(leaves),
+		Keys:       len(leaves),
+		ComputedAt: time.Now(),
+	}
+	c.mu.Lock()
+	c.commitment, c.leaves, c.indices = commitment, leaves, indices
+	c.mu.Unlock()
+	return commitment, nil
+}
+
+// Commitment returns the most recently computed commitment. If no
+// commitment has been computed, [ErrNoCommitment] is returned.
+func (c *Committer) Commitment() (Commitment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.commitment.ComputedAt.IsZero() {
+		return Commitment{}, ErrNoCommitment
+	}
+	return c.commitment, nil
+}
+
+// Proof returns a proof that the key is included in the most recently
+// computed commitment. If the key is not included, [ErrNotCommitted] is
+// returned.
+func (c *Committer) Proof(pk types.PublicKey) (Proof, Commitment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.commitment.ComputedAt.IsZero() {
+		return Proof{}, Commitment{}, ErrNoCommitment
+	}
+	index, ok := c.indices[pk]
+	if !ok {
+		return Proof{}, Commitment{}, ErrNotCommitted
+	}
+	return Proof{
+		PublicKey: pk,
+		Index:     index,
+		Hashes:    merkleProof(c.leaves, index),
+	}, c.commitment, nil
+}
+
+// NewCommitter returns a Committer for the keys. No commitment is
+// computed until [Committer.Refresh] is called.
+func NewCommitter(keys KeyLister) *Committer {
+	return &Committer{keys: keys}
+}
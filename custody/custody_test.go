@@ -0,0 +1,78 @@
+package custody
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/vault"
+)
+
+type keyList []vault.KeyMeta
+
+func (kl keyList) Keys(offset, limit int) ([]vault.KeyMeta, int, error) {
+	if offset > len(kl) {
+		offset = len(kl)
+	}
+	end := min(offset+limit, len(kl))
+	return kl[offset:end], len(kl), nil
+}
+
+func TestMerkleProofs(t *testing.T) {
+	for n := 1; n <= 33; n++ {
+		leaves := make([]types.Hash256, n)
+		for i := range leaves {
+			leaves[i] = LeafHash(types.GeneratePrivateKey().PublicKey())
+		}
+		root := merkleRoot(leaves)
+		for i := range leaves {
+			proof := merkleProof(leaves, i)
+			if r, ok := proofRoot(leaves[i], i, n, proof); !ok || r != root {
+				t.Fatalf("n=%d: proof for leaf %d is invalid", n, i)
+			} else if r, ok := proofRoot(leaves[i], (i+1)%n, n, proof); n > 1 && ok && r == root {
+				t.Fatalf("n=%d: proof for leaf %d valid at wrong index", n, i)
+			}
+		}
+	}
+}
+
+func TestCommitter(t *testing.T) {
+	var keys keyList
+	for i := range 2500 {
+		keys = append(keys, vault.KeyMeta{
+			PublicKey: types.GeneratePrivateKey().PublicKey(),
+			SeedID:    vault.SeedID(i/1000 + 1),
+			Index:     uint64(i % 1000),
+		})
+	}
+
+	c := NewCommitter(keys)
+	if _, err := c.Commitment(); err != ErrNoCommitment {
+		t.Fatalf("expected %v, got %v", ErrNoCommitment, err)
+	}
+	commitment, err := c.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	} else if commitment.Keys != len(keys) {
+		t.Fatalf("expected %d keys, got %d", len(keys), commitment.Keys)
+	}
+
+	for _, i := range []int{0, 999, 1000, 2499} {
+		proof, pc, err := c.Proof(keys[i].PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		} else if pc != commitment {
+			t.Fatal("proof is for a different commitment")
+		} else if !proof.Verify(commitment) {
+			t.Fatalf("proof for key %d is invalid", i)
+		}
+
+		proof.PublicKey = keys[(i+1)%len(keys)].PublicKey
+		if proof.Verify(commitment) {
+			t.Fatalf("proof for key %d valid for another key", i)
+		}
+	}
+
+	if _, _, err := c.Proof(types.GeneratePrivateKey().PublicKey()); err != ErrNotCommitted {
+		t.Fatalf("expected %v, got %v", ErrNotCommitted, err)
+	}
+}
@@ -0,0 +1,81 @@
+package custody
+
+import (
+	"math/bits"
+
+	"go.sia.tech/core/types"
+)
+
+// Leaf and node hashes are domain-separated so a node can never be passed
+// off as a leaf.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash returns the Merkle leaf of a public key.
+func LeafHash(pk types.PublicKey) types.Hash256 {
+	var buf [1 + len(pk)]byte
+	buf[0] = leafPrefix
+	copy(buf[1:], pk[:])
+	return types.HashBytes(buf[:])
+}
+
+func nodeHash(left, right types.Hash256) types.Hash256 {
+	var buf [1 + 2*len(left)]byte
+	buf[0] = nodePrefix
+	copy(buf[1:], left[:])
+	copy(buf[1+len(left):], right[:])
+	return types.HashBytes(buf[:])
+}
+
+// split returns the largest power of two less than n. n must be greater
+// than one.
+func split(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+// merkleRoot returns the root of the tree over leaves. The tree is split
+// at the largest power of two less than the number of leaves, as in
+// RFC 6962. The root of an empty tree is the zero hash.
+func merkleRoot(leaves []types.Hash256) types.Hash256 {
+	switch len(leaves) {
+	case 0:
+		return types.Hash256{}
+	case 1:
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return nodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merkleProof returns the sibling hashes on the path from the leaf at
+// index to the root, ordered from the leaf up.
+func merkleProof(leaves []types.Hash256, index int) []types.Hash256 {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if index < k {
+		return append(merkleProof(leaves[:k], index), merkleRoot(leaves[k:]))
+	}
+	return append(merkleProof(leaves[k:], index-k), merkleRoot(leaves[:k]))
+}
+
+// proofRoot returns the root implied by a leaf, its position, and its
+// proof. It returns false if the proof has the wrong length.
+func proofRoot(leaf types.Hash256, index, total int, proof []types.Hash256) (types.Hash256, bool) {
+	if total == 1 {
+		return leaf, len(proof) == 0
+	} else if len(proof) == 0 {
+		return types.Hash256{}, false
+	}
+	k := split(total)
+	sibling, rest := proof[len(proof)-1], proof[:len(proof)-1]
+	if index < k {
+		left, ok := proofRoot(leaf, index, k, rest)
+		return nodeHash(left, sibling), ok
+	}
+	right, ok := proofRoot(leaf, index-k, total-k, rest)
+	return nodeHash(sibling, right), ok
+}
@@ -0,0 +1,99 @@
+// Package hook runs external commands in response to vault events.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.sia.tech/vaultd/api"
+)
+
+// DefaultTimeout is the time a command can run before it is killed if no
+// timeout is set.
+const DefaultTimeout = 30 * time.Second
+
+// An Exec is an [api.SignHook] that runs a command after each successful
+// sign request. The event is written to the command's stdin as JSON. A
+// non-zero exit status is reported as an error.
+type Exec struct {
+	args    []string
+	timeout time.Duration
+}
+
+// AfterSign implements api.SignHook.
+func (e *Exec) AfterSign(ctx context.Context, ev api.SignEvent) error {
+	buf, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.args[0], e.args[1:]...)
+	cmd.Stdin = bytes.NewReader(buf)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command %q failed: %w: %s", e.args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// NewExec returns an Exec that runs the command. Arguments are split on
+// whitespace. The command is killed if it runs longer than the timeout.
+// Zero uses [DefaultTimeout].
+func NewExec(command string, timeout time.Duration) (*Exec, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	} else if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Exec{args: args, timeout: timeout}, nil
+}
+
+// A Challenge is a [vault.ChallengeResponder] that runs a command, such as
+// "ykchalresp -2 -x", to get a hardware token's response. The hex-encoded
+// challenge is appended to the command's arguments and the hex-encoded
+// response is read from its stdout.
+type Challenge struct {
+	args    []string
+	timeout time.Duration
+}
+
+// Respond implements vault.ChallengeResponder.
+func (c *Challenge) Respond(challenge []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.args[0], append(c.args[1:], hex.EncodeToString(challenge))...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("command %q failed: %w: %s", c.args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	resp, err := hex.DecodeString(string(bytes.TrimSpace(out)))
+	if err != nil {
+		return nil, fmt.Errorf("command %q returned an invalid response: %w", c.args[0], err)
+	}
+	return resp, nil
+}
+
+// NewChallenge returns a Challenge that runs the command. Arguments are
+// split on whitespace. The command is killed if it runs longer than the
+// timeout, which should leave time to touch the token. Zero uses
+// [DefaultTimeout].
+func NewChallenge(command string, timeout time.Duration) (*Challenge, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	} else if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Challenge{args: args, timeout: timeout}, nil
+}
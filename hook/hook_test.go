@@ -0,0 +1,107 @@
+package hook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/api"
+	"go.sia.tech/vaultd/hook"
+	"lukechampine.com/frand"
+)
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hook.sh")
+	out := filepath.Join(dir, "event.json")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := hook.NewExec(script+" "+out, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := api.SignEvent{
+		Route:         "POST /v2/sign",
+		TransactionID: frand.Entropy256(),
+		MinerFee:      types.Siacoins(1),
+		FullySigned:   true,
+		Metadata:      map[string]string{"ticket": "OPS-123"},
+	}
+	if err := h.AfterSign(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got api.SignEvent
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatal(err)
+	} else if got.TransactionID != ev.TransactionID || !got.MinerFee.Equals(ev.MinerFee) || got.Metadata["ticket"] != "OPS-123" {
+		t.Fatalf("unexpected event %+v", got)
+	}
+
+	// failures include the command's output
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho ledger unavailable\nexit 1\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	h, err = hook.NewExec(script, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h.AfterSign(context.Background(), ev); err == nil || !strings.Contains(err.Error(), "ledger unavailable") {
+		t.Fatalf("expected command output in error, got %v", err)
+	}
+
+	if _, err := hook.NewExec(" ", 0); err == nil {
+		t.Fatal("expected empty command to fail")
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	// the script answers with the challenge reversed, like a token that
+	// echoes a transformation of its input
+	script := filepath.Join(t.TempDir(), "token.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n[ \"$1\" = -2 ] || exit 1\necho \"$2\" | rev\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	c, err := hook.NewChallenge(script+" -2", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Respond([]byte{0x12, 0x34, 0xab})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(resp, []byte{0xba, 0x43, 0x21}) {
+		t.Fatalf("unexpected response %x", resp)
+	}
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho no token >&2\nexit 1\n"), 0700); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Respond([]byte{1}); err == nil || !strings.Contains(err.Error(), "no token") {
+		t.Fatalf("expected command output in error, got %v", err)
+	}
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho not hex\n"), 0700); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Respond([]byte{1}); err == nil || !strings.Contains(err.Error(), "invalid response") {
+		t.Fatalf("expected invalid response error, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package importkey encrypts seed phrases to ephemeral X25519 keys so the
+// plaintext phrase never appears in a request body that may be logged by
+// a proxy.
+package importkey
+
+import (
+	"crypto/ecdh"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20poly1305"
+	"lukechampine.com/frand"
+)
+
+// kdfPrefix domain-separates the key encrypting an envelope.
+const kdfPrefix = "vaultd seed import"
+
+var (
+	// ErrUnknownKey is returned when an envelope is encrypted to a key
+	// that was never generated, has expired, or has already been used.
+	ErrUnknownKey = errors.New("unknown or expired import key")
+	// ErrDecrypt is returned when an envelope cannot be decrypted.
+	ErrDecrypt = errors.New("failed to decrypt envelope")
+)
+
+type (
+	// A PublicKey is an X25519 public key.
+	PublicKey [32]byte
+
+	// An Envelope is a message encrypted to an import key. Ciphertext is
+	// the XChaCha20-Poly1305 nonce followed by the sealed message. The
+	// encryption key is BLAKE2b-256("vaultd seed import" || shared
+	// secret || sender || recipient).
+	Envelope struct {
+		// Sender is the sender's ephemeral public key.
+		Sender PublicKey `json:"sender"`
+		// Recipient is the import key the message is encrypted to.
+		Recipient  PublicKey `json:"recipient"`
+		Ciphertext []byte    `json:"ciphertext"`
+	}
+
+	// A Keyring holds unused import keys. Each key can decrypt a single
+	// envelope before it expires.
+	Keyring struct {
+		lifetime time.Duration
+		maxKeys  int
+
+		mu   sync.Mutex
+		keys map[PublicKey]key
+	}
+
+	key struct {
+		private *ecdh.PrivateKey
+		expires time.Time
+	}
+)
+
+// String implements fmt.Stringer.
+func (pk PublicKey) String() string {
+	return hex.EncodeToString(pk[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (pk PublicKey) MarshalText() ([]byte, error) {
+	return []byte(pk.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (pk *PublicKey) UnmarshalText(b []byte) error {
+	if hex.DecodedLen(len(b)) != len(pk) {
+		return fmt.Errorf("invalid public key length %d", len(b))
+	}
+	_, err := hex.Decode(pk[:], b)
+	return err
+}
+
+func encryptionKey(shared []byte, sender, recipient PublicKey) [32]byte {
+	buf := make([]byte, 0, len(kdfPrefix)+len(shared)+64)
+	buf = append(buf, kdfPrefix...)
+	buf = append(buf, shared...)
+	buf = append(buf, sender[:]...)
+	buf = append(buf, recipient[:]...)
+	defer clear(buf)
+	return blake2b.Sum256(buf)
+}
+
+// Seal encrypts the message to the recipient using a new ephemeral key.
+func Seal(recipient PublicKey, msg []byte) (Envelope, error) {
+	rk, err := ecdh.X25519().NewPublicKey(recipient[:])
+	if err != nil {
+		return Envelope{}, fmt.Errorf("invalid recipient: %w", err)
+	}
+	sk, err := ecdh.X25519().GenerateKey(frand.Reader)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	shared, err := sk.ECDH(rk)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to compute shared secret: %w", err)
+	}
+	defer clear(shared)
+
+	e := Envelope{Recipient: recipient}
+	copy(e.Sender[:], sk.PublicKey().Bytes())
+	k := encryptionKey(shared, e.Sender, e.Recipient)
+	defer clear(k[:])
+	aead, err := chacha20poly1305.NewX(k[:])
+	if err != nil {
+		return Envelope{}, err
+	}
+	nonce := frand.Bytes(aead.NonceSize())
+	e.Ciphertext = aead.Seal(nonce, nonce, msg, nil)
+	return e, nil
+}
+
+// Generate returns a new import key and the time it expires.
+func (kr *Keyring) Generate() (PublicKey, time.Time, error) {
+	sk, err := ecdh.X25519().GenerateKey(frand.Reader)
+	if err != nil {
+		return PublicKey{}, time.Time{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	var pk PublicKey
+	copy(pk[:], sk.PublicKey().Bytes())
+	expires := time.Now().Add(kr.lifetime)
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+	if len(kr.keys) >= kr.maxKeys {
+		// drop the key closest to expiring
+		var oldest PublicKey
+		var oldestExpires time.Time
+		for pk, k := range kr.keys {
+			if oldestExpires.IsZero() || k.expires.Before(oldestExpires) {
+				oldest, oldestExpires = pk, k.expires
+			}
+		}
+		delete(kr.keys, oldest)
+	}
+	kr.keys[pk] = key{private: sk, expires: expires}
+	return pk, expires, nil
+}
+
+// Open decrypts the envelope. The import key is removed, even if the
+// envelope cannot be decrypted, so it cannot be used again.
+func (kr *Keyring) Open(e Envelope) ([]byte, error) {
+	kr.mu.Lock()
+	k, ok := kr.keys[e.Recipient]
+	delete(kr.keys, e.Recipient)
+	kr.mu.Unlock()
+	if !ok || time.Now().After(k.expires) {
+		return nil, ErrUnknownKey
+	}
+
+	sender, err := ecdh.X25519().NewPublicKey(e.Sender[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+	shared, err := k.private.ECDH(sender)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute shared secret: %w", err)
+	}
+	defer clear(shared)
+
+	ek := encryptionKey(shared, e.Sender, e.Recipient)
+	defer clear(ek[:])
+	aead, err := chacha20poly1305.NewX(ek[:])
+	if err != nil {
+		return nil, err
+	} else if len(e.Ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := e.Ciphertext[:aead.NonceSize()], e.Ciphertext[aead.NonceSize():]
+	msg, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return msg, nil
+}
+
+// prune removes expired keys. The caller must hold the lock.
+func (kr *Keyring) prune() {
+	now := time.Now()
+	for pk, k := range kr.keys {
+		if now.After(k.expires) {
+			delete(kr.keys, pk)
+		}
+	}
+}
+
+// NewKeyring returns a Keyring whose keys expire after lifetime. At most
+// maxKeys unused keys are held; generating another key discards the key
+// closest to expiring.
+func NewKeyring(lifetime time.Duration, maxKeys int) *Keyring {
+	return &Keyring{
+		lifetime: lifetime,
+		maxKeys:  maxKeys,
+		keys:     make(map[PublicKey]key),
+	}
+}
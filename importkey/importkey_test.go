@@ -0,0 +1,96 @@
+package importkey_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"go.sia.tech/vaultd/importkey"
+)
+
+func TestSealOpen(t *testing.T) {
+	kr := importkey.NewKeyring(time.Minute, 2)
+
+	pk, expires, err := kr.Generate()
+	if err != nil {
+		t.Fatal(err)
+	} else if time.Until(expires) <= 0 {
+		t.Fatalf("expected key to expire in the future, got %v", expires)
+	}
+
+	msg := []byte("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about")
+	e, err := importkey.Seal(pk, msg)
+	if err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(e.Ciphertext, msg) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	// a tampered envelope consumes the key
+	tampered := e
+	tampered.Ciphertext = bytes.Clone(e.Ciphertext)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	if _, err := kr.Open(tampered); !errors.Is(err, importkey.ErrDecrypt) {
+		t.Fatalf("expected %v, got %v", importkey.ErrDecrypt, err)
+	} else if _, err := kr.Open(e); !errors.Is(err, importkey.ErrUnknownKey) {
+		t.Fatalf("expected %v, got %v", importkey.ErrUnknownKey, err)
+	}
+
+	pk, _, err = kr.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err = importkey.Seal(pk, msg)
+	if err != nil {
+		t.Fatal(err)
+	} else if opened, err := kr.Open(e); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(opened, msg) {
+		t.Fatalf("expected %q, got %q", msg, opened)
+	} else if _, err := kr.Open(e); !errors.Is(err, importkey.ErrUnknownKey) {
+		t.Fatalf("expected a key to be single use, got %v", err)
+	}
+}
+
+func TestKeyringLimits(t *testing.T) {
+	kr := importkey.NewKeyring(time.Minute, 2)
+
+	var keys []importkey.PublicKey
+	for range 3 {
+		pk, _, err := kr.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, pk)
+		time.Sleep(time.Millisecond)
+	}
+
+	// the oldest key was discarded
+	for i, pk := range keys {
+		e, err := importkey.Seal(pk, []byte("foo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = kr.Open(e)
+		if i == 0 && !errors.Is(err, importkey.ErrUnknownKey) {
+			t.Fatalf("expected %v, got %v", importkey.ErrUnknownKey, err)
+		} else if i > 0 && err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// expired keys cannot be used
+	kr = importkey.NewKeyring(time.Millisecond, 2)
+	pk, _, err := kr.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	e, err := importkey.Seal(pk, []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := kr.Open(e); !errors.Is(err, importkey.ErrUnknownKey) {
+		t.Fatalf("expected %v, got %v", importkey.ErrUnknownKey, err)
+	}
+}
@@ -0,0 +1,254 @@
+// Package bip39 encodes and decodes 12 to 24 word BIP39 phrases in each of
+// the word lists defined by BIP39 that vaultd supports. Seeds are derived
+// from the phrase's entropy the same way as go.sia.tech/coreutils/wallet, so
+// the same entropy produces the same seed in every language. Longer phrases
+// are derived the same way, by hashing all of their entropy.
+package bip39
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/text/unicode/norm"
+	"lukechampine.com/frand"
+)
+
+// WordListSize is the number of words in a BIP39 word list.
+const WordListSize = 2048
+
+// minWords and maxWords are the number of words in phrases with 128 and 256
+// bits of entropy.
+const (
+	minWords = 12
+	maxWords = 24
+)
+
+// Languages of the BIP39 word lists.
+const (
+	English            Language = "english"
+	Spanish            Language = "spanish"
+	French             Language = "french"
+	Japanese           Language = "japanese"
+	ChineseSimplified  Language = "chinese_simplified"
+	ChineseTraditional Language = "chinese_traditional"
+)
+
+var (
+	// ErrUnknownWord is returned when a word in the phrase is not found in
+	// any of the word lists.
+	ErrUnknownWord = errors.New("word not found")
+	// ErrChecksum is returned when the phrase's checksum does not match its
+	// entropy.
+	ErrChecksum = errors.New("invalid checksum")
+)
+
+type (
+	// A Language identifies one of the BIP39 word lists.
+	Language string
+
+	// A wordList maps the NFKD normalized form of each word to its index.
+	wordList struct {
+		words []string
+		index map[string]uint64
+	}
+)
+
+// wordLists are the word lists in the order phrases are matched against
+// them. The Chinese word lists share characters, so a phrase can contain
+// words from both; the checksum decides between them.
+var wordLists = []struct {
+	lang Language
+	list *wordList
+}{
+	{English, newWordList(english)},
+	{Spanish, newWordList(spanish)},
+	{French, newWordList(french)},
+	{Japanese, newWordList(japanese)},
+	{ChineseSimplified, newWordList(chineseSimplified)},
+	{ChineseTraditional, newWordList(chineseTraditional)},
+}
+
+func newWordList(words []string) *wordList {
+	l := &wordList{
+		words: words,
+		index: make(map[string]uint64, len(words)),
+	}
+	for i, w := range words {
+		l.index[norm.NFKD.String(w)] = uint64(i)
+	}
+	return l
+}
+
+// wordListFor returns the word list of the language.
+func wordListFor(lang Language) (*wordList, bool) {
+	for _, l := range wordLists {
+		if l.lang == lang {
+			return l.list, true
+		}
+	}
+	return nil, false
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *Language) UnmarshalText(b []byte) error {
+	if _, ok := wordListFor(Language(b)); !ok {
+		return fmt.Errorf("unknown BIP39 language %q", b)
+	}
+	*l = Language(b)
+	return nil
+}
+
+// IsWord returns true if the word is in any of the word lists.
+func IsWord(word string) bool {
+	word = norm.NFKD.String(word)
+	for _, l := range wordLists {
+		if _, ok := l.list.index[word]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidWordCount returns true if BIP39 defines phrases of n words: 12, 15,
+// 18, 21, or 24.
+func ValidWordCount(n int) bool {
+	return n >= minWords && n <= maxWords && n%3 == 0
+}
+
+// entropyBytes returns the number of bytes of entropy in a phrase of n
+// words. Every 3 words encode 32 bits of entropy and 1 bit of checksum.
+func entropyBytes(n int) int {
+	return n / 3 * 4
+}
+
+// checksum returns the first len(entropy)/4 bits of the entropy's hash, as
+// the most significant bits of a byte.
+func checksum(entropy []byte) byte {
+	hash := sha256.Sum256(entropy)
+	bits := len(entropy) / 4
+	return hash[0] & ^byte(0xFF>>bits)
+}
+
+// decode converts the words to entropy using the word list. The words must
+// be NFKD normalized and there must be a valid number of them.
+func (l *wordList) decode(words []string) ([]byte, error) {
+	// pack the 11 bit index of each word into the entropy, followed by the
+	// checksum; the checksum is at most 8 bits, so it fits in one byte
+	n := entropyBytes(len(words))
+	buf := make([]byte, n+1)
+	for i, v := range words {
+		w, ok := l.index[v]
+		if !ok {
+			clear(buf)
+			return nil, fmt.Errorf("word %q: %w", v, ErrUnknownWord)
+		}
+		for b := range 11 {
+			if w&(1<<(10-b)) != 0 {
+				pos := i*11 + b
+				buf[pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+	}
+
+	entropy := buf[:n]
+	if checksum(entropy) != buf[n] {
+		clear(buf)
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+// DecodePhrase converts a 12, 15, 18, 21, or 24 word phrase in any of the
+// supported languages to its entropy and returns the phrase's language. The
+// caller should clear the entropy once it is no longer needed.
+func DecodePhrase(phrase string) ([]byte, Language, error) {
+	words := strings.Fields(norm.NFKD.String(phrase))
+	if !ValidWordCount(len(words)) {
+		return nil, "", fmt.Errorf("expected 12, 15, 18, 21, or 24 words, got %d", len(words))
+	}
+
+	var matched bool
+	for _, l := range wordLists {
+		if slices.ContainsFunc(words, func(w string) bool {
+			_, ok := l.list.index[w]
+			return !ok
+		}) {
+			continue
+		}
+		matched = true
+		if entropy, err := l.list.decode(words); err == nil {
+			return entropy, l.lang, nil
+		}
+	}
+	if matched {
+		return nil, "", ErrChecksum
+	}
+	for _, w := range words {
+		if !IsWord(w) {
+			return nil, "", fmt.Errorf("word %q: %w", w, ErrUnknownWord)
+		}
+	}
+	// every word is in some list, but no single list has all of them
+	return nil, "", fmt.Errorf("words from multiple languages: %w", ErrUnknownWord)
+}
+
+// EncodePhrase converts 16, 20, 24, 28, or 32 bytes of entropy to a 12, 15,
+// 18, 21, or 24 word phrase in the language. Japanese phrases are separated
+// by ideographic spaces, as BIP39 requires.
+func EncodePhrase(entropy []byte, lang Language) (string, error) {
+	l, ok := wordListFor(lang)
+	if !ok {
+		return "", fmt.Errorf("unknown BIP39 language %q", lang)
+	}
+	n := len(entropy) / 4 * 3
+	if len(entropy)%4 != 0 || !ValidWordCount(n) {
+		return "", fmt.Errorf("invalid entropy length %d", len(entropy))
+	}
+
+	buf := append(slices.Clone(entropy), checksum(entropy))
+	defer clear(buf)
+
+	// convert each group of 11 bits into a word
+	words := make([]string, n)
+	for i := range words {
+		var w int
+		for b := range 11 {
+			pos := i*11 + b
+			w = w<<1 | int(buf[pos/8]>>(7-pos%8)&1)
+		}
+		words[i] = l.words[w]
+	}
+
+	sep := " "
+	if lang == Japanese {
+		sep = "　"
+	}
+	return strings.Join(words, sep), nil
+}
+
+// NewPhrase returns a random 12 word phrase in the language.
+func NewPhrase(lang Language) (string, error) {
+	var entropy [16]byte
+	frand.Read(entropy[:])
+	defer clear(entropy[:])
+	return EncodePhrase(entropy[:], lang)
+}
+
+// SeedFromPhrase derives a 32-byte seed from a 12, 15, 18, 21, or 24 word
+// phrase in any of the supported languages and returns the phrase's
+// language.
+func SeedFromPhrase(seed *[32]byte, phrase string) (Language, error) {
+	entropy, lang, err := DecodePhrase(phrase)
+	if err != nil {
+		return "", err
+	}
+	defer clear(entropy)
+	h := blake2b.Sum256(entropy)
+	copy(seed[:], h[:])
+	clear(h[:])
+	return lang, nil
+}
@@ -0,0 +1,160 @@
+package bip39
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.sia.tech/coreutils/wallet"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/text/unicode/norm"
+	"lukechampine.com/frand"
+)
+
+func TestWordLists(t *testing.T) {
+	for _, l := range wordLists {
+		if len(l.list.words) != WordListSize {
+			t.Fatalf("%s: expected %d words, got %d", l.lang, WordListSize, len(l.list.words))
+		} else if len(l.list.index) != WordListSize {
+			t.Fatalf("%s: expected %d unique words, got %d", l.lang, WordListSize, len(l.list.index))
+		}
+	}
+
+	if !IsWord("abandon") || !IsWord("zoo") {
+		t.Fatal("expected English words")
+	} else if !IsWord(norm.NFC.String("ábaco")) {
+		t.Fatal("expected a composed Spanish word to match")
+	} else if IsWord("sia") {
+		t.Fatal("expected sia not to be a BIP39 word")
+	}
+}
+
+func TestPhraseLanguages(t *testing.T) {
+	for _, l := range wordLists {
+		entropy := frand.Bytes(16)
+
+		phrase, err := EncodePhrase(entropy, l.lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, lang, err := DecodePhrase(phrase)
+		if err != nil {
+			t.Fatalf("%s: %v", l.lang, err)
+		} else if !bytes.Equal(decoded, entropy) {
+			t.Fatalf("%s: expected entropy %x, got %x", l.lang, entropy, decoded)
+		} else if lang != l.lang && !(l.lang == ChineseTraditional && lang == ChineseSimplified) {
+			// a traditional Chinese phrase made entirely of characters
+			// shared with the simplified list can have a valid checksum
+			// in both
+			t.Fatalf("expected %s, got %s", l.lang, lang)
+		}
+
+		// the entropy decides the seed, not the language
+		english, err := EncodePhrase(entropy, English)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var seed, expected [32]byte
+		if err := wallet.SeedFromPhrase(&expected, english); err != nil {
+			t.Fatal(err)
+		} else if _, err := SeedFromPhrase(&seed, phrase); err != nil {
+			t.Fatal(err)
+		} else if seed != expected {
+			t.Fatalf("%s: expected seed %x, got %x", l.lang, expected, seed)
+		}
+	}
+}
+
+func TestPhraseLengths(t *testing.T) {
+	// test vectors from the BIP39 reference implementation
+	vectors := []struct {
+		entropy string
+		phrase  string
+	}{
+		{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will"},
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote"},
+		{"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c", "hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length"},
+	}
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		phrase, err := EncodePhrase(entropy, English)
+		if err != nil {
+			t.Fatal(err)
+		} else if phrase != v.phrase {
+			t.Fatalf("expected %q, got %q", v.phrase, phrase)
+		}
+
+		decoded, _, err := DecodePhrase(v.phrase)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(decoded, entropy) {
+			t.Fatalf("expected entropy %x, got %x", entropy, decoded)
+		}
+
+		// the seed is the hash of the phrase's entropy
+		var seed [32]byte
+		if _, err := SeedFromPhrase(&seed, v.phrase); err != nil {
+			t.Fatal(err)
+		} else if seed != blake2b.Sum256(entropy) {
+			t.Fatalf("expected seed %x, got %x", blake2b.Sum256(entropy), seed)
+		}
+	}
+
+	for _, n := range []int{15, 21} {
+		entropy := frand.Bytes(n / 3 * 4)
+		phrase, err := EncodePhrase(entropy, French)
+		if err != nil {
+			t.Fatal(err)
+		} else if words := strings.Fields(phrase); len(words) != n {
+			t.Fatalf("expected %d words, got %d", n, len(words))
+		} else if decoded, _, err := DecodePhrase(phrase); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(decoded, entropy) {
+			t.Fatalf("expected entropy %x, got %x", entropy, decoded)
+		}
+	}
+
+	if _, err := EncodePhrase(make([]byte, 17), English); err == nil {
+		t.Fatal("expected an error for invalid entropy length")
+	}
+}
+
+func TestDecodePhraseErrors(t *testing.T) {
+	phrase, err := NewPhrase(Spanish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Fields(phrase)
+
+	if _, _, err := DecodePhrase(strings.Join(words[:11], " ")); err == nil {
+		t.Fatal("expected an error for a short phrase")
+	} else if _, _, err := DecodePhrase(strings.Join(append(words, words[:2]...), " ")); err == nil {
+		t.Fatal("expected an error for a 14 word phrase")
+	}
+
+	unknown := append([]string(nil), words...)
+	unknown[5] = "sia"
+	if _, _, err := DecodePhrase(strings.Join(unknown, " ")); !errors.Is(err, ErrUnknownWord) {
+		t.Fatalf("expected %v, got %v", ErrUnknownWord, err)
+	}
+
+	// the checksum of zero entropy is 3, so repeating the first word of
+	// its phrase is always invalid
+	phrase, err = EncodePhrase(make([]byte, 16), Spanish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words = strings.Fields(phrase)
+	words[11] = words[0]
+	if _, _, err := DecodePhrase(strings.Join(words, " ")); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("expected %v, got %v", ErrChecksum, err)
+	}
+
+	if _, err := EncodePhrase(make([]byte, 16), "klingon"); err == nil {
+		t.Fatal("expected an error for an unknown language")
+	}
+}
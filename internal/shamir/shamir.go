@@ -0,0 +1,132 @@
+// Package shamir splits a secret into shares using Shamir's secret sharing
+// over GF(2^8). Any threshold of the shares recovers the secret; fewer
+// reveal nothing about it.
+package shamir
+
+import (
+	"errors"
+	"fmt"
+
+	"lukechampine.com/frand"
+)
+
+// MaxShares is the maximum number of shares a secret can be split into.
+const MaxShares = 255
+
+// A Share is one of the shares of a split secret. X is the share's
+// non-zero evaluation point and Y is the value of each byte's polynomial
+// at X.
+type Share struct {
+	X byte
+	Y []byte
+}
+
+// expTable and logTable map between the elements of GF(2^8), reduced by
+// the AES polynomial, and their logarithms to the base 3.
+var expTable, logTable = func() (exp [255]byte, log [256]byte) {
+	x := byte(1)
+	for i := range exp {
+		exp[i] = x
+		log[x] = byte(i)
+		// multiply by 3: x*2 ^ x
+		x2 := x << 1
+		if x&0x80 != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return
+}()
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+// Split splits the secret into n shares, any threshold of which recover
+// it.
+func Split(secret []byte, threshold, n int) ([]Share, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("secret is empty")
+	case threshold < 2:
+		return nil, errors.New("threshold must be at least 2")
+	case n < threshold:
+		return nil, errors.New("number of shares must be at least the threshold")
+	case n > MaxShares:
+		return nil, fmt.Errorf("number of shares must be at most %d", MaxShares)
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{
+			X: byte(i + 1),
+			Y: make([]byte, len(secret)),
+		}
+	}
+
+	// each byte of the secret is the constant term of a random polynomial
+	// of degree threshold-1
+	coeffs := make([]byte, threshold)
+	defer clear(coeffs)
+	for i, b := range secret {
+		frand.Read(coeffs[1:])
+		coeffs[0] = b
+		for _, share := range shares {
+			// Horner's method
+			var y byte
+			for j := len(coeffs) - 1; j >= 0; j-- {
+				y = mul(y, share.X) ^ coeffs[j]
+			}
+			share.Y[i] = y
+		}
+	}
+	return shares, nil
+}
+
+// Combine recovers a secret from its shares. The shares must come from the
+// same split and number at least its threshold; otherwise the result is
+// not the secret, and no error is returned.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares are required")
+	}
+	n := len(shares[0].Y)
+	for i, share := range shares {
+		switch {
+		case share.X == 0:
+			return nil, fmt.Errorf("share %d has an invalid x coordinate", i)
+		case len(share.Y) != n:
+			return nil, errors.New("shares have different lengths")
+		}
+		for _, other := range shares[:i] {
+			if other.X == share.X {
+				return nil, fmt.Errorf("duplicate share %d", share.X)
+			}
+		}
+	}
+
+	// evaluate the Lagrange interpolation polynomial at 0
+	secret := make([]byte, n)
+	for i, share := range shares {
+		basis := byte(1)
+		for j, other := range shares {
+			if i != j {
+				basis = mul(basis, div(other.X, other.X^share.X))
+			}
+		}
+		for k, y := range share.Y {
+			secret[k] ^= mul(y, basis)
+		}
+	}
+	return secret, nil
+}
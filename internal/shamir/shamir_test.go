@@ -0,0 +1,88 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+
+	"lukechampine.com/frand"
+)
+
+func TestField(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if div(mul(byte(a), byte(b)), byte(b)) != byte(a) {
+				t.Fatalf("(%d * %d) / %d != %d", a, b, b, a)
+			}
+		}
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := frand.Bytes(32)
+	shares, err := Split(secret, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(shares) != 5 {
+		t.Fatalf("expected 5 shares, got %d", len(shares))
+	}
+
+	// every combination of 3 shares recovers the secret
+	for i := range shares {
+		for j := i + 1; j < len(shares); j++ {
+			for k := j + 1; k < len(shares); k++ {
+				recovered, err := Combine([]Share{shares[k], shares[i], shares[j]})
+				if err != nil {
+					t.Fatal(err)
+				} else if !bytes.Equal(recovered, secret) {
+					t.Fatalf("shares %d, %d, %d: expected %x, got %x", i, j, k, secret, recovered)
+				}
+			}
+		}
+	}
+
+	// so do more than the threshold
+	if recovered, err := Combine(shares); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(recovered, secret) {
+		t.Fatalf("expected %x, got %x", secret, recovered)
+	}
+
+	// but not fewer
+	if recovered, err := Combine(shares[:2]); err != nil {
+		t.Fatal(err)
+	} else if bytes.Equal(recovered, secret) {
+		t.Fatal("expected 2 shares not to recover the secret")
+	}
+}
+
+func TestSplitCombineErrors(t *testing.T) {
+	secret := frand.Bytes(32)
+	for _, test := range []struct {
+		threshold, n int
+	}{
+		{1, 3},
+		{3, 2},
+		{2, MaxShares + 1},
+	} {
+		if _, err := Split(secret, test.threshold, test.n); err == nil {
+			t.Fatalf("expected an error splitting %d of %d", test.threshold, test.n)
+		}
+	}
+	if _, err := Split(nil, 2, 3); err == nil {
+		t.Fatal("expected an error for an empty secret")
+	}
+
+	shares, err := Split(secret, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Combine(shares[:1]); err == nil {
+		t.Fatal("expected an error for a single share")
+	} else if _, err := Combine([]Share{shares[0], shares[0]}); err == nil {
+		t.Fatal("expected an error for duplicate shares")
+	} else if _, err := Combine([]Share{shares[0], {X: 0, Y: shares[1].Y}}); err == nil {
+		t.Fatal("expected an error for a zero x coordinate")
+	} else if _, err := Combine([]Share{shares[0], {X: 2, Y: shares[1].Y[:16]}}); err == nil {
+		t.Fatal("expected an error for mismatched lengths")
+	}
+}
@@ -32,7 +32,7 @@ package siad
 // THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 var (
-	dict = []string{
+	englishDict = []string{
 		"abbey",
 		"abducts",
 		"ability",
@@ -24,14 +24,75 @@ const (
 	dictionarySize = 1626
 )
 
+// Languages of the siad dictionaries.
+const (
+	English  Language = "english"
+	German   Language = "german"
+	Japanese Language = "japanese"
+)
+
+type (
+	// A Language identifies one of the siad dictionaries.
+	Language string
+
+	// A dictionary is a list of dictionarySize words. Only the first
+	// prefixLen runes of each word are significant; no two words share
+	// a prefix.
+	dictionary struct {
+		words     []string
+		prefixLen int
+		// prefixes maps the prefix of each word to its index.
+		prefixes map[string]int
+	}
+)
+
+// dictionaries are the siad dictionaries in the order phrases are matched
+// against them.
+var dictionaries = []struct {
+	lang Language
+	dict *dictionary
+}{
+	{English, newDictionary(englishDict, 3)},
+	{German, newDictionary(germanDict, 4)},
+	{Japanese, newDictionary(japaneseDict, 3)},
+}
+
+// newDictionary indexes the words by their prefixes.
+func newDictionary(words []string, prefixLen int) *dictionary {
+	d := &dictionary{
+		words:     words,
+		prefixLen: prefixLen,
+		prefixes:  make(map[string]int, len(words)),
+	}
+	for i, w := range words {
+		d.prefixes[prefix(w, prefixLen)] = i
+	}
+	return d
+}
+
+// dictionaryFor returns the dictionary of the language.
+func dictionaryFor(lang Language) (*dictionary, bool) {
+	for _, d := range dictionaries {
+		if d.lang == lang {
+			return d.dict, true
+		}
+	}
+	return nil, false
+}
+
 var (
 	// ErrSeedLength is returned when the seed does not have the expected
 	// length of 38 bytes (32 bytes of entropy and 6 bytes of checksum).
 	ErrSeedLength = errors.New("invalid length")
 
-	// ErrUnknownWord is returned when a word in the phrase is not found in the
-	// english dictionary.
+	// ErrUnknownWord is returned when a word in the phrase is not found in
+	// any of the dictionaries.
 	ErrUnknownWord = errors.New("word not found")
+
+	// ErrChecksum is returned when the phrase's checksum does not match its
+	// entropy, usually because a word was mistyped or the words are out of
+	// order.
+	ErrChecksum = errors.New("invalid checksum")
 )
 
 // The conversion functions can be seen as changing the base of a number. A
@@ -81,45 +142,89 @@ func intToBytes(bi *big.Int) (bs []byte) {
 	return bs
 }
 
+// prefix returns the first n runes of s.
+func prefix(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// wordIndex returns the index of the dictionary word that shares the
+// first prefixLen runes of word. The word must be NFC normalized.
+func (d *dictionary) wordIndex(word string) (int, bool) {
+	p := prefix(word, d.prefixLen)
+	if i, ok := d.prefixes[p]; ok {
+		return i, true
+	} else if utf8.RuneCountInString(p) == d.prefixLen {
+		return 0, false
+	}
+	// siad matched words shorter than the prefix to the first word
+	// that starts with them
+	for i, w := range d.words {
+		if strings.HasPrefix(w, p) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// IsWord returns true if the word is in any of the siad dictionaries.
+// Like siad, only the prefix of the word is compared.
+func IsWord(word string) bool {
+	word = norm.NFC.String(word)
+	for _, d := range dictionaries {
+		if _, ok := d.dict.wordIndex(word); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// detectLanguage returns the first dictionary that contains every word. If
+// no dictionary does, the error names the first English word that was not
+// found.
+func detectLanguage(words []string) (Language, *dictionary, error) {
+	for _, d := range dictionaries {
+		if !slices.ContainsFunc(words, func(w string) bool {
+			_, ok := d.dict.wordIndex(w)
+			return !ok
+		}) {
+			return d.lang, d.dict, nil
+		}
+	}
+	english, _ := dictionaryFor(English)
+	for _, w := range words {
+		if _, ok := english.wordIndex(w); !ok {
+			return "", nil, fmt.Errorf("word %q: %w", w, ErrUnknownWord)
+		}
+	}
+	return "", nil, ErrUnknownWord
+}
+
+// DetectLanguage returns the language of the first dictionary that contains
+// every word of the phrase. If no dictionary does, the error names the
+// first English word that was not found.
+func DetectLanguage(phrase string) (Language, error) {
+	lang, _, err := detectLanguage(strings.Fields(norm.NFC.String(phrase)))
+	return lang, err
+}
+
 // phraseToInt coverts a phrase into a big.Int, using logic similar to
 // bytesToInt.
-func phraseToInt(p string) (*big.Int, error) {
-	const prefixLen = 3
-
+func phraseToInt(words []string, d *dictionary) (*big.Int, error) {
 	base := big.NewInt(1626)
 	exp := big.NewInt(1)
 	result := big.NewInt(-1)
-	for _, word := range strings.Fields(p) {
-		// Normalize the input.
-		word = norm.NFC.String(word)
-
-		// Get the first prefixLen runes from the string.
-		var prefix []byte
-		var runeCount int
-		for _, r := range word {
-			encR := make([]byte, utf8.RuneLen(r))
-			utf8.EncodeRune(encR, r)
-			prefix = append(prefix, encR...)
-
-			runeCount++
-			if runeCount == prefixLen {
-				break
-			}
-		}
-
-		// Find the index associated with the phrase.
-		var tmp *big.Int
-		found := false
-		for j, word := range dict {
-			if strings.HasPrefix(word, string(prefix)) {
-				tmp = big.NewInt(int64(j))
-				found = true
-				break
-			}
-		}
-		if !found {
+	for _, word := range words {
+		j, ok := d.wordIndex(word)
+		if !ok {
 			return nil, fmt.Errorf("word %q: %w", word, ErrUnknownWord)
 		}
+		tmp := big.NewInt(int64(j))
 
 		// Add the index to the int.
 		tmp.Add(tmp, big.NewInt(1))
@@ -132,23 +237,29 @@ func phraseToInt(p string) (*big.Int, error) {
 
 // intToPhrase converts a phrase into a big.Int, working in a fashion similar
 // to bytesToInt.
-func intToPhrase(bi *big.Int) string {
+func intToPhrase(bi *big.Int, d *dictionary) string {
 	var words []string
 	base := big.NewInt(dictionarySize)
 	for bi.Cmp(base) >= 0 {
 		i := new(big.Int).Mod(bi, base).Int64()
-		words = append(words, dict[i])
+		words = append(words, d.words[i])
 		bi.Sub(bi, base)
 		bi.Div(bi, base)
 	}
-	words = append(words, dict[bi.Int64()])
+	words = append(words, d.words[bi.Int64()])
 	return strings.Join(words, " ")
 }
 
 // SeedFromPhrase derives a 32-byte seed from the supplied 28/29 word
-// siad recovery phrase.
+// siad recovery phrase. The phrase's language is detected with
+// [DetectLanguage].
 func SeedFromPhrase(seed *[32]byte, phrase string) error {
-	b, err := phraseToInt(phrase)
+	words := strings.Fields(norm.NFC.String(phrase))
+	_, d, err := detectLanguage(words)
+	if err != nil {
+		return err
+	}
+	b, err := phraseToInt(words, d)
 	if err != nil {
 		return err
 	}
@@ -158,7 +269,7 @@ func SeedFromPhrase(seed *[32]byte, phrase string) error {
 	}
 	checksum := types.HashBytes(bs[:32])
 	if !bytes.Equal(checksum[:checksumBytes], bs[entropyBytes:]) {
-		return fmt.Errorf("invalid checksum: expected %x, got %x", checksum[:checksumBytes], bs[entropyBytes:])
+		return fmt.Errorf("%w: expected %x, got %x", ErrChecksum, checksum[:checksumBytes], bs[entropyBytes:])
 	}
 	copy(seed[:], bs)
 	return nil
@@ -166,9 +277,20 @@ func SeedFromPhrase(seed *[32]byte, phrase string) error {
 
 // SeedToPhrase converts a 32-byte seed into a checksummed 28/29 word siad recovery phrase.
 func SeedToPhrase(seed *[32]byte) string {
+	phrase, _ := SeedToPhraseLanguage(seed, English)
+	return phrase
+}
+
+// SeedToPhraseLanguage is like [SeedToPhrase], but uses the dictionary of
+// the given language.
+func SeedToPhraseLanguage(seed *[32]byte, lang Language) (string, error) {
+	d, ok := dictionaryFor(lang)
+	if !ok {
+		return "", fmt.Errorf("unknown language %q", lang)
+	}
 	checksum := types.HashBytes(seed[:])
 	checksumSeed := slices.Clone(seed[:])
 	checksumSeed = append(checksumSeed, checksum[:checksumBytes]...)
 	defer clear(checksumSeed)
-	return intToPhrase(bytesToInt(checksumSeed))
+	return intToPhrase(bytesToInt(checksumSeed), d), nil
 }
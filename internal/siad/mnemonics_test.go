@@ -3,10 +3,15 @@ package siad
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
+	"golang.org/x/text/unicode/norm"
+	"lukechampine.com/frand"
 )
 
 func TestMnemonic28(t *testing.T) {
@@ -94,3 +99,105 @@ func TestMnemonic29(t *testing.T) {
 		t.Fatalf("roundtrip failed: expected %q, got %q", phrase, roundtrip)
 	}
 }
+
+func TestDictionaries(t *testing.T) {
+	for _, d := range dictionaries {
+		if len(d.dict.words) != dictionarySize {
+			t.Fatalf("%s: expected %d words, got %d", d.lang, dictionarySize, len(d.dict.words))
+		}
+		prefixes := make(map[string]bool)
+		for _, word := range d.dict.words {
+			if norm.NFC.String(word) != word {
+				t.Fatalf("%s: word %q is not normalized", d.lang, word)
+			} else if utf8.RuneCountInString(word) < d.dict.prefixLen {
+				t.Fatalf("%s: word %q is shorter than the prefix", d.lang, word)
+			}
+			prefix := string([]rune(word)[:d.dict.prefixLen])
+			if prefixes[prefix] {
+				t.Fatalf("%s: duplicate prefix %q", d.lang, prefix)
+			}
+			prefixes[prefix] = true
+		}
+	}
+}
+
+func TestMnemonicLanguages(t *testing.T) {
+	var seed [32]byte
+	frand.Read(seed[:])
+
+	for _, lang := range []Language{English, German, Japanese} {
+		phrase, err := SeedToPhraseLanguage(&seed, lang)
+		if err != nil {
+			t.Fatal(err)
+		} else if detected, err := DetectLanguage(phrase); err != nil {
+			t.Fatal(err)
+		} else if detected != lang {
+			t.Fatalf("expected %s, got %s", lang, detected)
+		}
+
+		var decoded [32]byte
+		if err := SeedFromPhrase(&decoded, phrase); err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		} else if decoded != seed {
+			t.Fatalf("%s: expected seed %x, got %x", lang, seed, decoded)
+		}
+
+		// only the prefix of each word is significant
+		d, _ := dictionaryFor(lang)
+		words := strings.Fields(phrase)
+		for i, w := range words {
+			words[i] = string([]rune(w)[:d.prefixLen])
+		}
+		if err := SeedFromPhrase(&decoded, strings.Join(words, " ")); err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		} else if decoded != seed {
+			t.Fatalf("%s: expected seed %x, got %x", lang, seed, decoded)
+		}
+	}
+
+	if _, err := SeedToPhraseLanguage(&seed, "klingon"); err == nil {
+		t.Fatal("expected an error for an unknown language")
+	} else if _, err := DetectLanguage("abbey Abakus"); !errors.Is(err, ErrUnknownWord) {
+		t.Fatalf("expected %v, got %v", ErrUnknownWord, err)
+	}
+}
+
+func TestWordIndex(t *testing.T) {
+	english, _ := dictionaryFor(English)
+	for _, test := range []struct {
+		word  string
+		index int
+		ok    bool
+	}{
+		{"abbey", 0, true},
+		{"abbot", 0, true}, // only the prefix is significant
+		{"ab", 0, true},    // shorter words match the first word with the prefix
+		{"zoom", 1625, true},
+		{"zzz", 0, false},
+	} {
+		i, ok := english.wordIndex(test.word)
+		if ok != test.ok || i != test.index {
+			t.Fatalf("%q: expected (%d, %t), got (%d, %t)", test.word, test.index, test.ok, i, ok)
+		}
+	}
+}
+
+func TestMnemonicChecksum(t *testing.T) {
+	const phrase = "rodent colony illness junk waist leopard pierce oust wield viewpoint slackens axis jittery vampire rockets cistern eels oaks cell emotion eagle vortex pests cedar business cactus inorganic cocoa"
+
+	// a typo in any word but the last changes the entropy without
+	// changing the length
+	words := strings.Fields(phrase)
+	words[0] = "colony"
+	var seed [32]byte
+	if err := SeedFromPhrase(&seed, strings.Join(words, " ")); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("expected %v, got %v", ErrChecksum, err)
+	}
+
+	// swapping words is also caught
+	words = strings.Fields(phrase)
+	words[3], words[4] = words[4], words[3]
+	if err := SeedFromPhrase(&seed, strings.Join(words, " ")); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("expected %v, got %v", ErrChecksum, err)
+	}
+}
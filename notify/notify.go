@@ -0,0 +1,36 @@
+// Package notify delivers alerts for critical vault events to operators.
+package notify
+
+import "time"
+
+// Event types
+const (
+	EventUnlockFailed = "vault.unlockFailed"
+	EventUnlocked     = "vault.unlocked"
+	EventLocked       = "vault.locked"
+	EventSeedDeleted  = "seed.deleted"
+	EventSeedRestored = "seed.restored"
+	EventKeyMismatch  = "vault.keyMismatch"
+	EventReadOnly     = "vault.readOnly"
+	EventKeyExpiring  = "key.expiring"
+
+	EventSnapshotRestored = "vault.snapshotRestored"
+	EventStorageLimit     = "vault.storageLimit"
+
+	EventMaintenanceOverride = "vault.maintenanceOverride"
+)
+
+type (
+	// An Event is a critical event that operators should be alerted to.
+	Event struct {
+		Type      string    `json:"type"`
+		Subject   string    `json:"subject"`
+		Message   string    `json:"message"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+
+	// A Notifier delivers events to operators.
+	Notifier interface {
+		Notify(Event) error
+	}
+)
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// An SMTPNotifier delivers events by email.
+type SMTPNotifier struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+// Notify implements Notifier.
+func (n *SMTPNotifier) Notify(e Event) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", n.from)
+	fmt.Fprintf(&sb, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&sb, "Subject: [vaultd] %s\r\n", e.Subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", e.Timestamp.Format(time.RFC1123Z))
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	sb.WriteString(e.Message)
+	sb.WriteString("\r\n")
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, n.to, []byte(sb.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// NewSMTPNotifier returns a Notifier that emails events to the recipients
+// using the SMTP server at addr. If username is empty, no authentication is
+// used.
+func NewSMTPNotifier(addr, username, password, from string, to []string) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+	} else if from == "" {
+		return nil, errors.New("sender address is required")
+	} else if len(to) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+
+	n := &SMTPNotifier{
+		addr: addr,
+		from: from,
+		to:   to,
+	}
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n, nil
+}
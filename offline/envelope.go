@@ -0,0 +1,244 @@
+package offline
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.sia.tech/core/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// envelopeVersion is the version of encoded envelopes. Version 1
+	// requests have no nonce or timestamp and can still be decoded.
+	envelopeVersion = 2
+
+	chunkPrefix = "vaultd"
+)
+
+// ErrIncomplete is returned by [Unchunk] when chunks of the envelope are
+// missing.
+var ErrIncomplete = errors.New("incomplete envelope")
+
+// A Kind identifies the contents of an envelope.
+type Kind uint8
+
+// Envelope kinds
+const (
+	KindRequest Kind = iota + 1
+	KindResponse
+)
+
+// EncodeTo implements types.EncoderTo.
+func (sh SigHash) EncodeTo(e *types.Encoder) {
+	sh.PublicKey.EncodeTo(e)
+	sh.SigHash.EncodeTo(e)
+}
+
+// DecodeFrom implements types.DecoderFrom.
+func (sh *SigHash) DecodeFrom(d *types.Decoder) {
+	sh.PublicKey.DecodeFrom(d)
+	sh.SigHash.DecodeFrom(d)
+}
+
+// EncodeTo implements types.EncoderTo.
+func (s Signature) EncodeTo(e *types.Encoder) {
+	s.PublicKey.EncodeTo(e)
+	s.SigHash.EncodeTo(e)
+	s.Signature.EncodeTo(e)
+}
+
+// DecodeFrom implements types.DecoderFrom.
+func (s *Signature) DecodeFrom(d *types.Decoder) {
+	s.PublicKey.DecodeFrom(d)
+	s.SigHash.DecodeFrom(d)
+	s.Signature.DecodeFrom(d)
+}
+
+// EncodeTo implements types.EncoderTo.
+func (r SignRequest) EncodeTo(e *types.Encoder) {
+	r.TransactionID.EncodeTo(e)
+	types.EncodeSlice(e, r.SigHashes)
+	e.WriteString(r.Nonce)
+	// the timestamp is encoded in seconds, with 0 for none
+	var ts uint64
+	if !r.Timestamp.IsZero() {
+		ts = uint64(r.Timestamp.Unix())
+	}
+	e.WriteUint64(ts)
+}
+
+// DecodeFrom implements types.DecoderFrom.
+func (r *SignRequest) DecodeFrom(d *types.Decoder) {
+	r.TransactionID.DecodeFrom(d)
+	types.DecodeSlice(d, &r.SigHashes)
+	r.Nonce = d.ReadString()
+	if ts := d.ReadUint64(); ts != 0 {
+		r.Timestamp = time.Unix(int64(ts), 0)
+	}
+}
+
+// A v1Request is a sign request encoded in a version 1 envelope.
+type v1Request SignRequest
+
+// DecodeFrom implements types.DecoderFrom.
+func (r *v1Request) DecodeFrom(d *types.Decoder) {
+	r.TransactionID.DecodeFrom(d)
+	types.DecodeSlice(d, &r.SigHashes)
+}
+
+// EncodeTo implements types.EncoderTo.
+func (r SignResponse) EncodeTo(e *types.Encoder) {
+	r.TransactionID.EncodeTo(e)
+	types.EncodeSlice(e, r.Signatures)
+}
+
+// DecodeFrom implements types.DecoderFrom.
+func (r *SignResponse) DecodeFrom(d *types.Decoder) {
+	r.TransactionID.DecodeFrom(d)
+	types.DecodeSlice(d, &r.Signatures)
+}
+
+func encodeEnvelope(kind Kind, v types.EncoderTo) []byte {
+	var buf strings.Builder
+	e := types.NewEncoder(&buf)
+	e.WriteUint8(envelopeVersion)
+	e.WriteUint8(uint8(kind))
+	v.EncodeTo(e)
+	e.Flush()
+	return []byte(buf.String())
+}
+
+func decodeEnvelope(buf []byte, kind Kind, v types.DecoderFrom) error {
+	if k, err := EnvelopeKind(buf); err != nil {
+		return err
+	} else if k != kind {
+		return fmt.Errorf("unexpected envelope kind %d", k)
+	}
+	d := types.NewBufDecoder(buf[2:])
+	v.DecodeFrom(d)
+	return d.Err()
+}
+
+// EncodeRequest encodes a sign request as a compact binary envelope.
+func EncodeRequest(req SignRequest) []byte {
+	return encodeEnvelope(KindRequest, req)
+}
+
+// EncodeResponse encodes a sign response as a compact binary envelope.
+func EncodeResponse(resp SignResponse) []byte {
+	return encodeEnvelope(KindResponse, resp)
+}
+
+// EnvelopeKind returns the kind of an encoded envelope.
+func EnvelopeKind(buf []byte) (Kind, error) {
+	switch {
+	case len(buf) < 2:
+		return 0, errors.New("envelope too short")
+	case buf[0] == 0 || buf[0] > envelopeVersion:
+		return 0, fmt.Errorf("unsupported envelope version %d", buf[0])
+	}
+	switch kind := Kind(buf[1]); kind {
+	case KindRequest, KindResponse:
+		return kind, nil
+	default:
+		return 0, fmt.Errorf("unknown envelope kind %d", kind)
+	}
+}
+
+// DecodeRequest decodes a sign request from a binary envelope.
+func DecodeRequest(buf []byte) (req SignRequest, err error) {
+	if len(buf) > 0 && buf[0] == 1 {
+		err = decodeEnvelope(buf, KindRequest, (*v1Request)(&req))
+		return
+	}
+	err = decodeEnvelope(buf, KindRequest, &req)
+	return
+}
+
+// DecodeResponse decodes a sign response from a binary envelope.
+func DecodeResponse(buf []byte) (resp SignResponse, err error) {
+	err = decodeEnvelope(buf, KindResponse, &resp)
+	return
+}
+
+// envelopeID returns a short identifier for an envelope so chunks of
+// different envelopes are not mixed.
+func envelopeID(buf []byte) string {
+	h := blake2b.Sum256(buf)
+	return hex.EncodeToString(h[:4])
+}
+
+// Chunk splits an envelope into base64 encoded text chunks of at most size
+// data characters. Each chunk is prefixed with its position and the
+// envelope's ID so they can be scanned in any order.
+func Chunk(buf []byte, size int) []string {
+	if size <= 0 {
+		panic("chunk size must be positive") // developer error
+	}
+	data := base64.RawURLEncoding.EncodeToString(buf)
+	id := envelopeID(buf)
+	n := (len(data) + size - 1) / size
+	chunks := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		end := min((i+1)*size, len(data))
+		chunks = append(chunks, fmt.Sprintf("%s:%d/%d:%s:%s", chunkPrefix, i+1, n, id, data[i*size:end]))
+	}
+	return chunks
+}
+
+// Unchunk reassembles an envelope from its chunks. Chunks may be provided
+// in any order and duplicates are ignored.
+func Unchunk(chunks []string) ([]byte, error) {
+	var id string
+	var parts []string
+	for _, chunk := range chunks {
+		fields := strings.SplitN(strings.TrimSpace(chunk), ":", 4)
+		if len(fields) != 4 || fields[0] != chunkPrefix {
+			return nil, fmt.Errorf("invalid chunk %q", chunk)
+		}
+		pos, total, ok := strings.Cut(fields[1], "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid chunk position %q", fields[1])
+		}
+		i, err := strconv.Atoi(pos)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk position %q: %w", fields[1], err)
+		}
+		n, err := strconv.Atoi(total)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk position %q: %w", fields[1], err)
+		} else if n <= 0 || i <= 0 || i > n {
+			return nil, fmt.Errorf("invalid chunk position %q", fields[1])
+		}
+
+		if parts == nil {
+			id = fields[2]
+			parts = make([]string, n)
+		} else if fields[2] != id || n != len(parts) {
+			return nil, errors.New("chunks belong to different envelopes")
+		}
+		parts[i-1] = fields[3]
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("no chunks")
+	}
+	for i, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("%w: missing chunk %d/%d", ErrIncomplete, i+1, len(parts))
+		}
+	}
+
+	buf, err := base64.RawURLEncoding.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	} else if envelopeID(buf) != id {
+		return nil, errors.New("envelope checksum mismatch")
+	}
+	return buf, nil
+}
@@ -0,0 +1,90 @@
+package offline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestEnvelopeChunks(t *testing.T) {
+	var req SignRequest
+	frand.Read(req.TransactionID[:])
+	for range 10 {
+		req.SigHashes = append(req.SigHashes, SigHash{
+			PublicKey: frand.Entropy256(),
+			SigHash:   frand.Entropy256(),
+		})
+	}
+
+	buf := EncodeRequest(req)
+	if kind, err := EnvelopeKind(buf); err != nil {
+		t.Fatal(err)
+	} else if kind != KindRequest {
+		t.Fatalf("expected request, got %d", kind)
+	} else if _, err := DecodeResponse(buf); err == nil {
+		t.Fatal("expected kind error")
+	}
+
+	chunks := Chunk(buf, 100)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %d", len(chunks))
+	}
+
+	// reverse the chunks and add a duplicate to simulate scanning out of
+	// order
+	scanned := []string{chunks[0]}
+	for i := len(chunks) - 1; i >= 0; i-- {
+		scanned = append(scanned, chunks[i])
+	}
+	joined, err := Unchunk(scanned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeRequest(joined)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(req, decoded) {
+		t.Fatalf("expected %v, got %v", req, decoded)
+	}
+
+	// the nonce and timestamp are encoded to the second
+	req.Nonce, req.Timestamp = "foo", time.Now()
+	if decoded, err := DecodeRequest(EncodeRequest(req)); err != nil {
+		t.Fatal(err)
+	} else if decoded.Nonce != req.Nonce || decoded.Timestamp.Unix() != req.Timestamp.Unix() {
+		t.Fatalf("expected nonce %q at %v, got %q at %v", req.Nonce, req.Timestamp, decoded.Nonce, decoded.Timestamp)
+	}
+
+	// version 1 requests have no nonce or timestamp
+	var v1 strings.Builder
+	e := types.NewEncoder(&v1)
+	e.WriteUint8(1)
+	e.WriteUint8(uint8(KindRequest))
+	req.TransactionID.EncodeTo(e)
+	types.EncodeSlice(e, req.SigHashes)
+	e.Flush()
+	if decoded, err := DecodeRequest([]byte(v1.String())); err != nil {
+		t.Fatal(err)
+	} else if decoded.TransactionID != req.TransactionID || !reflect.DeepEqual(decoded.SigHashes, req.SigHashes) || decoded.Nonce != "" || !decoded.Timestamp.IsZero() {
+		t.Fatalf("unexpected version 1 request %v", decoded)
+	}
+
+	if _, err := Unchunk(chunks[1:]); err == nil {
+		t.Fatal("expected missing chunk error")
+	}
+
+	resp := SignResponse{
+		TransactionID: req.TransactionID,
+		Signatures: []Signature{
+			{PublicKey: frand.Entropy256(), SigHash: frand.Entropy256(), Signature: types.Signature(frand.Bytes(64))},
+		},
+	}
+	other := Chunk(EncodeResponse(resp), 100)
+	if _, err := Unchunk(append(chunks[1:], other[0])); err == nil {
+		t.Fatal("expected mixed envelope error")
+	}
+}
@@ -0,0 +1,330 @@
+package offline
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/vault"
+	"lukechampine.com/frand"
+)
+
+// ErrTransactionMismatch is returned when a response is merged into a
+// transaction other than the one the request was created for.
+var ErrTransactionMismatch = errors.New("response does not match transaction")
+
+type (
+	// A SigHash is a hash that should be signed by the key
+	// corresponding to PublicKey.
+	SigHash struct {
+		PublicKey types.PublicKey `json:"publicKey"`
+		SigHash   types.Hash256   `json:"sigHash"`
+	}
+
+	// A Signature is a signature produced by an offline signer.
+	Signature struct {
+		PublicKey types.PublicKey `json:"publicKey"`
+		SigHash   types.Hash256   `json:"sigHash"`
+		Signature types.Signature `json:"signature"`
+	}
+
+	// A SignRequest is exported by a watch-only instance and contains
+	// every sighash that needs a signature for a transaction. The
+	// offline signer never sees the transaction itself.
+	SignRequest struct {
+		TransactionID types.TransactionID `json:"transactionID"`
+		SigHashes     []SigHash           `json:"sigHashes"`
+		// Nonce and Timestamp let a signer with replay protection
+		// refuse a request it has already signed.
+		Nonce     string    `json:"nonce,omitempty"`
+		Timestamp time.Time `json:"timestamp,omitzero"`
+	}
+
+	// A SignResponse is produced by the offline signer and merged back
+	// into the transaction by the watch-only instance.
+	SignResponse struct {
+		TransactionID types.TransactionID `json:"transactionID"`
+		Signatures    []Signature         `json:"signatures"`
+	}
+
+	// A Signer signs sighashes with the key corresponding to the public
+	// key. If the key is not found, [vault.ErrNotFound] should be
+	// returned.
+	Signer interface {
+		Sign(types.PublicKey, types.Hash256) (types.Signature, error)
+	}
+
+	// A RequestSigner signs a whole sign request, so it can check the
+	// request's nonce before signing any of its sighashes.
+	RequestSigner interface {
+		SignRequest(SignRequest) (SignResponse, error)
+	}
+)
+
+// newNonce returns a random nonce for a sign request.
+func newNonce() string {
+	return hex.EncodeToString(frand.Bytes(16))
+}
+
+// v1PublicKey returns the ed25519 public key required to fill the
+// signature.
+func v1PublicKey(txn types.Transaction, sig types.TransactionSignature) (types.PublicKey, bool) {
+	getUnlockConditions := func(id types.Hash256) (types.UnlockConditions, bool) {
+		for _, input := range txn.SiacoinInputs {
+			if types.Hash256(input.ParentID) == id {
+				return input.UnlockConditions, true
+			}
+		}
+		for _, input := range txn.SiafundInputs {
+			if types.Hash256(input.ParentID) == id {
+				return input.UnlockConditions, true
+			}
+		}
+		return types.UnlockConditions{}, false
+	}
+
+	uc, ok := getUnlockConditions(sig.ParentID)
+	if !ok || sig.PublicKeyIndex >= uint64(len(uc.PublicKeys)) {
+		return types.PublicKey{}, false
+	}
+	uk := uc.PublicKeys[sig.PublicKeyIndex]
+	if uk.Algorithm != types.SpecifierEd25519 || len(uk.Key) != ed25519.PublicKeySize {
+		return types.PublicKey{}, false
+	}
+	return types.PublicKey(uk.Key), true
+}
+
+func v1SigHash(cs consensus.State, txn types.Transaction, sig types.TransactionSignature) types.Hash256 {
+	if sig.CoveredFields.WholeTransaction {
+		return cs.WholeSigHash(txn, sig.ParentID, sig.PublicKeyIndex, sig.Timelock, nil)
+	}
+	return cs.PartialSigHash(txn, sig.CoveredFields)
+}
+
+// walkPolicy calls fn for each ed25519 public key in the policy that could
+// contribute a signature, in the order signatures must appear in the
+// satisfied policy. fn returns whether the key was signed.
+func walkPolicy(policy types.SpendPolicy, fn func(types.PublicKey) bool) {
+	switch policy := policy.Type.(type) {
+	case types.PolicyTypeThreshold:
+		for _, sub := range policy.Of {
+			walkPolicy(sub, fn)
+		}
+	case types.PolicyTypePublicKey:
+		fn(types.PublicKey(policy))
+	case types.PolicyTypeUnlockConditions:
+		var signed uint64
+		for _, uk := range policy.PublicKeys {
+			if signed == policy.SignaturesRequired {
+				break
+			} else if uk.Algorithm != types.SpecifierEd25519 || len(uk.Key) != ed25519.PublicKeySize {
+				continue
+			}
+			if fn(types.PublicKey(uk.Key)) {
+				signed++
+			}
+		}
+	}
+}
+
+// NewV1Request returns a request for every unsigned signature in a v1
+// transaction. The request has a new nonce and the current time.
+func NewV1Request(cs consensus.State, txn types.Transaction) SignRequest {
+	req := SignRequest{
+		TransactionID: txn.ID(),
+		Nonce:         newNonce(),
+		Timestamp:     time.Now(),
+	}
+	for _, sig := range txn.Signatures {
+		if sig.Signature != nil {
+			continue
+		}
+		pk, ok := v1PublicKey(txn, sig)
+		if !ok {
+			continue
+		}
+		req.SigHashes = append(req.SigHashes, SigHash{
+			PublicKey: pk,
+			SigHash:   v1SigHash(cs, txn, sig),
+		})
+	}
+	return req
+}
+
+// NewV2Request returns a request for every public key referenced by the
+// spend policies of a v2 transaction. The request has a new nonce and the
+// current time.
+func NewV2Request(cs consensus.State, txn types.V2Transaction) SignRequest {
+	req := SignRequest{
+		TransactionID: txn.ID(),
+		Nonce:         newNonce(),
+		Timestamp:     time.Now(),
+	}
+	sigHash := cs.InputSigHash(txn)
+	seen := make(map[types.PublicKey]bool)
+	// no key is signed yet, so every key of an unlock conditions policy is
+	// requested; the signer may not hold the first ones
+	add := func(pk types.PublicKey) bool {
+		if !seen[pk] {
+			seen[pk] = true
+			req.SigHashes = append(req.SigHashes, SigHash{PublicKey: pk, SigHash: sigHash})
+		}
+		return false
+	}
+	for _, sci := range txn.SiacoinInputs {
+		walkPolicy(sci.SatisfiedPolicy.Policy, add)
+	}
+	for _, sfi := range txn.SiafundInputs {
+		walkPolicy(sfi.SatisfiedPolicy.Policy, add)
+	}
+	return req
+}
+
+// Sign signs every sighash in the request that the signer holds a key for.
+// Sighashes for unknown keys are skipped.
+func Sign(s Signer, req SignRequest) (SignResponse, error) {
+	resp := SignResponse{
+		TransactionID: req.TransactionID,
+	}
+	for _, sh := range req.SigHashes {
+		sig, err := s.Sign(sh.PublicKey, sh.SigHash)
+		if errors.Is(err, vault.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return SignResponse{}, fmt.Errorf("failed to sign %v: %w", sh.PublicKey, err)
+		}
+		resp.Signatures = append(resp.Signatures, Signature{
+			PublicKey: sh.PublicKey,
+			SigHash:   sh.SigHash,
+			Signature: sig,
+		})
+	}
+	return resp, nil
+}
+
+// signatureMap indexes the response's signatures, discarding any that
+// are invalid.
+func signatureMap(resp SignResponse) map[SigHash]types.Signature {
+	m := make(map[SigHash]types.Signature, len(resp.Signatures))
+	for _, sig := range resp.Signatures {
+		if !sig.PublicKey.VerifyHash(sig.SigHash, sig.Signature) {
+			continue
+		}
+		m[SigHash{PublicKey: sig.PublicKey, SigHash: sig.SigHash}] = sig.Signature
+	}
+	return m
+}
+
+// MergeV1 adds the signatures from the response to the transaction. The
+// sighashes are recomputed from the transaction so a response can only
+// fill signatures the transaction actually requires. It returns true if
+// the transaction is fully signed.
+func MergeV1(cs consensus.State, txn *types.Transaction, resp SignResponse) (bool, error) {
+	if txn.ID() != resp.TransactionID {
+		return false, ErrTransactionMismatch
+	}
+
+	sigs := signatureMap(resp)
+	var signed int
+	for i, sig := range txn.Signatures {
+		if sig.Signature != nil {
+			signed++
+			continue
+		}
+		pk, ok := v1PublicKey(*txn, sig)
+		if !ok {
+			continue
+		}
+		signature, ok := sigs[SigHash{PublicKey: pk, SigHash: v1SigHash(cs, *txn, sig)}]
+		if !ok {
+			continue
+		}
+		txn.Signatures[i].Signature = signature[:]
+		signed++
+	}
+	return signed == len(txn.Signatures), nil
+}
+
+// MergeV2 adds the signatures from the response to the satisfied policies
+// of the transaction's inputs. Each input's signatures are rebuilt from
+// its existing signatures and the response's, so an input partially
+// signed by one response can be completed by another. It returns true if
+// every input's policy has enough signatures.
+func MergeV2(cs consensus.State, txn *types.V2Transaction, resp SignResponse) (bool, error) {
+	if txn.ID() != resp.TransactionID {
+		return false, ErrTransactionMismatch
+	}
+
+	sigs := signatureMap(resp)
+	sigHash := cs.InputSigHash(*txn)
+
+	// existing holds the signatures of the input being merged
+	var existing []types.Signature
+	lookup := func(pk types.PublicKey) (types.Signature, bool) {
+		if sig, ok := sigs[SigHash{PublicKey: pk, SigHash: sigHash}]; ok {
+			return sig, true
+		}
+		for _, sig := range existing {
+			if pk.VerifyHash(sigHash, sig) {
+				return sig, true
+			}
+		}
+		return types.Signature{}, false
+	}
+
+	var satisfied func(types.SpendPolicy, []types.Signature) ([]types.Signature, bool)
+	satisfied = func(policy types.SpendPolicy, signatures []types.Signature) ([]types.Signature, bool) {
+		switch p := policy.Type.(type) {
+		case types.PolicyTypeThreshold:
+			var n uint8
+			for _, sub := range p.Of {
+				if n == p.N {
+					break
+				}
+				// signatures for partially satisfied sub-policies are
+				// discarded
+				if next, ok := satisfied(sub, signatures); ok {
+					signatures = next
+					n++
+				}
+			}
+			return signatures, n == p.N
+		case types.PolicyTypePublicKey, types.PolicyTypeUnlockConditions:
+			var n uint64
+			walkPolicy(policy, func(pk types.PublicKey) bool {
+				sig, ok := lookup(pk)
+				if ok {
+					signatures = append(signatures, sig)
+					n++
+				}
+				return ok
+			})
+			if uc, ok := p.(types.PolicyTypeUnlockConditions); ok {
+				return signatures, n >= uc.SignaturesRequired
+			}
+			return signatures, n == 1
+		default:
+			return signatures, false
+		}
+	}
+
+	merge := func(sp *types.SatisfiedPolicy) bool {
+		existing = sp.Signatures
+		var ok bool
+		sp.Signatures, ok = satisfied(sp.Policy, nil)
+		return ok
+	}
+
+	signed := true
+	for i := range txn.SiacoinInputs {
+		signed = merge(&txn.SiacoinInputs[i].SatisfiedPolicy) && signed
+	}
+	for i := range txn.SiafundInputs {
+		signed = merge(&txn.SiafundInputs[i].SatisfiedPolicy) && signed
+	}
+	return signed, nil
+}
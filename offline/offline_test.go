@@ -0,0 +1,194 @@
+package offline
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/testutil"
+	"go.sia.tech/vaultd/vault"
+	"lukechampine.com/frand"
+)
+
+type keySigner map[types.PublicKey]types.PrivateKey
+
+func (ks keySigner) Sign(pk types.PublicKey, sigHash types.Hash256) (types.Signature, error) {
+	sk, ok := ks[pk]
+	if !ok {
+		return types.Signature{}, vault.ErrNotFound
+	}
+	return sk.SignHash(sigHash), nil
+}
+
+func TestV1Requests(t *testing.T) {
+	n, _ := testutil.Network()
+	cs := n.GenesisState()
+
+	keys := make([]types.PrivateKey, 3)
+	for i := range keys {
+		keys[i] = types.GeneratePrivateKey()
+	}
+	uc := func(required uint64, indices ...int) types.UnlockConditions {
+		uc := types.UnlockConditions{SignaturesRequired: required}
+		for _, i := range indices {
+			uc.PublicKeys = append(uc.PublicKeys, keys[i].PublicKey().UnlockKey())
+		}
+		return uc
+	}
+
+	tests := []struct {
+		name       string
+		conditions types.UnlockConditions
+		// signatures are the public key indices of the transaction's
+		// signatures
+		signatures []uint64
+		// responses are the keys held by each signer, merged in order
+		responses [][]int
+		requested []int
+		signed    bool
+	}{
+		{"single", uc(1, 0), []uint64{0}, [][]int{{0}}, []int{0}, true},
+		{"unknown key", uc(1, 0), []uint64{0}, [][]int{{1}}, []int{0}, false},
+		{"multisig", uc(2, 0, 1, 2), []uint64{1, 2}, [][]int{{1, 2}}, []int{1, 2}, true},
+		{"partial merge", uc(2, 0, 1, 2), []uint64{0, 2}, [][]int{{0}, {2}}, []int{0, 2}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			txn := types.Transaction{
+				SiacoinInputs: []types.SiacoinInput{{
+					ParentID:         frand.Entropy256(),
+					UnlockConditions: test.conditions,
+				}},
+			}
+			for _, index := range test.signatures {
+				txn.Signatures = append(txn.Signatures, types.TransactionSignature{
+					ParentID:       types.Hash256(txn.SiacoinInputs[0].ParentID),
+					PublicKeyIndex: index,
+					CoveredFields:  types.CoveredFields{WholeTransaction: true},
+				})
+			}
+
+			req := NewV1Request(cs, txn)
+			if len(req.SigHashes) != len(test.requested) {
+				t.Fatalf("expected %d sighashes, got %d", len(test.requested), len(req.SigHashes))
+			}
+			for i, sh := range req.SigHashes {
+				if sh.PublicKey != keys[test.requested[i]].PublicKey() {
+					t.Fatalf("sighash %d: expected key %d", i, test.requested[i])
+				}
+			}
+
+			var signed bool
+			for _, held := range test.responses {
+				signer := make(keySigner)
+				for _, i := range held {
+					signer[keys[i].PublicKey()] = keys[i]
+				}
+				resp, err := Sign(signer, NewV1Request(cs, txn))
+				if err != nil {
+					t.Fatal(err)
+				} else if signed, err = MergeV1(cs, &txn, resp); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if signed != test.signed {
+				t.Fatalf("expected signed %v, got %v", test.signed, signed)
+			}
+			for i, sig := range txn.Signatures {
+				if sig.Signature == nil {
+					continue
+				}
+				pk := keys[test.requested[i]].PublicKey()
+				if !pk.VerifyHash(cs.WholeSigHash(txn, sig.ParentID, sig.PublicKeyIndex, sig.Timelock, nil), types.Signature(sig.Signature)) {
+					t.Fatalf("signature %d is invalid", i)
+				}
+			}
+		})
+	}
+}
+
+func TestV2Requests(t *testing.T) {
+	n, _ := testutil.Network()
+	cs := n.GenesisState()
+
+	keys := make([]types.PrivateKey, 3)
+	for i := range keys {
+		keys[i] = types.GeneratePrivateKey()
+	}
+	pk := func(i int) types.SpendPolicy { return types.PolicyPublicKey(keys[i].PublicKey()) }
+	uc := func(required uint64, indices ...int) types.SpendPolicy {
+		uc := types.UnlockConditions{SignaturesRequired: required}
+		for _, i := range indices {
+			uc.PublicKeys = append(uc.PublicKeys, keys[i].PublicKey().UnlockKey())
+		}
+		return types.SpendPolicy{Type: types.PolicyTypeUnlockConditions(uc)}
+	}
+
+	tests := []struct {
+		name   string
+		policy types.SpendPolicy
+		// responses are the keys held by each signer, merged in order
+		responses [][]int
+		requested []int
+		// signatures are the keys of the merged signatures, in order
+		signatures []int
+		signed     bool
+	}{
+		{"public key", pk(0), [][]int{{0}}, []int{0}, []int{0}, true},
+		{"unknown key", pk(0), [][]int{{1}}, []int{0}, nil, false},
+		{"threshold", types.PolicyThreshold(2, []types.SpendPolicy{pk(0), pk(1), pk(2)}), [][]int{{0, 1}}, []int{0, 1, 2}, []int{0, 1}, true},
+		{"threshold not met", types.PolicyThreshold(2, []types.SpendPolicy{pk(0), pk(1)}), [][]int{{1}}, []int{0, 1}, []int{1}, false},
+		{"threshold partial merge", types.PolicyThreshold(2, []types.SpendPolicy{pk(0), pk(1)}), [][]int{{0}, {1}}, []int{0, 1}, []int{0, 1}, true},
+		{"unlock conditions", uc(1, 0, 1), [][]int{{0, 1}}, []int{0, 1}, []int{0}, true},
+		{"unlock conditions later keys", uc(2, 0, 1, 2), [][]int{{1, 2}}, []int{0, 1, 2}, []int{1, 2}, true},
+		{"unlock conditions not met", uc(2, 0, 1, 2), [][]int{{2}}, []int{0, 1, 2}, []int{2}, false},
+		{"unlock conditions partial merge", uc(2, 0, 1, 2), [][]int{{2}, {0}}, []int{0, 1, 2}, []int{0, 2}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			txn := types.V2Transaction{
+				SiacoinInputs: []types.V2SiacoinInput{{
+					Parent:          types.SiacoinElement{ID: frand.Entropy256()},
+					SatisfiedPolicy: types.SatisfiedPolicy{Policy: test.policy},
+				}},
+			}
+
+			req := NewV2Request(cs, txn)
+			if len(req.SigHashes) != len(test.requested) {
+				t.Fatalf("expected %d sighashes, got %d", len(test.requested), len(req.SigHashes))
+			}
+			for i, sh := range req.SigHashes {
+				if sh.PublicKey != keys[test.requested[i]].PublicKey() {
+					t.Fatalf("sighash %d: expected key %d", i, test.requested[i])
+				}
+			}
+
+			var signed bool
+			for _, held := range test.responses {
+				signer := make(keySigner)
+				for _, i := range held {
+					signer[keys[i].PublicKey()] = keys[i]
+				}
+				resp, err := Sign(signer, req)
+				if err != nil {
+					t.Fatal(err)
+				} else if signed, err = MergeV2(cs, &txn, resp); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if signed != test.signed {
+				t.Fatalf("expected signed %v, got %v", test.signed, signed)
+			}
+
+			sigs := txn.SiacoinInputs[0].SatisfiedPolicy.Signatures
+			if len(sigs) != len(test.signatures) {
+				t.Fatalf("expected %d signatures, got %d", len(test.signatures), len(sigs))
+			}
+			sigHash := cs.InputSigHash(txn)
+			for i, sig := range sigs {
+				if !keys[test.signatures[i]].PublicKey().VerifyHash(sigHash, sig) {
+					t.Fatalf("signature %d: expected a signature from key %d", i, test.signatures[i])
+				}
+			}
+		})
+	}
+}
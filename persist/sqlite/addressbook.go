@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/addressbook"
+)
+
+// AddressBookEntries returns a paginated list of address book entries sorted
+// by creation time, ASC.
+func (s *Store) AddressBookEntries(limit, offset int) (entries []addressbook.Entry, err error) {
+	err = s.transaction(func(tx *txn) error {
+		rows, err := tx.Query(`SELECT id, address, label, verified, date_created, date_updated FROM address_book ORDER BY date_created ASC LIMIT $1 OFFSET $2`, limit, offset)
+		if err != nil {
+			return fmt.Errorf("failed to query address book: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			entry, err := scanAddressBookEntry(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan address book entry: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// AddressBookEntry returns the entry with the given ID. If the entry is not
+// found, [addressbook.ErrNotFound] is returned.
+func (s *Store) AddressBookEntry(id addressbook.ID) (entry addressbook.Entry, err error) {
+	err = s.transaction(func(tx *txn) error {
+		entry, err = addressBookEntry(tx, id)
+		return err
+	})
+	return
+}
+
+// AddAddressBookEntry adds a new entry to the address book. If the address is
+// already in the address book, [addressbook.ErrExists] is returned.
+func (s *Store) AddAddressBookEntry(addr types.Address, label string, verified bool) (entry addressbook.Entry, err error) {
+	err = s.transaction(func(tx *txn) error {
+		now := time.Now()
+		var id addressbook.ID
+		err := tx.QueryRow(`INSERT INTO address_book (address, label, verified, date_created, date_updated) VALUES ($1, $2, $3, $4, $4) RETURNING id`, sqlAddress(addr), label, verified, sqlTime(now)).Scan(&id)
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return addressbook.ErrExists
+		} else if err != nil {
+			return fmt.Errorf("failed to insert address book entry: %w", err)
+		}
+		entry, err = addressBookEntry(tx, id)
+		return err
+	})
+	return
+}
+
+// UpdateAddressBookEntry updates the label and verification status of an
+// entry. If the entry is not found, [addressbook.ErrNotFound] is returned.
+func (s *Store) UpdateAddressBookEntry(id addressbook.ID, label string, verified bool) (entry addressbook.Entry, err error) {
+	err = s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`UPDATE address_book SET label=$1, verified=$2, date_updated=$3 WHERE id=$4`, label, verified, sqlTime(time.Now()), id)
+		if err != nil {
+			return fmt.Errorf("failed to update address book entry: %w", err)
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return addressbook.ErrNotFound
+		}
+		entry, err = addressBookEntry(tx, id)
+		return err
+	})
+	return
+}
+
+// DeleteAddressBookEntry removes an entry from the address book. If the entry
+// is not found, [addressbook.ErrNotFound] is returned.
+func (s *Store) DeleteAddressBookEntry(id addressbook.ID) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`DELETE FROM address_book WHERE id=$1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete address book entry: %w", err)
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return addressbook.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func addressBookEntry(tx *txn, id addressbook.ID) (addressbook.Entry, error) {
+	entry, err := scanAddressBookEntry(tx.QueryRow(`SELECT id, address, label, verified, date_created, date_updated FROM address_book WHERE id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return addressbook.Entry{}, addressbook.ErrNotFound
+	} else if err != nil {
+		return addressbook.Entry{}, fmt.Errorf("failed to get address book entry: %w", err)
+	}
+	return entry, nil
+}
+
+func scanAddressBookEntry(s scanner) (entry addressbook.Entry, err error) {
+	err = s.Scan(&entry.ID, (*sqlAddress)(&entry.Address), &entry.Label, &entry.Verified, (*sqlTime)(&entry.CreatedAt), (*sqlTime)(&entry.UpdatedAt))
+	return
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/apitoken"
+)
+
+// APITokens returns every API token sorted by creation time, ASC.
+func (s *Store) APITokens() (tokens []apitoken.Token, err error) {
+	err = s.transaction(func(tx *txn) error {
+		rows, err := tx.Query(`SELECT id, name, scopes, date_created FROM api_tokens ORDER BY date_created ASC, id ASC`)
+		if err != nil {
+			return fmt.Errorf("failed to query API tokens: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			token, err := scanAPIToken(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan API token: %w", err)
+			}
+			tokens = append(tokens, token)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// APITokenByHash returns the API token with the given secret hash. If the
+// token is not found, [apitoken.ErrNotFound] is returned.
+func (s *Store) APITokenByHash(hash types.Hash256) (token apitoken.Token, err error) {
+	err = s.transaction(func(tx *txn) error {
+		token, err = scanAPIToken(tx.QueryRow(`SELECT id, name, scopes, date_created FROM api_tokens WHERE token_hash=$1`, sqlHash256(hash)))
+		if errors.Is(err, sql.ErrNoRows) {
+			return apitoken.ErrNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get API token: %w", err)
+		}
+		return nil
+	})
+	return
+}
+
+// AddAPIToken adds a new API token. If the name is already in use,
+// [apitoken.ErrExists] is returned.
+func (s *Store) AddAPIToken(name string, hash types.Hash256, scopes []apitoken.Scope) (token apitoken.Token, err error) {
+	err = s.transaction(func(tx *txn) error {
+		token, err = scanAPIToken(tx.QueryRow(`INSERT INTO api_tokens (name, token_hash, scopes, date_created) VALUES ($1, $2, $3, $4) RETURNING id, name, scopes, date_created`, name, sqlHash256(hash), encodeScopes(scopes), sqlTime(time.Now())))
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: api_tokens.name") {
+			return apitoken.ErrExists
+		} else if err != nil {
+			return fmt.Errorf("failed to insert API token: %w", err)
+		}
+		return nil
+	})
+	return
+}
+
+// DeleteAPIToken removes an API token. If the token is not found,
+// [apitoken.ErrNotFound] is returned.
+func (s *Store) DeleteAPIToken(id apitoken.ID) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`DELETE FROM api_tokens WHERE id=$1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete API token: %w", err)
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return apitoken.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// encodeScopes joins scopes into a comma-separated list.
+func encodeScopes(scopes []apitoken.Scope) string {
+	s := make([]string, len(scopes))
+	for i := range scopes {
+		s[i] = string(scopes[i])
+	}
+	return strings.Join(s, ",")
+}
+
+func scanAPIToken(s scanner) (token apitoken.Token, err error) {
+	var scopes string
+	if err = s.Scan(&token.ID, &token.Name, &scopes, (*sqlTime)(&token.CreatedAt)); err != nil {
+		return
+	}
+	for sc := range strings.SplitSeq(scopes, ",") {
+		if sc != "" {
+			token.Scopes = append(token.Scopes, apitoken.Scope(sc))
+		}
+	}
+	return
+}
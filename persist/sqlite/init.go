@@ -34,6 +34,7 @@ func (s *Store) initNewDatabase(target int64) error {
 
 func (s *Store) upgradeDatabase(current, target int64) error {
 	log := s.log.Named("migrations").With(zap.Int64("target", target))
+	log.Info("migrating database", zap.Int64("current", current))
 	for ; current < target; current++ {
 		version := current + 1 // initial schema is version 1, migration 0 is version 2, etc.
 		log := log.With(zap.Int64("version", version))
@@ -1,21 +1,77 @@
 CREATE TABLE seeds (
 	id INTEGER PRIMARY KEY,
 	seed_mac BLOB UNIQUE NOT NULL CHECK(length(seed_mac) = 32),
-	encrypted_seed BLOB UNIQUE NOT NULL CHECK(length(encrypted_seed) = 72),
-	date_created INTEGER NOT NULL
+	encrypted_seed BLOB UNIQUE NOT NULL CHECK(length(encrypted_seed) > 1), -- prefixed with the encryption format
+	date_created INTEGER NOT NULL,
+	date_deleted INTEGER, -- NULL unless the seed is deleted and awaiting purge
+	next_index INTEGER NOT NULL DEFAULT 0, -- the next index to allocate, incremented atomically
+	phrase_language TEXT NOT NULL DEFAULT '', -- the language of the recovery phrase, empty if unknown
+	date_expires INTEGER -- NULL unless signing with the seed's keys is refused after this time
 );
 CREATE INDEX seeds_date_created_idx ON seeds (date_created ASC);
+CREATE INDEX seeds_date_deleted_idx ON seeds (date_deleted);
 
 CREATE TABLE signing_keys (
 	public_key BLOB PRIMARY KEY CHECK(length(public_key) = 32),
 	seed_id INTEGER NOT NULL REFERENCES seeds (id),
-	seed_index INTEGER NOT NULL
+	seed_index INTEGER NOT NULL,
+	v1_address BLOB NOT NULL CHECK(length(v1_address) = 32),
+	v2_address BLOB NOT NULL CHECK(length(v2_address) = 32),
+	date_created INTEGER NOT NULL DEFAULT 0, -- keys derived before tracking use their seed's creation time
+	date_expires INTEGER -- NULL unless signing with the key is refused after this time
 );
 CREATE INDEX signing_keys_seed_id_idx ON signing_keys (seed_id);
 CREATE INDEX signing_keys_seed_id_seed_index_idx ON signing_keys (seed_id, seed_index ASC);
+CREATE INDEX signing_keys_v1_address_idx ON signing_keys (v1_address);
+CREATE INDEX signing_keys_v2_address_idx ON signing_keys (v2_address);
+CREATE INDEX signing_keys_date_created_idx ON signing_keys (date_created);
+
+CREATE TABLE index_reservations (
+	id INTEGER PRIMARY KEY,
+	seed_id INTEGER NOT NULL REFERENCES seeds (id),
+	start_index INTEGER NOT NULL,
+	end_index INTEGER NOT NULL CHECK(end_index > start_index), -- exclusive
+	date_created INTEGER NOT NULL
+);
+CREATE INDEX index_reservations_seed_id_idx ON index_reservations (seed_id);
 
 CREATE TABLE global_settings (
 	id INTEGER PRIMARY KEY NOT NULL DEFAULT 0 CHECK (id = 0), -- enforce a single row
 	db_version INTEGER NOT NULL, -- used for migrations
-	key_salt BLOB -- the salt used for deriving keys
+	key_salt BLOB, -- the salt used for deriving keys
+	key_version INTEGER NOT NULL DEFAULT 0, -- the key derivation used for the stored seeds
+	failed_unlocks INTEGER NOT NULL DEFAULT 0, -- consecutive failed unlock attempts
+	last_failed_unlock INTEGER NOT NULL DEFAULT 0 -- the time of the last failed unlock attempt
+);
+
+CREATE TABLE address_book (
+	id INTEGER PRIMARY KEY,
+	address BLOB UNIQUE NOT NULL CHECK(length(address) = 32),
+	label TEXT NOT NULL,
+	verified BOOLEAN NOT NULL DEFAULT false,
+	date_created INTEGER NOT NULL,
+	date_updated INTEGER NOT NULL
+);
+CREATE INDEX address_book_date_created_idx ON address_book (date_created ASC);
+
+CREATE TABLE sign_nonces (
+	nonce TEXT PRIMARY KEY,
+	date_expires INTEGER NOT NULL
+);
+CREATE INDEX sign_nonces_date_expires_idx ON sign_nonces (date_expires);
+
+CREATE TABLE api_tokens (
+	id INTEGER PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	token_hash BLOB UNIQUE NOT NULL CHECK(length(token_hash) = 32),
+	scopes TEXT NOT NULL,
+	date_created INTEGER NOT NULL
+);
+
+CREATE TABLE webhooks (
+	id INTEGER PRIMARY KEY,
+	callback_url TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	secret_key TEXT NOT NULL,
+	date_created INTEGER NOT NULL
 );
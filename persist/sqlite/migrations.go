@@ -1,6 +1,9 @@
 package sqlite
 
 import (
+	"fmt"
+
+	"go.sia.tech/core/types"
 	"go.uber.org/zap"
 )
 
@@ -23,4 +26,243 @@ var migrations = []func(tx *txn, log *zap.Logger) error{
 		_, err := tx.Exec(`CREATE INDEX seeds_date_created_idx ON seeds (date_created ASC);`)
 		return err
 	},
+	// migration 3: add the address book table
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`CREATE TABLE address_book (
+	id INTEGER PRIMARY KEY,
+	address BLOB UNIQUE NOT NULL CHECK(length(address) = 32),
+	label TEXT NOT NULL,
+	verified BOOLEAN NOT NULL DEFAULT false,
+	date_created INTEGER NOT NULL,
+	date_updated INTEGER NOT NULL
+);
+CREATE INDEX address_book_date_created_idx ON address_book (date_created ASC);`)
+		return err
+	},
+	// migration 4: store the v1 and v2 addresses of each signing key
+	func(tx *txn, log *zap.Logger) error {
+		rows, err := tx.Query(`SELECT public_key, seed_id, seed_index FROM signing_keys`)
+		if err != nil {
+			return fmt.Errorf("failed to query signing keys: %w", err)
+		}
+		type signingKey struct {
+			pk    sqlPublicKey
+			id    int64
+			index uint64
+		}
+		var keys []signingKey
+		for rows.Next() {
+			var key signingKey
+			if err := rows.Scan(&key.pk, &key.id, &key.index); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan signing key: %w", err)
+			}
+			keys = append(keys, key)
+		}
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("failed to close rows: %w", err)
+		}
+
+		_, err = tx.Exec(`DROP INDEX signing_keys_seed_id_idx;
+DROP INDEX signing_keys_seed_id_seed_index_idx;
+ALTER TABLE signing_keys RENAME TO signing_keys_old;
+CREATE TABLE signing_keys (
+	public_key BLOB PRIMARY KEY CHECK(length(public_key) = 32),
+	seed_id INTEGER NOT NULL REFERENCES seeds (id),
+	seed_index INTEGER NOT NULL,
+	v1_address BLOB NOT NULL CHECK(length(v1_address) = 32),
+	v2_address BLOB NOT NULL CHECK(length(v2_address) = 32)
+);`)
+		if err != nil {
+			return fmt.Errorf("failed to create signing keys table: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`INSERT INTO signing_keys (public_key, seed_id, seed_index, v1_address, v2_address) VALUES ($1, $2, $3, $4, $5)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		progress := newMigrationProgress(log, len(keys))
+		for _, key := range keys {
+			v1, v2 := keyAddresses(types.PublicKey(key.pk))
+			if _, err := stmt.Exec(key.pk, key.id, key.index, sqlAddress(v1), sqlAddress(v2)); err != nil {
+				return fmt.Errorf("failed to insert signing key: %w", err)
+			}
+			progress.Add(1)
+		}
+		log.Debug("migrated signing keys", zap.Int("count", len(keys)))
+
+		_, err = tx.Exec(`DROP TABLE signing_keys_old;
+CREATE INDEX signing_keys_seed_id_idx ON signing_keys (seed_id);
+CREATE INDEX signing_keys_seed_id_seed_index_idx ON signing_keys (seed_id, seed_index ASC);
+CREATE INDEX signing_keys_v1_address_idx ON signing_keys (v1_address);
+CREATE INDEX signing_keys_v2_address_idx ON signing_keys (v2_address);`)
+		return err
+	},
+	// migration 5: add soft deletion of seeds
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE seeds ADD COLUMN date_deleted INTEGER;
+CREATE INDEX seeds_date_deleted_idx ON seeds (date_deleted);`)
+		return err
+	},
+	// migration 6: add the sign request nonce table for replay protection
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`CREATE TABLE sign_nonces (
+	nonce TEXT PRIMARY KEY,
+	date_expires INTEGER NOT NULL
+);
+CREATE INDEX sign_nonces_date_expires_idx ON sign_nonces (date_expires);`)
+		return err
+	},
+	// migration 7: add the key derivation version. Seeds are rekeyed by
+	// the vault when it is next unlocked.
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE global_settings ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0;`)
+		return err
+	},
+	// migration 8: prefix encrypted seeds with their encryption format.
+	// Dropping the seeds table while signing keys reference it violates
+	// their foreign key, so the signing keys are moved to a temporary table
+	// while the seeds table is rebuilt.
+	func(tx *txn, log *zap.Logger) error {
+		_, err := tx.Exec(`CREATE TABLE signing_keys_tmp AS SELECT * FROM signing_keys;
+DROP TABLE signing_keys;
+DROP INDEX seeds_date_created_idx;
+DROP INDEX seeds_date_deleted_idx;
+ALTER TABLE seeds RENAME TO seeds_old;
+CREATE TABLE seeds (
+	id INTEGER PRIMARY KEY,
+	seed_mac BLOB UNIQUE NOT NULL CHECK(length(seed_mac) = 32),
+	encrypted_seed BLOB UNIQUE NOT NULL CHECK(length(encrypted_seed) > 1),
+	date_created INTEGER NOT NULL,
+	date_deleted INTEGER
+);`)
+		if err != nil {
+			return fmt.Errorf("failed to create seeds table: %w", err)
+		}
+
+		type seed struct {
+			id            int64
+			mac           []byte
+			encryptedSeed []byte
+			created       int64
+			deleted       *int64
+		}
+		rows, err := tx.Query(`SELECT id, seed_mac, encrypted_seed, date_created, date_deleted FROM seeds_old`)
+		if err != nil {
+			return fmt.Errorf("failed to query seeds: %w", err)
+		}
+		defer rows.Close()
+		var seeds []seed
+		for rows.Next() {
+			var s seed
+			if err := rows.Scan(&s.id, &s.mac, &s.encryptedSeed, &s.created, &s.deleted); err != nil {
+				return fmt.Errorf("failed to scan seed: %w", err)
+			}
+			seeds = append(seeds, s)
+		}
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("failed to close rows: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`INSERT INTO seeds (id, seed_mac, encrypted_seed, date_created, date_deleted) VALUES ($1, $2, $3, $4, $5)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		for _, s := range seeds {
+			// every existing seed was encrypted with XChaCha20-Poly1305
+			buf := append([]byte{1}, s.encryptedSeed...)
+			if _, err := stmt.Exec(s.id, s.mac, buf, s.created, s.deleted); err != nil {
+				return fmt.Errorf("failed to insert seed: %w", err)
+			}
+			clear(buf)
+			clear(s.encryptedSeed)
+		}
+		log.Debug("migrated seeds", zap.Int("count", len(seeds)))
+
+		_, err = tx.Exec(`DROP TABLE seeds_old;
+CREATE INDEX seeds_date_created_idx ON seeds (date_created ASC);
+CREATE INDEX seeds_date_deleted_idx ON seeds (date_deleted);
+CREATE TABLE signing_keys (
+	public_key BLOB PRIMARY KEY CHECK(length(public_key) = 32),
+	seed_id INTEGER NOT NULL REFERENCES seeds (id),
+	seed_index INTEGER NOT NULL,
+	v1_address BLOB NOT NULL CHECK(length(v1_address) = 32),
+	v2_address BLOB NOT NULL CHECK(length(v2_address) = 32)
+);
+INSERT INTO signing_keys (public_key, seed_id, seed_index, v1_address, v2_address) SELECT public_key, seed_id, seed_index, v1_address, v2_address FROM signing_keys_tmp;
+DROP TABLE signing_keys_tmp;
+CREATE INDEX signing_keys_seed_id_idx ON signing_keys (seed_id);
+CREATE INDEX signing_keys_seed_id_seed_index_idx ON signing_keys (seed_id, seed_index ASC);
+CREATE INDEX signing_keys_v1_address_idx ON signing_keys (v1_address);
+CREATE INDEX signing_keys_v2_address_idx ON signing_keys (v2_address);`)
+		return err
+	},
+	// migration 9: allocate indices from a counter on the seeds table
+	// instead of the highest derived index
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE seeds ADD COLUMN next_index INTEGER NOT NULL DEFAULT 0;
+UPDATE seeds SET next_index=COALESCE((SELECT MAX(seed_index)+1 FROM signing_keys WHERE seed_id=seeds.id), 0);`)
+		return err
+	},
+	// migration 10: record indices reserved for external derivation
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`CREATE TABLE index_reservations (
+	id INTEGER PRIMARY KEY,
+	seed_id INTEGER NOT NULL REFERENCES seeds (id),
+	start_index INTEGER NOT NULL,
+	end_index INTEGER NOT NULL CHECK(end_index > start_index), -- exclusive
+	date_created INTEGER NOT NULL
+);
+CREATE INDEX index_reservations_seed_id_idx ON index_reservations (seed_id);`)
+		return err
+	},
+	// migration 11: track when each key was derived. Existing keys use
+	// their seed's creation time.
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE signing_keys ADD COLUMN date_created INTEGER NOT NULL DEFAULT 0;
+UPDATE signing_keys SET date_created=COALESCE((SELECT date_created FROM seeds WHERE seeds.id=signing_keys.seed_id), 0);
+CREATE INDEX signing_keys_date_created_idx ON signing_keys (date_created);`)
+		return err
+	},
+	// migration 12: add scoped API tokens
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`CREATE TABLE api_tokens (
+	id INTEGER PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	token_hash BLOB UNIQUE NOT NULL CHECK(length(token_hash) = 32),
+	scopes TEXT NOT NULL,
+	date_created INTEGER NOT NULL
+);`)
+		return err
+	},
+	// migration 13: persist failed unlock attempts
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE global_settings ADD COLUMN failed_unlocks INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE global_settings ADD COLUMN last_failed_unlock INTEGER NOT NULL DEFAULT 0;`)
+		return err
+	},
+	// migration 14: add webhooks
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`CREATE TABLE webhooks (
+	id INTEGER PRIMARY KEY,
+	callback_url TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	secret_key TEXT NOT NULL,
+	date_created INTEGER NOT NULL
+);`)
+		return err
+	},
+	// migration 15: record the language of a seed's recovery phrase
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE seeds ADD COLUMN phrase_language TEXT NOT NULL DEFAULT '';`)
+		return err
+	},
+	// migration 16: add seed and key expirations
+	func(tx *txn, _ *zap.Logger) error {
+		_, err := tx.Exec(`ALTER TABLE seeds ADD COLUMN date_expires INTEGER;
+ALTER TABLE signing_keys ADD COLUMN date_expires INTEGER;`)
+		return err
+	},
 }
@@ -7,7 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+	"lukechampine.com/frand"
 )
 
 // nolint:misspell
@@ -63,6 +68,17 @@ func TestMigrationConsistency(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// add a signing key to migrate
+	pk := types.GeneratePrivateKey().PublicKey()
+	_, err = db.Exec(`INSERT INTO seeds (id, seed_mac, encrypted_seed, date_created) VALUES (1, $1, $2, $3)`, frand.Bytes(32), frand.Bytes(72), time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO signing_keys (public_key, seed_id, seed_index) VALUES ($1, 1, 0)`, pk[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	if err := db.Close(); err != nil {
 		t.Fatal(err)
 	}
@@ -77,7 +93,35 @@ func TestMigrationConsistency(t *testing.T) {
 	v := getDBVersion(store.db)
 	if v != expectedVersion {
 		t.Fatalf("expected version %d, got %d", expectedVersion, v)
-	} else if err := store.Close(); err != nil {
+	}
+	for _, addr := range []types.Address{types.StandardUnlockHash(pk), types.PolicyPublicKey(pk).Address()} {
+		if key, err := store.KeyByAddress(addr); err != nil {
+			t.Fatal(err)
+		} else if key.PublicKey != pk {
+			t.Fatalf("expected key %v for address %v, got %v", pk, addr, key.PublicKey)
+		}
+	}
+	// the next index continues after the highest derived index
+	if index, err := store.NextIndex(1); err != nil {
+		t.Fatal(err)
+	} else if index != 1 {
+		t.Fatalf("expected next index 1, got %d", index)
+	}
+	// existing keys use their seed's creation time
+	if meta, err := store.SeedMeta(1); err != nil {
+		t.Fatal(err)
+	} else if keys, _, err := store.Keys(vault.KeyFilter{}, 0, 10); err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 1 || !keys[0].CreatedAt.Equal(meta.CreatedAt) {
+		t.Fatalf("expected key created at %v, got %v", meta.CreatedAt, keys)
+	}
+	// the encrypted seed is prefixed with its encryption format
+	if buf, err := store.Seed(1); err != nil {
+		t.Fatal(err)
+	} else if len(buf) != 73 || buf[0] != 1 {
+		t.Fatalf("expected a 73-byte seed with format 1, got %d bytes with format %d", len(buf), buf[0])
+	}
+	if err := store.Close(); err != nil {
 		t.Fatal(err)
 	}
 
@@ -241,3 +285,31 @@ func TestMigrationConsistency(t *testing.T) {
 		}
 	}
 }
+
+func TestMigrationProgress(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	p := newMigrationProgress(zap.New(core), 3)
+	p.Add(1)
+	p.Add(1)
+	if logs.Len() != 0 {
+		t.Fatalf("expected no progress logs before the interval, got %d", logs.Len())
+	}
+
+	// progress is logged after the interval
+	p.start = p.start.Add(-time.Minute)
+	p.last = p.last.Add(-progressInterval)
+	p.Add(0)
+	if logs.Len() != 1 {
+		t.Fatalf("expected 1 progress log, got %d", logs.Len())
+	} else if eta := logs.All()[0].ContextMap()["eta"]; eta != 30*time.Second {
+		t.Fatalf("expected an ETA of 30s, got %v", eta)
+	}
+
+	// completion is always logged
+	p.Add(1)
+	if logs.Len() != 2 {
+		t.Fatalf("expected 2 progress logs, got %d", logs.Len())
+	} else if rows := logs.All()[1].ContextMap()["rows"]; rows != int64(3) {
+		t.Fatalf("expected 3 rows, got %v", rows)
+	}
+}
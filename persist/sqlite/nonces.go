@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// UseNonce records a sign request nonce until it expires. It returns false
+// if the nonce has already been used and has not expired. Expired nonces
+// are removed.
+func (s *Store) UseNonce(nonce string, expiration time.Time) (used bool, err error) {
+	err = s.transaction(func(tx *txn) error {
+		if _, err := tx.Exec(`DELETE FROM sign_nonces WHERE date_expires < $1`, sqlTime(time.Now())); err != nil {
+			return fmt.Errorf("failed to remove expired nonces: %w", err)
+		}
+		res, err := tx.Exec(`INSERT INTO sign_nonces (nonce, date_expires) VALUES ($1, $2) ON CONFLICT (nonce) DO NOTHING`, nonce, sqlTime(expiration))
+		if err != nil {
+			return fmt.Errorf("failed to insert nonce: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		used = n == 1
+		return nil
+	})
+	return
+}
@@ -11,6 +11,18 @@ type (
 		maxRetryAttempts int
 		busyTimeout      time.Duration
 		log              *zap.Logger
+		faults           Faults
+	}
+
+	// Faults configures fault injection for testing retry and timeout
+	// behavior. Faults should never be enabled in production.
+	Faults struct {
+		// Latency is added before every transaction attempt.
+		Latency time.Duration
+		// BusyRate is the probability, between 0 and 1, that a
+		// transaction attempt fails with a busy error instead of
+		// executing.
+		BusyRate float64
 	}
 
 	// An Option is a functional option for configuring a Store.
@@ -33,6 +45,14 @@ func WithBusyTimeout(busyTimeout time.Duration) Option {
 	}
 }
 
+// WithFaults injects latency and busy errors into every transaction after
+// the database is initialized.
+func WithFaults(f Faults) Option {
+	return func(o *options) {
+		o.faults = f
+	}
+}
+
 // WithLogger sets the logger used by the Store.
 func WithLogger(log *zap.Logger) Option {
 	return func(o *options) {
@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// progressInterval is the minimum time between progress logs.
+const progressInterval = 5 * time.Second
+
+// migrationProgress logs the progress of a migration that processes many
+// rows.
+type migrationProgress struct {
+	log   *zap.Logger
+	total int
+	done  int
+	start time.Time
+	last  time.Time
+}
+
+// Add records n processed rows and logs the progress if the interval has
+// passed or every row has been processed.
+func (p *migrationProgress) Add(n int) {
+	p.done += n
+	if time.Since(p.last) < progressInterval && p.done < p.total {
+		return
+	}
+	p.last = time.Now()
+
+	elapsed := time.Since(p.start)
+	var eta time.Duration
+	if p.done > 0 && p.done < p.total {
+		eta = time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+	}
+	p.log.Info("migration progress", zap.Int("rows", p.done), zap.Int("total", p.total), zap.Duration("elapsed", elapsed.Round(time.Millisecond)), zap.Duration("eta", eta.Round(time.Second)))
+}
+
+// newMigrationProgress returns a migrationProgress for total rows.
+func newMigrationProgress(log *zap.Logger, total int) *migrationProgress {
+	now := time.Now()
+	return &migrationProgress{
+		log:   log,
+		total: total,
+		start: now,
+		last:  now,
+	}
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.sia.tech/vaultd/snapshot"
+)
+
+// snapshotTables are the tables replaced when a snapshot is restored, in
+// foreign key order. Settings and sign request nonces are not restored so
+// the key salt cannot change and used nonces cannot be replayed.
+var snapshotTables = []string{"seeds", "signing_keys", "index_reservations", "address_book"}
+
+// CreateSnapshot writes a consistent copy of the database to fp. The file
+// must not exist.
+func (s *Store) CreateSnapshot(fp string) error {
+	if _, err := s.db.Exec(`VACUUM INTO $1`, fp); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// countContents returns the number of rows in the snapshot tables of the
+// schema.
+func countContents(tx *sql.Tx, schema string) (c snapshot.Contents, err error) {
+	counts := []struct {
+		table string
+		n     *int
+	}{
+		{"seeds", &c.Seeds},
+		{"signing_keys", &c.Keys},
+		{"address_book", &c.AddressBookEntries},
+	}
+	for _, count := range counts {
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM ` + schema + `.` + count.table).Scan(count.n); err != nil {
+			return snapshot.Contents{}, fmt.Errorf("failed to count %s: %w", count.table, err)
+		}
+	}
+	return
+}
+
+// attachSnapshot attaches the snapshot at fp read-only and calls fn in a
+// transaction. The transaction is committed if commit is true and fn
+// succeeds. The snapshot must have the same schema version as the
+// database.
+func (s *Store) attachSnapshot(fp string, commit bool, fn func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	// attached databases are per connection
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE $1 AS snapshot`, "file:"+fp+"?mode=ro"); err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer conn.ExecContext(ctx, `DETACH DATABASE snapshot`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current, version int
+	if err := tx.QueryRow(`SELECT db_version FROM main.global_settings`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to get database version: %w", err)
+	} else if err := tx.QueryRow(`SELECT db_version FROM snapshot.global_settings`).Scan(&version); errors.Is(err, sql.ErrNoRows) {
+		return errors.New("snapshot is not a vaultd database")
+	} else if err != nil {
+		return fmt.Errorf("failed to get snapshot version: %w", err)
+	} else if version != current {
+		return fmt.Errorf("snapshot version %d does not match database version %d", version, current)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	} else if !commit {
+		return nil
+	} else if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// PreviewSnapshot validates the snapshot at fp and returns the contents of
+// the snapshot and the database without changing either.
+func (s *Store) PreviewSnapshot(fp string) (p snapshot.Preview, err error) {
+	err = s.attachSnapshot(fp, false, func(tx *sql.Tx) error {
+		if p.Snapshot, err = countContents(tx, "snapshot"); err != nil {
+			return err
+		}
+		p.Current, err = countContents(tx, "main")
+		return err
+	})
+	return
+}
+
+// RestoreSnapshot replaces the seeds, signing keys, address book, and key
+// version with the contents of the snapshot at fp in a single transaction.
+// The snapshot must have the same schema version as the database.
+func (s *Store) RestoreSnapshot(fp string) error {
+	return s.attachSnapshot(fp, true, func(tx *sql.Tx) error {
+		for i := len(snapshotTables) - 1; i >= 0; i-- {
+			if _, err := tx.Exec(`DELETE FROM main.` + snapshotTables[i]); err != nil {
+				return fmt.Errorf("failed to clear %s: %w", snapshotTables[i], err)
+			}
+		}
+		for _, table := range snapshotTables {
+			if _, err := tx.Exec(`INSERT INTO main.` + table + ` SELECT * FROM snapshot.` + table); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", table, err)
+			}
+		}
+		// the restored seeds use the snapshot's key derivation
+		if _, err := tx.Exec(`UPDATE main.global_settings SET key_version=(SELECT key_version FROM snapshot.global_settings)`); err != nil {
+			return fmt.Errorf("failed to restore key version: %w", err)
+		}
+		return nil
+	})
+}
@@ -23,6 +23,7 @@ type (
 	// A Store is a persistent store that uses a SQL database as its backend.
 	Store struct {
 		maxRetryAttempts int
+		faults           Faults
 
 		db  *sql.DB
 		log *zap.Logger
@@ -47,7 +48,10 @@ func (s *Store) transaction(fn func(*txn) error) error {
 	for ; attempt < s.maxRetryAttempts; attempt++ {
 		attemptStart := time.Now()
 		log := log.With(zap.Int("attempt", attempt))
-		err = doTransaction(s.db, log, fn)
+		err = s.injectFault()
+		if err == nil {
+			err = doTransaction(s.db, log, fn)
+		}
 		if err == nil {
 			// no error, break out of the loop
 			return nil
@@ -65,6 +69,18 @@ func (s *Store) transaction(fn func(*txn) error) error {
 	return fmt.Errorf("transaction failed (attempt %d): %w", attempt, err)
 }
 
+// injectFault sleeps for the configured latency and returns a busy error
+// at the configured rate.
+func (s *Store) injectFault() error {
+	if s.faults.Latency > 0 {
+		time.Sleep(s.faults.Latency)
+	}
+	if s.faults.BusyRate > 0 && frand.Float64() < s.faults.BusyRate {
+		return sqlite3.Error{Code: sqlite3.ErrBusy}
+	}
+	return nil
+}
+
 func sqliteFilepath(fp string, busyTimeout time.Duration) string {
 	params := []string{
 		fmt.Sprintf("_busy_timeout=%d", busyTimeout.Milliseconds()),
@@ -107,9 +123,7 @@ func doTransaction(db *sql.DB, log *zap.Logger, fn func(tx *txn) error) error {
 	return nil
 }
 
-// OpenDatabase creates a new SQLite store and initializes the database. If the
-// database does not exist, it is created.
-func OpenDatabase(fp string, opts ...Option) (*Store, error) {
+func openDatabase(fp string, opts []Option, configure func(*sql.DB)) (*Store, error) {
 	defaultOptions := options{
 		maxRetryAttempts: 10,
 		busyTimeout:      10 * time.Second,
@@ -122,6 +136,7 @@ func OpenDatabase(fp string, opts ...Option) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
+	configure(db)
 	store := &Store{
 		maxRetryAttempts: defaultOptions.maxRetryAttempts,
 
@@ -131,7 +146,30 @@ func OpenDatabase(fp string, opts ...Option) (*Store, error) {
 	if err := store.init(); err != nil {
 		return nil, err
 	}
+	// faults are injected after initialization so the database can
+	// always be opened
+	store.faults = defaultOptions.faults
 	sqliteVersion, _, _ := sqlite3.Version()
 	store.log.Debug("database initialized", zap.String("sqliteVersion", sqliteVersion), zap.Int("schemaVersion", len(migrations)+1), zap.String("path", fp))
 	return store, nil
 }
+
+// OpenDatabase creates a new SQLite store and initializes the database. If the
+// database does not exist, it is created.
+func OpenDatabase(fp string, opts ...Option) (*Store, error) {
+	return openDatabase(fp, opts, func(*sql.DB) {})
+}
+
+// OpenMemoryDatabase creates a new in-memory SQLite store. The database is
+// discarded when the store is closed.
+func OpenMemoryDatabase(opts ...Option) (*Store, error) {
+	return openDatabase(":memory:", opts, func(db *sql.DB) {
+		// each connection to an in-memory database is a separate
+		// database, so the pool is limited to a single connection that
+		// is never closed
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	})
+}
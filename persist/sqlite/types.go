@@ -24,6 +24,28 @@ func (st *sqlTime) Scan(src any) error {
 	return errors.New("invalid type")
 }
 
+// sqlNullTime is a time that is stored as NULL when zero.
+type sqlNullTime time.Time
+
+func (st sqlNullTime) Value() (driver.Value, error) {
+	if time.Time(st).IsZero() {
+		return nil, nil
+	}
+	return time.Time(st).UnixMilli(), nil
+}
+
+func (st *sqlNullTime) Scan(src any) error {
+	switch t := src.(type) {
+	case nil:
+		*st = sqlNullTime{}
+		return nil
+	case int64:
+		*st = sqlNullTime(time.UnixMilli(t))
+		return nil
+	}
+	return errors.New("invalid type")
+}
+
 type sqlPublicKey types.PublicKey
 
 func (pk sqlPublicKey) Value() (driver.Value, error) {
@@ -51,3 +73,17 @@ func (h *sqlHash256) Scan(src any) error {
 	}
 	return errors.New("invalid type")
 }
+
+type sqlAddress types.Address
+
+func (a sqlAddress) Value() (driver.Value, error) {
+	return a[:], nil
+}
+
+func (a *sqlAddress) Scan(src any) error {
+	if b, ok := src.([]byte); ok {
+		copy((*a)[:], b)
+		return nil
+	}
+	return errors.New("invalid type")
+}
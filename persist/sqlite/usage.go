@@ -0,0 +1,45 @@
+package sqlite
+
+import "fmt"
+
+// Usage is the size and row counts of the database.
+type Usage struct {
+	// Size is the size of the database in bytes, excluding the
+	// write-ahead log.
+	Size               int64
+	Seeds              int
+	Keys               int
+	AddressBookEntries int
+	Nonces             int
+}
+
+// Usage returns the size and row counts of the database. Deleted seeds
+// awaiting purge are included.
+func (s *Store) Usage() (u Usage, err error) {
+	err = s.transaction(func(tx *txn) error {
+		var pageCount, pageSize int64
+		if err := tx.QueryRow(`PRAGMA page_count`).Scan(&pageCount); err != nil {
+			return fmt.Errorf("failed to get page count: %w", err)
+		} else if err := tx.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+			return fmt.Errorf("failed to get page size: %w", err)
+		}
+		u.Size = pageCount * pageSize
+
+		counts := []struct {
+			table string
+			n     *int
+		}{
+			{"seeds", &u.Seeds},
+			{"signing_keys", &u.Keys},
+			{"address_book", &u.AddressBookEntries},
+			{"sign_nonces", &u.Nonces},
+		}
+		for _, count := range counts {
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM ` + count.table).Scan(count.n); err != nil {
+				return fmt.Errorf("failed to count %s: %w", count.table, err)
+			}
+		}
+		return nil
+	})
+	return
+}
@@ -14,7 +14,7 @@ import (
 // key. If no salt has been set, KeySalt returns (nil, nil).
 func (s *Store) KeySalt() (salt []byte, err error) {
 	err = s.transaction(func(tx *txn) error {
-		err := s.db.QueryRow("SELECT key_salt FROM global_settings").Scan(&salt)
+		err := tx.QueryRow("SELECT key_salt FROM global_settings").Scan(&salt)
 		return err
 	})
 	return
@@ -39,7 +39,7 @@ func (s *Store) SetKeySalt(salt []byte) error {
 // [vault.ErrNotFound].
 func (s *Store) BytesForVerify() (buf []byte, err error) {
 	err = s.transaction(func(tx *txn) error {
-		err := s.db.QueryRow("SELECT encrypted_seed FROM seeds LIMIT 1").Scan(&buf)
+		err := tx.QueryRow("SELECT encrypted_seed FROM seeds LIMIT 1").Scan(&buf)
 		if errors.Is(err, sql.ErrNoRows) {
 			return vault.ErrNotFound
 		}
@@ -48,11 +48,196 @@ func (s *Store) BytesForVerify() (buf []byte, err error) {
 	return
 }
 
+// KeyVersion returns the version of the key derivation used to encrypt
+// and authenticate the stored seeds.
+func (s *Store) KeyVersion() (version int, err error) {
+	err = s.transaction(func(tx *txn) error {
+		return tx.QueryRow("SELECT key_version FROM global_settings").Scan(&version)
+	})
+	return
+}
+
+// FailedUnlocks returns the number of consecutive failed unlock attempts
+// and the time of the last one.
+func (s *Store) FailedUnlocks() (n int, last time.Time, err error) {
+	err = s.transaction(func(tx *txn) error {
+		return tx.QueryRow("SELECT failed_unlocks, last_failed_unlock FROM global_settings").Scan(&n, (*sqlTime)(&last))
+	})
+	return
+}
+
+// RecordFailedUnlock increments the number of consecutive failed unlock
+// attempts and returns the new count.
+func (s *Store) RecordFailedUnlock(timestamp time.Time) (n int, err error) {
+	err = s.transaction(func(tx *txn) error {
+		return tx.QueryRow("UPDATE global_settings SET failed_unlocks=failed_unlocks+1, last_failed_unlock=$1 RETURNING failed_unlocks", sqlTime(timestamp)).Scan(&n)
+	})
+	return
+}
+
+// ResetFailedUnlocks resets the number of consecutive failed unlock
+// attempts.
+func (s *Store) ResetFailedUnlocks() error {
+	return s.transaction(func(tx *txn) error {
+		_, err := tx.Exec("UPDATE global_settings SET failed_unlocks=0, last_failed_unlock=0")
+		return err
+	})
+}
+
+// RekeySeeds replaces the MAC and encrypted seed of every seed, including
+// deleted seeds, with the result of rekey and sets the key version in a
+// single transaction.
+func (s *Store) RekeySeeds(version int, rekey func(encryptedSeed []byte) (types.Hash256, []byte, error)) error {
+	return s.transaction(func(tx *txn) error {
+		rows, err := tx.Query(`SELECT id, encrypted_seed FROM seeds`)
+		if err != nil {
+			return fmt.Errorf("failed to query seeds: %w", err)
+		}
+		defer rows.Close()
+
+		type rekeyed struct {
+			id            int64
+			mac           types.Hash256
+			encryptedSeed []byte
+		}
+		var seeds []rekeyed
+		for rows.Next() {
+			var id int64
+			var encryptedSeed []byte
+			if err := rows.Scan(&id, &encryptedSeed); err != nil {
+				return fmt.Errorf("failed to scan seed: %w", err)
+			}
+			mac, buf, err := rekey(encryptedSeed)
+			clear(encryptedSeed)
+			if err != nil {
+				return fmt.Errorf("failed to rekey seed %d: %w", id, err)
+			}
+			seeds = append(seeds, rekeyed{id, mac, buf})
+		}
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("failed to close rows: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`UPDATE seeds SET seed_mac=$1, encrypted_seed=$2 WHERE id=$3`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		for _, seed := range seeds {
+			_, err := stmt.Exec(sqlHash256(seed.mac), seed.encryptedSeed, seed.id)
+			clear(seed.encryptedSeed)
+			if err != nil {
+				return fmt.Errorf("failed to update seed %d: %w", seed.id, err)
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE global_settings SET key_version=$1`, version)
+		return err
+	})
+}
+
 // SigningKeyIndex returns the seed and index associated with the given
 // public key. If the key is not found, [vault.ErrNotFound] is returned.
 func (s *Store) SigningKeyIndex(pk types.PublicKey) (id vault.SeedID, index uint64, err error) {
 	err = s.transaction(func(tx *txn) error {
-		err = tx.QueryRow(`SELECT seed_id, seed_index FROM signing_keys WHERE public_key=$1`, sqlPublicKey(pk)).Scan(&id, &index)
+		err = tx.QueryRow(`SELECT sk.seed_id, sk.seed_index FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id WHERE sk.public_key=$1 AND s.date_deleted IS NULL`, sqlPublicKey(pk)).Scan(&id, &index)
+		if errors.Is(err, sql.ErrNoRows) {
+			return vault.ErrNotFound
+		}
+		return err
+	})
+	return
+}
+
+// SetSeedExpiration sets the time after which the seed's keys cannot sign.
+// A zero time removes the expiration. If the seed is not found,
+// [vault.ErrNotFound] is returned.
+func (s *Store) SetSeedExpiration(id vault.SeedID, expiresAt time.Time) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`UPDATE seeds SET date_expires=$1 WHERE id=$2 AND date_deleted IS NULL`, sqlNullTime(expiresAt), id)
+		if err != nil {
+			return err
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return vault.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// SetKeyExpiration sets the time after which the key cannot sign. A zero
+// time removes the expiration. If the key is not found,
+// [vault.ErrNotFound] is returned.
+func (s *Store) SetKeyExpiration(pk types.PublicKey, expiresAt time.Time) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`UPDATE signing_keys SET date_expires=$1 WHERE public_key=$2 AND seed_id IN (SELECT id FROM seeds WHERE date_deleted IS NULL)`, sqlNullTime(expiresAt), sqlPublicKey(pk))
+		if err != nil {
+			return err
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return vault.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// KeyExpiration returns the earlier of the expirations of the key and its
+// seed, or the zero time if neither expires. A key that has not been
+// added to the store only has its seed's expiration.
+func (s *Store) KeyExpiration(seedID vault.SeedID, pk types.PublicKey) (expiresAt time.Time, err error) {
+	err = s.transaction(func(tx *txn) error {
+		var seedExpires, keyExpires time.Time
+		err := tx.QueryRow(`SELECT s.date_expires, sk.date_expires FROM seeds s LEFT JOIN signing_keys sk ON sk.seed_id=s.id AND sk.public_key=$1 WHERE s.id=$2`, sqlPublicKey(pk), seedID).Scan((*sqlNullTime)(&seedExpires), (*sqlNullTime)(&keyExpires))
+		if errors.Is(err, sql.ErrNoRows) {
+			return vault.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		expiresAt = seedExpires
+		if !keyExpires.IsZero() && (expiresAt.IsZero() || keyExpires.Before(expiresAt)) {
+			expiresAt = keyExpires
+		}
+		return nil
+	})
+	return
+}
+
+// Expirations returns the expirations of seeds that have not been deleted
+// and their keys that are before the given time, sorted by expiration,
+// ASC.
+func (s *Store) Expirations(before time.Time) (exps []vault.Expiration, err error) {
+	err = s.transaction(func(tx *txn) error {
+		const query = `SELECT id, NULL, date_expires FROM seeds WHERE date_deleted IS NULL AND date_expires < $1
+UNION ALL
+SELECT sk.seed_id, sk.public_key, sk.date_expires FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id WHERE s.date_deleted IS NULL AND sk.date_expires < $1
+ORDER BY 3 ASC`
+		rows, err := tx.Query(query, sqlTime(before))
+		if err != nil {
+			return fmt.Errorf("failed to query expirations: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var exp vault.Expiration
+			var pk []byte
+			if err := rows.Scan(&exp.SeedID, &pk, (*sqlTime)(&exp.ExpiresAt)); err != nil {
+				return fmt.Errorf("failed to scan expiration: %w", err)
+			}
+			copy(exp.PublicKey[:], pk)
+			exps = append(exps, exp)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// KeyByAddress returns the signing key whose v1 standard unlock hash or v2
+// public key policy address matches addr. If no key matches,
+// [vault.ErrNotFound] is returned.
+func (s *Store) KeyByAddress(addr types.Address) (key vault.KeyMeta, err error) {
+	err = s.transaction(func(tx *txn) error {
+		const query = `SELECT sk.public_key, sk.seed_id, sk.seed_index FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id WHERE (sk.v1_address=$1 OR sk.v2_address=$1) AND s.date_deleted IS NULL`
+
+		err := tx.QueryRow(query, sqlAddress(addr)).Scan((*sqlPublicKey)(&key.PublicKey), &key.SeedID, &key.Index)
 		if errors.Is(err, sql.ErrNoRows) {
 			return vault.ErrNotFound
 		}
@@ -65,13 +250,53 @@ func (s *Store) SigningKeyIndex(pk types.PublicKey) (id vault.SeedID, index uint
 // If the key is already in the store, nil is returned.
 func (s *Store) AddKeyIndex(id vault.SeedID, pk types.PublicKey, index uint64) error {
 	return s.transaction(func(tx *txn) error {
-		const query = `INSERT INTO signing_keys (public_key, seed_id, seed_index) VALUES ($1, $2, $3) ON CONFLICT (public_key) DO NOTHING`
+		const query = `INSERT INTO signing_keys (public_key, seed_id, seed_index, v1_address, v2_address, date_created) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (public_key) DO NOTHING`
 
-		_, err := tx.Exec(query, sqlPublicKey(pk), id, index)
-		return err
+		v1, v2 := keyAddresses(pk)
+		if _, err := tx.Exec(query, sqlPublicKey(pk), id, index, sqlAddress(v1), sqlAddress(v2), sqlTime(time.Now())); err != nil {
+			return err
+		}
+		return advanceNextIndex(tx, id, index+1)
+	})
+}
+
+// AddKeyIndices associates public keys with consecutive indices of the
+// given seed, starting at start, in a single transaction. Keys already in
+// the store are skipped.
+func (s *Store) AddKeyIndices(id vault.SeedID, start uint64, pks []types.PublicKey) error {
+	return s.transaction(func(tx *txn) error {
+		stmt, err := tx.Prepare(`INSERT INTO signing_keys (public_key, seed_id, seed_index, v1_address, v2_address, date_created) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (public_key) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		now := sqlTime(time.Now())
+		for i, pk := range pks {
+			v1, v2 := keyAddresses(pk)
+			if _, err := stmt.Exec(sqlPublicKey(pk), id, start+uint64(i), sqlAddress(v1), sqlAddress(v2), now); err != nil {
+				return fmt.Errorf("failed to add key %d: %w", start+uint64(i), err)
+			}
+		}
+		return advanceNextIndex(tx, id, start+uint64(len(pks)))
 	})
 }
 
+// advanceNextIndex ensures the seed's next index is at least index so
+// keys added directly are never allocated again.
+func advanceNextIndex(tx *txn, id vault.SeedID, index uint64) error {
+	if _, err := tx.Exec(`UPDATE seeds SET next_index=MAX(next_index, $1) WHERE id=$2`, index, id); err != nil {
+		return fmt.Errorf("failed to update next index: %w", err)
+	}
+	return nil
+}
+
+// keyAddresses returns the v1 standard unlock hash and the v2 public key
+// policy address of pk.
+func keyAddresses(pk types.PublicKey) (v1, v2 types.Address) {
+	return types.StandardUnlockHash(pk), types.PolicyPublicKey(pk).Address()
+}
+
 // Seeds returns a paginated list of seeds. The list is
 // sorted by creation time, ASC. Limit and offset are used
 // for pagination.
@@ -89,10 +314,13 @@ func (s *Store) Seeds(limit, offset int) (seeds []vault.SeedMeta, err error) {
 }
 
 // AddSeed adds an encrypted seed to the store. If the
-// seed has already been added, its metadata is returned.
-func (s *Store) AddSeed(mac types.Hash256, encryptedSeed []byte) (meta vault.SeedMeta, err error) {
+// seed has already been added, its metadata is returned. Adding
+// a deleted seed restores it.
+func (s *Store) AddSeed(mac types.Hash256, encryptedSeed []byte, language string) (meta vault.SeedMeta, err error) {
 	err = s.transaction(func(tx *txn) error {
-		err := tx.QueryRow(`INSERT INTO seeds (seed_mac, encrypted_seed, date_created) VALUES ($1, $2, $3) ON CONFLICT (seed_mac) DO UPDATE SET seed_mac=EXCLUDED.seed_mac RETURNING id`, sqlHash256(mac), encryptedSeed, sqlTime(time.Now())).Scan(&meta.ID)
+		// a seed added again keeps its original language unless it was
+		// unknown
+		err := tx.QueryRow(`INSERT INTO seeds (seed_mac, encrypted_seed, date_created, phrase_language) VALUES ($1, $2, $3, $4) ON CONFLICT (seed_mac) DO UPDATE SET date_deleted=NULL, phrase_language=COALESCE(NULLIF(seeds.phrase_language, ''), EXCLUDED.phrase_language) RETURNING id`, sqlHash256(mac), encryptedSeed, sqlTime(time.Now()), language).Scan(&meta.ID)
 		if err != nil {
 			return fmt.Errorf("failed to insert seed: %w", err)
 		}
@@ -103,11 +331,109 @@ func (s *Store) AddSeed(mac types.Hash256, encryptedSeed []byte) (meta vault.See
 	return
 }
 
+// SeedByMAC returns the metadata of the seed with the given MAC, including
+// deleted seeds, and the time it was deleted. The deletion time is zero if
+// the seed is not deleted. If no seed has the MAC, [vault.ErrNotFound] is
+// returned.
+func (s *Store) SeedByMAC(mac types.Hash256) (meta vault.SeedMeta, deletedAt time.Time, err error) {
+	err = s.transaction(func(tx *txn) error {
+		var deleted sql.NullInt64
+		err := tx.QueryRow(`SELECT id, date_created, phrase_language, date_expires, date_deleted FROM seeds WHERE seed_mac=$1`, sqlHash256(mac)).Scan(&meta.ID, (*sqlTime)(&meta.CreatedAt), &meta.Language, (*sqlNullTime)(&meta.ExpiresAt), &deleted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return vault.ErrNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get seed: %w", err)
+		} else if deleted.Valid {
+			deletedAt = time.UnixMilli(deleted.Int64)
+		}
+		seeds := []vault.SeedMeta{meta}
+		if err := decorateSeedMeta(tx, seeds); err != nil {
+			return fmt.Errorf("failed to decorate seed meta: %w", err)
+		}
+		meta = seeds[0]
+		return nil
+	})
+	return
+}
+
+// DeletedSeed returns the metadata of a seed that was deleted after
+// deletedAfter. If no such seed is found, [vault.ErrNotFound] is returned.
+func (s *Store) DeletedSeed(id vault.SeedID, deletedAfter time.Time) (meta vault.SeedMeta, err error) {
+	err = s.transaction(func(tx *txn) error {
+		meta.ID = id
+		err := tx.QueryRow(`SELECT date_created, phrase_language, date_expires FROM seeds WHERE id=$1 AND date_deleted >= $2`, id, sqlTime(deletedAfter)).Scan((*sqlTime)(&meta.CreatedAt), &meta.Language, (*sqlNullTime)(&meta.ExpiresAt))
+		if errors.Is(err, sql.ErrNoRows) {
+			return vault.ErrNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get seed: %w", err)
+		}
+		seeds := []vault.SeedMeta{meta}
+		if err := decorateSeedMeta(tx, seeds); err != nil {
+			return fmt.Errorf("failed to decorate seed meta: %w", err)
+		}
+		meta = seeds[0]
+		return nil
+	})
+	return
+}
+
+// DeleteSeed marks a seed as deleted. Deleted seeds are hidden from
+// listings and cannot be used for signing until they are restored or
+// purged. If the seed is not found, [vault.ErrNotFound] is returned.
+func (s *Store) DeleteSeed(id vault.SeedID) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`UPDATE seeds SET date_deleted=$1 WHERE id=$2 AND date_deleted IS NULL`, sqlTime(time.Now()), id)
+		if err != nil {
+			return err
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return vault.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// RestoreSeed restores a seed that was deleted after deletedAfter. If no
+// such seed is found, [vault.ErrNotFound] is returned.
+func (s *Store) RestoreSeed(id vault.SeedID, deletedAfter time.Time) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`UPDATE seeds SET date_deleted=NULL WHERE id=$1 AND date_deleted >= $2`, id, sqlTime(deletedAfter))
+		if err != nil {
+			return err
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return vault.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// PurgeSeeds permanently removes seeds deleted before deletedBefore and
+// their keys. It returns the number of seeds removed.
+func (s *Store) PurgeSeeds(deletedBefore time.Time) (n int, err error) {
+	err = s.transaction(func(tx *txn) error {
+		_, err := tx.Exec(`DELETE FROM signing_keys WHERE seed_id IN (SELECT id FROM seeds WHERE date_deleted < $1)`, sqlTime(deletedBefore))
+		if err != nil {
+			return fmt.Errorf("failed to delete signing keys: %w", err)
+		}
+		_, err = tx.Exec(`DELETE FROM index_reservations WHERE seed_id IN (SELECT id FROM seeds WHERE date_deleted < $1)`, sqlTime(deletedBefore))
+		if err != nil {
+			return fmt.Errorf("failed to delete index reservations: %w", err)
+		}
+		res, err := tx.Exec(`DELETE FROM seeds WHERE date_deleted < $1`, sqlTime(deletedBefore))
+		if err != nil {
+			return fmt.Errorf("failed to delete seeds: %w", err)
+		}
+		deleted, err := res.RowsAffected()
+		n = int(deleted)
+		return err
+	})
+	return
+}
+
 // Seed returns the encrypted seed associated with the given
 // seed ID. If the seed ID is not found, [vault.ErrNotFound] is returned.
 func (s *Store) Seed(id vault.SeedID) (encryptedSeed []byte, err error) {
 	err = s.transaction(func(tx *txn) error {
-		err = tx.QueryRow(`SELECT encrypted_seed FROM seeds WHERE id=$1`, id).Scan(&encryptedSeed)
+		err = tx.QueryRow(`SELECT encrypted_seed FROM seeds WHERE id=$1 AND date_deleted IS NULL`, id).Scan(&encryptedSeed)
 		if errors.Is(err, sql.ErrNoRows) {
 			return vault.ErrNotFound
 		}
@@ -126,13 +452,18 @@ func (s *Store) SeedMeta(id vault.SeedID) (meta vault.SeedMeta, err error) {
 	return
 }
 
-// SeedKeys returns a paginated list of public keys derived from the seed.
-func (s *Store) SeedKeys(id vault.SeedID, offset, limit int) (keys []types.PublicKey, err error) {
+// SeedKeys returns a paginated list of public keys derived from the seed
+// and the total number of keys derived from it.
+func (s *Store) SeedKeys(id vault.SeedID, offset, limit int) (keys []types.PublicKey, total int, err error) {
 	err = s.transaction(func(tx *txn) error {
 		if err := checkSeedExists(tx, id); err != nil {
 			return err
 		}
 
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM signing_keys WHERE seed_id=$1`, id).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count keys: %w", err)
+		}
+
 		rows, err := tx.Query(`SELECT public_key FROM signing_keys WHERE seed_id=$1 ORDER BY seed_index ASC LIMIT $2 OFFSET $3`, id, limit, offset)
 		if err != nil {
 			return fmt.Errorf("failed to query keys: %w", err)
@@ -151,25 +482,108 @@ func (s *Store) SeedKeys(id vault.SeedID, offset, limit int) (keys []types.Publi
 	return
 }
 
-// NextIndex returns the next index to be derived for the given seed ID.
-// If the seed ID is not found, [ErrNotFound] is returned.
+// NextIndex returns the next index to be allocated for the given seed ID.
+// If the seed ID is not found, [vault.ErrNotFound] is returned.
 func (s *Store) NextIndex(seedID vault.SeedID) (index uint64, err error) {
 	err = s.transaction(func(tx *txn) error {
-		if err := checkSeedExists(tx, seedID); err != nil {
-			return err
+		err := tx.QueryRow(`SELECT next_index FROM seeds WHERE id=$1 AND date_deleted IS NULL`, seedID).Scan(&index)
+		if errors.Is(err, sql.ErrNoRows) {
+			return vault.ErrNotFound
 		}
-		err = tx.QueryRow(`SELECT seed_index FROM signing_keys WHERE seed_id=$1 ORDER BY seed_index DESC LIMIT 1`, seedID).Scan(&index)
+		return err
+	})
+	return
+}
+
+// ReserveIndices atomically allocates n consecutive indices of the seed
+// and returns the first. Concurrent callers, including other processes
+// sharing the database, never receive overlapping indices. If the seed ID
+// is not found, [vault.ErrNotFound] is returned.
+func (s *Store) ReserveIndices(seedID vault.SeedID, n uint64) (start uint64, err error) {
+	err = s.transaction(func(tx *txn) error {
+		err := tx.QueryRow(`UPDATE seeds SET next_index=next_index+$1 WHERE id=$2 AND date_deleted IS NULL RETURNING next_index-$1`, n, seedID).Scan(&start)
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil
+			return vault.ErrNotFound
+		}
+		return err
+	})
+	return
+}
+
+// ReserveExternalIndices atomically allocates n consecutive indices of the
+// seed for keys derived outside of the vault and records the reservation.
+// It returns the first index. If the seed ID is not found,
+// [vault.ErrNotFound] is returned.
+func (s *Store) ReserveExternalIndices(seedID vault.SeedID, n uint64) (start uint64, err error) {
+	err = s.transaction(func(tx *txn) error {
+		err := tx.QueryRow(`UPDATE seeds SET next_index=next_index+$1 WHERE id=$2 AND date_deleted IS NULL RETURNING next_index-$1`, n, seedID).Scan(&start)
+		if errors.Is(err, sql.ErrNoRows) {
+			return vault.ErrNotFound
 		} else if err != nil {
-			return err
+			return fmt.Errorf("failed to reserve indices: %w", err)
+		}
+		_, err = tx.Exec(`INSERT INTO index_reservations (seed_id, start_index, end_index, date_created) VALUES ($1, $2, $3, $4)`, seedID, start, start+n, sqlTime(time.Now()))
+		if err != nil {
+			return fmt.Errorf("failed to record reservation: %w", err)
 		}
-		index++
 		return nil
 	})
 	return
 }
 
+// RegisterKeys adds keys derived outside of the vault in a single
+// transaction. Each index must be within a reservation of its seed,
+// otherwise [vault.ErrNotReserved] is returned. Keys already registered at
+// the same index are skipped.
+func (s *Store) RegisterKeys(keys []vault.KeyMeta) error {
+	return s.transaction(func(tx *txn) error {
+		reservedStmt, err := tx.Prepare(`SELECT EXISTS (SELECT 1 FROM index_reservations r INNER JOIN seeds s ON s.id=r.seed_id WHERE r.seed_id=$1 AND r.start_index <= $2 AND r.end_index > $2 AND s.date_deleted IS NULL)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare reservation statement: %w", err)
+		}
+		defer reservedStmt.Close()
+
+		existingStmt, err := tx.Prepare(`SELECT public_key FROM signing_keys WHERE seed_id=$1 AND seed_index=$2`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare existing key statement: %w", err)
+		}
+		defer existingStmt.Close()
+
+		insertStmt, err := tx.Prepare(`INSERT INTO signing_keys (public_key, seed_id, seed_index, v1_address, v2_address, date_created) VALUES ($1, $2, $3, $4, $5, $6)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare insert statement: %w", err)
+		}
+		defer insertStmt.Close()
+
+		now := sqlTime(time.Now())
+		for _, key := range keys {
+			var reserved bool
+			if err := reservedStmt.QueryRow(key.SeedID, key.Index).Scan(&reserved); err != nil {
+				return fmt.Errorf("failed to check reservation of key %d: %w", key.Index, err)
+			} else if !reserved {
+				return fmt.Errorf("key %d of seed %d: %w", key.Index, key.SeedID, vault.ErrNotReserved)
+			}
+
+			var existing sqlPublicKey
+			err := existingStmt.QueryRow(key.SeedID, key.Index).Scan(&existing)
+			if err == nil {
+				if types.PublicKey(existing) != key.PublicKey {
+					return fmt.Errorf("key %d of seed %d is already registered as %v", key.Index, key.SeedID, types.PublicKey(existing))
+				}
+				continue
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to get existing key %d: %w", key.Index, err)
+			}
+
+			v1, v2 := keyAddresses(key.PublicKey)
+			if _, err := insertStmt.Exec(sqlPublicKey(key.PublicKey), key.SeedID, key.Index, sqlAddress(v1), sqlAddress(v2), now); err != nil {
+				return fmt.Errorf("failed to add key %d: %w", key.Index, err)
+			}
+		}
+		return nil
+	})
+}
+
 func decorateSeedMeta(tx *txn, seeds []vault.SeedMeta) error {
 	stmt, err := tx.Prepare(`SELECT COALESCE(MAX(seed_index), 0) FROM signing_keys WHERE seed_id=$1`)
 	if err != nil {
@@ -186,7 +600,7 @@ func decorateSeedMeta(tx *txn, seeds []vault.SeedMeta) error {
 }
 
 func getSeeds(tx *txn, limit, offset int) ([]vault.SeedMeta, error) {
-	rows, err := tx.Query(`SELECT id, date_created FROM seeds ORDER BY date_created ASC LIMIT $1 OFFSET $2`, limit, offset)
+	rows, err := tx.Query(`SELECT id, date_created, phrase_language, date_expires FROM seeds WHERE date_deleted IS NULL ORDER BY date_created ASC LIMIT $1 OFFSET $2`, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query seeds: %w", err)
 	}
@@ -195,7 +609,7 @@ func getSeeds(tx *txn, limit, offset int) ([]vault.SeedMeta, error) {
 	var seeds []vault.SeedMeta
 	for rows.Next() {
 		var meta vault.SeedMeta
-		if err := rows.Scan(&meta.ID, (*sqlTime)(&meta.CreatedAt)); err != nil {
+		if err := rows.Scan(&meta.ID, (*sqlTime)(&meta.CreatedAt), &meta.Language, (*sqlNullTime)(&meta.ExpiresAt)); err != nil {
 			return nil, fmt.Errorf("failed to scan seed: %w", err)
 		}
 		seeds = append(seeds, meta)
@@ -208,7 +622,7 @@ func seedMeta(tx *txn, seedID vault.SeedID) (vault.SeedMeta, error) {
 		ID: seedID,
 	}
 
-	err := tx.QueryRow(`SELECT date_created FROM seeds WHERE id=$1`, seedID).Scan((*sqlTime)(&meta.CreatedAt))
+	err := tx.QueryRow(`SELECT date_created, phrase_language, date_expires FROM seeds WHERE id=$1 AND date_deleted IS NULL`, seedID).Scan((*sqlTime)(&meta.CreatedAt), &meta.Language, (*sqlNullTime)(&meta.ExpiresAt))
 	if errors.Is(err, sql.ErrNoRows) {
 		return vault.SeedMeta{}, vault.ErrNotFound
 	} else if err != nil {
@@ -223,7 +637,7 @@ func seedMeta(tx *txn, seedID vault.SeedID) (vault.SeedMeta, error) {
 
 func checkSeedExists(tx *txn, seedID vault.SeedID) error {
 	var exists bool
-	err := tx.QueryRow(`SELECT true FROM seeds WHERE id=$1`, seedID).Scan(&exists)
+	err := tx.QueryRow(`SELECT true FROM seeds WHERE id=$1 AND date_deleted IS NULL`, seedID).Scan(&exists)
 	if errors.Is(err, sql.ErrNoRows) {
 		return vault.ErrNotFound
 	} else if err != nil {
@@ -231,3 +645,194 @@ func checkSeedExists(tx *txn, seedID vault.SeedID) error {
 	}
 	return nil
 }
+
+// SampleKeys returns up to n randomly selected signing keys of seeds that
+// have not been deleted.
+func (s *Store) SampleKeys(n int) (keys []vault.KeyMeta, err error) {
+	err = s.transaction(func(tx *txn) error {
+		rows, err := tx.Query(`SELECT sk.public_key, sk.seed_id, sk.seed_index FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id WHERE s.date_deleted IS NULL ORDER BY RANDOM() LIMIT $1`, n)
+		if err != nil {
+			return fmt.Errorf("failed to query keys: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key vault.KeyMeta
+			if err := rows.Scan((*sqlPublicKey)(&key.PublicKey), &key.SeedID, &key.Index); err != nil {
+				return fmt.Errorf("failed to scan key: %w", err)
+			}
+			keys = append(keys, key)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// Keys returns a paginated list of the keys of seeds that have not been
+// deleted, sorted by seed ID and index, and the total number of keys.
+func (s *Store) Keys(filter vault.KeyFilter, offset, limit int) (keys []vault.KeyMeta, total int, err error) {
+	// zero values match every key
+	const where = `WHERE s.date_deleted IS NULL AND ($1 = 0 OR sk.seed_id = $1) AND sk.date_created > $2`
+	createdAfter := sqlTime(filter.CreatedAfter)
+
+	err = s.transaction(func(tx *txn) error {
+		err := tx.QueryRow(`SELECT COUNT(*) FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id `+where, filter.SeedID, createdAfter).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to count keys: %w", err)
+		}
+
+		rows, err := tx.Query(`SELECT sk.public_key, sk.seed_id, sk.seed_index, sk.date_created FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id `+where+` ORDER BY sk.seed_id ASC, sk.seed_index ASC LIMIT $3 OFFSET $4`, filter.SeedID, createdAfter, limit, offset)
+		if err != nil {
+			return fmt.Errorf("failed to query keys: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key vault.KeyMeta
+			if err := rows.Scan((*sqlPublicKey)(&key.PublicKey), &key.SeedID, &key.Index, (*sqlTime)(&key.CreatedAt)); err != nil {
+				return fmt.Errorf("failed to scan key: %w", err)
+			}
+			keys = append(keys, key)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// ExportSeeds returns the keys of every seed that has not been deleted,
+// sorted by creation time, ASC, in a single transaction. If the seeds have
+// more than maxKeys keys, [vault.ErrExportLimit] is returned.
+func (s *Store) ExportSeeds(maxKeys int) (seeds []vault.SeedExport, err error) {
+	err = s.transaction(func(tx *txn) error {
+		var total int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM signing_keys sk INNER JOIN seeds s ON s.id=sk.seed_id WHERE s.date_deleted IS NULL`).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count keys: %w", err)
+		} else if total > maxKeys {
+			return fmt.Errorf("%w: %d keys, the limit is %d", vault.ErrExportLimit, total, maxKeys)
+		}
+
+		rows, err := tx.Query(`SELECT id, next_index FROM seeds WHERE date_deleted IS NULL ORDER BY date_created ASC, id ASC`)
+		if err != nil {
+			return fmt.Errorf("failed to query seeds: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var seed vault.SeedExport
+			if err := rows.Scan(&seed.ID, &seed.NextIndex); err != nil {
+				return fmt.Errorf("failed to scan seed: %w", err)
+			}
+			seeds = append(seeds, seed)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		stmt, err := tx.Prepare(`SELECT public_key, seed_index, date_created FROM signing_keys WHERE seed_id=$1 ORDER BY seed_index ASC`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		for i := range seeds {
+			if err := func() error {
+				rows, err := stmt.Query(seeds[i].ID)
+				if err != nil {
+					return fmt.Errorf("failed to query keys: %w", err)
+				}
+				defer rows.Close()
+				for rows.Next() {
+					key := vault.KeyMeta{SeedID: seeds[i].ID}
+					if err := rows.Scan((*sqlPublicKey)(&key.PublicKey), &key.Index, (*sqlTime)(&key.CreatedAt)); err != nil {
+						return fmt.Errorf("failed to scan key: %w", err)
+					}
+					seeds[i].Keys = append(seeds[i].Keys, key)
+				}
+				return rows.Err()
+			}(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// CheckConsistency verifies the integrity of the database and the
+// relationships between seeds and signing keys. It returns every issue
+// found.
+func (s *Store) CheckConsistency() (issues []vault.ConsistencyIssue, err error) {
+	err = s.transaction(func(tx *txn) error {
+		rows, err := tx.Query(`PRAGMA quick_check`)
+		if err != nil {
+			return fmt.Errorf("failed to check database integrity: %w", err)
+		}
+		for rows.Next() {
+			var result string
+			if err := rows.Scan(&result); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan integrity result: %w", err)
+			} else if result != "ok" {
+				issues = append(issues, vault.ConsistencyIssue{Description: "database integrity: " + result})
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+
+		checks := []struct {
+			query       string
+			description string
+		}{
+			{`SELECT sk.seed_id, COUNT(*) FROM signing_keys sk LEFT JOIN seeds s ON s.id=sk.seed_id WHERE s.id IS NULL GROUP BY sk.seed_id`, "signing keys reference a missing seed"},
+			{`SELECT seed_id, COUNT(*) FROM signing_keys GROUP BY seed_id, seed_index HAVING COUNT(*) > 1`, "an index is assigned to multiple keys"},
+			// indices reserved for external derivation are not missing
+			// until they are registered
+			{`SELECT seed_id, missing FROM (SELECT k.seed_id, k.last_index + 1 - k.derived - COALESCE((SELECT SUM(MAX(MIN(r.end_index, k.last_index + 1) - r.start_index, 0)) FROM index_reservations r WHERE r.seed_id=k.seed_id), 0) + (SELECT COUNT(DISTINCT sk.seed_index) FROM signing_keys sk INNER JOIN index_reservations r ON r.seed_id=sk.seed_id AND sk.seed_index >= r.start_index AND sk.seed_index < r.end_index WHERE sk.seed_id=k.seed_id) AS missing FROM (SELECT seed_id, MAX(seed_index) AS last_index, COUNT(DISTINCT seed_index) AS derived FROM signing_keys GROUP BY seed_id) k) WHERE missing != 0`, "indices are missing below the last derived index"},
+			{`SELECT MIN(id), COUNT(*) FROM seeds GROUP BY seed_mac HAVING COUNT(*) > 1`, "multiple seeds have the same MAC"},
+			{`SELECT s.id, COUNT(*) FROM seeds s INNER JOIN signing_keys sk ON sk.seed_id=s.id WHERE sk.seed_index >= s.next_index GROUP BY s.id`, "keys are derived at or above the next index"},
+		}
+		for _, check := range checks {
+			rows, err := tx.Query(check.query)
+			if err != nil {
+				return fmt.Errorf("failed to check %q: %w", check.description, err)
+			}
+			for rows.Next() {
+				var id vault.SeedID
+				var count int
+				if err := rows.Scan(&id, &count); err != nil {
+					rows.Close()
+					return fmt.Errorf("failed to scan %q: %w", check.description, err)
+				}
+				issues = append(issues, vault.ConsistencyIssue{
+					SeedID:      id,
+					Description: fmt.Sprintf("%s (%d)", check.description, count),
+				})
+			}
+			if err := rows.Close(); err != nil {
+				return err
+			}
+		}
+
+		rows, err = tx.Query(`SELECT public_key, seed_id, v1_address, v2_address FROM signing_keys`)
+		if err != nil {
+			return fmt.Errorf("failed to query signing keys: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var pk sqlPublicKey
+			var id vault.SeedID
+			var v1, v2 sqlAddress
+			if err := rows.Scan(&pk, &id, &v1, &v2); err != nil {
+				return fmt.Errorf("failed to scan signing key: %w", err)
+			}
+			expectedV1, expectedV2 := keyAddresses(types.PublicKey(pk))
+			if types.Address(v1) != expectedV1 || types.Address(v2) != expectedV2 {
+				issues = append(issues, vault.ConsistencyIssue{
+					SeedID:      id,
+					Description: fmt.Sprintf("stored addresses do not match key %v", types.PublicKey(pk)),
+				})
+			}
+		}
+		return rows.Err()
+	})
+	return
+}
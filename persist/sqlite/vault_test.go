@@ -1,9 +1,23 @@
 package sqlite
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/wallet"
+	"go.sia.tech/vaultd/vault"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20poly1305"
 	"lukechampine.com/frand"
 )
 
@@ -21,7 +35,7 @@ func TestVaultSeeds(t *testing.T) {
 		t.Fatal("expected 0 seeds, got", len(seeds))
 	}
 
-	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72))
+	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72), "")
 	if err != nil {
 		t.Fatal(err)
 	} else if meta.ID != 1 {
@@ -64,3 +78,874 @@ func TestVaultSeeds(t *testing.T) {
 		t.Fatalf("expected LastIndex %d, got %d", 99, seeds[0].LastIndex)
 	}
 }
+
+func TestMemoryDatabase(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v := vault.New(db)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := v.NextKey(meta.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the secret is verified against the stored seed
+	v.Lock()
+	if err := v.Unlock("wrong"); !errors.Is(err, vault.ErrIncorrectSecret) {
+		t.Fatalf("expected %v, got %v", vault.ErrIncorrectSecret, err)
+	} else if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultLookAhead(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v := vault.New(db, vault.WithLookAhead(10))
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// derive a key ahead of the vault
+	sk := wallet.KeyFromSeed(&seed, 5)
+	hash := frand.Entropy256()
+	sig, err := v.Sign(sk.PublicKey(), hash)
+	if err != nil {
+		t.Fatal(err)
+	} else if !sk.PublicKey().VerifyHash(hash, sig) {
+		t.Fatal("invalid signature")
+	}
+
+	// the skipped keys should be stored
+	keys, total, err := v.SeedKeys(meta.ID, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 6 {
+		t.Fatalf("expected 6 keys, got %d", len(keys))
+	} else if total != 6 {
+		t.Fatalf("expected 6 total keys, got %d", total)
+	}
+	for i, pk := range keys {
+		if expected := wallet.KeyFromSeed(&seed, uint64(i)).PublicKey(); pk != expected {
+			t.Fatalf("expected key %d to be %v, got %v", i, expected, pk)
+		}
+	}
+
+	// keys past the look-ahead window are not found
+	sk = wallet.KeyFromSeed(&seed, 16)
+	if _, err := v.Sign(sk.PublicKey(), hash); !errors.Is(err, vault.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPurgeSeeds(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72), "")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.AddKeyIndex(meta.ID, frand.Entropy256(), 0); err != nil {
+		t.Fatal(err)
+	} else if err := db.DeleteSeed(meta.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the seed was deleted before the retention window
+	if err := db.RestoreSeed(meta.ID, time.Now().Add(time.Hour)); !errors.Is(err, vault.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// seeds deleted within the retention window are kept
+	if n, err := db.PurgeSeeds(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected 0 seeds purged, got %d", n)
+	}
+
+	if n, err := db.PurgeSeeds(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("expected 1 seed purged, got %d", n)
+	} else if err := db.RestoreSeed(meta.ID, time.Time{}); !errors.Is(err, vault.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 3; i++ {
+		if err := db.AddKeyIndex(meta.ID, frand.Entropy256(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if issues, err := db.CheckConsistency(); err != nil {
+		t.Fatal(err)
+	} else if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+
+	// skip an index and corrupt a stored address
+	if err := db.AddKeyIndex(meta.ID, frand.Entropy256(), 5); err != nil {
+		t.Fatal(err)
+	} else if _, err := db.db.Exec(`UPDATE signing_keys SET v1_address=$1 WHERE seed_index=0`, frand.Bytes(32)); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := db.CheckConsistency()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	}
+	for _, issue := range issues {
+		if issue.SeedID != meta.ID {
+			t.Fatalf("expected issue for seed %d, got %d", meta.ID, issue.SeedID)
+		}
+	}
+
+	// unregistered indices reserved for external derivation are not
+	// missing
+	if start, err := db.ReserveExternalIndices(meta.ID, 4); err != nil {
+		t.Fatal(err)
+	} else if start != 6 {
+		t.Fatalf("expected reservation to start at 6, got %d", start)
+	} else if err := db.AddKeyIndex(meta.ID, frand.Entropy256(), 7); err != nil {
+		t.Fatal(err)
+	} else if err := db.AddKeyIndex(meta.ID, frand.Entropy256(), 10); err != nil {
+		t.Fatal(err)
+	}
+	issues, err = db.CheckConsistency()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	} else if !slices.ContainsFunc(issues, func(issue vault.ConsistencyIssue) bool {
+		return issue.Description == "indices are missing below the last derived index (2)"
+	}) {
+		t.Fatalf("expected 2 missing indices, got %v", issues)
+	}
+}
+
+func TestVaultAuditKeys(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v := vault.New(db)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if _, err := v.NextKey(meta.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if mismatched, err := v.AuditKeys(10); err != nil {
+		t.Fatal(err)
+	} else if len(mismatched) != 0 {
+		t.Fatalf("expected no mismatched keys, got %v", mismatched)
+	}
+
+	// store a key that was not derived from the seed
+	corrupt := types.GeneratePrivateKey().PublicKey()
+	if err := db.AddKeyIndex(meta.ID, corrupt, 5); err != nil {
+		t.Fatal(err)
+	}
+	if mismatched, err := v.AuditKeys(10); err != nil {
+		t.Fatal(err)
+	} else if len(mismatched) != 1 || mismatched[0].PublicKey != corrupt || mismatched[0].Index != 5 {
+		t.Fatalf("expected corrupt key to be reported, got %v", mismatched)
+	}
+
+	v.Lock()
+	if _, err := v.AuditKeys(10); !errors.Is(err, vault.ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	const latency = 10 * time.Millisecond
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "vaultd.sqlite3"), WithFaults(Faults{Latency: latency}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	if _, err := db.Seeds(100, 0); err != nil {
+		t.Fatal(err)
+	} else if time.Since(start) < latency {
+		t.Fatalf("expected at least %v of latency, got %v", latency, time.Since(start))
+	}
+
+	// every attempt fails
+	db.faults = Faults{BusyRate: 1}
+	if _, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72), ""); err == nil || !strings.Contains(err.Error(), "database is locked") {
+		t.Fatalf("expected busy error, got %v", err)
+	} else if !strings.Contains(err.Error(), fmt.Sprintf("attempt %d", db.maxRetryAttempts)) {
+		t.Fatalf("expected the transaction to be retried, got %v", err)
+	} else if seeds, err := db.Seeds(100, 0); err == nil {
+		t.Fatalf("expected busy error, got %d seeds", len(seeds))
+	}
+
+	// transient busy errors are retried
+	db.faults = Faults{BusyRate: 0.5}
+	db.maxRetryAttempts = 50
+	for range 10 {
+		if _, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db.faults = Faults{}
+	if seeds, err := db.Seeds(100, 0); err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 10 {
+		t.Fatalf("expected 10 seeds, got %d", len(seeds))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "vaultd.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	before, err := db.Usage()
+	if err != nil {
+		t.Fatal(err)
+	} else if before.Size <= 0 || before.Seeds != 0 || before.Keys != 0 {
+		t.Fatalf("unexpected usage %+v", before)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(72), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range uint64(500) {
+		pk := wallet.KeyFromSeed(&seed, i).PublicKey()
+		if err := db.AddKeyIndex(meta.ID, pk, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	after, err := db.Usage()
+	if err != nil {
+		t.Fatal(err)
+	} else if after.Seeds != 1 || after.Keys != 500 {
+		t.Fatalf("unexpected usage %+v", after)
+	} else if after.Size <= before.Size {
+		t.Fatalf("expected size to grow from %d, got %d", before.Size, after.Size)
+	}
+}
+
+func TestVaultRekey(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "vaultd.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// store a seed using the original key derivation, which used the
+	// Argon2 key for both the AEAD and the MAC
+	salt := frand.Bytes(32)
+	if err := db.SetKeySalt(salt); err != nil {
+		t.Fatal(err)
+	}
+	key := argon2.IDKey([]byte("foo"), salt, 3, 64*1024, 4, 32)
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := frand.Entropy256()
+	mac, err := blake2b.New256(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac.Write(seed[:])
+	legacyMAC := types.Hash256(mac.Sum(nil))
+	// the seed is prefixed with the XChaCha20-Poly1305 format
+	nonce := frand.Bytes(aead.NonceSize())
+	meta, err := db.AddSeed(legacyMAC, append([]byte{1}, aead.Seal(nonce, nonce, seed[:], nil)...), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := vault.New(db)
+	defer v.Close()
+	if err := v.Unlock("bar"); !errors.Is(err, vault.ErrIncorrectSecret) {
+		t.Fatalf("expected ErrIncorrectSecret, got %v", err)
+	} else if version, err := db.KeyVersion(); err != nil {
+		t.Fatal(err)
+	} else if version != 0 {
+		t.Fatalf("expected key version 0 after a failed unlock, got %d", version)
+	} else if err := v.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	} else if version, err := db.KeyVersion(); err != nil {
+		t.Fatal(err)
+	} else if version != 1 {
+		t.Fatalf("expected key version 1, got %d", version)
+	} else if _, _, err := db.SeedByMAC(legacyMAC); !errors.Is(err, vault.ErrNotFound) {
+		t.Fatalf("expected the legacy MAC to be replaced, got %v", err)
+	}
+
+	// the rekeyed seed can still be used and detected as a duplicate
+	if pk, err := v.NextKey(meta.ID); err != nil {
+		t.Fatal(err)
+	} else if pk != wallet.KeyFromSeed(&seed, 0).PublicKey() {
+		t.Fatal("rekeyed seed derived the wrong key")
+	} else if dup, err := v.AddSeed(&seed); err != nil {
+		t.Fatal(err)
+	} else if dup.ID != meta.ID {
+		t.Fatalf("expected duplicate seed %d, got %d", meta.ID, dup.ID)
+	}
+
+	// the rekeyed vault unlocks with the new key derivation
+	v.Lock()
+	if err := v.Unlock("bar"); !errors.Is(err, vault.ErrIncorrectSecret) {
+		t.Fatalf("expected ErrIncorrectSecret, got %v", err)
+	} else if err := v.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultHardwareToken(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// an HMAC-SHA1 challenge-response token with a random key
+	newToken := func() vault.ChallengeResponder {
+		key := frand.Bytes(20)
+		return func(challenge []byte) ([]byte, error) {
+			h := hmac.New(sha1.New, key)
+			h.Write(challenge)
+			return h.Sum(nil), nil
+		}
+	}
+	token := newToken()
+
+	v := vault.New(db, vault.WithHardwareToken(token))
+	if err := v.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	}
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Close()
+
+	// the secret alone, or with a different token, cannot unlock the vault
+	for _, opts := range [][]vault.Option{nil, {vault.WithHardwareToken(newToken())}} {
+		v := vault.New(db, opts...)
+		if err := v.Unlock("foo"); !errors.Is(err, vault.ErrIncorrectSecret) {
+			t.Fatalf("expected %v, got %v", vault.ErrIncorrectSecret, err)
+		}
+		v.Close()
+	}
+	if err := db.ResetFailedUnlocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a token that does not respond is not a failed attempt
+	v = vault.New(db, vault.WithHardwareToken(func([]byte) ([]byte, error) {
+		return nil, errors.New("timed out waiting for touch")
+	}))
+	if err := v.Unlock("foo"); !errors.Is(err, vault.ErrHardwareToken) {
+		t.Fatalf("expected %v, got %v", vault.ErrHardwareToken, err)
+	} else if failed, _, err := db.FailedUnlocks(); err != nil {
+		t.Fatal(err)
+	} else if failed != 0 {
+		t.Fatalf("expected no failed unlocks, got %d", failed)
+	}
+	v.Close()
+
+	v = vault.New(db, vault.WithHardwareToken(token))
+	defer v.Close()
+	if err := v.Unlock("bar"); !errors.Is(err, vault.ErrIncorrectSecret) {
+		t.Fatalf("expected %v, got %v", vault.ErrIncorrectSecret, err)
+	} else if err := v.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	} else if pk, err := v.NextKey(meta.ID); err != nil {
+		t.Fatal(err)
+	} else if pk != wallet.KeyFromSeed(&seed, 0).PublicKey() {
+		t.Fatal("derived the wrong key")
+	}
+}
+
+func TestVaultNextKeys(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v := vault.New(db)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := v.NextKey(meta.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the batch continues after the existing key
+	pks, err := v.NextKeys(meta.ID, 1000)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pks) != 1000 {
+		t.Fatalf("expected 1000 keys, got %d", len(pks))
+	}
+	for i, pk := range pks {
+		if expected := wallet.KeyFromSeed(&seed, uint64(i+1)).PublicKey(); pk != expected {
+			t.Fatalf("expected key %d to be %v, got %v", i+1, expected, pk)
+		} else if id, index, err := db.SigningKeyIndex(pk); err != nil {
+			t.Fatal(err)
+		} else if id != meta.ID || index != uint64(i+1) {
+			t.Fatalf("expected key %d of seed %d, got key %d of seed %d", i+1, meta.ID, index, id)
+		}
+	}
+
+	if pk, err := v.NextKey(meta.ID); err != nil {
+		t.Fatal(err)
+	} else if pk != wallet.KeyFromSeed(&seed, 1001).PublicKey() {
+		t.Fatal("expected the next key to follow the batch")
+	} else if _, err := v.NextKeys(vault.SeedID(100), 10); !errors.Is(err, vault.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUnlockBackoff(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "vaultd.sqlite3")
+	db, err := OpenDatabase(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := vault.New(db)
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	// the secret is verified against the stored seeds
+	seed := frand.Entropy256()
+	if _, err := v.AddSeed(&seed); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+
+	for range 3 {
+		if err := v.Unlock("wrong"); !errors.Is(err, vault.ErrIncorrectSecret) {
+			t.Fatalf("expected %v, got %v", vault.ErrIncorrectSecret, err)
+		}
+	}
+	// the correct secret is also rejected during the backoff
+	if err := v.Unlock("foo bar baz"); !errors.Is(err, vault.ErrTooManyAttempts) {
+		t.Fatalf("expected %v, got %v", vault.ErrTooManyAttempts, err)
+	}
+	v.Close()
+	db.Close()
+
+	// the attempts persist across restarts
+	db, err = OpenDatabase(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	v = vault.New(db)
+	defer v.Close()
+	if n, _, err := db.FailedUnlocks(); err != nil {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatalf("expected 3 failed attempts, got %d", n)
+	} else if err := v.Unlock("foo bar baz"); !errors.Is(err, vault.ErrTooManyAttempts) {
+		t.Fatalf("expected %v, got %v", vault.ErrTooManyAttempts, err)
+	}
+
+	time.Sleep(time.Second)
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	} else if n, _, err := db.FailedUnlocks(); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected failed attempts to be reset, got %d", n)
+	}
+}
+
+func TestVaultReadOnly(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	reasons := make(chan error, 1)
+	v := vault.New(db, vault.WithReadOnlyHandler(func(err error) { reasons <- err }))
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []vault.SeedID
+	for range 4 {
+		seed := frand.Entropy256()
+		meta, err := v.AddSeed(&seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, meta.ID)
+	}
+
+	// corrupt every seed but the last
+	for _, id := range ids[:3] {
+		if _, err := db.db.Exec(`UPDATE seeds SET encrypted_seed=$1 WHERE id=$2`, append([]byte{1}, frand.Bytes(72)...), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, id := range ids[:3] {
+		if _, err := v.NextKey(id); err == nil {
+			t.Fatal("expected corrupted seed to fail")
+		} else if i < 2 && v.ReadOnly() != nil {
+			t.Fatal("expected vault to be writable")
+		}
+	}
+
+	select {
+	case err := <-reasons:
+		if !errors.Is(err, vault.ErrReadOnly) {
+			t.Fatalf("expected %v, got %v", vault.ErrReadOnly, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("read-only handler was not called")
+	}
+
+	// the intact seed can no longer be used
+	seed := frand.Entropy256()
+	if _, err := v.NextKey(ids[3]); !errors.Is(err, vault.ErrReadOnly) {
+		t.Fatalf("expected %v, got %v", vault.ErrReadOnly, err)
+	} else if _, err := v.AddSeed(&seed); !errors.Is(err, vault.ErrReadOnly) {
+		t.Fatalf("expected %v, got %v", vault.ErrReadOnly, err)
+	} else if _, err := v.Seeds(100, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultReadOnlyConcurrentSign(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	reasons := make(chan error, 10)
+	v := vault.New(db, vault.WithReadOnlyHandler(func(err error) { reasons <- err }))
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []types.PublicKey
+	for range 6 {
+		seed := frand.Entropy256()
+		meta, err := v.AddSeed(&seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pk, err := v.NextKey(meta.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, pk)
+		if _, err := db.db.Exec(`UPDATE seeds SET encrypted_seed=$1 WHERE id=$2`, append([]byte{1}, frand.Bytes(72)...), meta.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// signing with every corrupted seed at once makes the vault read-only
+	// exactly once
+	var wg sync.WaitGroup
+	for _, pk := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := v.Sign(pk, frand.Entropy256()); err == nil {
+				t.Error("expected corrupted seed to fail")
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err := <-reasons:
+		if !errors.Is(err, vault.ErrReadOnly) {
+			t.Fatalf("expected %v, got %v", vault.ErrReadOnly, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("read-only handler was not called")
+	}
+	select {
+	case err := <-reasons:
+		t.Fatalf("expected the read-only handler to be called once, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestVaultAutoLock(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	locked := make(chan struct{}, 1)
+	v := vault.New(db, vault.WithAutoLock(200*time.Millisecond, func() { locked <- struct{}{} }))
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deriving keys keeps the vault unlocked
+	for range 4 {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := v.NextKey(meta.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the vault to lock")
+	}
+	if v.Unlocked() {
+		t.Fatal("expected the vault to be locked")
+	} else if _, err := v.NextKey(meta.ID); !errors.Is(err, vault.ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+
+	// the timer restarts when the vault is unlocked again
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the vault to lock")
+	}
+}
+
+func TestVaultAutoLockConcurrentSign(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v := vault.New(db, vault.WithAutoLock(20*time.Millisecond, nil))
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := v.NextKey(meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// signers pause for around the auto-lock timeout so the vault locks
+	// between and during their requests
+	deadline := time.Now().Add(time.Second)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if _, err := v.Sign(pk, frand.Entropy256()); err != nil && !errors.Is(err, vault.ErrLocked) {
+					t.Error(err)
+					return
+				}
+				time.Sleep(time.Duration(frand.Intn(40)) * time.Millisecond)
+			}
+		}()
+	}
+	for time.Now().Before(deadline) {
+		if err := v.Unlock("foo bar baz"); err != nil && !errors.Is(err, vault.ErrUnlocked) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+}
+
+func TestReserveIndices(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "vaultd.sqlite3")
+	db, err := OpenDatabase(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a second store simulates another process sharing the database
+	db2, err := OpenDatabase(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+
+	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(73), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, reservations, size = 4, 25, 10
+	starts := make(chan uint64, 2*workers*reservations)
+	errCh := make(chan error, 2*workers)
+	for _, store := range []*Store{db, db2} {
+		for range workers {
+			go func() {
+				for range reservations {
+					start, err := store.ReserveIndices(meta.ID, size)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					starts <- start
+				}
+				errCh <- nil
+			}()
+		}
+	}
+	for range 2 * workers {
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(starts)
+
+	seen := make(map[uint64]bool)
+	for start := range starts {
+		if start%size != 0 || seen[start] {
+			t.Fatalf("overlapping reservation at %d", start)
+		}
+		seen[start] = true
+	}
+	if len(seen) != 2*workers*reservations {
+		t.Fatalf("expected %d reservations, got %d", 2*workers*reservations, len(seen))
+	} else if next, err := db2.NextIndex(meta.ID); err != nil {
+		t.Fatal(err)
+	} else if next != 2*workers*reservations*size {
+		t.Fatalf("expected next index %d, got %d", 2*workers*reservations*size, next)
+	}
+
+	// keys added above the counter advance it
+	if err := db.AddKeyIndex(meta.ID, frand.Entropy256(), 5000); err != nil {
+		t.Fatal(err)
+	} else if next, err := db.NextIndex(meta.ID); err != nil {
+		t.Fatal(err)
+	} else if next != 5001 {
+		t.Fatalf("expected next index 5001, got %d", next)
+	} else if _, err := db.ReserveIndices(100, 1); !errors.Is(err, vault.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestExportSeeds(t *testing.T) {
+	db, err := OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	meta, err := db.AddSeed(frand.Entropy256(), frand.Bytes(73), "")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := db.ReserveIndices(meta.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	pks := []types.PublicKey{frand.Entropy256(), frand.Entropy256()}
+	if err := db.AddKeyIndices(meta.ID, 2, pks); err != nil {
+		t.Fatal(err)
+	}
+
+	seeds, err := db.ExportSeeds(2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 1 || seeds[0].ID != meta.ID {
+		t.Fatalf("unexpected seeds %+v", seeds)
+	} else if seeds[0].NextIndex != 4 {
+		t.Fatalf("expected next index 4, got %d", seeds[0].NextIndex)
+	} else if len(seeds[0].Keys) != 2 || seeds[0].Keys[0].Index != 2 || seeds[0].Keys[1].PublicKey != pks[1] {
+		t.Fatalf("unexpected keys %+v", seeds[0].Keys)
+	}
+
+	if _, err := db.ExportSeeds(1); !errors.Is(err, vault.ErrExportLimit) {
+		t.Fatalf("expected ErrExportLimit, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.sia.tech/vaultd/webhooks"
+)
+
+// Webhooks returns every webhook sorted by creation time, ASC.
+func (s *Store) Webhooks() (hooks []webhooks.Webhook, err error) {
+	err = s.transaction(func(tx *txn) error {
+		rows, err := tx.Query(`SELECT id, callback_url, scopes, secret_key, date_created FROM webhooks ORDER BY date_created ASC, id ASC`)
+		if err != nil {
+			return fmt.Errorf("failed to query webhooks: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			hook, err := scanWebhook(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan webhook: %w", err)
+			}
+			hooks = append(hooks, hook)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// AddWebhook adds a new webhook.
+func (s *Store) AddWebhook(callbackURL, secretKey string, scopes []string) (hook webhooks.Webhook, err error) {
+	err = s.transaction(func(tx *txn) error {
+		hook, err = scanWebhook(tx.QueryRow(`INSERT INTO webhooks (callback_url, scopes, secret_key, date_created) VALUES ($1, $2, $3, $4) RETURNING id, callback_url, scopes, secret_key, date_created`, callbackURL, strings.Join(scopes, ","), secretKey, sqlTime(time.Now())))
+		if err != nil {
+			return fmt.Errorf("failed to insert webhook: %w", err)
+		}
+		return nil
+	})
+	return
+}
+
+// UpdateWebhook updates the callback URL and scopes of a webhook. If the
+// webhook is not found, [webhooks.ErrNotFound] is returned.
+func (s *Store) UpdateWebhook(id webhooks.ID, callbackURL string, scopes []string) (hook webhooks.Webhook, err error) {
+	err = s.transaction(func(tx *txn) error {
+		hook, err = scanWebhook(tx.QueryRow(`UPDATE webhooks SET callback_url=$1, scopes=$2 WHERE id=$3 RETURNING id, callback_url, scopes, secret_key, date_created`, callbackURL, strings.Join(scopes, ","), id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhooks.ErrNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to update webhook: %w", err)
+		}
+		return nil
+	})
+	return
+}
+
+// DeleteWebhook removes a webhook. If the webhook is not found,
+// [webhooks.ErrNotFound] is returned.
+func (s *Store) DeleteWebhook(id webhooks.ID) error {
+	return s.transaction(func(tx *txn) error {
+		res, err := tx.Exec(`DELETE FROM webhooks WHERE id=$1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			return webhooks.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func scanWebhook(s scanner) (hook webhooks.Webhook, err error) {
+	var scopes string
+	if err = s.Scan(&hook.ID, &hook.CallbackURL, &scopes, &hook.SecretKey, (*sqlTime)(&hook.CreatedAt)); err != nil {
+		return
+	}
+	for sc := range strings.SplitSeq(scopes, ",") {
+		if sc != "" {
+			hook.Scopes = append(hook.Scopes, sc)
+		}
+	}
+	return
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package serial
+
+import (
+	"errors"
+	"os"
+)
+
+// Open opens the serial device at path. Serial devices are currently only
+// supported on Linux.
+func Open(path string, baud int) (*os.File, error) {
+	return nil, errors.New("serial devices are only supported on Linux")
+}
@@ -0,0 +1,88 @@
+// Package serial serves offline sign requests over a serial port so a
+// dedicated signer can operate without a network stack.
+package serial
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.sia.tech/vaultd/offline"
+	"go.uber.org/zap"
+)
+
+const (
+	// chunkSize is the maximum number of data characters per response
+	// line.
+	chunkSize = 512
+
+	errorPrefix = "vaultd:error:"
+)
+
+// handle decodes a sign request envelope, signs it, and returns the
+// response envelope.
+func handle(buf []byte, s offline.RequestSigner) ([]byte, error) {
+	req, err := offline.DecodeRequest(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+	resp, err := s.SignRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return offline.EncodeResponse(resp), nil
+}
+
+// Serve reads sign request chunks, one per line, from rw and writes the
+// chunks of the response back, one per line. If a request cannot be
+// handled, a single line prefixed with "vaultd:error:" is written instead.
+// Serve returns when reading from rw fails.
+func Serve(rw io.ReadWriter, s offline.RequestSigner, log *zap.Logger) error {
+	writeLine := func(line string) error {
+		_, err := io.WriteString(rw, line+"\n")
+		return err
+	}
+
+	var chunks []string
+	sc := bufio.NewScanner(rw)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		chunks = append(chunks, line)
+		buf, err := offline.Unchunk(chunks)
+		if err != nil && !errors.Is(err, offline.ErrIncomplete) && len(chunks) > 1 {
+			// the chunk may belong to a new envelope, discard the
+			// previous chunks
+			chunks = []string{line}
+			buf, err = offline.Unchunk(chunks)
+		}
+		if errors.Is(err, offline.ErrIncomplete) {
+			continue
+		}
+		chunks = nil
+
+		if err == nil {
+			buf, err = handle(buf, s)
+		}
+		if err != nil {
+			log.Debug("failed to handle request", zap.Error(err))
+			if err := writeLine(errorPrefix + err.Error()); err != nil {
+				return fmt.Errorf("failed to write error: %w", err)
+			}
+			continue
+		}
+
+		for _, chunk := range offline.Chunk(buf, chunkSize) {
+			if err := writeLine(chunk); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+		log.Debug("signed request")
+	}
+	return sc.Err()
+}
@@ -0,0 +1,237 @@
+package serial
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/vaultd/api"
+	"go.sia.tech/vaultd/offline"
+	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/vault"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+type keySigner map[types.PublicKey]types.PrivateKey
+
+func (ks keySigner) Sign(pk types.PublicKey, sigHash types.Hash256) (types.Signature, error) {
+	sk, ok := ks[pk]
+	if !ok {
+		return types.Signature{}, vault.ErrNotFound
+	}
+	return sk.SignHash(sigHash), nil
+}
+
+func (ks keySigner) SignRequest(req offline.SignRequest) (offline.SignResponse, error) {
+	return offline.Sign(ks, req)
+}
+
+func newVault(t *testing.T) (*vault.Vault, *sqlite.Store) {
+	store, err := sqlite.OpenMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	v := vault.New(store)
+	t.Cleanup(func() { v.Close() })
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+	return v, store
+}
+
+func newKey(t *testing.T, v *vault.Vault) (vault.SeedID, types.PublicKey) {
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed((*[32]byte)(&seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := v.NextKey(meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meta.ID, pk
+}
+
+func TestServe(t *testing.T) {
+	sk := types.GeneratePrivateKey()
+	signer := keySigner{sk.PublicKey(): sk}
+
+	hostR, portW := io.Pipe()
+	portR, hostW := io.Pipe()
+	port := struct {
+		io.Reader
+		io.Writer
+	}{portR, portW}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Serve(port, signer, zaptest.NewLogger(t))
+	}()
+
+	req := offline.SignRequest{
+		TransactionID: frand.Entropy256(),
+		SigHashes: []offline.SigHash{
+			{PublicKey: sk.PublicKey(), SigHash: frand.Entropy256()},
+			{PublicKey: types.GeneratePrivateKey().PublicKey(), SigHash: frand.Entropy256()},
+		},
+	}
+
+	go func() {
+		// send garbage first to ensure the transport recovers
+		io.WriteString(hostW, "garbage\n")
+		for _, chunk := range offline.Chunk(offline.EncodeRequest(req), 32) {
+			io.WriteString(hostW, chunk+"\n")
+		}
+	}()
+
+	sc := bufio.NewScanner(hostR)
+	if !sc.Scan() {
+		t.Fatal(sc.Err())
+	} else if !strings.HasPrefix(sc.Text(), errorPrefix) {
+		t.Fatalf("expected error, got %q", sc.Text())
+	}
+
+	var chunks []string
+	for sc.Scan() {
+		chunks = append(chunks, sc.Text())
+		if _, err := offline.Unchunk(chunks); err == nil {
+			break
+		}
+	}
+	buf, err := offline.Unchunk(chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := offline.DecodeResponse(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.TransactionID != req.TransactionID {
+		t.Fatal("transaction ID mismatch")
+	} else if len(resp.Signatures) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(resp.Signatures))
+	} else if !sk.PublicKey().VerifyHash(req.SigHashes[0].SigHash, resp.Signatures[0].Signature) {
+		t.Fatal("invalid signature")
+	}
+
+	hostW.Close()
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeMaintenance(t *testing.T) {
+	v, _ := newVault(t)
+	_, pk := newKey(t, v)
+	signer := api.NewServer(nil, v, zaptest.NewLogger(t), api.WithMaintenanceWindows([]api.TimeWindow{{}}, time.Hour))
+
+	req := offline.SignRequest{
+		TransactionID: frand.Entropy256(),
+		SigHashes:     []offline.SigHash{{PublicKey: pk, SigHash: frand.Entropy256()}},
+	}
+	if _, err := handle(offline.EncodeRequest(req), signer); err == nil || !strings.Contains(err.Error(), "maintenance window") {
+		t.Fatalf("expected maintenance window error, got %v", err)
+	}
+}
+
+func TestServeSignPolicies(t *testing.T) {
+	v, _ := newVault(t)
+	var ids []vault.SeedID
+	var keys []types.PublicKey
+	for range 3 {
+		id, pk := newKey(t, v)
+		ids = append(ids, id)
+		keys = append(keys, pk)
+	}
+
+	// the first seed is only allowed on a day that is neither today nor
+	// yesterday, the second only from a network, which a serial request
+	// never has, and the third at any time
+	never := time.Now().UTC().Weekday() + 3
+	signer := api.NewServer(nil, v, zaptest.NewLogger(t), api.WithSignPolicies([]api.SignPolicy{
+		{Seeds: ids[:1], Times: []api.TimeWindow{{Days: []time.Weekday{never % 7}, End: time.Hour}}},
+		{Seeds: ids[1:2], Networks: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}},
+		{Seeds: ids[2:], Times: []api.TimeWindow{{}}},
+	}))
+
+	for i, pk := range keys {
+		req := offline.SignRequest{
+			TransactionID: frand.Entropy256(),
+			SigHashes:     []offline.SigHash{{PublicKey: pk, SigHash: frand.Entropy256()}},
+		}
+		resp, err := handle(offline.EncodeRequest(req), signer)
+		if i < 2 {
+			if err == nil || !strings.Contains(err.Error(), "sign policy") {
+				t.Fatalf("seed %d: expected sign policy error, got %v", i, err)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("seed %d: %v", i, err)
+		}
+		sr, err := offline.DecodeResponse(resp)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(sr.Signatures) != 1 || !pk.VerifyHash(req.SigHashes[0].SigHash, sr.Signatures[0].Signature) {
+			t.Fatalf("seed %d: expected a valid signature", i)
+		}
+	}
+}
+
+func TestServeReplayProtection(t *testing.T) {
+	v, store := newVault(t)
+	_, pk := newKey(t, v)
+	signer := api.NewServer(nil, v, zaptest.NewLogger(t), api.WithReplayProtection(store, time.Minute))
+
+	req := offline.SignRequest{
+		TransactionID: frand.Entropy256(),
+		SigHashes:     []offline.SigHash{{PublicKey: pk, SigHash: frand.Entropy256()}},
+	}
+	if _, err := handle(offline.EncodeRequest(req), signer); err == nil || !strings.Contains(err.Error(), "nonce is required") {
+		t.Fatalf("expected missing nonce error, got %v", err)
+	}
+
+	req.Nonce, req.Timestamp = hex.EncodeToString(frand.Bytes(16)), time.Now()
+	if _, err := handle(offline.EncodeRequest(req), signer); err != nil {
+		t.Fatal(err)
+	} else if _, err := handle(offline.EncodeRequest(req), signer); err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Fatalf("expected replay error, got %v", err)
+	}
+}
+
+type signHook chan api.SignEvent
+
+func (h signHook) AfterSign(_ context.Context, e api.SignEvent) error {
+	h <- e
+	return nil
+}
+
+func TestServeSignHook(t *testing.T) {
+	v, _ := newVault(t)
+	_, pk := newKey(t, v)
+	hook := make(signHook, 1)
+	signer := api.NewServer(nil, v, zaptest.NewLogger(t), api.WithSignHook(hook))
+
+	req := offline.SignRequest{
+		TransactionID: frand.Entropy256(),
+		SigHashes:     []offline.SigHash{{PublicKey: pk, SigHash: frand.Entropy256()}},
+	}
+	if _, err := handle(offline.EncodeRequest(req), signer); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-hook:
+		if e.Route != "serial" || e.TransactionID != req.TransactionID || !e.FullySigned {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sign hook was not called")
+	}
+}
@@ -0,0 +1,192 @@
+// Package snapshot manages named point-in-time copies of the vault's
+// database that can be restored after an erroneous bulk operation.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fileExt = ".sqlite3"
+
+var (
+	// ErrNotFound is returned when a snapshot does not exist.
+	ErrNotFound = errors.New("snapshot not found")
+	// ErrExists is returned when creating a snapshot with the name of an
+	// existing snapshot.
+	ErrExists = errors.New("snapshot already exists")
+	// ErrInvalidName is returned when a snapshot name contains characters
+	// other than letters, digits, '-', and '_', or is longer than 64
+	// characters.
+	ErrInvalidName = errors.New("snapshot names must be 1-64 letters, digits, '-', or '_'")
+
+	validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+type (
+	// A Store can copy its database to a file and restore it from one.
+	Store interface {
+		// CreateSnapshot writes a consistent copy of the database to fp.
+		CreateSnapshot(fp string) error
+		// RestoreSnapshot replaces the database's contents with the
+		// snapshot at fp.
+		RestoreSnapshot(fp string) error
+		// PreviewSnapshot validates the snapshot at fp and returns the
+		// contents of the snapshot and the database.
+		PreviewSnapshot(fp string) (Preview, error)
+	}
+
+	// Contents counts the rows restored from a snapshot.
+	Contents struct {
+		Seeds              int `json:"seeds"`
+		Keys               int `json:"keys"`
+		AddressBookEntries int `json:"addressBookEntries"`
+	}
+
+	// A Preview compares the contents of a snapshot with the current
+	// database.
+	Preview struct {
+		Snapshot Contents `json:"snapshot"`
+		Current  Contents `json:"current"`
+	}
+
+	// A Snapshot is a named copy of the database.
+	Snapshot struct {
+		Name      string    `json:"name"`
+		Size      int64     `json:"size"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	// A Manager creates, lists, and restores snapshots in a directory.
+	Manager struct {
+		dir   string
+		store Store
+
+		mu sync.Mutex // serializes snapshot operations
+	}
+)
+
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.dir, name+fileExt)
+}
+
+func (m *Manager) stat(name string) (Snapshot, error) {
+	info, err := os.Stat(m.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, ErrNotFound
+	} else if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Name: name, Size: info.Size(), CreatedAt: info.ModTime()}, nil
+}
+
+func (m *Manager) create(name string) (Snapshot, error) {
+	if !validName.MatchString(name) {
+		return Snapshot{}, ErrInvalidName
+	} else if _, err := m.stat(name); err == nil {
+		return Snapshot{}, ErrExists
+	} else if !errors.Is(err, ErrNotFound) {
+		return Snapshot{}, err
+	}
+
+	// write to a temporary file so a failed snapshot is never listed
+	tmp := m.path(name) + ".tmp"
+	os.Remove(tmp)
+	if err := m.store.CreateSnapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, err
+	} else if err := os.Chmod(tmp, 0600); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, fmt.Errorf("failed to set snapshot permissions: %w", err)
+	} else if err := os.Rename(tmp, m.path(name)); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+	return m.stat(name)
+}
+
+// Create creates a snapshot of the database.
+func (m *Manager) Create(name string) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create(name)
+}
+
+// Snapshots returns every snapshot sorted by creation time, ASC.
+func (m *Manager) Snapshots() ([]Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), fileExt)
+		if !ok || entry.IsDir() || !validName.MatchString(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat snapshot %q: %w", name, err)
+		}
+		snapshots = append(snapshots, Snapshot{Name: name, Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Preview validates a snapshot and returns what restoring it would change
+// without restoring it.
+func (m *Manager) Preview(name string) (Preview, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !validName.MatchString(name) {
+		return Preview{}, ErrInvalidName
+	} else if _, err := m.stat(name); err != nil {
+		return Preview{}, err
+	}
+	return m.store.PreviewSnapshot(m.path(name))
+}
+
+// Restore restores the database from a snapshot. The current state is
+// first saved as a snapshot named "pre-restore-<unix nanoseconds>", which is
+// returned so the restore can be undone.
+func (m *Manager) Restore(name string) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !validName.MatchString(name) {
+		return Snapshot{}, ErrInvalidName
+	} else if _, err := m.stat(name); err != nil {
+		return Snapshot{}, err
+	}
+
+	backup, err := m.create(fmt.Sprintf("pre-restore-%d", time.Now().UnixNano()))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to snapshot current state: %w", err)
+	} else if err := m.store.RestoreSnapshot(m.path(name)); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	return backup, nil
+}
+
+// NewManager returns a Manager that stores snapshots in dir. The directory
+// is created if it does not exist.
+func NewManager(dir string, s Store) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &Manager{dir: dir, store: s}, nil
+}
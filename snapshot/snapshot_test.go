@@ -0,0 +1,112 @@
+package snapshot_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.sia.tech/vaultd/persist/sqlite"
+	"go.sia.tech/vaultd/snapshot"
+	"go.sia.tech/vaultd/vault"
+	"lukechampine.com/frand"
+)
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "vaultd.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v := vault.New(db)
+	defer v.Close()
+	if err := v.Unlock("foo bar baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := frand.Entropy256()
+	meta, err := v.AddSeed(&seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := v.NextKey(meta.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := snapshot.NewManager(filepath.Join(dir, "snapshots"), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("../escape"); !errors.Is(err, snapshot.ErrInvalidName) {
+		t.Fatalf("expected %v, got %v", snapshot.ErrInvalidName, err)
+	} else if _, err := m.Create("good"); err != nil {
+		t.Fatal(err)
+	} else if _, err := m.Create("good"); !errors.Is(err, snapshot.ErrExists) {
+		t.Fatalf("expected %v, got %v", snapshot.ErrExists, err)
+	}
+
+	// an erroneous bulk operation
+	for range 100 {
+		if _, err := v.NextKey(meta.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed2 := frand.Entropy256()
+	if _, err := v.AddSeed(&seed2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Preview("missing"); !errors.Is(err, snapshot.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", snapshot.ErrNotFound, err)
+	} else if preview, err := m.Preview("good"); err != nil {
+		t.Fatal(err)
+	} else if preview.Snapshot != (snapshot.Contents{Seeds: 1, Keys: 3}) || preview.Current != (snapshot.Contents{Seeds: 2, Keys: 103}) {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+
+	backup, err := m.Restore("good")
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(backup.Name, "pre-restore-") {
+		t.Fatalf("unexpected backup name %q", backup.Name)
+	}
+
+	if keys, _, err := v.SeedKeys(meta.ID, 0, 1000); err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(keys))
+	} else if seeds, err := v.Seeds(100, 0); err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 1 {
+		t.Fatalf("expected 1 seed, got %d", len(seeds))
+	}
+
+	// the restored seed can still sign
+	if _, err := v.NextKey(meta.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := m.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(snapshots) != 2 || snapshots[0].Name != "good" || snapshots[1].Name != backup.Name {
+		t.Fatalf("unexpected snapshots %v", snapshots)
+	}
+
+	// undo the restore
+	if _, err := m.Restore(backup.Name); err != nil {
+		t.Fatal(err)
+	} else if seeds, err := v.Seeds(100, 0); err != nil {
+		t.Fatal(err)
+	} else if len(seeds) != 2 {
+		t.Fatalf("expected 2 seeds, got %d", len(seeds))
+	}
+
+	if _, err := m.Restore("missing"); !errors.Is(err, snapshot.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", snapshot.ErrNotFound, err)
+	}
+}
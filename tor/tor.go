@@ -0,0 +1,187 @@
+// Package tor publishes the API as an onion service using the control
+// port of an external Tor daemon.
+package tor
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Options configures an onion service.
+	Options struct {
+		// ControlAddress is the address of the Tor control port.
+		ControlAddress string
+		// ControlPassword is used to authenticate with the control port.
+		// If empty, cookie or null authentication is used.
+		ControlPassword string
+		// KeyPath is the path of the onion service's private key. If the
+		// file does not exist, a new key is generated and written to it.
+		// If empty, a new key is generated every time the service is
+		// published.
+		KeyPath string
+		// Port is the virtual port of the onion service.
+		Port int
+		// Target is the local address connections are forwarded to.
+		Target string
+	}
+
+	// A Service is a published onion service. The service is removed when
+	// it is closed or the Tor daemon's control connection is lost.
+	Service struct {
+		conn    *textproto.Conn
+		address string
+	}
+)
+
+// Address returns the onion address of the service.
+func (s *Service) Address() string {
+	return s.address
+}
+
+// Close removes the onion service.
+func (s *Service) Close() error {
+	return s.conn.Close()
+}
+
+// command sends a command and returns the reply lines. An error is
+// returned if the reply status is not 250.
+func command(conn *textproto.Conn, format string, args ...any) ([]string, error) {
+	id, err := conn.Cmd(format, args...)
+	if err != nil {
+		return nil, err
+	}
+	conn.StartResponse(id)
+	defer conn.EndResponse(id)
+
+	var lines []string
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return nil, err
+		} else if len(line) < 4 {
+			return nil, fmt.Errorf("malformed reply %q", line)
+		}
+		code, sep, text := line[:3], line[3], line[4:]
+		if code != "250" {
+			return nil, fmt.Errorf("tor: %s", line)
+		}
+		lines = append(lines, text)
+		if sep == ' ' {
+			return lines, nil
+		}
+	}
+}
+
+// quote returns s as a control protocol quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// authenticate authenticates with the control port using the password, a
+// cookie file, or null authentication.
+func authenticate(conn *textproto.Conn, password string) error {
+	if password != "" {
+		_, err := command(conn, "AUTHENTICATE %s", quote(password))
+		return err
+	}
+
+	lines, err := command(conn, "PROTOCOLINFO 1")
+	if err != nil {
+		return fmt.Errorf("failed to get protocol info: %w", err)
+	}
+	var methods []string
+	var cookieFile string
+	for _, line := range lines {
+		rest, ok := strings.CutPrefix(line, "AUTH METHODS=")
+		if !ok {
+			continue
+		}
+		m, rest, _ := strings.Cut(rest, " ")
+		methods = strings.Split(m, ",")
+		if s, ok := strings.CutPrefix(rest, "COOKIEFILE="); ok {
+			if cookieFile, err = strconv.Unquote(s); err != nil {
+				return fmt.Errorf("malformed cookie file %q", s)
+			}
+		}
+	}
+
+	for _, m := range methods {
+		switch m {
+		case "NULL":
+			_, err := command(conn, "AUTHENTICATE")
+			return err
+		case "COOKIE":
+			cookie, err := os.ReadFile(cookieFile)
+			if err != nil {
+				return fmt.Errorf("failed to read cookie file: %w", err)
+			}
+			_, err = command(conn, "AUTHENTICATE %s", hex.EncodeToString(cookie))
+			return err
+		}
+	}
+	return errors.New("no supported authentication method, set a control password")
+}
+
+// Publish publishes an onion service that forwards connections to the
+// target address.
+func Publish(opts Options) (*Service, error) {
+	if opts.Port == 0 {
+		opts.Port = 80
+	}
+
+	c, err := net.DialTimeout("tcp", opts.ControlAddress, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to control port: %w", err)
+	}
+	conn := textproto.NewConn(c)
+	if err := authenticate(conn, opts.ControlPassword); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	key := "NEW:ED25519-V3"
+	if opts.KeyPath != "" {
+		buf, err := os.ReadFile(opts.KeyPath)
+		if err == nil {
+			key = strings.TrimSpace(string(buf))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			conn.Close()
+			return nil, fmt.Errorf("failed to read onion key: %w", err)
+		}
+	}
+
+	lines, err := command(conn, "ADD_ONION %s Port=%d,%s", key, opts.Port, opts.Target)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to add onion service: %w", err)
+	}
+	s := &Service{conn: conn}
+	var privateKey string
+	for _, line := range lines {
+		if v, ok := strings.CutPrefix(line, "ServiceID="); ok {
+			s.address = v + ".onion"
+		} else if v, ok := strings.CutPrefix(line, "PrivateKey="); ok {
+			privateKey = v
+		}
+	}
+	if s.address == "" {
+		conn.Close()
+		return nil, errors.New("missing service ID")
+	}
+
+	if opts.KeyPath != "" && privateKey != "" {
+		if err := os.WriteFile(opts.KeyPath, []byte(privateKey+"\n"), 0600); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to write onion key: %w", err)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,104 @@
+package tor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// startControlServer starts a fake Tor control port that requires the
+// password and records the key of each ADD_ONION command.
+func startControlServer(t *testing.T, password string) (string, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	keys := make(chan string, 10)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				authenticated := false
+				sc := bufio.NewScanner(c)
+				for sc.Scan() {
+					cmd, args, _ := strings.Cut(sc.Text(), " ")
+					switch {
+					case cmd == "AUTHENTICATE" && args == quote(password):
+						authenticated = true
+						fmt.Fprint(c, "250 OK\r\n")
+					case cmd == "AUTHENTICATE":
+						fmt.Fprint(c, "515 Authentication failed\r\n")
+						return
+					case !authenticated:
+						fmt.Fprint(c, "514 Authentication required\r\n")
+						return
+					case cmd == "ADD_ONION":
+						key, _, _ := strings.Cut(args, " ")
+						keys <- key
+						fmt.Fprint(c, "250-ServiceID=abcdefghijklmnop\r\n")
+						if key == "NEW:ED25519-V3" {
+							fmt.Fprint(c, "250-PrivateKey=ED25519-V3:c2VjcmV0\r\n")
+						}
+						fmt.Fprint(c, "250 OK\r\n")
+					default:
+						fmt.Fprint(c, "510 Unrecognized command\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), keys
+}
+
+func TestPublish(t *testing.T) {
+	addr, keys := startControlServer(t, `p@ss "word"`)
+	keyPath := filepath.Join(t.TempDir(), "onion.key")
+
+	opts := Options{
+		ControlAddress:  addr,
+		ControlPassword: `p@ss "word"`,
+		KeyPath:         keyPath,
+		Target:          "127.0.0.1:9980",
+	}
+	s, err := Publish(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Address() != "abcdefghijklmnop.onion" {
+		t.Fatalf("unexpected address %q", s.Address())
+	} else if key := <-keys; key != "NEW:ED25519-V3" {
+		t.Fatalf("expected new key, got %q", key)
+	}
+
+	// the generated key should be reused
+	buf, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "ED25519-V3:c2VjcmV0\n" {
+		t.Fatalf("unexpected key file %q", buf)
+	}
+	s2, err := Publish(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if key := <-keys; key != "ED25519-V3:c2VjcmV0" {
+		t.Fatalf("expected stored key, got %q", key)
+	}
+
+	opts.ControlPassword = "wrong"
+	if _, err := Publish(opts); err == nil {
+		t.Fatal("expected authentication error")
+	}
+}
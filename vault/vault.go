@@ -2,11 +2,14 @@ package vault
 
 import (
 	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"hash"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.sia.tech/core/types"
@@ -18,6 +21,42 @@ import (
 	"lukechampine.com/frand"
 )
 
+// DefaultSeedRetention is the default period during which a deleted seed
+// can be restored.
+const DefaultSeedRetention = 30 * 24 * time.Hour
+
+const (
+	// unlockBackoffThreshold is the number of consecutive failed unlock
+	// attempts allowed before further attempts are delayed.
+	unlockBackoffThreshold = 3
+	// unlockBackoffBase is the delay after the first attempt past the
+	// threshold. It doubles with each further failure.
+	unlockBackoffBase = time.Second
+	// maxUnlockBackoff is the longest delay between unlock attempts.
+	maxUnlockBackoff = time.Hour
+)
+
+// maxDecryptFailures is the number of seeds that can fail to decrypt after
+// the Vault is unlocked before it becomes read-only.
+const maxDecryptFailures = 3
+
+// currentKeyVersion is the version of the key derivation used for newly
+// stored seeds. Version 0 used the Argon2 key for both the AEAD and the
+// MAC. Version 1 derives separate subkeys for each with HKDF.
+const currentKeyVersion = 1
+
+// Encrypted seeds are prefixed with their encryption format so older
+// formats can still be decrypted after the format used for new seeds
+// changes.
+const (
+	// seedFormatXChaCha20Poly1305 is the 24-byte nonce followed by the
+	// XChaCha20-Poly1305 ciphertext of the seed.
+	seedFormatXChaCha20Poly1305 byte = 1
+
+	// currentSeedFormat is the format used to encrypt new seeds.
+	currentSeedFormat = seedFormatXChaCha20Poly1305
+)
+
 var (
 	// ErrInvalidSize is returned when a key has an invalid size.
 	ErrInvalidSize = errors.New("invalid key size")
@@ -30,32 +69,164 @@ var (
 	// ErrUnlocked is returned when unlocking a vault
 	// that is already unlocked.
 	ErrUnlocked = errors.New("already unlocked")
+	// ErrTooManyAttempts is returned when unlocking a vault before the
+	// backoff from previous failed attempts has elapsed.
+	ErrTooManyAttempts = errors.New("too many failed unlock attempts")
+	// ErrReadOnly is returned when signing, deriving keys, or adding
+	// seeds after repeated seed decryption failures.
+	ErrReadOnly = errors.New("vault is read-only after repeated seed decryption failures")
 	// ErrLocked is returned when trying to access a locked vault.
 	ErrLocked = errors.New("vault is locked")
+	// ErrNotReserved is returned when registering a key whose index was
+	// not reserved for external derivation.
+	ErrNotReserved = errors.New("index not reserved")
+	// ErrKeyMismatch is returned when a registered key is not the key
+	// derived from its seed and index.
+	ErrKeyMismatch = errors.New("key does not match its seed and index")
+	// ErrExpired is returned when signing with a key whose expiration, or
+	// its seed's expiration, has passed.
+	ErrExpired = errors.New("key expired")
+	// ErrHardwareToken is returned when the hardware token does not
+	// respond to the unlock challenge.
+	ErrHardwareToken = errors.New("hardware token challenge failed")
+	// ErrExportLimit is returned when exporting more keys than the
+	// limit.
+	ErrExportLimit = errors.New("too many keys to export")
+)
+
+// Import actions
+const (
+	// ImportCreate adds a new seed.
+	ImportCreate ImportAction = "create"
+	// ImportRestore restores a deleted seed.
+	ImportRestore ImportAction = "restore"
+	// ImportExists leaves an existing seed unchanged.
+	ImportExists ImportAction = "exists"
 )
 
 type (
 	// A SeedID is a unique identifier for a seed.
 	SeedID int64
 
+	// An ImportAction is the effect of adding a seed.
+	ImportAction string
+
+	// A SeedPreview describes the effect of adding a seed without adding
+	// it.
+	SeedPreview struct {
+		Action ImportAction
+		// Seed is the existing seed. It is empty if Action is
+		// [ImportCreate].
+		Seed SeedMeta
+	}
+
 	// SeedMeta contains metadata about a seed.
 	SeedMeta struct {
 		ID        SeedID
 		LastIndex uint64
+		// Language is the language of the seed's recovery phrase. It is
+		// empty if the seed was not imported from a phrase or was added
+		// before languages were recorded.
+		Language string
+		// ExpiresAt is the time after which the seed's keys cannot sign.
+		// It is zero if the seed does not expire.
+		ExpiresAt time.Time
 		CreatedAt time.Time
 	}
 
+	// An Expiration is the time after which a seed's keys, or a single
+	// key, cannot sign. PublicKey is empty for a seed's expiration.
+	Expiration struct {
+		SeedID    SeedID
+		PublicKey types.PublicKey
+		ExpiresAt time.Time
+	}
+
+	// A SeedExport contains the public keys derived from a seed.
+	SeedExport struct {
+		ID SeedID
+		// FirstKey is the public key at index 0. It is empty if the key
+		// is not stored and the Vault is locked.
+		FirstKey types.PublicKey
+		// NextIndex is the next index that has not been derived or
+		// reserved.
+		NextIndex uint64
+		// Keys are the stored keys of the seed, sorted by index.
+		Keys []KeyMeta
+	}
+
+	// KeyMeta identifies a derived key.
+	KeyMeta struct {
+		PublicKey types.PublicKey
+		SeedID    SeedID
+		Index     uint64
+		CreatedAt time.Time
+	}
+
+	// A KeyFilter limits the keys returned by [Vault.FilterKeys]. Zero
+	// values match every key.
+	KeyFilter struct {
+		SeedID       SeedID
+		CreatedAfter time.Time
+	}
+
+	// A ConsistencyIssue is a problem found by a consistency check of
+	// the store.
+	ConsistencyIssue struct {
+		// SeedID is the seed the issue affects, if any.
+		SeedID      SeedID `json:"seedID,omitempty"`
+		Description string `json:"description"`
+	}
+
 	// A Store is a persistent store for seeds and keys.
 	Store interface {
 		// SigningKeyIndex returns the seed and index associated with the given
 		// public key. If the key is not found, [ErrNotFound] is returned.
 		SigningKeyIndex(types.PublicKey) (SeedID, uint64, error)
+		// SetSeedExpiration sets the time after which the seed's keys
+		// cannot sign. A zero time removes the expiration. If the seed
+		// ID is not found, [ErrNotFound] is returned.
+		SetSeedExpiration(SeedID, time.Time) error
+		// SetKeyExpiration sets the time after which the key cannot
+		// sign. A zero time removes the expiration. If the key is not
+		// found, [ErrNotFound] is returned.
+		SetKeyExpiration(types.PublicKey, time.Time) error
+		// KeyExpiration returns the earlier of the expirations of the
+		// key and its seed, or the zero time if neither expires.
+		KeyExpiration(SeedID, types.PublicKey) (time.Time, error)
+		// Expirations returns the expirations of seeds that have not
+		// been deleted and their keys that are before the given time,
+		// sorted by expiration, ASC.
+		Expirations(before time.Time) ([]Expiration, error)
+		// KeyByAddress returns the key whose v1 standard unlock hash or v2
+		// public key policy address matches addr. If no key matches,
+		// [ErrNotFound] is returned.
+		KeyByAddress(addr types.Address) (KeyMeta, error)
 		// AddKeyIndex associates a public key with the given seed ID and index.
 		// If the key is already in the store, nil is returned.
 		AddKeyIndex(seedID SeedID, pk types.PublicKey, index uint64) error
-		// NextIndex returns the next index to be derived for the given seed ID.
-		// If the seed ID is not found, [ErrNotFound] is returned.
+		// AddKeyIndices associates public keys with consecutive indices
+		// of the given seed, starting at start, in a single transaction.
+		// Keys already in the store are skipped.
+		AddKeyIndices(seedID SeedID, start uint64, pks []types.PublicKey) error
+		// NextIndex returns the next index to be allocated for the given
+		// seed ID. If the seed ID is not found, [ErrNotFound] is returned.
 		NextIndex(seedID SeedID) (index uint64, err error)
+		// ReserveIndices atomically allocates n consecutive indices of the
+		// seed and returns the first. Concurrent callers never receive
+		// overlapping indices. If the seed ID is not found, [ErrNotFound]
+		// is returned.
+		ReserveIndices(seedID SeedID, n uint64) (start uint64, err error)
+		// ReserveExternalIndices atomically allocates n consecutive
+		// indices of the seed for keys derived outside of the vault and
+		// records the reservation. It returns the first index. If the
+		// seed ID is not found, [ErrNotFound] is returned.
+		ReserveExternalIndices(seedID SeedID, n uint64) (start uint64, err error)
+		// RegisterKeys adds keys derived outside of the vault in a single
+		// transaction. Each index must be within a reservation of its
+		// seed, otherwise [ErrNotReserved] is returned. Keys already
+		// registered at the same index are skipped.
+		RegisterKeys(keys []KeyMeta) error
 
 		// KeySalt returns the salt used to derive the key encryption
 		// key. If no salt has been set, KeySalt should return (nil, nil).
@@ -64,33 +235,112 @@ type (
 		// If a salt has already been set, [keys.ErrSaltSet] is returned.
 		SetKeySalt([]byte) error
 
+		// FailedUnlocks returns the number of consecutive failed unlock
+		// attempts and the time of the last one.
+		FailedUnlocks() (n int, last time.Time, err error)
+		// RecordFailedUnlock increments the number of consecutive failed
+		// unlock attempts and returns the new count.
+		RecordFailedUnlock(time.Time) (n int, err error)
+		// ResetFailedUnlocks resets the number of consecutive failed
+		// unlock attempts.
+		ResetFailedUnlocks() error
+
 		// BytesForVerify returns random encrypted bytes for verifying
 		// the encryption key.
 		BytesForVerify() ([]byte, error)
 
+		// KeyVersion returns the version of the key derivation used to
+		// encrypt and authenticate the stored seeds.
+		KeyVersion() (int, error)
+		// RekeySeeds replaces the MAC and encrypted seed of every seed,
+		// including deleted seeds, with the result of rekey and sets the
+		// key version in a single transaction.
+		RekeySeeds(version int, rekey func(encryptedSeed []byte) (types.Hash256, []byte, error)) error
+
 		// AddSeed adds an encrypted seed to the store. If the
 		// seed has already been added, its metadata is returned.
-		AddSeed(mac types.Hash256, encryptedSeed []byte) (meta SeedMeta, err error)
+		// The language of the seed's recovery phrase may be empty.
+		AddSeed(mac types.Hash256, encryptedSeed []byte, language string) (meta SeedMeta, err error)
 		// Seeds returns a paginated list of seeds. The list is
-		// sorted by creation time, ASC.
+		// sorted by creation time, ASC. Deleted seeds are excluded.
 		Seeds(limit, offset int) ([]SeedMeta, error)
+		// SeedByMAC returns the metadata of the seed with the given MAC,
+		// including deleted seeds, and the time it was deleted. The
+		// deletion time is zero if the seed is not deleted. If no seed has
+		// the MAC, [ErrNotFound] is returned.
+		SeedByMAC(types.Hash256) (SeedMeta, time.Time, error)
+		// DeletedSeed returns the metadata of a seed that was deleted
+		// after deletedAfter. If no such seed is found, [ErrNotFound] is
+		// returned.
+		DeletedSeed(id SeedID, deletedAfter time.Time) (SeedMeta, error)
+		// DeleteSeed marks a seed as deleted. If the seed ID is not
+		// found, [ErrNotFound] is returned.
+		DeleteSeed(SeedID) error
+		// RestoreSeed restores a seed that was deleted after
+		// deletedAfter. If no such seed is found, [ErrNotFound] is
+		// returned.
+		RestoreSeed(id SeedID, deletedAfter time.Time) error
+		// PurgeSeeds permanently removes seeds deleted before
+		// deletedBefore and returns the number removed.
+		PurgeSeeds(deletedBefore time.Time) (int, error)
 		// Seed returns the encrypted seed associated with the given
 		// seed ID. If the seed ID is not found, [ErrNotFound] is returned.
 		Seed(SeedID) ([]byte, error)
 		// SeedMeta returns metadata about the seed. If the seed ID is
 		// not found, [ErrNotFound] is returned.
 		SeedMeta(SeedID) (SeedMeta, error)
-		// SeedKeys returns a paginated list of public keys derived from the seed.
-		SeedKeys(id SeedID, offset, limit int) ([]types.PublicKey, error)
+		// SeedKeys returns a paginated list of public keys derived from
+		// the seed and the total number of keys derived from it.
+		SeedKeys(id SeedID, offset, limit int) ([]types.PublicKey, int, error)
+		// Keys returns a paginated list of the keys of seeds that have
+		// not been deleted matching the filter, sorted by seed ID and
+		// index, and the total number of matching keys.
+		Keys(filter KeyFilter, offset, limit int) ([]KeyMeta, int, error)
+
+		// CheckConsistency verifies the store's integrity and returns
+		// every issue found.
+		CheckConsistency() ([]ConsistencyIssue, error)
+		// SampleKeys returns up to n randomly selected keys of seeds
+		// that have not been deleted.
+		SampleKeys(n int) ([]KeyMeta, error)
+		// ExportSeeds returns the keys of every seed that has not been
+		// deleted, sorted by creation time, ASC, in a single
+		// transaction. If the seeds have more than maxKeys keys,
+		// [ErrExportLimit] is returned.
+		ExportSeeds(maxKeys int) ([]SeedExport, error)
 	}
 
+	// An Option configures a Vault.
+	Option func(*Vault)
+
+	// A ChallengeResponder returns a hardware token's response to a
+	// challenge, such as the HMAC-SHA1 challenge-response of a YubiKey.
+	// The response must be the same for the same challenge.
+	ChallengeResponder func(challenge []byte) ([]byte, error)
+
 	// A Vault is a secure store for recovery phrases
 	Vault struct {
 		tg *threadgroup.ThreadGroup
 
-		aead  cipher.AEAD
-		mac   hash.Hash
-		store Store
+		lookAhead     uint64
+		seedRetention time.Duration
+
+		autoLockAfter time.Duration
+		onAutoLock    func()
+		autoLock      *time.Timer   // guarded by mu
+		lockChanged   chan struct{} // closed when the lock state changes, guarded by mu
+		lastUsed      atomic.Int64  // unix nanoseconds of the last sign or derivation
+
+		hardwareToken ChallengeResponder
+
+		onReadOnly      func(error)
+		decryptFailures int   // seeds that failed to decrypt since the last unlock, guarded by mu
+		readOnly        error // the reason the Vault is read-only, guarded by mu
+
+		aead       cipher.AEAD
+		mac        hash.Hash
+		keyVersion int
+		store      Store
 
 		mu sync.Mutex // protects atomicity of key derivation
 	}
@@ -115,48 +365,323 @@ func (v *Vault) Unlocked() bool {
 // Close closes the Vault.
 func (v *Vault) Close() error {
 	v.tg.Stop()
+	v.mu.Lock()
+	v.lock()
+	v.mu.Unlock()
 	return nil
 }
 
-// derivePrivateKey derives a private key from the seed ID and index.
+// lock clears the keys and stops the auto-lock timer.
 // It is expected that the caller holds the mutex.
-func (v *Vault) derivePrivateKey(id SeedID, index uint64) (types.PrivateKey, error) {
+func (v *Vault) lock() {
+	if v.aead != nil {
+		v.notifyLockChanged()
+	}
+	v.aead = nil
+	v.mac = nil
+	if v.autoLock != nil {
+		v.autoLock.Stop()
+		v.autoLock = nil
+	}
+}
+
+// notifyLockChanged wakes every caller waiting on [Vault.LockChanged].
+// It is expected that the caller holds the mutex.
+func (v *Vault) notifyLockChanged() {
+	close(v.lockChanged)
+	v.lockChanged = make(chan struct{})
+}
+
+// LockChanged returns a channel that is closed the next time the Vault
+// is locked or unlocked.
+func (v *Vault) LockChanged() <-chan struct{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.lockChanged
+}
+
+// touch records a sign or derive operation for the auto-lock timer.
+func (v *Vault) touch() {
+	v.lastUsed.Store(time.Now().UnixNano())
+}
+
+// checkAutoLock locks the Vault if it has been idle for longer than the
+// auto-lock timeout. Otherwise, the check is rescheduled for when the
+// timeout would next elapse.
+func (v *Vault) checkAutoLock() {
+	done, err := v.tg.Add()
+	if err != nil {
+		return
+	}
+	defer done()
+
+	v.mu.Lock()
+	if v.isUnlocked() != nil || v.autoLock == nil {
+		v.mu.Unlock()
+		return
+	}
+	idle := time.Since(time.Unix(0, v.lastUsed.Load()))
+	if idle < v.autoLockAfter {
+		v.autoLock.Reset(v.autoLockAfter - idle)
+		v.mu.Unlock()
+		return
+	}
+	v.lock()
+	v.mu.Unlock()
+
+	if v.onAutoLock != nil {
+		v.onAutoLock()
+	}
+}
+
+// decryptSeed decrypts the seed with the given ID into seed. The caller
+// is responsible for clearing it.
+// It is expected that the caller holds the mutex.
+func (v *Vault) decryptSeed(id SeedID, seed *[32]byte) error {
 	if err := v.isUnlocked(); err != nil {
-		return types.PrivateKey{}, err
+		return err
+	} else if v.readOnly != nil {
+		return v.readOnly
 	}
 
 	encryptedSeed, err := v.store.Seed(id)
 	if err != nil {
-		return types.PrivateKey{}, fmt.Errorf("failed to get seed: %w", err)
+		return fmt.Errorf("failed to get seed: %w", err)
 	}
 	defer clear(encryptedSeed)
 
-	var seed [32]byte
-	defer clear(seed[:])
-	buf, err := v.aead.Open(seed[:0], encryptedSeed[:v.aead.NonceSize()], encryptedSeed[v.aead.NonceSize():], nil)
+	if err := openSeed(v.aead, encryptedSeed, seed); err != nil {
+		v.recordDecryptFailure(id, err)
+		return err
+	}
+	return nil
+}
+
+// recordDecryptFailure makes the Vault read-only once too many seeds have
+// failed to decrypt. Failures after a successful unlock suggest corrupted
+// seeds or an incorrect key, so keys derived or signatures produced by the
+// Vault can no longer be trusted.
+// It is expected that the caller holds the mutex.
+func (v *Vault) recordDecryptFailure(id SeedID, err error) {
+	v.decryptFailures++
+	if v.decryptFailures < maxDecryptFailures || v.readOnly != nil {
+		return
+	}
+	v.readOnly = fmt.Errorf("%w: %d seeds failed to decrypt, last seed %d: %v", ErrReadOnly, v.decryptFailures, id, err)
+	if v.onReadOnly != nil {
+		go v.onReadOnly(v.readOnly)
+	}
+}
+
+// ReadOnly returns the reason the Vault refuses to sign, derive keys, or
+// add seeds, or nil if it does not. Once read-only, the Vault stays
+// read-only until it is recreated.
+func (v *Vault) ReadOnly() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.readOnly
+}
+
+// seedMAC returns the MAC used to detect duplicate seeds. It is expected
+// that the caller holds the mutex and the vault is unlocked.
+func (v *Vault) seedMAC(seed *[32]byte) (types.Hash256, error) {
+	return computeMAC(v.mac, seed)
+}
+
+// seedKeys derives the seed encryption and MAC keys from the Argon2 key
+// using the key derivation of the given version.
+func seedKeys(key []byte, version int) (cipher.AEAD, hash.Hash, error) {
+	encryptionKey, macKey := key, key
+	switch version {
+	case 0:
+	case 1:
+		var err error
+		encryptionKey, err = hkdf.Key(sha256.New, key, nil, "vaultd seed encryption", chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive encryption key: %w", err)
+		}
+		defer clear(encryptionKey)
+		macKey, err = hkdf.Key(sha256.New, key, nil, "vaultd seed mac", 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive MAC key: %w", err)
+		}
+		defer clear(macKey)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key version %d", version)
+	}
+
+	aead, err := chacha20poly1305.NewX(encryptionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create AEAD: %w", err)
+	}
+	mac, err := blake2b.New256(macKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create MAC: %w", err)
+	}
+	return aead, mac, nil
+}
+
+// openSeed decrypts an encrypted seed of any supported format into seed.
+func openSeed(aead cipher.AEAD, encryptedSeed []byte, seed *[32]byte) error {
+	if len(encryptedSeed) == 0 {
+		return fmt.Errorf("failed to decrypt seed: %w", ErrInvalidSize)
+	}
+
+	var buf []byte
+	var err error
+	switch format, ciphertext := encryptedSeed[0], encryptedSeed[1:]; format {
+	case seedFormatXChaCha20Poly1305:
+		if len(ciphertext) < aead.NonceSize() {
+			return fmt.Errorf("failed to decrypt seed: %w", ErrInvalidSize)
+		}
+		buf, err = aead.Open(seed[:0], ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+	default:
+		return fmt.Errorf("unsupported seed encryption format %d", format)
+	}
 	if err != nil {
-		return types.PrivateKey{}, fmt.Errorf("failed to decrypt seed: %w", err)
+		return fmt.Errorf("failed to decrypt seed: %w", err)
 	} else if len(buf) != 32 {
 		panic(fmt.Errorf("unexpected seed size %d: %w", len(buf), ErrInvalidSize)) // developer error
 	}
+	return nil
+}
+
+// sealSeed encrypts a seed with a random nonce using the current format.
+func sealSeed(aead cipher.AEAD, seed *[32]byte) []byte {
+	n := aead.NonceSize()
+	buf := make([]byte, 1+n, 1+n+len(seed)+aead.Overhead())
+	buf[0] = currentSeedFormat
+	frand.Read(buf[1:])
+	return aead.Seal(buf, buf[1:], seed[:], nil)
+}
+
+// computeMAC returns the MAC of a seed.
+func computeMAC(mac hash.Hash, seed *[32]byte) (types.Hash256, error) {
+	mac.Reset()
+	if _, err := mac.Write(seed[:]); err != nil {
+		return types.Hash256{}, fmt.Errorf("failed to write seed to mac: %w", err)
+	}
+	return types.Hash256(mac.Sum(nil)), nil
+}
+
+// derivePrivateKey derives a private key from the seed ID and index.
+// It is expected that the caller holds the mutex.
+func (v *Vault) derivePrivateKey(id SeedID, index uint64) (types.PrivateKey, error) {
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := v.decryptSeed(id, &seed); err != nil {
+		return types.PrivateKey{}, err
+	}
 	return wallet.KeyFromSeed(&seed, index), nil
 }
 
+// scanAhead derives up to lookAhead keys past the last derived index of
+// each seed, searching for pk. If pk is found, every key up to and
+// including it is added to the store so later derivations continue after
+// it. If pk is not found, [ErrNotFound] is returned.
+// It is expected that the caller holds the mutex.
+func (v *Vault) scanAhead(pk types.PublicKey) (SeedID, uint64, error) {
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		seeds, err := v.store.Seeds(pageSize, offset)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get seeds: %w", err)
+		}
+
+		for _, meta := range seeds {
+			index, found, err := v.scanSeed(meta.ID, pk)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to scan seed %d: %w", meta.ID, err)
+			} else if found {
+				return meta.ID, index, nil
+			}
+		}
+
+		if len(seeds) < pageSize {
+			return 0, 0, ErrNotFound
+		}
+	}
+}
+
+// scanSeed searches the next lookAhead keys of a single seed for pk.
+// It is expected that the caller holds the mutex.
+func (v *Vault) scanSeed(id SeedID, pk types.PublicKey) (uint64, bool, error) {
+	start, err := v.store.NextIndex(id)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get next index: %w", err)
+	}
+
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := v.decryptSeed(id, &seed); err != nil {
+		return 0, false, err
+	}
+
+	for index := start; index < start+v.lookAhead; index++ {
+		sk := wallet.KeyFromSeed(&seed, index)
+		derived := sk.PublicKey()
+		clear(sk)
+		if derived != pk {
+			continue
+		}
+
+		pks := make([]types.PublicKey, 0, index-start+1)
+		for i := start; i <= index; i++ {
+			sk := wallet.KeyFromSeed(&seed, i)
+			pks = append(pks, sk.PublicKey())
+			clear(sk)
+		}
+		if err := v.store.AddKeyIndices(id, start, pks); err != nil {
+			return 0, false, fmt.Errorf("failed to add key indices: %w", err)
+		}
+		return index, true, nil
+	}
+	return 0, false, nil
+}
+
 // Sign returns the signature for a hash. If the key is not
 // found, it returns [ErrNotFound].
 func (v *Vault) Sign(pk types.PublicKey, hash types.Hash256) (types.Signature, error) {
+	return v.SignChecked(pk, hash, nil)
+}
+
+// SignChecked is like [Vault.Sign], but calls check with the ID of the
+// key's seed before signing. If check returns an error, the hash is not
+// signed and the error is returned.
+func (v *Vault) SignChecked(pk types.PublicKey, hash types.Hash256, check func(SeedID) error) (types.Signature, error) {
 	done, err := v.tg.Add()
 	if err != nil {
 		return types.Signature{}, err
 	}
 	defer done()
 
+	v.touch()
 	seedID, index, err := v.store.SigningKeyIndex(pk)
+	if errors.Is(err, ErrNotFound) && v.lookAhead > 0 {
+		v.mu.Lock()
+		seedID, index, err = v.scanAhead(pk)
+		v.mu.Unlock()
+	}
 	if err != nil {
 		return types.Signature{}, fmt.Errorf("failed to get signing key: %w", err)
 	}
 
+	expiresAt, err := v.store.KeyExpiration(seedID, pk)
+	if err != nil {
+		return types.Signature{}, fmt.Errorf("failed to get key expiration: %w", err)
+	} else if !expiresAt.IsZero() && !time.Now().Before(expiresAt) {
+		return types.Signature{}, fmt.Errorf("%w at %s", ErrExpired, expiresAt.Format(time.RFC3339))
+	} else if check != nil {
+		if err := check(seedID); err != nil {
+			return types.Signature{}, err
+		}
+	}
+
+	// the mutex guards the seed key against a concurrent lock and the
+	// decryption failure count
+	v.mu.Lock()
 	sk, err := v.derivePrivateKey(seedID, index)
+	v.mu.Unlock()
 	if err != nil {
 		return types.Signature{}, fmt.Errorf("failed to derive private key: %w", err)
 	}
@@ -167,6 +692,13 @@ func (v *Vault) Sign(pk types.PublicKey, hash types.Hash256) (types.Signature, e
 // AddSeed adds a seed to the Vault and returns its ID. If the seed has
 // already been added, the existing ID is returned.
 func (v *Vault) AddSeed(seed *[32]byte) (SeedMeta, error) {
+	return v.AddSeedWithLanguage(seed, "")
+}
+
+// AddSeedWithLanguage adds a seed to the Vault and records the language of
+// the phrase it was imported from. If the seed has already been added, the
+// existing ID is returned and its language is only set if it was unknown.
+func (v *Vault) AddSeedWithLanguage(seed *[32]byte, language string) (SeedMeta, error) {
 	done, err := v.tg.Add()
 	if err != nil {
 		return SeedMeta{}, err
@@ -178,20 +710,67 @@ func (v *Vault) AddSeed(seed *[32]byte) (SeedMeta, error) {
 
 	if err := v.isUnlocked(); err != nil {
 		return SeedMeta{}, err
+	} else if v.readOnly != nil {
+		return SeedMeta{}, v.readOnly
 	}
 
-	v.mac.Reset()
-	if _, err := v.mac.Write(seed[:]); err != nil {
-		return SeedMeta{}, fmt.Errorf("failed to write seed to mac: %w", err)
+	mac, err := v.seedMAC(seed)
+	if err != nil {
+		return SeedMeta{}, err
 	}
-	mac := types.Hash256(v.mac.Sum(nil))
 
-	n := v.aead.NonceSize()
-	buf := make([]byte, n, n+len(seed)+v.aead.Overhead())
-	frand.Read(buf[:n])
-	encrypted := v.aead.Seal(buf, buf, seed[:], nil)
+	encrypted := sealSeed(v.aead, seed)
 	defer clear(encrypted)
-	return v.store.AddSeed(mac, encrypted)
+	return v.store.AddSeed(mac, encrypted, language)
+}
+
+// PreviewSeed returns the effect of adding a seed without adding it. The
+// seed is matched against existing seeds, including deleted seeds, by its
+// MAC.
+func (v *Vault) PreviewSeed(seed *[32]byte) (SeedPreview, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return SeedPreview{}, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.isUnlocked(); err != nil {
+		return SeedPreview{}, err
+	}
+
+	mac, err := v.seedMAC(seed)
+	if err != nil {
+		return SeedPreview{}, err
+	}
+	meta, deletedAt, err := v.store.SeedByMAC(mac)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return SeedPreview{Action: ImportCreate}, nil
+	case err != nil:
+		return SeedPreview{}, fmt.Errorf("failed to get seed: %w", err)
+	case !deletedAt.IsZero():
+		return SeedPreview{Action: ImportRestore, Seed: meta}, nil
+	default:
+		return SeedPreview{Action: ImportExists, Seed: meta}, nil
+	}
+}
+
+// PreviewRestoreSeed returns the metadata of a deleted seed that can be
+// restored without restoring it. If the seed is not deleted or its
+// retention period has passed, [ErrNotFound] is returned.
+func (v *Vault) PreviewRestoreSeed(id SeedID) (SeedMeta, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return SeedMeta{}, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.DeletedSeed(id, time.Now().Add(-v.seedRetention))
 }
 
 // Seeds returns a paginated list of seeds. The list is
@@ -222,11 +801,26 @@ func (v *Vault) SeedMeta(id SeedID) (SeedMeta, error) {
 	return v.store.SeedMeta(id)
 }
 
-// SeedKeys returns a paginated list of public keys derived from the seed.
-func (v *Vault) SeedKeys(id SeedID, offset, limit int) ([]types.PublicKey, error) {
+// NextIndex returns the next index of the seed that has not been derived
+// or reserved. If the seed ID is not found, [ErrNotFound] is returned.
+func (v *Vault) NextIndex(id SeedID) (uint64, error) {
 	done, err := v.tg.Add()
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.NextIndex(id)
+}
+
+// SeedKeys returns a paginated list of public keys derived from the seed
+// and the total number of keys derived from it.
+func (v *Vault) SeedKeys(id SeedID, offset, limit int) ([]types.PublicKey, int, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, 0, err
 	}
 	defer done()
 
@@ -235,32 +829,344 @@ func (v *Vault) SeedKeys(id SeedID, offset, limit int) ([]types.PublicKey, error
 	return v.store.SeedKeys(id, offset, limit)
 }
 
+// Keys returns a paginated list of the keys of every seed, sorted by seed
+// ID and index, and the total number of keys.
+func (v *Vault) Keys(offset, limit int) ([]KeyMeta, int, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.Keys(KeyFilter{}, offset, limit)
+}
+
+// FilterKeys returns a paginated list of the keys of every seed matching
+// the filter, sorted by seed ID and index, and the total number of
+// matching keys.
+func (v *Vault) FilterKeys(filter KeyFilter, offset, limit int) ([]KeyMeta, int, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.Keys(filter, offset, limit)
+}
+
+// ExportSeeds returns the keys of every seed that has not been deleted,
+// read in a single transaction. If the key at index 0 of a seed is not
+// stored, it is derived if the Vault is unlocked. If the seeds have more
+// than maxKeys keys, [ErrExportLimit] is returned.
+func (v *Vault) ExportSeeds(maxKeys int) ([]SeedExport, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	seeds, err := v.store.ExportSeeds(maxKeys)
+	if err != nil {
+		return nil, err
+	}
+	for i := range seeds {
+		if len(seeds[i].Keys) > 0 && seeds[i].Keys[0].Index == 0 {
+			seeds[i].FirstKey = seeds[i].Keys[0].PublicKey
+		} else if v.isUnlocked() == nil {
+			sk, err := v.derivePrivateKey(seeds[i].ID, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to derive first key of seed %d: %w", seeds[i].ID, err)
+			}
+			seeds[i].FirstKey = sk.PublicKey()
+			clear(sk[:])
+		}
+	}
+	return seeds, nil
+}
+
+// SetSeedExpiration sets the time after which the seed's keys cannot sign.
+// A zero time removes the expiration. If the seed ID is not found,
+// [ErrNotFound] is returned.
+func (v *Vault) SetSeedExpiration(id SeedID, expiresAt time.Time) error {
+	done, err := v.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.SetSeedExpiration(id, expiresAt)
+}
+
+// SetKeyExpiration sets the time after which the key cannot sign, even if
+// its seed has not expired. A zero time removes the expiration. If the key
+// is not found, [ErrNotFound] is returned.
+func (v *Vault) SetKeyExpiration(pk types.PublicKey, expiresAt time.Time) error {
+	done, err := v.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.SetKeyExpiration(pk, expiresAt)
+}
+
+// Expirations returns the seed and key expirations before the given time,
+// sorted by expiration, ASC.
+func (v *Vault) Expirations(before time.Time) ([]Expiration, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.Expirations(before)
+}
+
+// DeleteSeed deletes a seed. The seed is hidden from listings and cannot
+// be used for signing, but it can be restored with [Vault.RestoreSeed]
+// until the retention period passes and it is purged.
+func (v *Vault) DeleteSeed(id SeedID) error {
+	done, err := v.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.DeleteSeed(id)
+}
+
+// RestoreSeed restores a deleted seed. If the seed is not deleted or its
+// retention period has passed, [ErrNotFound] is returned.
+func (v *Vault) RestoreSeed(id SeedID) error {
+	done, err := v.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.RestoreSeed(id, time.Now().Add(-v.seedRetention))
+}
+
+// PurgeSeeds permanently removes seeds deleted longer than the retention
+// period ago and returns the number removed.
+func (v *Vault) PurgeSeeds() (int, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.PurgeSeeds(time.Now().Add(-v.seedRetention))
+}
+
+// CheckConsistency verifies that every signing key references an existing
+// seed, that each seed's indices have no gaps or duplicates, and that seed
+// MACs are unique. It returns every issue found.
+func (v *Vault) CheckConsistency() ([]ConsistencyIssue, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.CheckConsistency()
+}
+
+// AuditKeys re-derives a random sample of up to n stored keys and returns
+// the keys whose stored public key does not match the derived one. A
+// mismatch indicates store corruption or a seed that was encrypted
+// incorrectly. If the vault is locked, [ErrLocked] is returned.
+func (v *Vault) AuditKeys(n int) ([]KeyMeta, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.isUnlocked(); err != nil {
+		return nil, err
+	}
+
+	keys, err := v.store.SampleKeys(n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample keys: %w", err)
+	}
+
+	var mismatched []KeyMeta
+	for _, key := range keys {
+		sk, err := v.derivePrivateKey(key.SeedID, key.Index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key %d of seed %d: %w", key.Index, key.SeedID, err)
+		}
+		derived := sk.PublicKey()
+		clear(sk)
+		if derived != key.PublicKey {
+			mismatched = append(mismatched, key)
+		}
+	}
+	return mismatched, nil
+}
+
+// KeyByAddress returns the key whose v1 standard unlock hash or v2 public
+// key policy address matches addr. If no key matches, [ErrNotFound] is
+// returned.
+func (v *Vault) KeyByAddress(addr types.Address) (KeyMeta, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return KeyMeta{}, err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.KeyByAddress(addr)
+}
+
 // NextKey returns the next public key derived from the seed.
 func (v *Vault) NextKey(id SeedID) (types.PublicKey, error) {
-	done, err := v.tg.Add()
+	pks, err := v.NextKeys(id, 1)
 	if err != nil {
 		return types.PublicKey{}, err
 	}
+	return pks[0], nil
+}
+
+// NextKeys derives the next count public keys of the seed. The seed is
+// decrypted once and the keys are added in a single transaction.
+func (v *Vault) NextKeys(id SeedID, count uint64) ([]types.PublicKey, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return nil, err
+	}
 	defer done()
 
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	index, err := v.store.NextIndex(id)
+	v.touch()
+	// decrypt the seed before reserving so a locked vault does not waste
+	// indices
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := v.decryptSeed(id, &seed); err != nil {
+		return nil, err
+	}
+
+	start, err := v.store.ReserveIndices(id, count)
 	if err != nil {
-		return types.PublicKey{}, fmt.Errorf("failed to get next index: %w", err)
+		return nil, fmt.Errorf("failed to reserve indices: %w", err)
+	}
+
+	pks := make([]types.PublicKey, count)
+	for i := range pks {
+		sk := wallet.KeyFromSeed(&seed, start+uint64(i))
+		pks[i] = sk.PublicKey()
+		clear(sk)
+	}
+	if err := v.store.AddKeyIndices(id, start, pks); err != nil {
+		return nil, fmt.Errorf("failed to add key indices: %w", err)
+	}
+	return pks, nil
+}
+
+// ReserveIndices reserves the next n indices of the seed so that keys
+// can be derived from them outside of the vault. No keys are derived, so
+// the Vault does not need to be unlocked. It returns the first reserved
+// index.
+func (v *Vault) ReserveIndices(id SeedID, n uint64) (uint64, error) {
+	if n == 0 {
+		return 0, errors.New("at least one index must be reserved")
 	}
 
-	sk, err := v.derivePrivateKey(id, index)
+	done, err := v.tg.Add()
 	if err != nil {
-		return types.PublicKey{}, fmt.Errorf("failed to derive private key: %w", err)
+		return 0, err
 	}
-	defer clear(sk)
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.ReserveExternalIndices(id, n)
+}
+
+// RegisterKeys adds keys derived outside of the vault from reserved
+// indices. Each key is re-derived from its seed and compared before any
+// are added, so the Vault must be unlocked. If a key does not match,
+// [ErrKeyMismatch] is returned.
+func (v *Vault) RegisterKeys(keys []KeyMeta) error {
+	done, err := v.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.touch()
+	seeds := make(map[SeedID]*[32]byte)
+	defer func() {
+		for _, seed := range seeds {
+			clear(seed[:])
+		}
+	}()
+	for _, key := range keys {
+		seed, ok := seeds[key.SeedID]
+		if !ok {
+			seed = new([32]byte)
+			if err := v.decryptSeed(key.SeedID, seed); err != nil {
+				return err
+			}
+			seeds[key.SeedID] = seed
+		}
+		sk := wallet.KeyFromSeed(seed, key.Index)
+		pk := sk.PublicKey()
+		clear(sk)
+		if pk != key.PublicKey {
+			return fmt.Errorf("key %d of seed %d: %w", key.Index, key.SeedID, ErrKeyMismatch)
+		}
+	}
+	return v.store.RegisterKeys(keys)
+}
+
+// Initialized returns true if the Vault has been unlocked before. The first
+// unlock generates the salt that derives the seed encryption key from the
+// secret.
+func (v *Vault) Initialized() (bool, error) {
+	done, err := v.tg.Add()
+	if err != nil {
+		return false, err
+	}
+	defer done()
 
-	if err := v.store.AddKeyIndex(id, sk.PublicKey(), index); err != nil {
-		return types.PublicKey{}, fmt.Errorf("failed to add key index: %w", err)
+	salt, err := v.store.KeySalt()
+	if err != nil {
+		return false, fmt.Errorf("failed to get key salt: %w", err)
 	}
-	return sk.PublicKey(), nil
+	return len(salt) > 0, nil
 }
 
 // Unlock unlocks the Vault with the given secret. If the Vault is
@@ -280,6 +1186,13 @@ func (v *Vault) Unlock(secret string) error {
 		return ErrUnlocked
 	}
 
+	failed, last, err := v.store.FailedUnlocks()
+	if err != nil {
+		return fmt.Errorf("failed to get failed unlock attempts: %w", err)
+	} else if wait := time.Until(last.Add(unlockBackoff(failed))); wait > 0 {
+		return fmt.Errorf("%w, try again in %v", ErrTooManyAttempts, wait.Round(time.Second))
+	}
+
 	salt, err := v.store.KeySalt()
 	if err != nil {
 		return fmt.Errorf("failed to get key salt: %w", err)
@@ -290,15 +1203,21 @@ func (v *Vault) Unlock(secret string) error {
 		}
 	}
 
-	encryptionKey := argon2.IDKey([]byte(secret), salt, 3, 64*1024, 4, 32)
-	aead, err := chacha20poly1305.NewX(encryptionKey)
-	if err != nil {
-		return fmt.Errorf("failed to create AEAD: %w", err)
+	key := argon2.IDKey([]byte(secret), salt, 3, 64*1024, 4, 32)
+	defer clear(key)
+	if v.hardwareToken != nil {
+		if err := mixHardwareToken(key, v.hardwareToken); err != nil {
+			return err
+		}
 	}
 
-	mac, err := blake2b.New256(encryptionKey)
+	version, err := v.store.KeyVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get key version: %w", err)
+	}
+	aead, mac, err := seedKeys(key, version)
 	if err != nil {
-		return fmt.Errorf("failed to create MAC: %w", err)
+		return err
 	}
 
 	buf, err := v.store.BytesForVerify()
@@ -307,17 +1226,121 @@ func (v *Vault) Unlock(secret string) error {
 	} else if err == nil {
 		defer clear(buf)
 
-		_, err := aead.Open(buf[aead.NonceSize():], buf[:aead.NonceSize()], buf[aead.NonceSize():], nil)
-		if err != nil {
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := openSeed(aead, buf, &seed); err != nil {
 			if strings.Contains(err.Error(), "message authentication failed") {
+				if _, err := v.store.RecordFailedUnlock(time.Now()); err != nil {
+					return fmt.Errorf("failed to record failed unlock attempt: %w", err)
+				}
 				return ErrIncorrectSecret
 			}
 			return fmt.Errorf("failed to verify encryption key: %w", err)
 		}
 	}
 
+	if version < currentKeyVersion {
+		// rekey the seeds now that the secret is verified
+		newAEAD, newMAC, err := seedKeys(key, currentKeyVersion)
+		if err != nil {
+			return err
+		}
+		err = v.store.RekeySeeds(currentKeyVersion, func(encryptedSeed []byte) (types.Hash256, []byte, error) {
+			var seed [32]byte
+			defer clear(seed[:])
+			if err := openSeed(aead, encryptedSeed, &seed); err != nil {
+				return types.Hash256{}, nil, err
+			}
+			mac, err := computeMAC(newMAC, &seed)
+			if err != nil {
+				return types.Hash256{}, nil, err
+			}
+			return mac, sealSeed(newAEAD, &seed), nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to rekey seeds: %w", err)
+		}
+		aead, mac, version = newAEAD, newMAC, currentKeyVersion
+	}
+
+	if failed > 0 {
+		if err := v.store.ResetFailedUnlocks(); err != nil {
+			return fmt.Errorf("failed to reset failed unlock attempts: %w", err)
+		}
+	}
+
 	v.aead = aead
 	v.mac = mac
+	v.keyVersion = version
+	v.decryptFailures = 0
+	v.notifyLockChanged()
+	if v.autoLockAfter > 0 {
+		v.touch()
+		v.autoLock = time.AfterFunc(v.autoLockAfter, v.checkAutoLock)
+	}
+	return nil
+}
+
+// mixHardwareToken challenges the hardware token and mixes its response
+// into the key, so the secret alone cannot unlock the Vault.
+func mixHardwareToken(key []byte, token ChallengeResponder) error {
+	h, err := blake2b.New256(key)
+	if err != nil {
+		return err
+	}
+	h.Write([]byte("vaultd hardware token challenge"))
+	resp, err := token(h.Sum(nil))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrHardwareToken, err)
+	}
+	defer clear(resp)
+	if len(resp) == 0 {
+		return fmt.Errorf("%w: empty response", ErrHardwareToken)
+	}
+
+	h, _ = blake2b.New256(nil)
+	h.Write(key)
+	h.Write(resp)
+	h.Sum(key[:0])
+	return nil
+}
+
+// unlockBackoff returns how long to wait after the last of n consecutive
+// failed unlock attempts before the next attempt.
+func unlockBackoff(n int) time.Duration {
+	if n < unlockBackoffThreshold {
+		return 0
+	}
+	shift := n - unlockBackoffThreshold
+	if shift >= 32 {
+		return maxUnlockBackoff
+	}
+	return min(unlockBackoffBase<<shift, maxUnlockBackoff)
+}
+
+// SyncKeyVersion locks the Vault if the stored seeds no longer use the
+// key version it was unlocked with, for example after a snapshot taken
+// before an upgrade is restored. The seeds are rekeyed when the Vault is
+// next unlocked.
+func (v *Vault) SyncKeyVersion() error {
+	done, err := v.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.isUnlocked() != nil {
+		return nil
+	}
+
+	version, err := v.store.KeyVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get key version: %w", err)
+	} else if version != v.keyVersion {
+		v.lock()
+	}
 	return nil
 }
 
@@ -333,14 +1356,67 @@ func (v *Vault) Lock() {
 
 	v.mu.Lock()
 	defer v.mu.Unlock()
-	v.aead = nil
-	v.mac = nil
+	v.lock()
+}
+
+// WithLookAhead sets the number of keys past the last derived index of
+// each seed that are searched when signing with an unknown public key.
+// Keys derived by an external system ahead of the vault can then still be
+// used for signing. The default, zero, disables the search.
+func WithLookAhead(n uint64) Option {
+	return func(v *Vault) {
+		v.lookAhead = n
+	}
+}
+
+// WithAutoLock locks the Vault when no key has been used to sign or been
+// derived for the given duration. onLock, if not nil, is called after the
+// Vault locks itself. Zero, the default, disables auto-locking.
+func WithAutoLock(after time.Duration, onLock func()) Option {
+	return func(v *Vault) {
+		v.autoLockAfter = after
+		v.onAutoLock = onLock
+	}
+}
+
+// WithReadOnlyHandler calls fn with the reason when the Vault becomes
+// read-only after repeated seed decryption failures.
+func WithReadOnlyHandler(fn func(error)) Option {
+	return func(v *Vault) {
+		v.onReadOnly = fn
+	}
+}
+
+// WithSeedRetention sets how long deleted seeds can be restored before
+// they are purged. The default is [DefaultSeedRetention].
+func WithSeedRetention(d time.Duration) Option {
+	return func(v *Vault) {
+		v.seedRetention = d
+	}
+}
+
+// WithHardwareToken requires a hardware token to unlock the Vault. Its
+// response to a challenge derived from the secret is mixed into the seed
+// encryption key, so unlocking requires both the secret and the token.
+// The token must be required from the first unlock; a Vault initialized
+// without it cannot be unlocked with it, and vice versa.
+func WithHardwareToken(cr ChallengeResponder) Option {
+	return func(v *Vault) {
+		v.hardwareToken = cr
+	}
 }
 
 // New creates a new Vault.
-func New(s Store) *Vault {
-	return &Vault{
+func New(s Store, opts ...Option) *Vault {
+	v := &Vault{
 		tg:    threadgroup.New(),
 		store: s,
+
+		seedRetention: DefaultSeedRetention,
+		lockChanged:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(v)
 	}
+	return v
 }
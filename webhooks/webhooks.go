@@ -0,0 +1,279 @@
+// Package webhooks delivers vault events to registered HTTP callbacks so
+// external systems can react to them in near real time.
+package webhooks
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+// Scopes select the events a webhook receives.
+const (
+	// ScopeAll receives every event.
+	ScopeAll = "all"
+	// ScopeVault receives lock and unlock events.
+	ScopeVault = "vault"
+	// ScopeSeeds receives seed events.
+	ScopeSeeds = "seeds"
+	// ScopeSigning receives an event for every successful sign request.
+	ScopeSigning = "signing"
+)
+
+// Event types
+const (
+	EventUnlocked  = "vault.unlocked"
+	EventLocked    = "vault.locked"
+	EventSeedAdded = "seed.added"
+	EventSigned    = "signing.signed"
+	EventTest      = "test"
+)
+
+// deliveryTimeout is the maximum time to deliver an event to a webhook.
+const deliveryTimeout = 10 * time.Second
+
+var (
+	// ErrNotFound is returned when a webhook is not found.
+	ErrNotFound = errors.New("webhook not found")
+)
+
+type (
+	// An ID is a unique identifier for a webhook.
+	ID int64
+
+	// A Webhook is a callback URL that receives the events in its scopes.
+	// Events are sent with the secret key as the basic auth password so
+	// the receiver can verify their origin.
+	Webhook struct {
+		ID          ID        `json:"id"`
+		CallbackURL string    `json:"callbackURL"`
+		Scopes      []string  `json:"scopes"`
+		SecretKey   string    `json:"secretKey"`
+		CreatedAt   time.Time `json:"createdAt"`
+	}
+
+	// An Event is the body POSTed to a webhook's callback URL.
+	Event struct {
+		ID        types.Hash256 `json:"id"`
+		Event     string        `json:"event"`
+		Scope     string        `json:"scope"`
+		Data      any           `json:"data"`
+		Timestamp time.Time     `json:"timestamp"`
+	}
+
+	// A Store persists webhooks.
+	Store interface {
+		// Webhooks returns every webhook sorted by creation time, ASC.
+		Webhooks() ([]Webhook, error)
+		// AddWebhook adds a new webhook.
+		AddWebhook(callbackURL, secretKey string, scopes []string) (Webhook, error)
+		// UpdateWebhook updates the callback URL and scopes of a webhook.
+		// If the webhook is not found, [ErrNotFound] is returned.
+		UpdateWebhook(id ID, callbackURL string, scopes []string) (Webhook, error)
+		// DeleteWebhook removes a webhook. If the webhook is not found,
+		// [ErrNotFound] is returned.
+		DeleteWebhook(ID) error
+	}
+
+	// A Manager registers webhooks and broadcasts events to them.
+	Manager struct {
+		store  Store
+		log    *zap.Logger
+		client *http.Client
+
+		wg sync.WaitGroup
+
+		mu    sync.Mutex // protects hooks
+		hooks map[ID]Webhook
+	}
+)
+
+// validate returns an error if the callback URL is not an absolute HTTP(S)
+// URL or a scope is unknown.
+func validate(callbackURL string, scopes []string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callback URL must be an absolute http or https URL")
+	} else if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeAll, ScopeVault, ScopeSeeds, ScopeSigning:
+		default:
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+// send POSTs the event to the webhook's callback URL.
+func (m *Manager) send(hook Webhook, e Event) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.CallbackURL, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("", hook.SecretKey)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Webhooks returns every registered webhook sorted by ID, ASC.
+func (m *Manager) Webhooks() []Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hooks := make([]Webhook, 0, len(m.hooks))
+	for _, hook := range m.hooks {
+		hooks = append(hooks, hook)
+	}
+	slices.SortFunc(hooks, func(a, b Webhook) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return hooks
+}
+
+// Register adds a webhook with a new random secret key.
+func (m *Manager) Register(callbackURL string, scopes []string) (Webhook, error) {
+	if err := validate(callbackURL, scopes); err != nil {
+		return Webhook{}, err
+	}
+	hook, err := m.store.AddWebhook(callbackURL, hex.EncodeToString(frand.Bytes(16)), scopes)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to add webhook: %w", err)
+	}
+	m.mu.Lock()
+	m.hooks[hook.ID] = hook
+	m.mu.Unlock()
+	return hook, nil
+}
+
+// Update changes the callback URL and scopes of a webhook. If the webhook
+// is not found, [ErrNotFound] is returned.
+func (m *Manager) Update(id ID, callbackURL string, scopes []string) (Webhook, error) {
+	if err := validate(callbackURL, scopes); err != nil {
+		return Webhook{}, err
+	}
+	hook, err := m.store.UpdateWebhook(id, callbackURL, scopes)
+	if err != nil {
+		return Webhook{}, err
+	}
+	m.mu.Lock()
+	m.hooks[hook.ID] = hook
+	m.mu.Unlock()
+	return hook, nil
+}
+
+// Delete removes a webhook. If the webhook is not found, [ErrNotFound] is
+// returned.
+func (m *Manager) Delete(id ID) error {
+	if err := m.store.DeleteWebhook(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.hooks, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// Test synchronously sends a test event to a webhook and returns the
+// delivery error, if any. If the webhook is not found, [ErrNotFound] is
+// returned.
+func (m *Manager) Test(id ID) error {
+	m.mu.Lock()
+	hook, ok := m.hooks[id]
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return m.send(hook, Event{
+		ID:        frand.Entropy256(),
+		Event:     EventTest,
+		Scope:     ScopeAll,
+		Timestamp: time.Now(),
+	})
+}
+
+// BroadcastEvent sends the event in the background to every webhook with
+// the event's scope. Delivery failures are logged.
+func (m *Manager) BroadcastEvent(event, scope string, data any) {
+	e := Event{
+		ID:        frand.Entropy256(),
+		Event:     event,
+		Scope:     scope,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, hook := range m.hooks {
+		if !slices.Contains(hook.Scopes, ScopeAll) && !slices.Contains(hook.Scopes, scope) {
+			continue
+		}
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.send(hook, e); err != nil {
+				m.log.Warn("failed to deliver webhook event", zap.Int64("webhook", int64(hook.ID)), zap.String("event", e.Event), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// Close waits for pending deliveries to complete.
+func (m *Manager) Close() error {
+	m.wg.Wait()
+	return nil
+}
+
+// NewManager returns a Manager that broadcasts events to the webhooks in
+// the store.
+func NewManager(s Store, log *zap.Logger) (*Manager, error) {
+	hooks, err := s.Webhooks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	m := &Manager{
+		store:  s,
+		log:    log,
+		client: &http.Client{Timeout: deliveryTimeout},
+		hooks:  make(map[ID]Webhook, len(hooks)),
+	}
+	for _, hook := range hooks {
+		m.hooks[hook.ID] = hook
+	}
+	return m, nil
+}